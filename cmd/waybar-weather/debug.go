@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux || freebsd || darwin || windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+// logMemoryStats logs the memory usage and the number of goroutines at the given interval until the
+// context ends, so that the footprint of the daemon can be watched over a long run.
+func logMemoryStats(ctx context.Context, interval time.Duration, log *logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		attrs := []any{
+			slog.Uint64("heap_alloc", stats.HeapAlloc),
+			slog.Uint64("heap_objects", stats.HeapObjects),
+			slog.Uint64("sys", stats.Sys),
+			slog.Uint64("gc_cycles", uint64(stats.NumGC)),
+			slog.Int("goroutines", runtime.NumGoroutine()),
+		}
+		if rss, err := residentSetSize(); err == nil {
+			attrs = append(attrs, slog.Uint64("rss", rss))
+		}
+		log.Info("memory statistics", attrs...)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// residentSetSize returns the resident set size of the process in bytes.
+func residentSetSize() (uint64, error) {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read process memory statistics: %w", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, errors.New("unexpected format of process memory statistics")
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse resident set size: %w", err)
+	}
+	return pages * uint64(os.Getpagesize()), nil //nolint:gosec
+}
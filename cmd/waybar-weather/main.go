@@ -2,7 +2,7 @@
 //
 // SPDX-License-Identifier: MIT
 
-//go:build linux
+//go:build linux || freebsd || darwin || windows
 
 // Package main implements the waybar-weather service.
 package main
@@ -17,6 +17,7 @@ import (
 	"syscall"
 
 	"github.com/wneessen/waybar-weather/internal/config"
+	"github.com/wneessen/waybar-weather/internal/control"
 	"github.com/wneessen/waybar-weather/internal/i18n"
 	"github.com/wneessen/waybar-weather/internal/logger"
 	"github.com/wneessen/waybar-weather/internal/service"
@@ -38,7 +39,23 @@ func main() {
 
 	// Read config
 	confPath := flag.String("config", "", "path to the config file")
+	pprofAddr := flag.String("pprof", "", "serve runtime profiles on the given address, e.g. localhost:6060")
+	once := flag.Bool("once", false, "exit after the first output, e.g. for status bars that run a command periodically")
+	soak := flag.Duration("soak", 0, "log memory statistics at the given interval, e.g. for long-run tests")
 	flag.Parse()
+	var args []string
+	for flag.NArg() > 0 {
+		// Allow flags after the command and its arguments as well
+		args = append(args, flag.Arg(0))
+		if err := flag.CommandLine.Parse(flag.Args()[1:]); err != nil {
+			log.Error("failed to parse command line flags", logger.Err(err))
+			os.Exit(1)
+		}
+	}
+	var command string
+	if len(args) > 0 {
+		command = args[0]
+	}
 	conf, err := config.New()
 	if err != nil {
 		log.Error("failed to load config", logger.Err(err))
@@ -53,7 +70,55 @@ func main() {
 			os.Exit(1)
 		}
 	}
+
+	switch command {
+	case "":
+	case "gen-waybar-config":
+		if err = writeWaybarConfig(os.Stdout, conf, *confPath); err != nil {
+			log.Error("failed to generate Waybar config", logger.Err(err))
+			os.Exit(1)
+		}
+		return
+	case "status":
+		if err = writeStatus(os.Stdout, conf); err != nil {
+			log.Error("failed to show status", logger.Err(err))
+			os.Exit(1)
+		}
+		return
+	case "backend":
+		if len(args) != 2 {
+			log.Error("usage: waybar-weather backend <name>")
+			os.Exit(1)
+		}
+		if err = control.SwitchBackend(conf.SocketPath(), args[1]); err != nil {
+			log.Error("failed to switch weather backend", logger.Err(err))
+			os.Exit(1)
+		}
+		return
+	case "serve":
+		if len(conf.Instances) == 0 {
+			log.Error("no instances configured, nothing to serve")
+			os.Exit(1)
+		}
+	case "attach":
+		if len(args) != 2 {
+			log.Error("usage: waybar-weather attach <instance>")
+			os.Exit(1)
+		}
+	default:
+		log.Error("unknown command", slog.String("command", command))
+		os.Exit(1)
+	}
+
 	log = logger.NewLogger(conf.LogLevel)
+	log.SetPrivacy(conf.LogCoordinates)
+
+	if command == "attach" {
+		// Write errors for a closed stdout instead of terminating the process with SIGPIPE
+		signal.Notify(make(chan os.Signal, 1), syscall.SIGPIPE)
+		control.Attach(ctx, conf.SocketPath(), args[1], os.Stdout, log)
+		return
+	}
 
 	t, err := i18n.New(conf.Locale)
 	if err != nil {
@@ -68,6 +133,21 @@ func main() {
 		os.Exit(1)
 	}
 
+	if command == "serve" {
+		serv.DisableStdout()
+	}
+	if *once {
+		serv.ExitAfterFirstOutput()
+	}
+
+	// Debugging aids for the memory footprint in long runs
+	if *pprofAddr != "" {
+		go servePprof(ctx, *pprofAddr, log)
+	}
+	if *soak > 0 {
+		go logMemoryStats(ctx, *soak, log)
+	}
+
 	// Start the service loop
 	log.Info(t.Get("starting waybar-weather service"), slog.String("version", version),
 		slog.String("commit", commit), slog.String("date", date))
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build (linux || freebsd || darwin || windows) && !nopprof
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+// pprofShutdownTimeout is the maximum time the pprof server may take to shut down
+const pprofShutdownTimeout = 5 * time.Second
+
+// servePprof serves the runtime profiles on the given address until the context ends. The address
+// should be a loopback address, since the profiles are served without authentication.
+func servePprof(ctx context.Context, addr string, log *logger.Logger) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		ctxShutdown, cancelShutdown := context.WithTimeout(context.Background(), pprofShutdownTimeout)
+		defer cancelShutdown()
+		_ = server.Shutdown(ctxShutdown)
+	}()
+	log.Info("serving runtime profiles", slog.String("address", "http://"+addr+"/debug/pprof/"))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error("failed to serve runtime profiles", logger.Err(err))
+	}
+}
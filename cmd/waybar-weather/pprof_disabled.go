@@ -0,0 +1,18 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build (linux || freebsd || darwin || windows) && nopprof
+
+package main
+
+import (
+	"context"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+// servePprof is a no-op, since the runtime profiles are not part of builds with the nopprof tag.
+func servePprof(_ context.Context, _ string, log *logger.Logger) {
+	log.Warn("runtime profiles are not available in this build, rebuild without the nopprof tag")
+}
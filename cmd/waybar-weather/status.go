@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux || freebsd || darwin || windows
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/config"
+	"github.com/wneessen/waybar-weather/internal/control"
+	"github.com/wneessen/waybar-weather/internal/service"
+)
+
+// writeStatus requests the status of the running daemon and writes it as human-readable tables to w.
+func writeStatus(w io.Writer, conf *config.Config) error {
+	raw, err := control.Status(conf.SocketPath())
+	if err != nil {
+		return fmt.Errorf("failed to request status, is waybar-weather running? %w", err)
+	}
+	var status service.Status
+	if err = json.Unmarshal(raw, &status); err != nil {
+		return fmt.Errorf("failed to parse status: %w", err)
+	}
+
+	now := time.Now()
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Running since\t%s\n", formatAge(status.Started, now))
+
+	_, _ = fmt.Fprintln(tw, "\nLOCATION\t")
+	if status.Location.Available {
+		_, _ = fmt.Fprintf(tw, "Coordinates\t%s\n", status.Location.Coordinates)
+		_, _ = fmt.Fprintf(tw, "Address\t%s\n", status.Location.Address)
+		_, _ = fmt.Fprintf(tw, "Source\t%s\n", status.Location.Source)
+		_, _ = fmt.Fprintf(tw, "Accuracy\t%.0f m\n", status.Location.Accuracy)
+		_, _ = fmt.Fprintf(tw, "Updated\t%s\n", formatAge(status.Location.UpdatedAt, now))
+		if status.Location.Speed != nil {
+			_, _ = fmt.Fprintf(tw, "Speed\t%.1f km/h\n", *status.Location.Speed*3.6)
+		}
+		if status.Location.Heading != nil {
+			_, _ = fmt.Fprintf(tw, "Heading\t%.0f°\n", *status.Location.Heading)
+		}
+	} else {
+		_, _ = fmt.Fprintln(tw, "Coordinates\tnot available yet")
+	}
+
+	_, _ = fmt.Fprintln(tw, "\nGEOLOCATION PROVIDER\tLAST RESULT\tACCURACY\tSTATE\tWORKER\tRESTARTS")
+	for _, provider := range status.Providers {
+		accuracy := "-"
+		if !provider.LastResult.IsZero() {
+			accuracy = fmt.Sprintf("%.0f m", provider.Accuracy)
+		}
+		state := "usable"
+		if !provider.ExcludedUntil.IsZero() {
+			state = "excluded until " + formatTime(provider.ExcludedUntil)
+		}
+		worker := provider.Worker
+		if worker == "" {
+			worker = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\n", provider.Name, formatAge(provider.LastResult, now),
+			accuracy, state, worker, provider.Restarts)
+	}
+
+	for _, provider := range status.Providers {
+		if provider.LastPanic != "" {
+			_, _ = fmt.Fprintf(tw, "Last panic of %s\t%s (%s)\n", provider.Name, provider.LastPanic,
+				formatAge(provider.LastPanicAt, now))
+		}
+	}
+
+	_, _ = fmt.Fprintln(tw, "\nWEATHER\t")
+	_, _ = fmt.Fprintf(tw, "Source\t%s\n", status.Weather.Source)
+	if !status.Weather.ExcludedUntil.IsZero() {
+		_, _ = fmt.Fprintf(tw, "Excluded until\t%s\n", formatTime(status.Weather.ExcludedUntil))
+	}
+	_, _ = fmt.Fprintf(tw, "Geocoder\t%s\n", status.Weather.Geocoder)
+	if quota := status.Weather.GeocoderQuota; quota != nil {
+		_, _ = fmt.Fprintf(tw, "Geocoder quota\t%d of %d remaining, resets %s\n", quota.Remaining, quota.Limit,
+			formatTime(quota.Reset))
+	}
+	_, _ = fmt.Fprintf(tw, "Last fetch\t%s (%s)\n", formatAge(status.Weather.LastAttempt, now),
+		status.Weather.Latency.Round(time.Millisecond))
+	_, _ = fmt.Fprintf(tw, "Last success\t%s\n", formatAge(status.Weather.LastSuccess, now))
+	_, _ = fmt.Fprintf(tw, "Data age\t%s\n", formatAge(status.Weather.DataTime, now))
+	if status.Weather.LastError != "" {
+		_, _ = fmt.Fprintf(tw, "Last error\t%s\n", status.Weather.LastError)
+	}
+
+	_, _ = fmt.Fprintln(tw, "\nJOB\tLAST RUN\tNEXT RUN")
+	for _, job := range status.Jobs {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", job.Name, formatAge(job.LastRun, now), formatTime(job.NextRun))
+	}
+
+	_, _ = fmt.Fprintln(tw, "\nCACHE\tHITS\tMISSES\tERRORS")
+	for _, stats := range status.Caches {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", stats.Namespace, stats.Hits, stats.Misses, stats.Errors)
+	}
+
+	_, _ = fmt.Fprintln(tw, "\nAPI HOST\tREQUESTS TODAY\tLIMIT")
+	for _, host := range status.Budget.Hosts {
+		limit := "unlimited"
+		if host.Limit > 0 {
+			limit = strconv.FormatUint(uint64(host.Limit), 10)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\n", host.Host, host.Requests, limit)
+	}
+	if status.Budget.Exhausted {
+		_, _ = fmt.Fprintln(tw, "Budget\texhausted, requests are refused until midnight")
+	}
+
+	_, _ = fmt.Fprintln(tw, "\nRECENT ERRORS\t")
+	if len(status.Errors) == 0 {
+		_, _ = fmt.Fprintln(tw, "none\t")
+	}
+	for _, record := range status.Errors {
+		_, _ = fmt.Fprintf(tw, "%s\t%s %s %s\n", formatTime(record.Time), record.Level, record.Message, record.Attrs)
+	}
+	return tw.Flush()
+}
+
+// formatAge returns the time with the time that has passed since then, or "never" for the zero time.
+func formatAge(t, now time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return fmt.Sprintf("%s (%s ago)", formatTime(t), now.Sub(t).Round(time.Second))
+}
+
+// formatTime returns the local time, or "-" for the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
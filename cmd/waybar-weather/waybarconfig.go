@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux || freebsd || darwin || windows
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/wneessen/waybar-weather/internal/config"
+)
+
+const waybarModuleName = "custom/weather"
+
+// waybarModule represents the Waybar custom module configuration for waybar-weather.
+type waybarModule struct {
+	Exec            string            `json:"exec"`
+	ReturnType      string            `json:"return-type"`
+	RestartInterval int               `json:"restart-interval"`
+	HideEmptyText   bool              `json:"hide-empty-text"`
+	Format          string            `json:"format,omitempty"`
+	FormatIcons     map[string]string `json:"format-icons,omitempty"`
+	OnClick         string            `json:"on-click"`
+}
+
+// writeWaybarConfig writes the recommended Waybar custom module configuration for the running executable
+// and the given configuration file to w.
+func writeWaybarConfig(w io.Writer, conf *config.Config, confPath string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to determine path of the executable: %w", err)
+	}
+	exec := executable
+	if confPath != "" {
+		absPath, err := filepath.Abs(confPath)
+		if err != nil {
+			return fmt.Errorf("failed to determine path of the config file: %w", err)
+		}
+		exec += " -config " + absPath
+	}
+
+	module := waybarModule{
+		Exec:            exec,
+		ReturnType:      "json",
+		RestartInterval: 60,
+		HideEmptyText:   true,
+		OnClick:         "pkill -USR1 " + filepath.Base(executable),
+	}
+
+	// With more than two display modes, the icon shows which mode is currently displayed
+	if conf.Templates.Daily != "" || conf.Templates.Astro != "" {
+		module.Format = "{icon} {}"
+		module.FormatIcons = map[string]string{"current": "🌡️", "hourly": "🕑", "daily": "📅", "astro": "🔭"}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	encoder.SetEscapeHTML(false)
+	if err = encoder.Encode(map[string]waybarModule{waybarModuleName: module}); err != nil {
+		return fmt.Errorf("failed to encode Waybar config: %w", err)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ require (
 	github.com/Xuanwo/go-locale v1.1.3
 	github.com/go-co-op/gocron/v2 v2.18.0
 	github.com/godbus/dbus/v5 v5.1.0
+	github.com/google/uuid v1.6.0
 	github.com/hectormalot/omgo v0.1.3
 	github.com/kkyr/fig v0.5.0
 	github.com/mattn/go-runewidth v0.0.19
@@ -22,7 +23,6 @@ require (
 	github.com/clipperhouse/stringish v0.1.1 // indirect
 	github.com/clipperhouse/uax29/v2 v2.3.0 // indirect
 	github.com/google/go-cmp v0.7.0 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/jonboulle/clockwork v0.5.0 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/mdlayher/genetlink v1.3.2 // indirect
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package cache implements a key-value cache with expiring entries and pluggable storage backends, which
+// is used for the results of the external APIs, e.g. the geocoding, elevation and weather lookups.
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Backend is implemented by all storage backends of the cache. Expired entries must not be returned.
+type Backend interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+}
+
+// Stats represents the usage of a namespace of the cache.
+type Stats struct {
+	Namespace string `json:"namespace"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Sets      uint64 `json:"sets"`
+	Errors    uint64 `json:"errors"`
+}
+
+// Store is a namespace of a cache backend, which stores values as JSON and counts its hits and misses.
+type Store struct {
+	backend   Backend
+	namespace string
+	ttl       time.Duration
+
+	hits, misses, sets, errors atomic.Uint64
+}
+
+// NewStore returns a new Store for the namespace of the backend. Entries expire after the given TTL.
+func NewStore(backend Backend, namespace string, ttl time.Duration) *Store {
+	return &Store{backend: backend, namespace: namespace, ttl: ttl}
+}
+
+// Get decodes the cached value of the key into v. Returns false if the key is not cached, has expired or
+// the value could not be decoded.
+func (s *Store) Get(key string, v any) bool {
+	data, ok := s.backend.Get(s.key(key))
+	if !ok {
+		s.misses.Add(1)
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		s.errors.Add(1)
+		s.misses.Add(1)
+		return false
+	}
+	s.hits.Add(1)
+	return true
+}
+
+// Set caches the value of the key.
+func (s *Store) Set(key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.errors.Add(1)
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	if err = s.backend.Set(s.key(key), data, s.ttl); err != nil {
+		s.errors.Add(1)
+		return fmt.Errorf("failed to store cache entry: %w", err)
+	}
+	s.sets.Add(1)
+	return nil
+}
+
+// Delete removes the key from the cache.
+func (s *Store) Delete(key string) error {
+	return s.backend.Delete(s.key(key))
+}
+
+// Stats returns the usage of the namespace.
+func (s *Store) Stats() Stats {
+	return Stats{
+		Namespace: s.namespace,
+		Hits:      s.hits.Load(),
+		Misses:    s.misses.Load(),
+		Sets:      s.sets.Load(),
+		Errors:    s.errors.Load(),
+	}
+}
+
+// key returns the key prefixed with the namespace.
+func (s *Store) key(key string) string {
+	return s.namespace + "/" + key
+}
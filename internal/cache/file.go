@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// File is a Backend that stores every entry in a file of a directory, so that the entries survive a
+// restart. The file names are derived from the keys, which may contain coordinates.
+type File struct {
+	dir string
+
+	mu         sync.Mutex
+	lastPurged time.Time
+}
+
+// NewFile returns a new File backend for the given directory, which is created if it doesn't exist.
+func NewFile(dir string) (*File, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &File{dir: dir}, nil
+}
+
+// Get returns the value of the key, if it is cached and not expired. Expired entries are removed.
+func (f *File) Get(key string) ([]byte, bool) {
+	data, err := os.ReadFile(f.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var e entry
+	if err = json.Unmarshal(data, &e); err != nil || e.expired() {
+		_ = f.Delete(key)
+		return nil, false
+	}
+	return e.Value, true
+}
+
+// Set stores the value of the key. A TTL of 0 keeps the entry until it is deleted.
+func (f *File) Set(key string, value []byte, ttl time.Duration) error {
+	data, err := json.Marshal(newEntry(value, ttl))
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	tmp, err := os.CreateTemp(f.dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close cache file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), f.path(key)); err != nil {
+		return fmt.Errorf("failed to replace cache file: %w", err)
+	}
+	f.purge()
+	return nil
+}
+
+// purge removes the files of all expired entries. The files are checked at most every purgeInterval.
+func (f *File) purge() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if time.Since(f.lastPurged) < purgeInterval {
+		return
+	}
+	f.lastPurged = time.Now()
+
+	files, err := filepath.Glob(filepath.Join(f.dir, "*.json"))
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var e entry
+		if err = json.Unmarshal(data, &e); err != nil || e.expired() {
+			_ = os.Remove(file)
+		}
+	}
+}
+
+// Delete removes the key.
+func (f *File) Delete(key string) error {
+	if err := os.Remove(f.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove cache file: %w", err)
+	}
+	return nil
+}
+
+// path returns the path of the file of the key.
+func (f *File) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(f.dir, hex.EncodeToString(sum[:])+".json")
+}
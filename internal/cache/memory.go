@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// purgeInterval is the minimum time between two removals of all expired entries.
+const purgeInterval = 10 * time.Minute
+
+// entry represents a cached value with its expiry.
+type entry struct {
+	Value   []byte    `json:"value"`
+	Expires time.Time `json:"expires"`
+}
+
+// expired returns true if the entry has expired.
+func (e entry) expired() bool {
+	return !e.Expires.IsZero() && time.Now().After(e.Expires)
+}
+
+// Memory is a Backend that keeps the entries in memory, so they are lost when the process ends.
+type Memory struct {
+	mu         sync.Mutex
+	entries    map[string]entry
+	lastPurged time.Time
+}
+
+// NewMemory returns a new, empty Memory backend.
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]entry)}
+}
+
+// Get returns the value of the key, if it is cached and not expired.
+func (m *Memory) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if e.expired() {
+		delete(m.entries, key)
+		return nil, false
+	}
+	return e.Value, true
+}
+
+// Set stores the value of the key. A TTL of 0 keeps the entry until the process ends.
+func (m *Memory) Set(key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = newEntry(value, ttl)
+	m.purge()
+	return nil
+}
+
+// purge removes all expired entries, so that entries that are never read again, e.g. the addresses of
+// the places passed on a trip, don't accumulate. The entries are checked at most every purgeInterval.
+// The caller is expected to hold the lock.
+func (m *Memory) purge() {
+	if time.Since(m.lastPurged) < purgeInterval {
+		return
+	}
+	m.lastPurged = time.Now()
+	for key, e := range m.entries {
+		if e.expired() {
+			delete(m.entries, key)
+		}
+	}
+}
+
+// Delete removes the key.
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+// newEntry returns a new entry for the value that expires after the TTL.
+func newEntry(value []byte, ttl time.Duration) entry {
+	e := entry{Value: value}
+	if ttl > 0 {
+		e.Expires = time.Now().Add(ttl)
+	}
+	return e
+}
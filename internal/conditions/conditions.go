@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package conditions provides the table of the WMO weather codes with their descriptions, icons and
+// categories, and the mappings of the condition codes of the weather backends to WMO weather codes. The
+// built-in table is embedded and can be overridden by a file in the same format.
+package conditions
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+)
+
+//go:embed conditions.json
+var builtin []byte
+
+// Groups lists the condition groups, a coarse classification of the weather codes for styling.
+var Groups = []string{"clear", "cloudy", "rain", "snow", "storm", "fog"}
+
+// categoryGroups maps the condition categories to their condition group, if they differ.
+var categoryGroups = map[string]string{"thunderstorm": "storm"}
+
+// Condition represents the presentation of a WMO weather code.
+type Condition struct {
+	// Description is the message ID of the localized description
+	Description string `json:"description"`
+	IconDay     string `json:"icon_day"`
+	IconNight   string `json:"icon_night"`
+	// Category is used as CSS class and for the hooks. Allowed values: clear, cloudy, fog, rain, snow,
+	// thunderstorm
+	Category string `json:"category"`
+	// Image is the name of the image icon of an icon theme, which is suffixed with "-day" or "-night" if
+	// ImageDayNight is set
+	Image         string `json:"image"`
+	ImageDayNight bool   `json:"image_day_night"`
+	Precipitation bool   `json:"precipitation"`
+}
+
+// Table holds the WMO weather codes and the condition code mappings of the weather backends.
+type Table struct {
+	codes    map[int]Condition
+	backends map[string]map[string]int
+}
+
+// file represents the format of the built-in table and its overrides.
+type file struct {
+	Codes    map[string]json.RawMessage `json:"codes"`
+	Backends map[string]json.RawMessage `json:"backends"`
+}
+
+// Load returns the built-in table, overridden by the file at path if path is not empty. The fields of a
+// weather code and the codes of a backend mapping that are set in the file replace the built-in ones,
+// everything else is kept.
+func Load(path string) (*Table, error) {
+	table := &Table{codes: make(map[int]Condition), backends: make(map[string]map[string]int)}
+	if err := table.merge(builtin); err != nil {
+		return nil, fmt.Errorf("failed to parse built-in condition table: %w", err)
+	}
+	if path == "" {
+		return table, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read condition table: %w", err)
+	}
+	if err = table.merge(data); err != nil {
+		return nil, fmt.Errorf("failed to parse condition table %s: %w", path, err)
+	}
+	return table, nil
+}
+
+// merge merges the table in data into the table and validates the result.
+func (t *Table) merge(data []byte) error {
+	var parsed file
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return err
+	}
+	for key, raw := range parsed.Codes {
+		code, err := strconv.Atoi(key)
+		if err != nil {
+			return fmt.Errorf("invalid weather code: %s", key)
+		}
+		condition := t.codes[code]
+		if err = json.Unmarshal(raw, &condition); err != nil {
+			return fmt.Errorf("invalid weather code %d: %w", code, err)
+		}
+		t.codes[code] = condition
+	}
+	for backend, raw := range parsed.Backends {
+		mapping := t.backends[backend]
+		if mapping == nil {
+			mapping = make(map[string]int)
+		}
+		if err := json.Unmarshal(raw, &mapping); err != nil {
+			return fmt.Errorf("invalid mapping of backend %s: %w", backend, err)
+		}
+		t.backends[backend] = mapping
+	}
+
+	for code, condition := range t.codes {
+		if condition.Description == "" || condition.Category == "" || condition.Image == "" {
+			return fmt.Errorf("weather code %d requires a description, category and image", code)
+		}
+		if !slices.Contains(Groups, categoryGroup(condition.Category)) {
+			return fmt.Errorf("invalid condition category of weather code %d: %s", code, condition.Category)
+		}
+	}
+	for backend, mapping := range t.backends {
+		for key, code := range mapping {
+			if _, ok := t.codes[code]; !ok {
+				return fmt.Errorf("backend %s maps %s to unknown weather code %d", backend, key, code)
+			}
+		}
+	}
+	return nil
+}
+
+// Lookup returns the condition of the WMO weather code and whether the code is known.
+func (t *Table) Lookup(code float64) (Condition, bool) {
+	condition, ok := t.codes[int(code)]
+	return condition, ok && float64(int(code)) == code
+}
+
+// Description returns the message ID of the description of the WMO weather code.
+func (t *Table) Description(code float64) string {
+	condition, _ := t.Lookup(code)
+	return condition.Description
+}
+
+// Icon returns the emoji of the WMO weather code for day or night.
+func (t *Table) Icon(code float64, isDaytime bool) string {
+	condition, _ := t.Lookup(code)
+	if isDaytime {
+		return condition.IconDay
+	}
+	return condition.IconNight
+}
+
+// Category returns the condition category of the WMO weather code, as used for the CSS classes and hooks.
+func (t *Table) Category(code float64) string {
+	condition, _ := t.Lookup(code)
+	return condition.Category
+}
+
+// Group returns the condition group of the WMO weather code, which is derived from its category.
+func (t *Table) Group(code float64) string {
+	condition, _ := t.Lookup(code)
+	return categoryGroup(condition.Category)
+}
+
+// categoryGroup returns the condition group of the condition category.
+func categoryGroup(category string) string {
+	if group, ok := categoryGroups[category]; ok {
+		return group
+	}
+	return category
+}
+
+// ImageName returns the name of the image icon for the WMO weather code, as used for the file names of an
+// icon theme. Icons that differ between day and night carry a "-day" or "-night" suffix.
+func (t *Table) ImageName(code float64, isDaytime bool) string {
+	condition, _ := t.Lookup(code)
+	switch {
+	case !condition.ImageDayNight:
+		return condition.Image
+	case isDaytime:
+		return condition.Image + "-day"
+	default:
+		return condition.Image + "-night"
+	}
+}
+
+// IsPrecipitation returns true if the WMO weather code represents any kind of precipitation.
+func (t *Table) IsPrecipitation(code float64) bool {
+	condition, _ := t.Lookup(code)
+	return condition.Precipitation
+}
+
+// Backend returns the mapping of the condition codes of the weather backend to WMO weather codes. Numeric
+// condition codes are given as decimal strings.
+func (t *Table) Backend(name string) map[string]int {
+	return t.backends[name]
+}
@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package conditions
+
+import "github.com/vorlif/spreak/localize"
+
+// descriptions lists the descriptions of the built-in table, so that they are extracted for translation.
+// Descriptions of an overriding table are shown untranslated, unless they match one of them.
+var _ = []localize.MsgID{
+	"Clear sky",
+	"Mainly clear",
+	"Partly cloudy",
+	"Overcast",
+	"Fog",
+	"Depositing rime fog",
+	"Light drizzle",
+	"Moderate drizzle",
+	"Dense drizzle",
+	"Light freezing drizzle",
+	"Dense freezing drizzle",
+	"Slight rain",
+	"Moderate rain",
+	"Heavy rain",
+	"Light freezing rain",
+	"Heavy freezing rain",
+	"Slight snow fall",
+	"Moderate snow fall",
+	"Heavy snow fall",
+	"Snow grains",
+	"Slight rain showers",
+	"Moderate rain showers",
+	"Violent rain showers",
+	"Slight snow showers",
+	"Heavy snow showers",
+	"Thunderstorm",
+	"Thunderstorm with slight hail",
+	"Thunderstorm with heavy hail",
+}
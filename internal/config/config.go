@@ -7,47 +7,269 @@ package config
 import (
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/kkyr/fig"
+
+	"github.com/wneessen/waybar-weather/internal/coordinate"
+	"github.com/wneessen/waybar-weather/internal/event"
+	"github.com/wneessen/waybar-weather/internal/rules"
 )
 
 const (
 	configEnv         = "WAYBARWEATHER"
 	DefaultTextTpl    = "{{.Current.ConditionIcon}} {{.Current.Temperature}}{{.TempUnit}}"
 	DefaultAltTextTpl = "{{.Forecast.ConditionIcon}} {{.Forecast.Temperature}}{{.TempUnit}}"
-	DefaultTooltipTpl = "{{.Address.City}}, {{.Address.Country}}\n" +
+	DefaultTooltipTpl = "{{if .Place}}{{.Place}}" +
+		"{{else if .Address.AddressFound}}{{.Address.City}}, {{.Address.Country}}" +
+		"{{if .AddressIsStale}} ({{loc \"lastknown\"}}){{end}}" +
+		"{{else}}{{.Coordinates}}{{end}}" +
+		"{{if .LocationIsCoarse}} ({{.LocationAccuracyText}} {{loc \"via\"}} {{.LocationSource}}){{end}}\n" +
 		"{{.Current.Condition}}\n" +
+		"{{if .Capabilities.Alerts}}{{range .Alerts}}⚠️ {{.Headline}}\n{{end}}" +
+		"{{else}}{{range .DeltaAlerts}}⚠️ {{.Message}}\n{{end}}{{end}}" +
+		"{{if .Flood.Level}}🌊 {{if eq .Flood.Level \"danger\"}}{{loc \"flooddanger\"}}{{else}}{{loc \"floodwarning\"}}" +
+		"{{end}}{{if .Flood.Name}} {{.Flood.Name}}{{end}}: {{floatFormat .Flood.Max 0}} m³/s " +
+		"{{localizedDate .Flood.MaxDate}}\n{{end}}" +
 		"{{loc \"apparent\"}}: {{.Current.ApparentTemperature}}{{.TempUnit}}\n" +
 		"{{loc \"humidity\"}}: {{.Current.Humidity}}%\n" +
-		"{{loc \"pressure\"}}: {{.Current.PressureMSL}} {{.PressureUnit}}\n" +
+		"{{loc \"pressure\"}}: {{.Current.PressureText}} {{.PressureUnit}}\n" +
+		"{{if .PrecipitationEnd.EndKnown}}{{loc \"precipends\"}}: ~{{localizedTime .PrecipitationEnd.Time}}\n{{end}}" +
+		"{{if .PrecipitationChart.Available}}{{.PrecipitationChart.Bars}}\n{{.PrecipitationChart.Labels}}\n{{end}}" +
+		"{{range .ForecastChanges}}🔄 {{.Message}}\n{{end}}" +
+		"{{range .UVWindows}}☀️ {{loc \"highuv\"}} {{localizedTime .Start}}–{{localizedTime .End}}\n{{end}}" +
+		"{{if .Ventilation.Drying}}{{loc \"ventdry\"}}\n{{else if .Ventilation.Humidifying}}" +
+		"{{loc \"venthumid\"}}\n{{end}}" +
 		"\n" +
-		`🌅 {{localizedTime .SunriseTime}} • 🌇 {{localizedTime .SunsetTime}}`
+		"{{if .MidnightSun}}☀️ {{loc \"midnightsun\"}}{{else if .PolarNight}}🌑 {{loc \"polarnight\"}}{{else}}" +
+		`🌅 {{localizedTime .SunriseTime}} • 🌇 {{localizedTime .SunsetTime}}{{end}}` +
+		"{{range .Twilight.Shown}}\n{{.Name}}: {{if .Available}}{{localizedTime .Dawn}} – " +
+		"{{localizedTime .Dusk}}{{else}}{{loc \"nodarkness\"}}{{end}}{{end}}" +
+		"{{if .Trip.Available}}\n{{.Trip.Name}} {{localizedTime .Trip.Arrival}}: {{.Trip.ConditionIcon}} " +
+		"{{.Trip.Temperature}}{{.TempUnit}} {{.Trip.Condition}}{{end}}" +
+		"{{if .Ensemble.Available}}\n{{loc \"tomorrow\"}}: " +
+		"{{floatFormat .Ensemble.TemperatureMaxLow 0}}–{{floatFormat .Ensemble.TemperatureMaxHigh 0}}{{.TempUnit}} " +
+		"{{loc \"likely\"}}{{end}}"
+	DefaultBriefingTpl = "{{.Today.ConditionIcon}} {{.Today.Condition}}\n" +
+		"↑{{.Today.TemperatureMax}}{{.TempUnit}} ↓{{.Today.TemperatureMin}}{{.TempUnit}}" +
+		" • 💧{{.Today.PrecipitationProbability}}%"
 )
 
+// DefaultPluginTimeout is the maximum runtime of a plugin, if no timeout is configured.
+const DefaultPluginTimeout = 10 * time.Second
+
+// DefaultGeofenceRadius is the radius of a geofence in meters, if no radius is configured.
+const DefaultGeofenceRadius = 200
+
+// Weekdays lists the weekday names used for the morning briefing.
+var Weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// HookConditions lists the weather condition categories hook commands can be configured for.
+var HookConditions = []string{"clear", "cloudy", "fog", "rain", "snow", "thunderstorm"}
+
+// TextPresets maps the names of the predefined text templates to the templates.
+var TextPresets = map[string]string{
+	// City, current condition and temperature with today's low and high, e.g. "Berlin ☀️ 21° (14°/23°)"
+	"highlow": "{{if .Place}}{{.Place}} {{else if .Address.AddressFound}}{{.Address.City}} {{end}}" +
+		"{{.Current.ConditionIcon}} {{floatFormat .Current.Temperature 0}}° " +
+		"({{floatFormat .Today.TemperatureMin 0}}°/{{floatFormat .Today.TemperatureMax 0}}°)",
+	// Current condition and temperature with the date and time, e.g. "☀️ 21°C • Oct 16 14:05"
+	"clock": "{{.Current.ConditionIcon}} {{.Current.Temperature}}{{.TempUnit}} • " +
+		"{{localizedDate .Now}} {{localizedTime .Now}}",
+}
+
+// ClockPresets lists the text presets that show the time and are therefore re-rendered every minute.
+var ClockPresets = []string{"clock"}
+
+// WindUnits lists the supported units of the wind speed.
+var WindUnits = []string{"kmh", "mph", "ms", "kn", "beaufort"}
+
+// PressureUnits lists the supported units of the air pressure.
+var PressureUnits = []string{"hpa", "inhg", "mmhg"}
+
+// MaxOutputSignal is the highest offset of the output signal to SIGRTMIN, so that it doesn't exceed
+// SIGRTMAX.
+const MaxOutputSignal = 30
+
+// modelPattern matches the name of a single Open-Meteo weather model, multiple models per request are not
+// supported since they change the structure of the response.
+var modelPattern = regexp.MustCompile(`^[a-z0-9_]*$`)
+
+// classPrefixPattern matches a prefix that keeps the CSS classes valid identifiers.
+var classPrefixPattern = regexp.MustCompile(`^([A-Za-z_-][A-Za-z0-9_-]*)?$`)
+
+// MaxPrecision is the maximum number of decimal places of the formatted values.
+const MaxPrecision = 3
+
+// MaxChartHours is the maximum number of hours of the hourly charts.
+const MaxChartHours = 48
+
+// DefaultSatellite is the name of the satellite whose passes are predicted if no names are configured.
+const DefaultSatellite = "ISS (ZARYA)"
+
+// TwilightPhases lists the twilight phases that can be shown.
+var TwilightPhases = []string{"civil", "nautical", "astronomical"}
+
+// PercentageSources lists the values that can be emitted as percentage of the module output.
+var PercentageSources = []string{"sun", "humidity", "precipitation_probability"}
+
+// OutputFormats lists the supported formats of the module output.
+var OutputFormats = []string{"json", "text", "polybar", "eww"}
+
+// PrimaryIcons lists the icons that can be shown by the default text templates.
+var PrimaryIcons = []string{"condition", "temperature"}
+
+// DefaultTemperatureRamp are the icons of the temperature bands from freezing to hot.
+var DefaultTemperatureRamp = []string{"🥶", "❄️", "😊", "☀️", "🥵"}
+
+// DefaultTemperatureBreakpoints separate the bands of the default temperature ramp, keyed by units.
+var DefaultTemperatureBreakpoints = map[string][]float64{
+	"metric":   {0, 10, 20, 28},
+	"imperial": {32, 50, 68, 82},
+}
+
+// GlyphModes lists the modes of the condition icons: auto detects whether an emoji font is installed.
+var GlyphModes = []string{"auto", "emoji", "text"}
+
+// WindDirectionFormats lists the supported formats of the wind direction text.
+var WindDirectionFormats = []string{"abbreviation", "words", "arrow"}
+
+// WeatherBackends maps the names of the built-in weather backends to their Open-Meteo compatible forecast
+// endpoints.
+var WeatherBackends = map[string]string{
+	"open-meteo": "https://api.open-meteo.com/v1/forecast",
+	"met.no":     "https://api.open-meteo.com/v1/metno",
+}
+
+// WeatherProviders lists the built-in weather backends whose API is not Open-Meteo compatible.
+var WeatherProviders = []string{"weatherkit", "pirateweather", "brightsky", "metoffice", "envcanada"}
+
 // Config represents the application's configuration structure.
 type Config struct {
 	// Allowed values: metric, imperial
-	Units    string     `fig:"units" default:"metric"`
+	Units string `fig:"units" default:"metric"`
+	// Allowed values: kmh, mph, ms, kn, beaufort (empty follows the units)
+	WindUnit string `fig:"wind_unit"`
+	// Allowed values: mm, inch (empty follows the units)
+	PrecipitationUnit string `fig:"precipitation_unit"`
+	// Allowed values: hpa, inhg, mmhg
+	PressureUnit string `fig:"pressure_unit" default:"hpa"`
+	// Allowed values: sea_level, surface
+	Pressure string     `fig:"pressure" default:"sea_level"`
 	Locale   string     `fig:"locale"`
 	LogLevel slog.Level `fig:"loglevel" default:"0"`
+	// Allowed values: exact, round, redact
+	LogCoordinates string `fig:"log_coordinates" default:"round"`
+	// Restrict the output to plain ASCII characters
+	ASCIIOnly bool `fig:"ascii_only"`
+	// Allowed values: json, text, polybar, eww
+	OutputFormat string `fig:"output_format" default:"json"`
+	// Time to the first output after which the startup timing is logged as warning (0 disables)
+	StartupBudget time.Duration `fig:"startup_budget" default:"15s"`
+	// TCP address of the HTTP endpoint serving the metrics in the Prometheus text format (empty disables)
+	MetricsListen string `fig:"metrics_listen"`
+	// Write the output on every update, even if nothing visible has changed
+	EmitUnchanged bool `fig:"emit_unchanged"`
+	// JSON file overriding the descriptions, icons and categories of the WMO weather codes and the condition
+	// code mappings of the weather backends (empty uses the built-in table)
+	Conditions string `fig:"conditions"`
 
 	Weather struct {
 		// Allowed value: 1 to 24
 		ForecastHours uint `fig:"forecast_hours" default:"3"`
+		// Allowed values: nws (empty disables observations)
+		ObservationSource string `fig:"observation_source"`
+		// Allowed values: model, observation
+		PrimarySource string `fig:"primary_source" default:"model"`
+		// Allowed values: open-meteo, open-elevation (empty disables the elevation lookup)
+		ElevationSource string `fig:"elevation_source"`
+		// Name of the active weather backend, either a built-in one (open-meteo, met.no, weatherkit,
+		// pirateweather, brightsky, metoffice, envcanada) or one of the configured backends
+		Backend string `fig:"backend" default:"open-meteo"`
+		// Additional Open-Meteo compatible forecast endpoints, e.g. a self-hosted instance, by name
+		Backends map[string]string `fig:"backends"`
+		// Developer account of the Apple WeatherKit backend, which is available if a key file is set
+		WeatherKit struct {
+			TeamID    string `fig:"team_id"`
+			ServiceID string `fig:"service_id"`
+			KeyID     string `fig:"key_id"`
+			// Private key file (.p8) downloaded from the developer account
+			KeyFile string `fig:"key_file"`
+		} `fig:"weatherkit"`
+		// API key of the Pirate Weather backend, which is available if the key is set
+		PirateWeather struct {
+			APIKey string `fig:"apikey"`
+		} `fig:"pirateweather"`
+		// API key of the Met Office backend, which is available if the key is set
+		MetOffice struct {
+			APIKey string `fig:"apikey"`
+			// Region of the severe weather warnings, e.g. se for London & South East England
+			WarningsRegion string `fig:"warnings_region" default:"UK"`
+		} `fig:"metoffice"`
+		// Thermostat setpoints in the configured unit, used as base for the degree days (0 disables)
+		HeatingSetpoint float64 `fig:"heating_setpoint"`
+		CoolingSetpoint float64 `fig:"cooling_setpoint"`
+		// Open-Meteo weather model the forecast is requested from, e.g. icon_d2 (empty lets Open-Meteo
+		// select the best model for the location)
+		Model string `fig:"model"`
+		// Age of the run of the weather model above which the forecast is flagged as stale
+		ModelRunMaxAge time.Duration `fig:"model_run_max_age" default:"12h"`
 	} `fig:"weather"`
 
 	Intervals struct {
 		WeatherUpdate time.Duration `fig:"weather_update" default:"15m"`
 		Output        time.Duration `fig:"output" default:"30s"`
+		// Write the output only on request via the real-time signal SIGRTMIN+N instead of periodically,
+		// Linux only (0 disables)
+		OutputSignal int `fig:"output_signal"`
 	} `fig:"intervals"`
 
 	Templates struct {
 		Text    string `fig:"text"`
 		AltText string `fig:"alt_text"`
 		Tooltip string `fig:"tooltip"`
+		// Predefined text template, used if no text template is set. Allowed values: highlow, clock
+		TextPreset string `fig:"text_preset"`
+		// Re-render the output at the start of every minute, for templates that show the time
+		Clock bool `fig:"clock"`
+		// Optional templates for the daily and astronomical display modes
+		Daily string `fig:"daily"`
+		Astro string `fig:"astro"`
+		// Allowed values: decimal, dms, geohash, maidenhead
+		CoordinateFormat string `fig:"coordinate_format" default:"decimal"`
+		// Allowed values: abbreviation, words, arrow
+		WindDirectionFormat string `fig:"wind_direction_format" default:"abbreviation"`
+		// Rules in the form "<expression> -> <class>" to add custom CSS classes to the output
+		ClassRules []string `fig:"class_rules"`
+		// Prefix of the condition, time of day and stale CSS classes
+		ClassPrefix string `fig:"class_prefix" default:"weather-"`
+		// Append the attribution of the data providers in use to the tooltip
+		Attribution bool `fig:"attribution"`
+		// Hours of the hourly charts, starting with the current hour
+		ChartHours int `fig:"chart_hours" default:"12"`
+		// Show the hourly precipitation chart in the default tooltip if precipitation is expected
+		PrecipitationChart bool `fig:"precipitation_chart"`
+		// Twilight phases shown in the default tooltip. Allowed values: civil, nautical, astronomical
+		Twilight []string `fig:"twilight"`
+		// Value emitted as percentage, e.g. for format-icons. Allowed values: sun, humidity,
+		// precipitation_probability (empty disables the percentage)
+		Percentage string `fig:"percentage"`
+		// Value emitted as alt, e.g. for format-icons. Allowed values: mode (the display mode),
+		// condition_group
+		Alt string `fig:"alt" default:"mode"`
+		// Decimal places of the temperatures, wind speeds and precipitation amounts (unset keeps the
+		// precision of the weather data)
+		Precision struct {
+			Temperature   *int `fig:"temperature"`
+			Wind          *int `fig:"wind"`
+			Precipitation *int `fig:"precipitation"`
+		} `fig:"precision"`
 	} `fig:"templates"`
 
 	GeoLocation struct {
@@ -57,12 +279,368 @@ type Config struct {
 		DisableGeolocationFile bool   `fig:"disable_geolocation_file"`
 		DisableICHNAEA         bool   `fig:"disable_ichnaea"`
 		DisableGPSD            bool   `fig:"disable_gpsd"`
+		DisableGeoClue         bool   `fig:"disable_geoclue"`
+		// Only available on macOS
+		DisableCoreLocation bool `fig:"disable_corelocation"`
+		// Only available on Windows
+		DisableWindowsLocation bool `fig:"disable_windows_location"`
+		// Speed in km/h above which location updates are deferred until the position stabilizes (0 disables)
+		MovementSpeed float64 `fig:"movement_speed" default:"25"`
+		// Time without movement after which the position is considered stable
+		MovementSettle time.Duration `fig:"movement_settle" default:"5m"`
+		// A new location within the accuracy radius of the current location is only applied if its accuracy
+		// is at most this fraction of the current accuracy
+		AccuracyImprovement float64 `fig:"accuracy_improvement" default:"0.5"`
+		// Accuracy in meters above which the location is marked as coarse in the tooltip
+		CoarseAccuracy float64 `fig:"coarse_accuracy" default:"5000"`
+		// Staged strategy for the first location at startup: wait for a result with the given accuracy in
+		// meters, then for a result of any accuracy from local providers, then for an IP-based result and
+		// finally fall back to the cached or configured location (a timeout of 0 skips the stage)
+		StartupAccuracy       float64       `fig:"startup_accuracy" default:"100"`
+		StartupPreciseTimeout time.Duration `fig:"startup_precise_timeout" default:"5s"`
+		StartupAnyTimeout     time.Duration `fig:"startup_any_timeout" default:"3s"`
+		StartupIPTimeout      time.Duration `fig:"startup_ip_timeout" default:"10s"`
+		// File the last location is cached in, used as fallback at startup
+		LocationCache        string `fig:"location_cache"`
+		DisableLocationCache bool   `fig:"disable_location_cache"`
+		// Location used if no other location is available at startup
+		FallbackLatitude  float64 `fig:"fallback_latitude"`
+		FallbackLongitude float64 `fig:"fallback_longitude"`
+		// Number of providers tracked at the same time (0 = all providers)
+		Workers int `fig:"workers"`
 	} `fig:"geolocation"`
 
+	APIBudget struct {
+		// Daily request limit for all APIs without a dedicated limit (0 = unlimited)
+		Daily uint `fig:"daily"`
+		// Daily request limits per API host
+		Hosts     map[string]uint `fig:"hosts"`
+		StateFile string          `fig:"state_file"`
+	} `fig:"api_budget"`
+
 	GeoCoder struct {
 		Provider string `fig:"provider" default:"nominatim"`
 		APIKey   string `fig:"apikey"`
 	} `fig:"geocoder"`
+
+	Hooks struct {
+		// Commands to run when the weather condition category changes, keyed by category
+		Conditions map[string]string `fig:"conditions"`
+		Sunrise    string            `fig:"sunrise"`
+		Sunset     string            `fig:"sunset"`
+		// Commands to run on service events, keyed by event, e.g. location-changed
+		Events map[string]string `fig:"events"`
+		// LEDs, e.g. the keyboard backlight, that are lit while their condition is true, keyed by name
+		LEDs map[string]LEDHook `fig:"leds"`
+		// Time a new condition category has to persist before its hook is run
+		Debounce time.Duration `fig:"debounce" default:"10m"`
+		Timeout  time.Duration `fig:"timeout" default:"30s"`
+	} `fig:"hooks"`
+
+	Plugins map[string]Plugin `fig:"plugins"`
+
+	Indoor struct {
+		// JSON file with the readings of an indoor sensor, e.g. {"temperature": 21.5, "humidity": 55}
+		File string `fig:"file"`
+		// Readings older than this are ignored
+		MaxAge time.Duration `fig:"max_age" default:"1h"`
+	} `fig:"indoor"`
+
+	WeatherCard struct {
+		// Show the weather details as a persistent notification that is updated in place
+		Enabled bool `fig:"enabled"`
+		// Content of the notification (Default: the tooltip template)
+		Template string `fig:"template"`
+	} `fig:"weather_card"`
+
+	Briefing struct {
+		// Local time (HH:MM) of the daily briefing notification, empty disables the briefing
+		Time string `fig:"time"`
+		// Weekdays on which the briefing is sent (Default: every day)
+		Weekdays []string `fig:"weekdays"`
+		Template string   `fig:"template"`
+	} `fig:"briefing"`
+
+	UV struct {
+		// UV index from which the time ranges of today with a high UV index are shown
+		Threshold float64 `fig:"threshold" default:"6"`
+	} `fig:"uv"`
+
+	Umbrella struct {
+		// Local time (HH:MM) until which precipitation adds the umbrella class, e.g. when you're usually home
+		Home string `fig:"home" default:"18:00"`
+	} `fig:"umbrella"`
+
+	// Times in the time zone of the weather location in which notifications and hooks are suppressed
+	QuietHours struct {
+		// Time ranges "HH:MM-HH:MM", a range past midnight ends on the next day
+		Ranges []string `fig:"ranges"`
+		// Weekdays on which the ranges start (Default: every day)
+		Weekdays []string `fig:"weekdays"`
+		// Weekdays that are quiet all day, e.g. the weekend
+		AllDay []string `fig:"all_day"`
+		// Dates that are quiet all day, e.g. holidays, as YYYY-MM-DD or MM-DD for every year
+		Dates []string `fig:"dates"`
+	} `fig:"quiet_hours"`
+
+	Geofences map[string]Geofence `fig:"geofences"`
+
+	// Unix socket the instances attach to (Default: $XDG_RUNTIME_DIR/waybar-weather.sock)
+	Socket string `fig:"socket"`
+	// Additional instances with their own templates, e.g. one per monitor, served via the socket
+	Instances map[string]Instance `fig:"instances"`
+
+	Accessibility struct {
+		// Announce significant weather changes via short notifications that screen readers can read out
+		Announcements bool `fig:"announcements"`
+		// Command that is run instead of sending a notification, e.g. a speech-dispatcher call
+		Command string `fig:"command"`
+		// Time before the start or end of precipitation at which it is announced
+		LeadTime time.Duration `fig:"lead_time" default:"30m"`
+	} `fig:"accessibility"`
+
+	Trip struct {
+		// Name and coordinates of the destination (no coordinates disable the trip mode)
+		Name      string  `fig:"name" default:"Trip"`
+		Latitude  float64 `fig:"latitude"`
+		Longitude float64 `fig:"longitude"`
+		// Expected arrival, either as local date and time (2006-01-02T15:04) or as time of day (15:04)
+		Arrival string `fig:"arrival"`
+	} `fig:"trip"`
+
+	// Uncertainty range of tomorrow's high and low from the Open-Meteo Ensemble API
+	Ensemble struct {
+		Enabled bool `fig:"enabled"`
+		// Ensemble model of Open-Meteo, e.g. icon_seamless, gfs_seamless or ecmwf_ifs025
+		Model string `fig:"model" default:"icon_seamless"`
+		// Percentiles of the ensemble members that span the likely range
+		LowerPercentile float64 `fig:"lower_percentile" default:"10"`
+		UpperPercentile float64 `fig:"upper_percentile" default:"90"`
+	} `fig:"ensemble"`
+
+	DeltaAlerts struct {
+		// Time ahead of now the forecast is compared with the current conditions
+		Window time.Duration `fig:"window" default:"3h"`
+		// Changes within the window that raise an alert, in the configured units (0 disables)
+		TemperatureDrop float64 `fig:"temperature_drop"`
+		TemperatureRise float64 `fig:"temperature_rise"`
+		WindIncrease    float64 `fig:"wind_increase"`
+		PressureFall    float64 `fig:"pressure_fall"`
+		// Send a notification when an alert is raised
+		Notify bool `fig:"notify"`
+	} `fig:"delta_alerts"`
+
+	Flood struct {
+		// Point on the river whose discharge is fetched from the Open-Meteo Flood API (0, 0 disables)
+		Latitude  float64 `fig:"latitude"`
+		Longitude float64 `fig:"longitude"`
+		// Name of the river shown in the tooltip
+		Name string `fig:"name"`
+		// River discharge in m³/s from which the flood warning or danger is shown (0 disables)
+		Warning float64 `fig:"warning"`
+		Danger  float64 `fig:"danger"`
+		// Interval of the updates, the forecast is updated once a day
+		Interval time.Duration `fig:"interval" default:"6h"`
+	} `fig:"flood"`
+
+	Earthquakes struct {
+		// Earthquake feed that is monitored. Allowed values: usgs, emsc (empty disables)
+		Source string `fig:"source"`
+		// Earthquakes from this magnitude within the radius in km of the current location are notified
+		MinMagnitude float64 `fig:"min_magnitude" default:"4.5"`
+		Radius       float64 `fig:"radius" default:"300"`
+		// Interval of the feed requests and the period of the earthquakes that are shown
+		Interval time.Duration `fig:"interval" default:"5m"`
+		MaxAge   time.Duration `fig:"max_age" default:"24h"`
+	} `fig:"earthquakes"`
+
+	Satellites struct {
+		// Predict tonight's visible passes of satellites from the orbital elements of CelesTrak
+		Enabled bool `fig:"enabled"`
+		// CelesTrak group of the orbital elements, e.g. stations, visual or starlink
+		Group string `fig:"group" default:"stations"`
+		// Satellites whose names start with one of the names, case-insensitive (default: ISS (ZARYA))
+		Names []string `fig:"names"`
+		// Minimum elevation in degrees a pass has to reach
+		MinElevation float64 `fig:"min_elevation" default:"10"`
+		MaxPasses    int     `fig:"max_passes" default:"5"`
+		// Interval of the updates of the orbital elements, CelesTrak updates them a few times a day
+		Interval time.Duration `fig:"interval" default:"12h"`
+	} `fig:"satellites"`
+
+	ForecastDiff struct {
+		// Changes of the daily high temperature (in the configured units) and of the precipitation
+		// probability (in percentage points) compared to the first forecast of the day that are shown
+		// (0 disables)
+		Temperature              float64 `fig:"temperature"`
+		PrecipitationProbability float64 `fig:"precipitation_probability"`
+		// File the first forecast of the day is persisted to (Default: ~/.cache/waybar-weather/forecast.json)
+		StateFile string `fig:"state_file"`
+	} `fig:"forecast_diff"`
+
+	Cache struct {
+		// Allowed values: memory, file
+		Backend string `fig:"backend" default:"memory"`
+		// Directory of the file backend (Default: ~/.cache/waybar-weather/cache)
+		Dir string `fig:"dir"`
+		// Time after which the cached API results expire (0 disables the cache of the API)
+		Geocode   time.Duration `fig:"geocode" default:"24h"`
+		Elevation time.Duration `fig:"elevation" default:"720h"`
+		Weather   time.Duration `fig:"weather" default:"10m"`
+		Alerts    time.Duration `fig:"alerts" default:"10m"`
+		// Orbital elements of the satellites
+		Astronomy time.Duration `fig:"astronomy" default:"12h"`
+	} `fig:"cache"`
+
+	// Base URLs that replace the API hosts, keyed by host, e.g. to run against mock servers
+	Endpoints map[string]string `fig:"endpoints"`
+
+	Icons struct {
+		// Directory with weather icon images named by condition (e.g. clear-day.svg), empty disables the icon paths
+		Path string `fig:"path"`
+		// File extension of the icon images
+		Extension string `fig:"extension" default:"svg"`
+		// File the path of the current condition icon is written to, e.g. for eww or AGS
+		File string `fig:"file"`
+		// Condition icons as emoji or as text. Allowed values: auto, emoji, text
+		Glyphs string `fig:"glyphs" default:"auto"`
+		// Show the current moon phase instead of the moon for a clear night
+		MoonAtNight bool `fig:"moon_at_night"`
+		// Icon of the default text templates. Allowed values: condition, temperature
+		Primary string `fig:"primary" default:"condition"`
+		// Icons of the temperature bands from cold to hot
+		TemperatureRamp []string `fig:"temperature_ramp"`
+		// Ascending temperatures in the configured units that separate the bands of the ramp
+		TemperatureBreakpoints []float64 `fig:"temperature_breakpoints"`
+	} `fig:"icons"`
+
+	// Reduced updates while a fullscreen window is focused, detected via the IPC of Sway or Hyprland
+	Fullscreen struct {
+		Enabled bool `fig:"enabled"`
+		// Interval in which the compositor is queried for a fullscreen window
+		CheckInterval time.Duration `fig:"check_interval" default:"30s"`
+		WeatherUpdate time.Duration `fig:"weather_update" default:"1h"`
+		Output        time.Duration `fig:"output" default:"5m"`
+	} `fig:"fullscreen"`
+
+	// Name of the profile to use (empty selects the profile automatically by Wi-Fi SSID)
+	Profile  string             `fig:"profile"`
+	Profiles map[string]Profile `fig:"profiles"`
+}
+
+// Profile represents a named set of settings that is activated manually or automatically when
+// connected to one of the given Wi-Fi networks.
+type Profile struct {
+	SSIDs []string `fig:"ssids"`
+	// Static location of the profile, overrides the geolocation providers if set
+	Latitude  float64 `fig:"latitude"`
+	Longitude float64 `fig:"longitude"`
+
+	Intervals struct {
+		WeatherUpdate time.Duration `fig:"weather_update"`
+		Output        time.Duration `fig:"output"`
+	} `fig:"intervals"`
+	// Weather backend used while the profile is active
+	Backend string `fig:"backend"`
+}
+
+// Plugin represents an external executable that receives the weather state as JSON on stdin and
+// returns additional tooltip lines and CSS classes as JSON on stdout.
+type Plugin struct {
+	Command string `fig:"command"`
+	// fig doesn't support defaults for map values, so the default is applied in Validate
+	Timeout time.Duration `fig:"timeout"`
+}
+
+// LEDHook lights a LED of /sys/class/leds, e.g. the keyboard backlight, while its condition is true. The
+// previous brightness and color are restored once the condition is false again.
+type LEDHook struct {
+	// Condition in the syntax of the class rules, e.g. "weather_code >= 95"
+	When string `fig:"when"`
+	// Name of the LED in /sys/class/leds, e.g. "tpacpi::kbd_backlight"
+	Device string `fig:"device"`
+	// Brightness while the condition is true, 0 uses the maximum brightness of the LED
+	Brightness int `fig:"brightness"`
+	// Intensities of the channels of a multicolor LED, e.g. "255 0 0" for red
+	Color string `fig:"color"`
+}
+
+// Instance represents an additional output of the module with its own templates, e.g. for a Waybar
+// instance on another monitor. Empty templates fall back to the global templates.
+type Instance struct {
+	Text    string `fig:"text"`
+	AltText string `fig:"alt_text"`
+	Daily   string `fig:"daily"`
+	Astro   string `fig:"astro"`
+	Tooltip string `fig:"tooltip"`
+}
+
+// Geofence represents a circular area with a display label and commands that are run when entering
+// or leaving the area.
+type Geofence struct {
+	Latitude  float64 `fig:"latitude"`
+	Longitude float64 `fig:"longitude"`
+	// Radius in meters, fig doesn't support defaults for map values, so the default is applied in Validate
+	Radius  float64 `fig:"radius"`
+	Label   string  `fig:"label"`
+	OnEnter string  `fig:"on_enter"`
+	OnExit  string  `fig:"on_exit"`
+	// Weather backend used while the location is inside the geofence
+	Backend string `fig:"backend"`
+}
+
+// SocketPath returns the path of the Unix socket the instances attach to.
+func (c *Config) SocketPath() string {
+	if c.Socket != "" {
+		return c.Socket
+	}
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "waybar-weather.sock")
+}
+
+// WeatherBackendURL returns the forecast endpoint of the weather backend with the given name. Configured
+// backends take precedence over the built-in ones.
+func (c *Config) WeatherBackendURL(name string) (string, bool) {
+	if backendURL, ok := c.Weather.Backends[name]; ok {
+		return backendURL, true
+	}
+	backendURL, ok := WeatherBackends[name]
+	return backendURL, ok
+}
+
+// HasFlood returns true if a river point for the flood forecast is configured.
+func (c *Config) HasFlood() bool {
+	return c.Flood.Latitude != 0 || c.Flood.Longitude != 0
+}
+
+// HasTrip returns true if a trip destination is configured.
+func (c *Config) HasTrip() bool {
+	return c.Trip.Latitude != 0 || c.Trip.Longitude != 0
+}
+
+// ParseArrival parses the arrival of a trip, either as local date and time or as time of day. A time of day
+// refers to its next occurrence after now.
+func ParseArrival(value string, now time.Time) (time.Time, error) {
+	if arrival, err := time.ParseInLocation("2006-01-02T15:04", value, now.Location()); err == nil {
+		return arrival, nil
+	}
+	clock, err := time.ParseInLocation("15:04", value, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected 2006-01-02T15:04 or 15:04, got %q", value)
+	}
+	arrival := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !arrival.After(now) {
+		arrival = arrival.AddDate(0, 0, 1)
+	}
+	return arrival, nil
+}
+
+// HasLocation returns true if the profile defines a static location.
+func (p Profile) HasLocation() bool {
+	return p.Latitude != 0 || p.Longitude != 0
 }
 
 func NewFromFile(path, file string) (*Config, error) {
@@ -91,15 +669,370 @@ func (c *Config) Validate() error {
 	if c.Units != "metric" && c.Units != "imperial" {
 		return fmt.Errorf("invalid units: %s", c.Units)
 	}
+	if c.WindUnit != "" && !slices.Contains(WindUnits, c.WindUnit) {
+		return fmt.Errorf("invalid wind unit: %s", c.WindUnit)
+	}
+	if c.PrecipitationUnit != "" && c.PrecipitationUnit != "mm" && c.PrecipitationUnit != "inch" {
+		return fmt.Errorf("invalid precipitation unit: %s", c.PrecipitationUnit)
+	}
+	if !slices.Contains(PressureUnits, c.PressureUnit) {
+		return fmt.Errorf("invalid pressure unit: %s", c.PressureUnit)
+	}
+	if !slices.Contains(OutputFormats, c.OutputFormat) {
+		return fmt.Errorf("invalid output format: %s", c.OutputFormat)
+	}
+	if c.Intervals.OutputSignal < 0 || c.Intervals.OutputSignal > MaxOutputSignal {
+		return fmt.Errorf("output signal must be between 0 and %d", MaxOutputSignal)
+	}
+	if c.Fullscreen.Enabled && (c.Fullscreen.CheckInterval <= 0 || c.Fullscreen.WeatherUpdate <= 0 ||
+		c.Fullscreen.Output <= 0) {
+		return fmt.Errorf("fullscreen intervals must be positive")
+	}
+	if c.Pressure != "sea_level" && c.Pressure != "surface" {
+		return fmt.Errorf("invalid pressure: %s", c.Pressure)
+	}
+	if c.LogCoordinates != "exact" && c.LogCoordinates != "round" && c.LogCoordinates != "redact" {
+		return fmt.Errorf("invalid log coordinates mode: %s", c.LogCoordinates)
+	}
 	if c.Weather.ForecastHours < 1 || c.Weather.ForecastHours > 24 {
 		return fmt.Errorf("invalid forcast hours: %d", c.Weather.ForecastHours)
 	}
+	if _, ok := c.WeatherBackendURL(c.Weather.Backend); !ok && !slices.Contains(WeatherProviders, c.Weather.Backend) {
+		return fmt.Errorf("invalid weather backend: %s", c.Weather.Backend)
+	}
+	for name, backendURL := range c.Weather.Backends {
+		if slices.Contains(WeatherProviders, name) {
+			return fmt.Errorf("weather backend %s conflicts with a built-in backend", name)
+		}
+		if _, err := url.ParseRequestURI(backendURL); err != nil {
+			return fmt.Errorf("invalid URL of weather backend %s: %w", name, err)
+		}
+	}
+	weatherKit := c.Weather.WeatherKit
+	if c.Weather.Backend == "weatherkit" && weatherKit.KeyFile == "" {
+		return fmt.Errorf("weatherkit backend requires a key file")
+	}
+	if weatherKit.KeyFile != "" && (weatherKit.TeamID == "" || weatherKit.ServiceID == "" || weatherKit.KeyID == "") {
+		return fmt.Errorf("weatherkit backend requires a team ID, service ID and key ID")
+	}
+	if c.Weather.Backend == "pirateweather" && c.Weather.PirateWeather.APIKey == "" {
+		return fmt.Errorf("pirateweather backend requires an API key")
+	}
+	if c.Weather.Backend == "metoffice" && c.Weather.MetOffice.APIKey == "" {
+		return fmt.Errorf("metoffice backend requires an API key")
+	}
+	for host, endpoint := range c.Endpoints {
+		if _, err := url.ParseRequestURI(endpoint); err != nil {
+			return fmt.Errorf("invalid endpoint for %s: %w", host, err)
+		}
+	}
+	if c.Weather.ObservationSource != "" && c.Weather.ObservationSource != "nws" {
+		return fmt.Errorf("invalid observation source: %s", c.Weather.ObservationSource)
+	}
+	if c.Weather.ElevationSource != "" && c.Weather.ElevationSource != "open-meteo" &&
+		c.Weather.ElevationSource != "open-elevation" {
+		return fmt.Errorf("invalid elevation source: %s", c.Weather.ElevationSource)
+	}
+	if c.Weather.HeatingSetpoint != 0 && c.Weather.CoolingSetpoint != 0 &&
+		c.Weather.HeatingSetpoint >= c.Weather.CoolingSetpoint {
+		return fmt.Errorf("heating setpoint must be below cooling setpoint")
+	}
+	if c.Weather.PrimarySource != "model" && c.Weather.PrimarySource != "observation" {
+		return fmt.Errorf("invalid primary source: %s", c.Weather.PrimarySource)
+	}
+	if _, ok := c.Profiles[c.Profile]; c.Profile != "" && !ok {
+		return fmt.Errorf("unknown profile: %s", c.Profile)
+	}
+	for condition := range c.Hooks.Conditions {
+		if !slices.Contains(HookConditions, condition) {
+			return fmt.Errorf("invalid hook condition: %s", condition)
+		}
+	}
+	for name := range c.Hooks.Events {
+		if !slices.Contains(event.Kinds, event.Kind(name)) {
+			return fmt.Errorf("invalid hook event: %s", name)
+		}
+	}
+	for name, led := range c.Hooks.LEDs {
+		if _, err := rules.ParseExpression(led.When); err != nil {
+			return fmt.Errorf("invalid condition of LED hook %s: %w", name, err)
+		}
+		if strings.Trim(led.Device, ".") == "" || strings.Contains(led.Device, "/") || led.Brightness < 0 {
+			return fmt.Errorf("invalid device or brightness of LED hook %s", name)
+		}
+	}
+	if c.Hooks.Timeout <= 0 {
+		return fmt.Errorf("invalid hook timeout: %s", c.Hooks.Timeout)
+	}
+	if c.StartupBudget < 0 {
+		return fmt.Errorf("invalid startup budget: %s", c.StartupBudget)
+	}
+	if c.GeoLocation.Workers < 0 {
+		return fmt.Errorf("invalid number of geolocation workers: %d", c.GeoLocation.Workers)
+	}
+	if c.GeoLocation.MovementSpeed < 0 {
+		return fmt.Errorf("invalid movement speed: %f", c.GeoLocation.MovementSpeed)
+	}
+	if c.Briefing.Time != "" {
+		if _, err := time.Parse("15:04", c.Briefing.Time); err != nil {
+			return fmt.Errorf("invalid briefing time: %s", c.Briefing.Time)
+		}
+	}
+	for _, day := range c.Briefing.Weekdays {
+		if !slices.Contains(Weekdays, day) {
+			return fmt.Errorf("invalid briefing weekday: %s", day)
+		}
+	}
+	if c.HasFlood() {
+		if c.Flood.Latitude < -90 || c.Flood.Latitude > 90 || c.Flood.Longitude < -180 || c.Flood.Longitude > 180 {
+			return fmt.Errorf("invalid flood river point: %f, %f", c.Flood.Latitude, c.Flood.Longitude)
+		}
+		if c.Flood.Warning < 0 || c.Flood.Danger < 0 {
+			return fmt.Errorf("invalid flood thresholds")
+		}
+		if c.Flood.Interval < time.Hour {
+			return fmt.Errorf("flood interval must be at least 1h")
+		}
+	}
+	switch c.Earthquakes.Source {
+	case "", "usgs", "emsc":
+	default:
+		return fmt.Errorf("invalid earthquake source: %s", c.Earthquakes.Source)
+	}
+	if c.Earthquakes.Source != "" {
+		if c.Earthquakes.MinMagnitude < 0 || c.Earthquakes.Radius <= 0 || c.Earthquakes.MaxAge <= 0 {
+			return fmt.Errorf("invalid earthquake thresholds")
+		}
+		if c.Earthquakes.Interval < time.Minute {
+			return fmt.Errorf("earthquake interval must be at least 1m")
+		}
+	}
+	if c.Satellites.Enabled {
+		if c.Satellites.Group == "" || strings.ContainsAny(c.Satellites.Group, "&?#/ ") {
+			return fmt.Errorf("invalid satellite group: %s", c.Satellites.Group)
+		}
+		if len(c.Satellites.Names) == 0 {
+			c.Satellites.Names = []string{DefaultSatellite}
+		}
+		if c.Satellites.MinElevation < 0 || c.Satellites.MinElevation >= 90 {
+			return fmt.Errorf("invalid satellite minimum elevation: %f", c.Satellites.MinElevation)
+		}
+		if c.Satellites.MaxPasses <= 0 {
+			return fmt.Errorf("satellite max passes must be greater than 0")
+		}
+		// CelesTrak blocks clients that download the same data more often than every 2 hours
+		if c.Satellites.Interval < 2*time.Hour {
+			return fmt.Errorf("satellite interval must be at least 2h")
+		}
+	}
+	if c.ForecastDiff.Temperature < 0 || c.ForecastDiff.PrecipitationProbability < 0 {
+		return fmt.Errorf("invalid forecast diff thresholds")
+	}
+	if c.UV.Threshold <= 0 {
+		return fmt.Errorf("invalid UV threshold: %f", c.UV.Threshold)
+	}
+	if _, err := time.Parse("15:04", c.Umbrella.Home); err != nil {
+		return fmt.Errorf("invalid umbrella home time: %s", c.Umbrella.Home)
+	}
+	for _, period := range c.QuietHours.Ranges {
+		start, end, ok := strings.Cut(period, "-")
+		if _, err := time.Parse("15:04", strings.TrimSpace(start)); err != nil || !ok {
+			return fmt.Errorf("invalid quiet hours: %s", period)
+		}
+		if _, err := time.Parse("15:04", strings.TrimSpace(end)); err != nil {
+			return fmt.Errorf("invalid quiet hours: %s", period)
+		}
+	}
+	for _, day := range slices.Concat(c.QuietHours.Weekdays, c.QuietHours.AllDay) {
+		if !slices.Contains(Weekdays, day) {
+			return fmt.Errorf("invalid quiet hours weekday: %s", day)
+		}
+	}
+	for _, date := range c.QuietHours.Dates {
+		_, errDate := time.Parse(time.DateOnly, date)
+		_, errDay := time.Parse("01-02", date)
+		if errDate != nil && errDay != nil {
+			return fmt.Errorf("invalid quiet hours date: %s", date)
+		}
+	}
+	if c.Briefing.Template == "" {
+		c.Briefing.Template = DefaultBriefingTpl
+	}
+	if !slices.Contains(coordinate.Formats, c.Templates.CoordinateFormat) {
+		return fmt.Errorf("invalid coordinate format: %s", c.Templates.CoordinateFormat)
+	}
+	if c.Ensemble.Enabled && (!modelPattern.MatchString(c.Ensemble.Model) || c.Ensemble.Model == "") {
+		return fmt.Errorf("invalid ensemble model: %s", c.Ensemble.Model)
+	}
+	if c.Ensemble.LowerPercentile < 0 || c.Ensemble.UpperPercentile > 100 ||
+		c.Ensemble.LowerPercentile >= c.Ensemble.UpperPercentile {
+		return fmt.Errorf("invalid ensemble percentiles")
+	}
+	if !classPrefixPattern.MatchString(c.Templates.ClassPrefix) {
+		return fmt.Errorf("invalid CSS class prefix: %s", c.Templates.ClassPrefix)
+	}
+	if !modelPattern.MatchString(c.Weather.Model) {
+		return fmt.Errorf("invalid weather model: %s", c.Weather.Model)
+	}
+	if c.Templates.ChartHours < 1 || c.Templates.ChartHours > MaxChartHours {
+		return fmt.Errorf("chart hours must be between 1 and %d", MaxChartHours)
+	}
+	for _, phase := range c.Templates.Twilight {
+		if !slices.Contains(TwilightPhases, phase) {
+			return fmt.Errorf("invalid twilight phase: %s", phase)
+		}
+	}
+	if c.Templates.Alt != "mode" && c.Templates.Alt != "condition_group" {
+		return fmt.Errorf("invalid alt: %s", c.Templates.Alt)
+	}
+	precisions := []struct {
+		name  string
+		value *int
+	}{
+		{"temperature", c.Templates.Precision.Temperature},
+		{"wind", c.Templates.Precision.Wind},
+		{"precipitation", c.Templates.Precision.Precipitation},
+	}
+	for _, precision := range precisions {
+		if precision.value != nil && (*precision.value < 0 || *precision.value > MaxPrecision) {
+			return fmt.Errorf("%s precision must be between 0 and %d", precision.name, MaxPrecision)
+		}
+	}
+	if c.Templates.Percentage != "" && !slices.Contains(PercentageSources, c.Templates.Percentage) {
+		return fmt.Errorf("invalid percentage: %s", c.Templates.Percentage)
+	}
+	if !slices.Contains(WindDirectionFormats, c.Templates.WindDirectionFormat) {
+		return fmt.Errorf("invalid wind direction format: %s", c.Templates.WindDirectionFormat)
+	}
+	for _, rule := range c.Templates.ClassRules {
+		if _, err := rules.Parse(rule); err != nil {
+			return fmt.Errorf("invalid class rule: %w", err)
+		}
+	}
+	for name, plugin := range c.Plugins {
+		if plugin.Command == "" {
+			return fmt.Errorf("missing command for plugin %s", name)
+		}
+		if plugin.Timeout < 0 {
+			return fmt.Errorf("invalid timeout for plugin %s", name)
+		}
+		if plugin.Timeout == 0 {
+			plugin.Timeout = DefaultPluginTimeout
+			c.Plugins[name] = plugin
+		}
+	}
+	if c.GeoLocation.AccuracyImprovement <= 0 || c.GeoLocation.AccuracyImprovement > 1 {
+		return fmt.Errorf("invalid accuracy improvement: %f", c.GeoLocation.AccuracyImprovement)
+	}
+	if c.GeoLocation.StartupAccuracy < 0 || c.GeoLocation.StartupPreciseTimeout < 0 ||
+		c.GeoLocation.StartupAnyTimeout < 0 || c.GeoLocation.StartupIPTimeout < 0 {
+		return fmt.Errorf("invalid startup geolocation settings")
+	}
+	if c.GeoLocation.FallbackLatitude < -90 || c.GeoLocation.FallbackLatitude > 90 ||
+		c.GeoLocation.FallbackLongitude < -180 || c.GeoLocation.FallbackLongitude > 180 {
+		return fmt.Errorf("invalid fallback location")
+	}
+	for name, instance := range c.Instances {
+		if instance.Daily != "" && c.Templates.Daily == "" {
+			return fmt.Errorf("daily template of instance %s requires a global daily template", name)
+		}
+		if instance.Astro != "" && c.Templates.Astro == "" {
+			return fmt.Errorf("astro template of instance %s requires a global astro template", name)
+		}
+	}
+	if c.Accessibility.LeadTime <= 0 {
+		return fmt.Errorf("invalid announcement lead time: %s", c.Accessibility.LeadTime)
+	}
+	if c.HasTrip() {
+		if c.Trip.Latitude < -90 || c.Trip.Latitude > 90 || c.Trip.Longitude < -180 || c.Trip.Longitude > 180 {
+			return fmt.Errorf("invalid trip destination")
+		}
+		if _, err := ParseArrival(c.Trip.Arrival, time.Now()); err != nil {
+			return fmt.Errorf("invalid trip arrival: %w", err)
+		}
+	}
+	if c.DeltaAlerts.Window < time.Hour || c.DeltaAlerts.Window > 24*time.Hour {
+		return fmt.Errorf("invalid delta alert window: %s", c.DeltaAlerts.Window)
+	}
+	if c.DeltaAlerts.TemperatureDrop < 0 || c.DeltaAlerts.TemperatureRise < 0 || c.DeltaAlerts.WindIncrease < 0 ||
+		c.DeltaAlerts.PressureFall < 0 {
+		return fmt.Errorf("invalid delta alert threshold")
+	}
+	if c.Cache.Backend != "memory" && c.Cache.Backend != "file" {
+		return fmt.Errorf("invalid cache backend: %s", c.Cache.Backend)
+	}
+	if c.Cache.Geocode < 0 || c.Cache.Elevation < 0 || c.Cache.Weather < 0 || c.Cache.Alerts < 0 ||
+		c.Cache.Astronomy < 0 {
+		return fmt.Errorf("invalid cache TTL")
+	}
+	if !slices.Contains(GlyphModes, c.Icons.Glyphs) {
+		return fmt.Errorf("invalid glyph mode: %s", c.Icons.Glyphs)
+	}
+	if c.Icons.File != "" && c.Icons.Path == "" {
+		return fmt.Errorf("icon file requires an icon path")
+	}
+	for name, fence := range c.Geofences {
+		if fence.Latitude < -90 || fence.Latitude > 90 || fence.Longitude < -180 || fence.Longitude > 180 {
+			return fmt.Errorf("invalid location for geofence %s", name)
+		}
+		if fence.Radius < 0 {
+			return fmt.Errorf("invalid radius for geofence %s", name)
+		}
+		if _, ok := c.WeatherBackendURL(fence.Backend); fence.Backend != "" && !ok &&
+			!slices.Contains(WeatherProviders, fence.Backend) {
+			return fmt.Errorf("invalid weather backend for geofence %s: %s", name, fence.Backend)
+		}
+		if fence.Radius == 0 {
+			fence.Radius = DefaultGeofenceRadius
+			c.Geofences[name] = fence
+		}
+	}
+	for name, profile := range c.Profiles {
+		if profile.Latitude < -90 || profile.Latitude > 90 || profile.Longitude < -180 || profile.Longitude > 180 {
+			return fmt.Errorf("invalid location for profile %s", name)
+		}
+		if _, ok := c.WeatherBackendURL(profile.Backend); profile.Backend != "" && !ok &&
+			!slices.Contains(WeatherProviders, profile.Backend) {
+			return fmt.Errorf("invalid weather backend for profile %s: %s", name, profile.Backend)
+		}
+	}
+	if c.Templates.TextPreset != "" {
+		preset, ok := TextPresets[c.Templates.TextPreset]
+		if !ok {
+			return fmt.Errorf("invalid text preset: %s", c.Templates.TextPreset)
+		}
+		if c.Templates.Text == "" {
+			c.Templates.Text = preset
+			c.Templates.Clock = c.Templates.Clock || slices.Contains(ClockPresets, c.Templates.TextPreset)
+		}
+	}
+	if !slices.Contains(PrimaryIcons, c.Icons.Primary) {
+		return fmt.Errorf("invalid primary icon: %s", c.Icons.Primary)
+	}
+	if len(c.Icons.TemperatureRamp) == 0 {
+		c.Icons.TemperatureRamp = DefaultTemperatureRamp
+		if len(c.Icons.TemperatureBreakpoints) == 0 {
+			c.Icons.TemperatureBreakpoints = DefaultTemperatureBreakpoints[c.Units]
+		}
+	}
+	if len(c.Icons.TemperatureBreakpoints) != len(c.Icons.TemperatureRamp)-1 ||
+		!slices.IsSorted(c.Icons.TemperatureBreakpoints) {
+		return fmt.Errorf("temperature ramp requires one ascending breakpoint less than icons")
+	}
 	if c.Templates.Text == "" {
 		c.Templates.Text = DefaultTextTpl
 	}
 	if c.Templates.AltText == "" {
 		c.Templates.AltText = DefaultAltTextTpl
 	}
+	if c.Icons.Primary == "temperature" {
+		if c.Templates.Text == DefaultTextTpl || c.Templates.Text == TextPresets[c.Templates.TextPreset] {
+			c.Templates.Text = strings.ReplaceAll(c.Templates.Text, ".ConditionIcon", ".TemperatureIcon")
+		}
+		if c.Templates.AltText == DefaultAltTextTpl {
+			c.Templates.AltText = strings.ReplaceAll(c.Templates.AltText, ".ConditionIcon", ".TemperatureIcon")
+		}
+	}
 	if c.Templates.Tooltip == "" {
 		c.Templates.Tooltip = DefaultTooltipTpl
 	}
@@ -107,6 +1040,26 @@ func (c *Config) Validate() error {
 		home, _ := os.UserHomeDir()
 		c.GeoLocation.File = filepath.Join(home, ".config", "waybar-weather", "geolocation")
 	}
+	if c.GeoLocation.LocationCache == "" {
+		// Without a cache directory, the location would be cached relative to the working directory
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			c.GeoLocation.DisableLocationCache = true
+		}
+		c.GeoLocation.LocationCache = filepath.Join(cache, "waybar-weather", "location.json")
+	}
+	if c.APIBudget.StateFile == "" {
+		cache, _ := os.UserCacheDir()
+		c.APIBudget.StateFile = filepath.Join(cache, "waybar-weather", "api-usage.json")
+	}
+	if c.ForecastDiff.StateFile == "" {
+		cache, _ := os.UserCacheDir()
+		c.ForecastDiff.StateFile = filepath.Join(cache, "waybar-weather", "forecast.json")
+	}
+	if c.Cache.Dir == "" {
+		cache, _ := os.UserCacheDir()
+		c.Cache.Dir = filepath.Join(cache, "waybar-weather", "cache")
+	}
 
 	return nil
 }
@@ -0,0 +1,339 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package control implements the Unix socket of the daemon. It serves the module output to additional
+// instances, e.g. Waybar instances on other monitors, so that they share a single daemon and its API
+// traffic, and reports the status of the daemon.
+package control
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/output"
+)
+
+const (
+	// handshakeTimeout is the time a client has to send the name of its instance
+	handshakeTimeout = 5 * time.Second
+	// clientQueueSize is the number of outputs that are queued while a client is stalled
+	clientQueueSize = 1
+	// maxLineSize is the maximum size of a single output line
+	maxLineSize = 1024 * 1024
+	// retryDelay is the time the client waits before it reconnects to the daemon
+	retryDelay = 5 * time.Second
+	// backendTimeout is the time the client waits for the daemon to switch the weather backend
+	backendTimeout = 30 * time.Second
+)
+
+// Requests a client sends as first line after connecting to the socket.
+const (
+	// RequestAttach is followed by the name of the instance, whose output the client receives
+	RequestAttach = "attach"
+	// RequestStatus makes the daemon respond with its status as a single JSON line
+	RequestStatus = "status"
+	// RequestBackend is followed by the name of the weather backend the daemon switches to
+	RequestBackend = "backend"
+)
+
+// Response is sent by the daemon as a single JSON line in reply to requests that change its state.
+type Response struct {
+	Error string `json:"error,omitempty"`
+}
+
+// ErrInUse is returned if another daemon is already listening on the socket.
+var ErrInUse = errors.New("another daemon is already listening on the socket")
+
+// Server serves the output of the module instances to the clients attached to the socket and reports
+// the status of the daemon.
+type Server struct {
+	path      string
+	instances []string
+	status    func() any
+	backend   func(context.Context, string) error
+	logger    *logger.Logger
+
+	mu      sync.Mutex
+	clients map[*client]struct{}
+	last    map[string]any
+}
+
+// client represents an attached instance.
+type client struct {
+	instance string
+	conn     net.Conn
+	writer   output.Writer
+}
+
+// NewServer returns a new Server that listens on the Unix socket at path and serves the given instances.
+// The status function returns the status of the daemon, which is reported as JSON. The backend function
+// switches the weather backend.
+func NewServer(path string, instances []string, status func() any, backend func(context.Context, string) error,
+	log *logger.Logger,
+) *Server {
+	return &Server{
+		path:      path,
+		instances: instances,
+		status:    status,
+		backend:   backend,
+		logger:    log,
+		clients:   make(map[*client]struct{}),
+		last:      make(map[string]any),
+	}
+}
+
+// Listen accepts clients on the socket until the context ends. If another daemon is already listening
+// on the socket, an error is returned.
+func (s *Server) Listen(ctx context.Context) error {
+	if conn, err := net.Dial("unix", s.path); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("%w: %s", ErrInUse, s.path)
+	}
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on socket: %w", err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	if err = os.Chmod(s.path, 0o600); err != nil {
+		return fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	s.logger.Debug("listening on control socket", slog.String("path", s.path))
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept client: %w", err)
+		}
+		go s.handle(ctx, conn)
+	}
+}
+
+// Publish sends the output of the given instance to all clients attached to that instance. The output is
+// kept, so that it can be sent to clients attaching later. Clients that fail to receive the output are
+// disconnected and an error is returned.
+func (s *Server) Publish(instance string, v any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last[instance] = v
+	var failed int
+	for c := range s.clients {
+		if c.instance != instance {
+			continue
+		}
+		if err := c.writer.Write(v); err != nil {
+			s.logger.Debug("failed to write to client", logger.Err(err), slog.String("instance", instance))
+			_ = c.conn.Close()
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to write output to %d clients of instance %s", failed, instance)
+	}
+	return nil
+}
+
+// handle reads the request of the client. Status requests are answered right away, attached clients are
+// registered until they disconnect.
+func (s *Server) handle(ctx context.Context, conn net.Conn) {
+	defer s.logger.RecoverPanic("control_client")
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_ = conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		s.logger.Warn("failed to read request from client", logger.Err(err))
+		return
+	}
+	request, instance, _ := strings.Cut(strings.TrimSpace(line), " ")
+	switch request {
+	case RequestStatus:
+		if err = output.NewJSONLines(conn).Write(s.status()); err != nil {
+			s.logger.Warn("failed to send status to client", logger.Err(err))
+		}
+		return
+	case RequestBackend:
+		var response Response
+		if err = s.backend(ctx, instance); err != nil {
+			response.Error = err.Error()
+		}
+		if err = output.NewJSONLines(conn).Write(response); err != nil {
+			s.logger.Warn("failed to send response to client", logger.Err(err))
+		}
+		return
+	case RequestAttach:
+	default:
+		s.logger.Warn("client sent unknown request", slog.String("request", request))
+		return
+	}
+	if !slices.Contains(s.instances, instance) {
+		s.logger.Warn("client requested unknown instance", slog.String("instance", instance))
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	ctxClient, cancelClient := context.WithCancel(ctx)
+	defer cancelClient()
+	c := &client{
+		instance: instance,
+		conn:     conn,
+		writer:   output.NewAsync(ctxClient, output.NewJSONLines(conn), s.logger, clientQueueSize),
+	}
+
+	s.mu.Lock()
+	s.clients[c] = struct{}{}
+	if last, ok := s.last[instance]; ok {
+		_ = c.writer.Write(last)
+	}
+	s.mu.Unlock()
+	s.logger.Debug("client attached", slog.String("instance", instance))
+
+	// The client doesn't send anything else, so reading only returns once it has disconnected
+	_, _ = io.Copy(io.Discard, reader)
+
+	s.mu.Lock()
+	delete(s.clients, c)
+	s.mu.Unlock()
+	s.logger.Debug("client detached", slog.String("instance", instance))
+}
+
+// Attach connects to the daemon listening on the socket at path and copies the output of the given
+// instance to w until the context ends or w has been closed. If the daemon is not running or goes away,
+// the connection is retried periodically.
+func Attach(ctx context.Context, path, instance string, w io.Writer, log *logger.Logger) {
+	for {
+		err := attach(ctx, path, instance, w)
+		if errors.Is(err, output.ErrBrokenPipe) {
+			log.Info("output has been closed, detaching")
+			return
+		}
+		if err != nil {
+			log.Warn("failed to attach to daemon, retrying", logger.Err(err), slog.Duration("retry_in", retryDelay))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+// attach copies the output of a single connection to w. Returns output.ErrBrokenPipe if w has been closed.
+func attach(ctx context.Context, path, instance string, w io.Writer) error {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to connect to socket: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	if _, err = io.WriteString(conn, RequestAttach+" "+instance+"\n"); err != nil {
+		return fmt.Errorf("failed to send attach request: %w", err)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+	for scanner.Scan() {
+		if _, err = w.Write(append(scanner.Bytes(), '\n')); err != nil {
+			return fmt.Errorf("%w: %w", output.ErrBrokenPipe, err)
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read from daemon: %w", err)
+	}
+	return fmt.Errorf("daemon has closed the connection")
+}
+
+// Status requests the status of the daemon listening on the socket at path and returns it as JSON.
+func Status(path string) (json.RawMessage, error) {
+	conn, err := net.DialTimeout("unix", path, handshakeTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to socket: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	if _, err = io.WriteString(conn, RequestStatus+"\n"); err != nil {
+		return nil, fmt.Errorf("failed to send status request: %w", err)
+	}
+	reader := bufio.NewReaderSize(conn, bufio.MaxScanTokenSize)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read status: %w", err)
+	}
+	return line, nil
+}
+
+// SwitchBackend makes the daemon listening on the socket at path switch to the weather backend with the
+// given name.
+func SwitchBackend(path, name string) error {
+	conn, err := net.DialTimeout("unix", path, handshakeTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to socket: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	// Switching waits for the running weather update to finish
+	_ = conn.SetDeadline(time.Now().Add(backendTimeout))
+
+	if _, err = io.WriteString(conn, RequestBackend+" "+name+"\n"); err != nil {
+		return fmt.Errorf("failed to send backend request: %w", err)
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	var response Response
+	if err = json.Unmarshal(line, &response); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if response.Error != "" {
+		return errors.New(response.Error)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package coordinate formats geographic coordinates for display.
+package coordinate
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Supported coordinate formats
+const (
+	FormatDecimal    = "decimal"
+	FormatDMS        = "dms"
+	FormatGeohash    = "geohash"
+	FormatMaidenhead = "maidenhead"
+)
+
+// Formats lists all supported coordinate formats.
+var Formats = []string{FormatDecimal, FormatDMS, FormatGeohash, FormatMaidenhead}
+
+const (
+	geohashAlphabet  = "0123456789bcdefghjkmnpqrstuvwxyz"
+	geohashPrecision = 8 // roughly 20m
+)
+
+// Format returns the coordinates in the given format. Unknown formats fall back to decimal degrees.
+func Format(lat, lon float64, format string) string {
+	switch format {
+	case FormatDMS:
+		return DMS(lat, lon)
+	case FormatGeohash:
+		return Geohash(lat, lon, geohashPrecision)
+	case FormatMaidenhead:
+		return Maidenhead(lat, lon)
+	default:
+		return Decimal(lat, lon)
+	}
+}
+
+// Decimal returns the coordinates in decimal degrees with 4 decimal places (e.g. "52.5200, 13.4050").
+func Decimal(lat, lon float64) string {
+	return fmt.Sprintf("%.4f, %.4f", lat, lon)
+}
+
+// DMS returns the coordinates in degrees, minutes and seconds (e.g. "52°31'12.0"N 13°24'18.0"E").
+func DMS(lat, lon float64) string {
+	latHemisphere, lonHemisphere := "N", "E"
+	if lat < 0 {
+		latHemisphere = "S"
+	}
+	if lon < 0 {
+		lonHemisphere = "W"
+	}
+	return dms(lat) + latHemisphere + " " + dms(lon) + lonHemisphere
+}
+
+func dms(value float64) string {
+	// Round to the displayed precision first, so that we don't end up with 60 seconds
+	tenths := math.Round(math.Abs(value) * 36000)
+	degrees := math.Floor(tenths / 36000)
+	minutes := math.Floor((tenths - degrees*36000) / 600)
+	seconds := (tenths - degrees*36000 - minutes*600) / 10
+	return fmt.Sprintf("%.0f°%02.0f'%04.1f\"", degrees, minutes, seconds)
+}
+
+// Geohash returns the geohash of the coordinates with the given number of characters.
+func Geohash(lat, lon float64, precision int) string {
+	latRange, lonRange := [2]float64{-90, 90}, [2]float64{-180, 180}
+	var hash strings.Builder
+	bit, char, even := 0, 0, true
+	for hash.Len() < precision {
+		target, value := &lonRange, lon
+		if !even {
+			target, value = &latRange, lat
+		}
+		mid := (target[0] + target[1]) / 2
+		char <<= 1
+		if value >= mid {
+			char |= 1
+			target[0] = mid
+		} else {
+			target[1] = mid
+		}
+		even = !even
+
+		if bit++; bit == 5 {
+			hash.WriteByte(geohashAlphabet[char])
+			bit, char = 0, 0
+		}
+	}
+	return hash.String()
+}
+
+// Maidenhead returns the 6 character Maidenhead locator of the coordinates (e.g. "JO62qm"), as used by
+// ham radio operators.
+func Maidenhead(lat, lon float64) string {
+	lon = math.Min(math.Max(lon+180, 0), 359.99999)
+	lat = math.Min(math.Max(lat+90, 0), 179.99999)
+	return string([]byte{
+		byte('A' + int(lon/20)),
+		byte('A' + int(lat/10)),
+		byte('0' + int(math.Mod(lon, 20)/2)),
+		byte('0' + int(math.Mod(lat, 10))),
+		byte('a' + int(math.Mod(lon, 2)*12)),
+		byte('a' + int(math.Mod(lat, 1)*24)),
+	})
+}
@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package elevation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wneessen/waybar-weather/internal/cache"
+)
+
+// cachePrecision is the amount of decimal places of the coordinates used as cache key (~110m)
+const cachePrecision = 3
+
+// Provider is implemented by all elevation lookup services.
+type Provider interface {
+	Name() string
+	Lookup(ctx context.Context, lat, lon float64) (float64, error)
+}
+
+// Cache wraps a Provider and caches the elevation per location, so that the elevation API is only
+// queried once per location.
+type Cache struct {
+	provider Provider
+	store    *cache.Store
+}
+
+// NewCache returns a new Cache for the given Provider, which caches the elevations in the store.
+func NewCache(provider Provider, store *cache.Store) *Cache {
+	return &Cache{
+		provider: provider,
+		store:    store,
+	}
+}
+
+// Name returns the name of the underlying Provider.
+func (c *Cache) Name() string {
+	return c.provider.Name()
+}
+
+// Lookup returns the cached elevation for the given coordinates or queries the underlying Provider
+// if the location has not been looked up before.
+func (c *Cache) Lookup(ctx context.Context, lat, lon float64) (float64, error) {
+	key := fmt.Sprintf("%s/%.*f,%.*f", c.provider.Name(), cachePrecision, lat, cachePrecision, lon)
+	var elevation float64
+	if c.store.Get(key, &elevation) {
+		return elevation, nil
+	}
+
+	elevation, err := c.provider.Lookup(ctx, lat, lon)
+	if err != nil {
+		return 0, err
+	}
+	_ = c.store.Set(key, elevation)
+	return elevation, nil
+}
@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package openelevation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/http"
+)
+
+const (
+	APIEndpoint = "https://api.open-elevation.com/api/v1/lookup"
+	APITimeout  = time.Second * 10
+	name        = "open-elevation"
+)
+
+type OpenElevation struct {
+	http *http.Client
+}
+
+type Response struct {
+	Results []Result `json:"results"`
+}
+
+type Result struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Elevation float64 `json:"elevation"`
+}
+
+func New(client *http.Client) *OpenElevation {
+	return &OpenElevation{http: client}
+}
+
+func (o *OpenElevation) Name() string {
+	return name
+}
+
+// Lookup returns the elevation in meters for the given coordinates using the Open-Elevation API.
+func (o *OpenElevation) Lookup(ctx context.Context, lat, lon float64) (float64, error) {
+	var response Response
+	apiUrl, err := url.Parse(APIEndpoint)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse API endpoint: %w", err)
+	}
+	query := apiUrl.Query()
+	query.Set("locations", fmt.Sprintf("%f,%f", lat, lon))
+	apiUrl.RawQuery = query.Encode()
+
+	if _, err = o.http.GetWithTimeout(ctx, apiUrl.String(), &response, nil, APITimeout); err != nil {
+		return 0, fmt.Errorf("failed to get elevation from Open-Elevation API: %w", err)
+	}
+	if len(response.Results) != 1 {
+		return 0, errors.New("no elevation returned for coordinates")
+	}
+	return response.Results[0].Elevation, nil
+}
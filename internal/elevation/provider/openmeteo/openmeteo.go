@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package openmeteo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/http"
+)
+
+const (
+	APIEndpoint = "https://api.open-meteo.com/v1/elevation"
+	APITimeout  = time.Second * 10
+	name        = "open-meteo"
+)
+
+type OpenMeteo struct {
+	http *http.Client
+}
+
+type Response struct {
+	Elevation []float64 `json:"elevation"`
+}
+
+func New(client *http.Client) *OpenMeteo {
+	return &OpenMeteo{http: client}
+}
+
+func (o *OpenMeteo) Name() string {
+	return name
+}
+
+// Lookup returns the elevation in meters for the given coordinates using the Open-Meteo
+// elevation API.
+func (o *OpenMeteo) Lookup(ctx context.Context, lat, lon float64) (float64, error) {
+	var response Response
+	apiUrl, err := url.Parse(APIEndpoint)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse API endpoint: %w", err)
+	}
+	query := apiUrl.Query()
+	query.Set("latitude", fmt.Sprintf("%f", lat))
+	query.Set("longitude", fmt.Sprintf("%f", lon))
+	apiUrl.RawQuery = query.Encode()
+
+	if _, err = o.http.GetWithTimeout(ctx, apiUrl.String(), &response, nil, APITimeout); err != nil {
+		return 0, fmt.Errorf("failed to get elevation from Open-Meteo API: %w", err)
+	}
+	if len(response.Elevation) != 1 {
+		return 0, errors.New("no elevation returned for coordinates")
+	}
+	return response.Elevation[0], nil
+}
@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package event provides a publish/subscribe bus for the events exchanged between the subsystems of
+// the service.
+package event
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+// Kind identifies the type of event.
+type Kind string
+
+const (
+	// LocationChanged is published when a new location has been applied
+	LocationChanged Kind = "location-changed"
+	// WeatherUpdated is published when new weather data has been fetched
+	WeatherUpdated Kind = "weather-updated"
+	// SleepResume is published when the system resumed from sleep
+	SleepResume Kind = "sleep-resume"
+	// NetworkUp is published when a network connection has been established
+	NetworkUp Kind = "network-up"
+	// ConfigReloaded is published when the effective configuration changed, e.g. by a profile switch
+	ConfigReloaded Kind = "config-reloaded"
+	// TimeChanged is published when the system time has been changed, e.g. manually
+	TimeChanged Kind = "time-changed"
+)
+
+// Kinds lists all kinds of events.
+var Kinds = []Kind{LocationChanged, WeatherUpdated, SleepResume, NetworkUp, ConfigReloaded, TimeChanged}
+
+// Event represents an event published on the Bus.
+type Event struct {
+	Kind Kind
+	Time time.Time
+	// Source names the subsystem or provider that caused the event, if any
+	Source string
+}
+
+// Bus delivers published events to all subscribers of the event kind.
+type Bus struct {
+	logger *logger.Logger
+
+	mu          sync.RWMutex
+	subscribers map[Kind]map[chan Event]struct{}
+}
+
+// New initializes and returns a new Bus.
+func New(log *logger.Logger) *Bus {
+	return &Bus{
+		logger:      log,
+		subscribers: make(map[Kind]map[chan Event]struct{}),
+	}
+}
+
+// Subscribe adds a subscriber for the given event kinds with the given buffer size, returning an event
+// channel and an unsubscribe function.
+func (b *Bus) Subscribe(size int, kinds ...Kind) (<-chan Event, func()) {
+	eventChan := make(chan Event, size)
+	b.mu.Lock()
+	for _, kind := range kinds {
+		if _, ok := b.subscribers[kind]; !ok {
+			b.subscribers[kind] = make(map[chan Event]struct{})
+		}
+		b.subscribers[kind][eventChan] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsub := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			for _, kind := range kinds {
+				delete(b.subscribers[kind], eventChan)
+			}
+			b.mu.Unlock()
+			close(eventChan)
+		})
+	}
+	return eventChan, unsub
+}
+
+// Publish delivers an event of the given kind to all its subscribers. Publishing never blocks; if the
+// buffer of a subscriber is full, the event is dropped for that subscriber.
+func (b *Bus) Publish(kind Kind, source string) {
+	ev := Event{Kind: kind, Time: time.Now(), Source: source}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subscribers[kind] {
+		select {
+		case ch <- ev:
+		default:
+			b.logger.Debug("event subscriber is busy, dropping event", slog.String("event", string(kind)))
+		}
+	}
+}
@@ -0,0 +1,269 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package forecast_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"maps"
+	"math"
+	stdhttp "net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hectormalot/omgo"
+
+	"github.com/wneessen/waybar-weather/internal/conditions"
+	"github.com/wneessen/waybar-weather/internal/forecast"
+	"github.com/wneessen/waybar-weather/internal/forecast/provider/brightsky"
+	"github.com/wneessen/waybar-weather/internal/forecast/provider/envcanada"
+	"github.com/wneessen/waybar-weather/internal/forecast/provider/metoffice"
+	"github.com/wneessen/waybar-weather/internal/forecast/provider/pirateweather"
+	"github.com/wneessen/waybar-weather/internal/forecast/provider/weatherkit"
+	"github.com/wneessen/waybar-weather/internal/http"
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+// The fixtures in testdata are recorded responses of the backends for the same weather in the native units
+// of each API: 3 °C that feel like -6 °C, heavy rain of 8 mm per hour, a west wind of 36 km/h (10 m/s)
+// with gusts of 54 km/h, 80 % humidity and 1013 hPa, from 11:00 to 13:00 UTC at sea level in London.
+const (
+	conformanceLat = 51.5
+	conformanceLon = -0.12
+	// conformanceTolerance is the maximum difference of the normalized values in the units of the options
+	conformanceTolerance = 0.01
+	// conformanceHour is the index of the hour whose values are compared. The precipitation of an hour is
+	// reported at its end, so the first hour has none
+	conformanceHour = 1
+)
+
+// conformanceBody represents the parts of the Open-Meteo compatible response body that are compared.
+type conformanceBody struct {
+	CurrentWeather map[string]any    `json:"current_weather"`
+	HourlyUnits    map[string]string `json:"hourly_units"`
+	Hourly         map[string][]any  `json:"hourly"`
+	DailyUnits     map[string]string `json:"daily_units"`
+	Daily          map[string][]any  `json:"daily"`
+}
+
+// fixtureTransport answers the requests of a backend with the fixture of the first matching path prefix.
+type fixtureTransport struct {
+	dir      string
+	fixtures map[string]string
+}
+
+func (f *fixtureTransport) RoundTrip(request *stdhttp.Request) (*stdhttp.Response, error) {
+	for prefix, name := range f.fixtures {
+		if !strings.HasPrefix(request.URL.Path, prefix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(f.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		return &stdhttp.Response{
+			StatusCode: stdhttp.StatusOK,
+			Status:     "200 OK",
+			Header:     stdhttp.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(bytes.NewReader(data)),
+			Request:    request,
+		}, nil
+	}
+	return &stdhttp.Response{
+		StatusCode: stdhttp.StatusNotFound,
+		Status:     "404 Not Found",
+		Body:       io.NopCloser(strings.NewReader("{}")),
+		Request:    request,
+	}, nil
+}
+
+// TestProvider_Conformance checks that all backends normalize their recorded responses to the same fields
+// and values in the units of the options, so that e.g. a wind speed in m/s or a pressure in Pa is not
+// taken for km/h or hPa.
+func TestProvider_Conformance(t *testing.T) {
+	table, err := conditions.Load("")
+	if err != nil {
+		t.Fatalf("failed to load condition table: %s", err)
+	}
+	backends := []struct {
+		provider func(*testing.T, *http.Client) forecast.Provider
+		fixtures map[string]string
+		// unsupported are the hourly values the backend doesn't provide
+		unsupported []string
+	}{
+		{
+			provider: func(_ *testing.T, client *http.Client) forecast.Provider {
+				return pirateweather.New(client, "apikey", table.Backend("pirateweather"))
+			},
+			fixtures: map[string]string{"/forecast/": "forecast.json"},
+		},
+		{
+			provider: func(t *testing.T, client *http.Client) forecast.Provider {
+				provider, err := weatherkit.New(client, "team", "service", "key", testKeyFile(t),
+					table.Backend("weatherkit"))
+				if err != nil {
+					t.Fatalf("failed to create WeatherKit backend: %s", err)
+				}
+				return provider
+			},
+			fixtures: map[string]string{"/api/v1/weather/": "weather.json"},
+		},
+		{
+			provider: func(_ *testing.T, client *http.Client) forecast.Provider { return brightsky.New(client) },
+			fixtures: map[string]string{"/weather": "weather.json", "/current_weather": "current_weather.json"},
+		},
+		{
+			provider: func(_ *testing.T, client *http.Client) forecast.Provider {
+				return envcanada.New(client, table.Backend("envcanada"))
+			},
+			fixtures: map[string]string{"/collections/citypageweather-realtime/items": "items.json"},
+			// The city pages don't provide precipitation amounts
+			unsupported: []string{"precipitation"},
+		},
+		{
+			provider: func(_ *testing.T, client *http.Client) forecast.Provider {
+				return metoffice.New(client, "apikey", "se", table.Backend("metoffice"))
+			},
+			fixtures: map[string]string{
+				"/sitespecific/v0/point/hourly": "hourly.json",
+				"/sitespecific/v0/point/daily":  "daily.json",
+			},
+		},
+	}
+	units := []struct {
+		name    string
+		options omgo.Options
+		current map[string]float64
+		hourly  map[string]float64
+	}{
+		{
+			name:    "metric",
+			options: omgo.Options{TemperatureUnit: "celsius", WindspeedUnit: "kmh", PrecipitationUnit: "mm"},
+			current: map[string]float64{"temperature": 3, "windspeed": 36, "winddirection": 270, "weathercode": 65},
+			hourly: map[string]float64{
+				"temperature_2m": 3, "apparent_temperature": -6, "wind_speed_10m": 36, "wind_gusts_10m": 54,
+				"wind_direction_10m": 270, "relative_humidity_2m": 80, "pressure_msl": 1013, "surface_pressure": 1013,
+				"freezing_level_height": 461.54, "precipitation": 8, "weather_code": 65, "is_day": 1, "uv_index": 1,
+			},
+		},
+		{
+			name:    "imperial",
+			options: omgo.Options{TemperatureUnit: "fahrenheit", WindspeedUnit: "mph", PrecipitationUnit: "inch"},
+			current: map[string]float64{"temperature": 37.4, "windspeed": 22.37, "winddirection": 270, "weathercode": 65},
+			hourly: map[string]float64{
+				"temperature_2m": 37.4, "apparent_temperature": 21.2, "wind_speed_10m": 22.37, "wind_gusts_10m": 33.55,
+				"wind_direction_10m": 270, "relative_humidity_2m": 80, "pressure_msl": 1013, "surface_pressure": 1013,
+				"freezing_level_height": 461.54, "precipitation": 0.31, "weather_code": 65, "is_day": 1, "uv_index": 1,
+			},
+		},
+	}
+
+	for _, unit := range units {
+		t.Run(unit.name, func(t *testing.T) {
+			var reference *conformanceBody
+			var referenceName string
+			for _, backend := range backends {
+				client := http.New(logger.NewLogger(slog.LevelError), nil)
+				provider := backend.provider(t, client)
+				client.Transport = &fixtureTransport{
+					dir:      filepath.Join("testdata", provider.Name()),
+					fixtures: backend.fixtures,
+				}
+				t.Run(provider.Name(), func(t *testing.T) {
+					data, err := provider.Forecast(context.Background(), conformanceLat, conformanceLon, time.UTC,
+						&unit.options)
+					if err != nil {
+						t.Fatalf("failed to get forecast: %s", err)
+					}
+					var body conformanceBody
+					if err = json.Unmarshal(data, &body); err != nil {
+						t.Fatalf("failed to decode forecast: %s", err)
+					}
+
+					if times := body.Hourly["time"]; !slices.Equal(times, []any{"2026-01-15T11:00",
+						"2026-01-15T12:00", "2026-01-15T13:00"}) {
+						t.Errorf("unexpected hourly times: %v", times)
+					}
+					if current := body.CurrentWeather["time"]; current != "2026-01-15T13:00" {
+						t.Errorf("unexpected time of the current weather: %v", current)
+					}
+					for field, expected := range unit.current {
+						value, _ := body.CurrentWeather[field].(float64)
+						if math.Abs(value-expected) > conformanceTolerance {
+							t.Errorf("expected current %s of %v, got %v", field, expected, value)
+						}
+					}
+					for field, expected := range unit.hourly {
+						values, ok := body.Hourly[field]
+						switch {
+						case !ok && (field == "uv_index" || slices.Contains(backend.unsupported, field)):
+							continue
+						case !ok || len(values) <= conformanceHour:
+							t.Errorf("hourly %s is missing", field)
+							continue
+						case slices.Contains(backend.unsupported, field):
+							continue
+						}
+						value, _ := values[conformanceHour].(float64)
+						if math.Abs(value-expected) > conformanceTolerance {
+							t.Errorf("expected hourly %s of %v, got %v", field, expected, value)
+						}
+					}
+
+					// The optional UV index is the only field a backend may leave out
+					hourlyUnits := maps.Clone(body.HourlyUnits)
+					delete(hourlyUnits, "uv_index")
+					if reference == nil {
+						reference, referenceName = &body, provider.Name()
+						reference.HourlyUnits = hourlyUnits
+						return
+					}
+					if !maps.Equal(hourlyUnits, reference.HourlyUnits) {
+						t.Errorf("hourly units differ from %s: %v, expected %v", referenceName, hourlyUnits,
+							reference.HourlyUnits)
+					}
+					if !maps.Equal(body.DailyUnits, reference.DailyUnits) {
+						t.Errorf("daily units differ from %s: %v, expected %v", referenceName, body.DailyUnits,
+							reference.DailyUnits)
+					}
+					if fields, expected := slices.Sorted(maps.Keys(body.Daily)),
+						slices.Sorted(maps.Keys(reference.Daily)); !slices.Equal(fields, expected) {
+						t.Errorf("daily fields differ from %s: %v, expected %v", referenceName, fields, expected)
+					}
+				})
+			}
+		})
+	}
+}
+
+// testKeyFile returns the path of a PKCS #8 key file with a new ECDSA key for the WeatherKit backend.
+func testKeyFile(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "weatherkit.p8")
+	if err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write key file: %s", err)
+	}
+	return path
+}
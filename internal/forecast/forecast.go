@@ -0,0 +1,309 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package forecast provides the weather backends whose API is not Open-Meteo compatible. Their forecasts
+// are converted into Open-Meteo compatible response bodies, so that they are validated, cached and
+// rendered like the forecasts of the built-in backends.
+package forecast
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hectormalot/omgo"
+	"github.com/nathan-osman/go-sunrise"
+)
+
+const (
+	// timeLayout and dateLayout are the time formats of the Open-Meteo API
+	timeLayout = "2006-01-02T15:04"
+	dateLayout = "2006-01-02"
+
+	// lapseRate is the standard atmospheric temperature lapse rate in °C per meter
+	lapseRate = 0.0065
+	// sunriseElevation is the elevation of the sun at sunrise and sunset in degrees
+	sunriseElevation = -0.833
+)
+
+// Provider is implemented by all weather backends whose API is not Open-Meteo compatible.
+type Provider interface {
+	// Name returns the name of the backend.
+	Name() string
+	// Endpoint returns the URL of the API, which is probed to check whether the backend is reachable.
+	Endpoint() string
+	// Forecast returns the forecast for the given coordinates as Open-Meteo compatible response body in the
+	// units of the options. Dates and times are given in the time zone zone.
+	Forecast(ctx context.Context, lat, lon float64, zone *time.Location, opts *omgo.Options) ([]byte, error)
+	// Capabilities returns the optional data sets the backend provides.
+	Capabilities() Capabilities
+}
+
+// Capabilities describes the optional data sets of a weather backend. The service only requests the data
+// sets a backend provides and the templates can check them to skip or substitute sections.
+type Capabilities struct {
+	// Minutely is set if the precipitation of the current hour is refined by a minute forecast
+	Minutely bool `json:"minutely"`
+	// Alerts is set if the backend provides official weather warnings, it implements AlertProvider then
+	Alerts     bool `json:"alerts"`
+	AirQuality bool `json:"air_quality"`
+	Marine     bool `json:"marine"`
+}
+
+// AlertProvider is implemented by the weather backends that provide the official weather warnings of
+// their country and report the Alerts capability.
+type AlertProvider interface {
+	// Alerts returns the weather warnings in effect for the given coordinates.
+	Alerts(ctx context.Context, lat, lon float64) ([]Alert, error)
+}
+
+// Alert represents an official weather warning.
+type Alert struct {
+	Headline string
+	// Severity as given by the issuer, e.g. "yellow" or "warning"
+	Severity string
+	// Expires is the end of the warning, zero if unknown
+	Expires time.Time
+}
+
+// Data represents a forecast in the metric units of the Open-Meteo API. Values that a backend doesn't
+// provide are NaN and estimated from the other values, if possible.
+type Data struct {
+	Latitude  float64
+	Longitude float64
+	// Elevation of the forecast location in meters
+	Elevation float64
+	Current   Current
+	Hours     []Hour
+	Days      []Day
+}
+
+// Current represents the current conditions.
+type Current struct {
+	Time        time.Time
+	Temperature float64
+	// WeatherCode is the WMO weather code of the conditions
+	WeatherCode   int
+	WindSpeed     float64
+	WindDirection float64
+}
+
+// Hour represents the forecast of the hour starting at Time. Temperatures are given in °C, the wind speed
+// in km/h, the humidity in percent, pressures in hPa and heights in meters.
+type Hour struct {
+	Time                time.Time
+	Temperature         float64
+	ApparentTemperature float64
+	WeatherCode         int
+	WindSpeed           float64
+	WindDirection       float64
+	Humidity            float64
+	PressureMSL         float64
+	SurfacePressure     float64
+	FreezingLevel       float64
+	// WindGust is the maximum wind speed during the hour
+	WindGust float64
+	// Precipitation is the amount in mm during the hour
+	Precipitation float64
+	// UVIndex is NaN if the backend doesn't provide it
+	UVIndex float64
+}
+
+// Day represents the forecast of the day of Date.
+type Day struct {
+	Date           time.Time
+	TemperatureMax float64
+	TemperatureMin float64
+	WeatherCode    int
+	// PrecipitationProbability is the maximum probability of precipitation in percent
+	PrecipitationProbability float64
+}
+
+// response represents the parts of an Open-Meteo API response the service processes.
+type response struct {
+	Latitude         float64           `json:"latitude"`
+	Longitude        float64           `json:"longitude"`
+	Elevation        float64           `json:"elevation"`
+	UTCOffsetSeconds int               `json:"utc_offset_seconds"`
+	Timezone         string            `json:"timezone"`
+	TimezoneAbbr     string            `json:"timezone_abbreviation"`
+	CurrentWeather   currentWeather    `json:"current_weather"`
+	HourlyUnits      map[string]string `json:"hourly_units"`
+	Hourly           map[string]any    `json:"hourly"`
+	DailyUnits       map[string]string `json:"daily_units"`
+	Daily            map[string]any    `json:"daily"`
+}
+
+type currentWeather struct {
+	Time          string  `json:"time"`
+	Temperature   float64 `json:"temperature"`
+	WeatherCode   int     `json:"weathercode"`
+	WindSpeed     float64 `json:"windspeed"`
+	WindDirection float64 `json:"winddirection"`
+}
+
+// windUnits maps the wind speed units of the Open-Meteo API to their unit text and the factor to convert
+// a wind speed in km/h.
+var windUnits = map[string]struct {
+	unit   string
+	factor float64
+}{
+	"":    {"km/h", 1},
+	"kmh": {"km/h", 1},
+	"mph": {"mp/h", 1 / 1.609344},
+	"ms":  {"m/s", 1 / 3.6},
+	"kn":  {"kn", 1 / 1.852},
+}
+
+// Body returns the forecast as Open-Meteo compatible response body in the units of the options. Times are
+// given as wall clock times of the time zone zone, using the UTC offset in effect at each point in time like the
+// Open-Meteo API does, so that the times after a daylight saving time change are correct.
+// The precipitation of an hour is reported at the end of the hour, as the Open-Meteo API does.
+func (d *Data) Body(zone *time.Location, opts *omgo.Options) ([]byte, error) {
+	wind, ok := windUnits[opts.WindspeedUnit]
+	if !ok {
+		return nil, fmt.Errorf("unsupported wind speed unit: %s", opts.WindspeedUnit)
+	}
+	temperature, temperatureUnit := func(value float64) float64 { return value }, "°C"
+	if opts.TemperatureUnit == "fahrenheit" {
+		temperature, temperatureUnit = func(value float64) float64 { return value*9/5 + 32 }, "°F"
+	}
+	precipitation, precipitationUnit := func(value float64) float64 { return value }, "mm"
+	if opts.PrecipitationUnit == "inch" {
+		precipitation, precipitationUnit = func(value float64) float64 { return value / 25.4 }, "inch"
+	}
+
+	abbr, offset := d.Current.Time.In(zone).Zone()
+	wallTime := func(t time.Time) time.Time {
+		_, offset := t.In(zone).Zone()
+		return t.UTC().Add(time.Duration(offset) * time.Second)
+	}
+	body := response{
+		Latitude:         d.Latitude,
+		Longitude:        d.Longitude,
+		Elevation:        d.Elevation,
+		UTCOffsetSeconds: offset,
+		Timezone:         zone.String(),
+		TimezoneAbbr:     abbr,
+		CurrentWeather: currentWeather{
+			Time:          wallTime(d.Current.Time).Format(timeLayout),
+			Temperature:   temperature(d.Current.Temperature),
+			WeatherCode:   d.Current.WeatherCode,
+			WindSpeed:     d.Current.WindSpeed * wind.factor,
+			WindDirection: d.Current.WindDirection,
+		},
+		HourlyUnits: map[string]string{
+			"temperature_2m": temperatureUnit, "apparent_temperature": temperatureUnit, "wind_speed_10m": wind.unit,
+			"wind_gusts_10m": wind.unit, "wind_direction_10m": "°", "relative_humidity_2m": "%", "pressure_msl": "hPa",
+			"surface_pressure": "hPa", "freezing_level_height": "m", "precipitation": precipitationUnit,
+			"weather_code": "wmo code", "is_day": "",
+		},
+		DailyUnits: map[string]string{
+			"temperature_2m_max": temperatureUnit, "temperature_2m_min": temperatureUnit,
+			"weather_code": "wmo code", "precipitation_probability_max": "%",
+		},
+	}
+
+	hourly := map[string][]float64{}
+	times := make([]string, 0, len(d.Hours))
+	for i, hour := range d.Hours {
+		times = append(times, wallTime(hour.Time).Format(timeLayout))
+		isDay := 0.0
+		if sunrise.Elevation(d.Latitude, d.Longitude, hour.Time) > sunriseElevation {
+			isDay = 1
+		}
+		surfacePressure := hour.SurfacePressure
+		if math.IsNaN(surfacePressure) {
+			surfacePressure = hour.PressureMSL * math.Pow(1-lapseRate*d.Elevation/288.15, 5.255)
+		}
+		freezingLevel := hour.FreezingLevel
+		if math.IsNaN(freezingLevel) {
+			freezingLevel = max(d.Elevation+hour.Temperature/lapseRate, 0)
+		}
+		apparentTemperature := hour.ApparentTemperature
+		if math.IsNaN(apparentTemperature) {
+			apparentTemperature = apparent(hour.Temperature, hour.Humidity, hour.WindSpeed)
+		}
+		previous := 0.0
+		if i > 0 {
+			previous = d.Hours[i-1].Precipitation
+		}
+		for metric, value := range map[string]float64{
+			"temperature_2m":        temperature(hour.Temperature),
+			"apparent_temperature":  temperature(apparentTemperature),
+			"weather_code":          float64(hour.WeatherCode),
+			"wind_speed_10m":        hour.WindSpeed * wind.factor,
+			"wind_gusts_10m":        hour.WindGust * wind.factor,
+			"wind_direction_10m":    hour.WindDirection,
+			"relative_humidity_2m":  hour.Humidity,
+			"pressure_msl":          hour.PressureMSL,
+			"surface_pressure":      surfacePressure,
+			"freezing_level_height": freezingLevel,
+			"precipitation":         precipitation(previous),
+			"is_day":                isDay,
+		} {
+			hourly[metric] = append(hourly[metric], value)
+		}
+	}
+	// The UV index is optional, since not every backend provides it
+	if uv := d.uvIndex(); uv != nil {
+		hourly["uv_index"] = uv
+		body.HourlyUnits["uv_index"] = ""
+	}
+	body.Hourly = map[string]any{"time": times}
+	for metric, values := range hourly {
+		body.Hourly[metric] = values
+	}
+
+	daily := map[string][]float64{}
+	dates := make([]string, 0, len(d.Days))
+	for _, day := range d.Days {
+		dates = append(dates, day.Date.Format(dateLayout))
+		daily["temperature_2m_max"] = append(daily["temperature_2m_max"], temperature(day.TemperatureMax))
+		daily["temperature_2m_min"] = append(daily["temperature_2m_min"], temperature(day.TemperatureMin))
+		daily["weather_code"] = append(daily["weather_code"], float64(day.WeatherCode))
+		daily["precipitation_probability_max"] = append(daily["precipitation_probability_max"],
+			day.PrecipitationProbability)
+	}
+	body.Daily = map[string]any{"time": dates}
+	for metric, values := range daily {
+		body.Daily[metric] = values
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode forecast: %w", err)
+	}
+	return data, nil
+}
+
+// apparent returns the apparent temperature in °C by the formula of Steadman for the given temperature in
+// °C, relative humidity in percent and wind speed in km/h, which is also used by Open-Meteo.
+func apparent(temperature, humidity, windSpeed float64) float64 {
+	vaporPressure := humidity / 100 * 6.105 * math.Exp(17.27*temperature/(237.7+temperature))
+	return temperature + 0.33*vaporPressure - 0.70*windSpeed/3.6 - 4.00
+}
+
+// TimezoneName returns the IANA name of the time zone, which the APIs use to determine the days of the
+// daily forecast. Falls back to UTC for time zones without a known name.
+func TimezoneName(zone *time.Location) string {
+	if _, err := time.LoadLocation(zone.String()); err != nil || zone.String() == "Local" {
+		return "UTC"
+	}
+	return zone.String()
+}
+
+// uvIndex returns the hourly UV index, or nil if it is missing for any hour.
+func (d *Data) uvIndex() []float64 {
+	values := make([]float64, 0, len(d.Hours))
+	for _, hour := range d.Hours {
+		if math.IsNaN(hour.UVIndex) {
+			return nil
+		}
+		values = append(values, hour.UVIndex)
+	}
+	return values
+}
@@ -0,0 +1,302 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package brightsky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"time"
+
+	"github.com/hectormalot/omgo"
+
+	"github.com/wneessen/waybar-weather/internal/forecast"
+	"github.com/wneessen/waybar-weather/internal/http"
+)
+
+const (
+	APIEndpoint = "https://api.brightsky.dev"
+	APITimeout  = time.Second * 10
+	name        = "brightsky"
+
+	// forecastDays is the number of days requested from the API, including the previous day
+	forecastDays = 8
+)
+
+// BrightSky fetches the forecasts and observations of the Deutscher Wetterdienst (DWD) from the Bright Sky
+// API. The current conditions are taken from the observations of the nearest station.
+type BrightSky struct {
+	http *http.Client
+}
+
+// record is a weather record of the API in the default DWD units. The precipitation is the amount during
+// the preceding hour. Values a station doesn't report are null.
+type record struct {
+	Timestamp                time.Time `json:"timestamp"`
+	Condition                string    `json:"condition"`
+	Icon                     string    `json:"icon"`
+	Temperature              *float64  `json:"temperature"`
+	Precipitation            *float64  `json:"precipitation"`
+	PrecipitationProbability *float64  `json:"precipitation_probability"`
+	PressureMSL              *float64  `json:"pressure_msl"`
+	RelativeHumidity         *float64  `json:"relative_humidity"`
+	CloudCover               *float64  `json:"cloud_cover"`
+	WindSpeed                *float64  `json:"wind_speed"`
+	WindGustSpeed            *float64  `json:"wind_gust_speed"`
+	WindDirection            *float64  `json:"wind_direction"`
+}
+
+type source struct {
+	ObservationType string  `json:"observation_type"`
+	Height          float64 `json:"height"`
+}
+
+type weatherResponse struct {
+	Weather []record `json:"weather"`
+	Sources []source `json:"sources"`
+}
+
+type currentResponse struct {
+	Weather struct {
+		Timestamp       time.Time `json:"timestamp"`
+		Condition       string    `json:"condition"`
+		Icon            string    `json:"icon"`
+		Temperature     *float64  `json:"temperature"`
+		Precipitation60 *float64  `json:"precipitation_60"`
+		CloudCover      *float64  `json:"cloud_cover"`
+		WindSpeed10     *float64  `json:"wind_speed_10"`
+		WindDirection10 *float64  `json:"wind_direction_10"`
+	} `json:"weather"`
+}
+
+func New(client *http.Client) *BrightSky {
+	return &BrightSky{http: client}
+}
+
+func (b *BrightSky) Name() string {
+	return name
+}
+
+func (b *BrightSky) Endpoint() string {
+	return APIEndpoint
+}
+
+func (b *BrightSky) Capabilities() forecast.Capabilities {
+	return forecast.Capabilities{}
+}
+
+// Forecast returns the forecast for the given coordinates as Open-Meteo compatible response body. The past
+// hours are taken from the observations, the upcoming ones from the MOSMIX forecast of the DWD. The daily
+// forecast is aggregated from the hourly records, since the API doesn't provide one.
+func (b *BrightSky) Forecast(ctx context.Context, lat, lon float64, zone *time.Location,
+	opts *omgo.Options,
+) ([]byte, error) {
+	now := time.Now().In(zone)
+	start := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, zone)
+	query := url.Values{
+		"lat":       {fmt.Sprintf("%.4f", lat)},
+		"lon":       {fmt.Sprintf("%.4f", lon)},
+		"date":      {start.Format(time.RFC3339)},
+		"last_date": {start.AddDate(0, 0, forecastDays).Format(time.RFC3339)},
+		"tz":        {forecast.TimezoneName(zone)},
+	}
+	var response weatherResponse
+	if _, err := b.http.GetWithTimeout(ctx, APIEndpoint+"/weather?"+query.Encode(), &response, nil,
+		APITimeout); err != nil {
+		return nil, fmt.Errorf("failed to get forecast from Bright Sky API: %w", err)
+	}
+	if len(response.Weather) == 0 {
+		return nil, errors.New("Bright Sky API did not return weather data")
+	}
+
+	data := forecast.Data{Latitude: lat, Longitude: lon}
+	for _, src := range response.Sources {
+		if src.ObservationType == "forecast" {
+			data.Elevation = src.Height
+			break
+		}
+	}
+	// Values missing in a record are carried over from the previous one
+	var last record
+	for i, rec := range response.Weather {
+		rec = fill(rec, last)
+		last = rec
+		precipitation := 0.0
+		if i+1 < len(response.Weather) && response.Weather[i+1].Precipitation != nil {
+			precipitation = *response.Weather[i+1].Precipitation
+		}
+		gust := *rec.WindSpeed
+		if rec.WindGustSpeed != nil {
+			gust = max(*rec.WindGustSpeed, gust)
+		}
+		data.Hours = append(data.Hours, forecast.Hour{
+			Time:                rec.Timestamp,
+			Temperature:         *rec.Temperature,
+			ApparentTemperature: math.NaN(),
+			WeatherCode:         weatherCode(rec.Condition, rec.Icon, rec.CloudCover, rec.Precipitation),
+			WindSpeed:           *rec.WindSpeed,
+			WindGust:            gust,
+			WindDirection:       *rec.WindDirection,
+			Humidity:            *rec.RelativeHumidity,
+			PressureMSL:         *rec.PressureMSL,
+			SurfacePressure:     math.NaN(),
+			FreezingLevel:       math.NaN(),
+			UVIndex:             math.NaN(),
+			Precipitation:       precipitation,
+		})
+	}
+	data.Days = aggregateDays(response.Weather, data.Hours, zone)
+	data.Current = b.current(ctx, lat, lon, zone, data.Hours, now)
+	return data.Body(zone, opts)
+}
+
+// current returns the current conditions observed by the nearest station. If no observation is available,
+// the conditions of the current hour of the forecast are returned instead.
+func (b *BrightSky) current(ctx context.Context, lat, lon float64, zone *time.Location, hours []forecast.Hour,
+	now time.Time,
+) forecast.Current {
+	var current forecast.Current
+	for _, hour := range hours {
+		if hour.Time.After(now) {
+			break
+		}
+		current = forecast.Current{
+			Time:          hour.Time,
+			Temperature:   hour.Temperature,
+			WeatherCode:   hour.WeatherCode,
+			WindSpeed:     hour.WindSpeed,
+			WindDirection: hour.WindDirection,
+		}
+	}
+
+	query := url.Values{
+		"lat": {fmt.Sprintf("%.4f", lat)},
+		"lon": {fmt.Sprintf("%.4f", lon)},
+		"tz":  {forecast.TimezoneName(zone)},
+	}
+	var response currentResponse
+	if _, err := b.http.GetWithTimeout(ctx, APIEndpoint+"/current_weather?"+query.Encode(), &response, nil,
+		APITimeout); err != nil || response.Weather.Temperature == nil || response.Weather.Timestamp.IsZero() {
+		return current
+	}
+	observed := response.Weather
+	current.Time = observed.Timestamp
+	current.Temperature = *observed.Temperature
+	current.WeatherCode = weatherCode(observed.Condition, observed.Icon, observed.CloudCover,
+		observed.Precipitation60)
+	if observed.WindSpeed10 != nil {
+		current.WindSpeed = *observed.WindSpeed10
+	}
+	if observed.WindDirection10 != nil {
+		current.WindDirection = *observed.WindDirection10
+	}
+	return current
+}
+
+// aggregateDays returns the daily forecast of the hourly records. The weather code of a day is the most
+// severe one of its hours, like in the daily forecast of Open-Meteo.
+func aggregateDays(records []record, hours []forecast.Hour, zone *time.Location) []forecast.Day {
+	var days []forecast.Day
+	for i, hour := range hours {
+		local := hour.Time.In(zone)
+		date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, zone)
+		if len(days) == 0 || !days[len(days)-1].Date.Equal(date) {
+			days = append(days, forecast.Day{
+				Date:           date,
+				TemperatureMax: hour.Temperature,
+				TemperatureMin: hour.Temperature,
+			})
+		}
+		day := &days[len(days)-1]
+		day.TemperatureMax = max(day.TemperatureMax, hour.Temperature)
+		day.TemperatureMin = min(day.TemperatureMin, hour.Temperature)
+		day.WeatherCode = max(day.WeatherCode, hour.WeatherCode)
+		if probability := records[i].PrecipitationProbability; probability != nil {
+			day.PrecipitationProbability = max(day.PrecipitationProbability, *probability)
+		}
+	}
+	return days
+}
+
+// fill returns the record with its missing values taken from the previous record. Values that are missing
+// in both are set to zero.
+func fill(rec, previous record) record {
+	for _, field := range []struct{ value, fallback **float64 }{
+		{&rec.Temperature, &previous.Temperature},
+		{&rec.PressureMSL, &previous.PressureMSL},
+		{&rec.RelativeHumidity, &previous.RelativeHumidity},
+		{&rec.WindSpeed, &previous.WindSpeed},
+		{&rec.WindDirection, &previous.WindDirection},
+	} {
+		switch {
+		case *field.value != nil:
+		case *field.fallback != nil:
+			*field.value = *field.fallback
+		default:
+			*field.value = new(float64)
+		}
+	}
+	return rec
+}
+
+// weatherCode returns the WMO weather code of the condition of a record. The intensity of rain and snow is
+// derived from the precipitation of the hour, the cloudiness of dry conditions from the cloud cover.
+func weatherCode(condition, icon string, cloudCover, precipitation *float64) int {
+	amount := 0.0
+	if precipitation != nil {
+		amount = *precipitation
+	}
+	switch condition {
+	case "thunderstorm":
+		return 95
+	case "hail":
+		return 96
+	case "sleet":
+		return 66
+	case "fog":
+		return 45
+	case "snow":
+		switch {
+		case amount < 1:
+			return 71
+		case amount < 4:
+			return 73
+		default:
+			return 75
+		}
+	case "rain":
+		switch {
+		case amount < 2.5:
+			return 61
+		case amount < 7.6:
+			return 63
+		default:
+			return 65
+		}
+	}
+
+	if cloudCover != nil {
+		switch {
+		case *cloudCover < 20:
+			return 0
+		case *cloudCover < 50:
+			return 1
+		case *cloudCover < 80:
+			return 2
+		default:
+			return 3
+		}
+	}
+	switch icon {
+	case "clear-day", "clear-night":
+		return 0
+	case "partly-cloudy-day", "partly-cloudy-night":
+		return 2
+	default:
+		return 3
+	}
+}
@@ -0,0 +1,387 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package envcanada
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hectormalot/omgo"
+
+	"github.com/wneessen/waybar-weather/internal/forecast"
+	"github.com/wneessen/waybar-weather/internal/http"
+)
+
+const (
+	APIEndpoint = "https://api.weather.gc.ca/collections/citypageweather-realtime/items"
+	APITimeout  = time.Second * 10
+	name        = "envcanada"
+
+	// searchRadius is the distance in degrees around the location within which the nearest city is searched
+	searchRadius = 1.5
+)
+
+// compassPoints are the wind directions of the API in clockwise order, starting at north
+var compassPoints = []string{
+	"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW",
+}
+
+// EnvCanada fetches the city page forecasts of Environment and Climate Change Canada from the MSC GeoMet
+// API. The forecast of the city nearest to the location is used.
+type EnvCanada struct {
+	http *http.Client
+	// codes maps the icon codes of Environment Canada to WMO weather codes
+	codes map[string]int
+}
+
+// number is a numeric value of the API, which is given as number or string and may be empty. Empty values
+// are NaN.
+type number float64
+
+func (n *number) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "" || text == "null" {
+		*n = number(math.NaN())
+		return nil
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse Environment Canada value: %w", err)
+	}
+	*n = number(value)
+	return nil
+}
+
+// value returns the value, or fallback if the value is empty.
+func (n number) value(fallback float64) float64 {
+	if math.IsNaN(float64(n)) {
+		return fallback
+	}
+	return float64(n)
+}
+
+// measure is a localized value of the API.
+type measure struct {
+	Value struct {
+		EN number `json:"en"`
+	} `json:"value"`
+}
+
+type text struct {
+	EN string `json:"en"`
+}
+
+type wind struct {
+	Speed     measure `json:"speed"`
+	Gust      measure `json:"gust"`
+	Direction struct {
+		Value text `json:"value"`
+	} `json:"direction"`
+}
+
+type properties struct {
+	CurrentConditions struct {
+		Timestamp        text    `json:"timestamp"`
+		Temperature      measure `json:"temperature"`
+		RelativeHumidity measure `json:"relativeHumidity"`
+		Pressure         measure `json:"pressure"`
+		Wind             wind    `json:"wind"`
+		IconCode         struct {
+			Value number `json:"value"`
+		} `json:"iconCode"`
+	} `json:"currentConditions"`
+	HourlyForecastGroup struct {
+		HourlyForecasts []struct {
+			Timestamp   time.Time `json:"timestamp"`
+			Temperature measure   `json:"temperature"`
+			Humidex     measure   `json:"humidex"`
+			WindChill   measure   `json:"windChill"`
+			Wind        wind      `json:"wind"`
+			IconCode    struct {
+				Value number `json:"value"`
+			} `json:"iconCode"`
+		} `json:"hourlyForecasts"`
+	} `json:"hourlyForecastGroup"`
+	ForecastGroup struct {
+		Forecasts []struct {
+			Period struct {
+				TextForecastName text `json:"textForecastName"`
+			} `json:"period"`
+			Temperatures struct {
+				Temperature []struct {
+					Class text   `json:"class"`
+					Value number `json:"value"`
+				} `json:"temperature"`
+			} `json:"temperatures"`
+			AbbreviatedForecast struct {
+				IconCode struct {
+					Value number `json:"value"`
+				} `json:"icon"`
+				PoP measure `json:"pop"`
+			} `json:"abbreviatedForecast"`
+		} `json:"forecasts"`
+	} `json:"forecastGroup"`
+	Warnings []struct {
+		Type        text   `json:"type"`
+		Description text   `json:"description"`
+		ExpiryTime  string `json:"expiryTime"`
+	} `json:"warnings"`
+}
+
+type featureCollection struct {
+	Features []struct {
+		Geometry struct {
+			Coordinates []float64 `json:"coordinates"`
+		} `json:"geometry"`
+		Properties json.RawMessage `json:"properties"`
+	} `json:"features"`
+}
+
+func New(client *http.Client, codes map[string]int) *EnvCanada {
+	return &EnvCanada{http: client, codes: codes}
+}
+
+func (e *EnvCanada) Name() string {
+	return name
+}
+
+func (e *EnvCanada) Endpoint() string {
+	return APIEndpoint
+}
+
+func (e *EnvCanada) Capabilities() forecast.Capabilities {
+	return forecast.Capabilities{Alerts: true}
+}
+
+// Forecast returns the forecast for the given coordinates as Open-Meteo compatible response body. The
+// city pages don't provide precipitation amounts, nor the humidity and pressure of the upcoming hours, so
+// the precipitation is reported as zero and the current humidity and pressure are used for all hours.
+func (e *EnvCanada) Forecast(ctx context.Context, lat, lon float64, zone *time.Location,
+	opts *omgo.Options,
+) ([]byte, error) {
+	page, err := e.cityPage(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	hourly := page.HourlyForecastGroup.HourlyForecasts
+	if len(hourly) == 0 {
+		return nil, errors.New("Environment Canada API did not return hourly forecast data")
+	}
+
+	current := page.CurrentConditions
+	humidity := current.RelativeHumidity.Value.EN.value(50)
+	// The pressure is given in kPa
+	pressure := current.Pressure.Value.EN.value(101.325) * 10
+	// The elevation of the city is unknown, so the freezing level is estimated from sea level
+	data := forecast.Data{Latitude: lat, Longitude: lon}
+	for _, hour := range hourly {
+		temperature := hour.Temperature.Value.EN.value(0)
+		apparentTemperature := hour.Humidex.Value.EN.value(hour.WindChill.Value.EN.value(temperature))
+		data.Hours = append(data.Hours, forecast.Hour{
+			Time:                hour.Timestamp,
+			Temperature:         temperature,
+			ApparentTemperature: apparentTemperature,
+			WeatherCode:         e.weatherCode(hour.IconCode.Value.value(10)),
+			WindSpeed:           hour.Wind.Speed.Value.EN.value(0),
+			WindGust:            max(hour.Wind.Gust.Value.EN.value(0), hour.Wind.Speed.Value.EN.value(0)),
+			WindDirection:       direction(hour.Wind.Direction.Value.EN),
+			Humidity:            humidity,
+			PressureMSL:         pressure,
+			SurfacePressure:     math.NaN(),
+			FreezingLevel:       math.NaN(),
+			UVIndex:             math.NaN(),
+		})
+	}
+	data.Current = forecast.Current{
+		Time:          data.Hours[0].Time,
+		Temperature:   data.Hours[0].Temperature,
+		WeatherCode:   data.Hours[0].WeatherCode,
+		WindSpeed:     data.Hours[0].WindSpeed,
+		WindDirection: data.Hours[0].WindDirection,
+	}
+	if observed, err := time.Parse(time.RFC3339, current.Timestamp.EN); err == nil &&
+		!math.IsNaN(float64(current.Temperature.Value.EN)) {
+		data.Current = forecast.Current{
+			Time:          observed,
+			Temperature:   float64(current.Temperature.Value.EN),
+			WeatherCode:   e.weatherCode(current.IconCode.Value.value(10)),
+			WindSpeed:     current.Wind.Speed.Value.EN.value(0),
+			WindDirection: direction(current.Wind.Direction.Value.EN),
+		}
+	}
+	data.Days = e.days(page, data.Hours, zone)
+	return data.Body(zone, opts)
+}
+
+// Alerts returns the warnings, watches and statements in effect for the city nearest to the location.
+func (e *EnvCanada) Alerts(ctx context.Context, lat, lon float64) ([]forecast.Alert, error) {
+	page, err := e.cityPage(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	alerts := make([]forecast.Alert, 0, len(page.Warnings))
+	for _, warning := range page.Warnings {
+		if warning.Description.EN == "" {
+			continue
+		}
+		alert := forecast.Alert{
+			Headline: warning.Description.EN,
+			Severity: strings.ToLower(warning.Type.EN),
+		}
+		if expires, err := time.Parse(time.RFC3339, warning.ExpiryTime); err == nil {
+			alert.Expires = expires
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// cityPage returns the city page of the city nearest to the coordinates.
+func (e *EnvCanada) cityPage(ctx context.Context, lat, lon float64) (properties, error) {
+	query := url.Values{
+		"f":    {"json"},
+		"lang": {"en"},
+		"bbox": {fmt.Sprintf("%.4f,%.4f,%.4f,%.4f", lon-searchRadius, lat-searchRadius, lon+searchRadius,
+			lat+searchRadius)},
+	}
+	var response featureCollection
+	if _, err := e.http.GetWithTimeout(ctx, APIEndpoint+"?"+query.Encode(), &response, nil,
+		APITimeout); err != nil {
+		return properties{}, fmt.Errorf("failed to get city pages from Environment Canada API: %w", err)
+	}
+
+	nearest, distance := -1, math.Inf(1)
+	for i, feature := range response.Features {
+		if len(feature.Geometry.Coordinates) < 2 {
+			continue
+		}
+		dLon := (feature.Geometry.Coordinates[0] - lon) * math.Cos(lat*math.Pi/180)
+		dLat := feature.Geometry.Coordinates[1] - lat
+		if d := dLon*dLon + dLat*dLat; d < distance {
+			nearest, distance = i, d
+		}
+	}
+	if nearest < 0 {
+		return properties{}, errors.New("Environment Canada API did not return a city near the location")
+	}
+	var page properties
+	if err := json.Unmarshal(response.Features[nearest].Properties, &page); err != nil {
+		return properties{}, fmt.Errorf("failed to decode Environment Canada city page: %w", err)
+	}
+	return page, nil
+}
+
+// days returns the daily forecast of the city page. The forecast periods are named after the day they
+// belong to, e.g. "Tonight" or "Monday night". Values missing in the periods, like the high of the current
+// day in the evening, are taken from the hourly forecast.
+func (e *EnvCanada) days(page properties, hours []forecast.Hour, zone *time.Location) []forecast.Day {
+	byDate := make(map[time.Time]*forecast.Day)
+	dayOf := func(date time.Time) *forecast.Day {
+		if day, ok := byDate[date]; ok {
+			return day
+		}
+		day := &forecast.Day{Date: date, TemperatureMax: math.NaN(), TemperatureMin: math.NaN()}
+		byDate[date] = day
+		return day
+	}
+
+	now := time.Now().In(zone)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, zone)
+	codes := make(map[time.Time]int)
+	for _, period := range page.ForecastGroup.Forecasts {
+		periodName := strings.ToLower(period.Period.TextForecastName.EN)
+		weekday, night := strings.CutSuffix(periodName, " night")
+		date := today
+		if weekday != "today" && weekday != "tonight" {
+			offset := slices.IndexFunc([]time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday,
+				time.Thursday, time.Friday, time.Saturday}, func(day time.Weekday) bool {
+				return strings.ToLower(day.String()) == weekday
+			})
+			if offset < 0 {
+				continue
+			}
+			date = today.AddDate(0, 0, (offset-int(today.Weekday())+7)%7)
+		}
+		night = night || weekday == "tonight"
+
+		day := dayOf(date)
+		for _, temperature := range period.Temperatures.Temperature {
+			switch {
+			case temperature.Class.EN == "high" && !math.IsNaN(float64(temperature.Value)):
+				day.TemperatureMax = float64(temperature.Value)
+			case temperature.Class.EN == "low" && !math.IsNaN(float64(temperature.Value)):
+				day.TemperatureMin = float64(temperature.Value)
+			}
+		}
+		if code, ok := e.codes[strconv.Itoa(int(period.AbbreviatedForecast.IconCode.Value.value(-1)))]; ok {
+			if _, set := codes[date]; !set || !night {
+				codes[date] = code
+			}
+		}
+		day.PrecipitationProbability = max(day.PrecipitationProbability,
+			period.AbbreviatedForecast.PoP.Value.EN.value(0))
+	}
+
+	for _, hour := range hours {
+		local := hour.Time.In(zone)
+		day := dayOf(time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, zone))
+		if _, ok := codes[day.Date]; !ok {
+			day.WeatherCode = max(day.WeatherCode, hour.WeatherCode)
+		}
+		day.TemperatureMax = fallback(day.TemperatureMax, hour.Temperature, math.Max)
+		day.TemperatureMin = fallback(day.TemperatureMin, hour.Temperature, math.Min)
+	}
+
+	result := make([]forecast.Day, 0, len(byDate))
+	for date, day := range byDate {
+		if code, ok := codes[date]; ok {
+			day.WeatherCode = code
+		}
+		// Days with only a high or only a low use it for both
+		if math.IsNaN(day.TemperatureMax) {
+			day.TemperatureMax = day.TemperatureMin
+		}
+		if math.IsNaN(day.TemperatureMin) {
+			day.TemperatureMin = day.TemperatureMax
+		}
+		if math.IsNaN(day.TemperatureMax) {
+			continue
+		}
+		result = append(result, *day)
+	}
+	slices.SortFunc(result, func(a, b forecast.Day) int { return a.Date.Compare(b.Date) })
+	return result
+}
+
+// weatherCode returns the WMO weather code of the icon code.
+func (e *EnvCanada) weatherCode(icon float64) int {
+	return e.codes[strconv.Itoa(int(icon))]
+}
+
+// fallback combines the value with the candidate by the given function, or returns the candidate if the
+// value is NaN.
+func fallback(value, candidate float64, combine func(float64, float64) float64) float64 {
+	if math.IsNaN(value) {
+		return candidate
+	}
+	return combine(value, candidate)
+}
+
+// direction returns the direction in degrees of the compass point. Variable winds are reported as north.
+func direction(point string) float64 {
+	index := slices.Index(compassPoints, strings.ToUpper(point))
+	if index < 0 {
+		return 0
+	}
+	return float64(index) * 22.5
+}
@@ -0,0 +1,275 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package metoffice
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	stdhttp "net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hectormalot/omgo"
+
+	"github.com/wneessen/waybar-weather/internal/forecast"
+	"github.com/wneessen/waybar-weather/internal/http"
+)
+
+const (
+	APIEndpoint      = "https://data.hub.api.metoffice.gov.uk/sitespecific/v0"
+	WarningsEndpoint = "https://www.metoffice.gov.uk/public/data/PWSCache/WarningsRSS/Region"
+	APITimeout       = time.Second * 10
+	name             = "metoffice"
+
+	// timeLayout is the time format of the API, which omits the seconds
+	timeLayout = "2006-01-02T15:04Z07:00"
+)
+
+// MetOffice fetches the site-specific forecast of the Met Office from its Weather DataHub API and the
+// severe weather warnings of the National Severe Weather Warning Service.
+type MetOffice struct {
+	http   *http.Client
+	apikey string
+	region string
+	// codes maps the significant weather codes of the Met Office to WMO weather codes
+	codes map[string]int
+}
+
+// apiTime is a time of the API, which doesn't conform to RFC 3339.
+type apiTime struct {
+	time.Time
+}
+
+func (t *apiTime) UnmarshalJSON(data []byte) error {
+	parsed, err := time.Parse(timeLayout, strings.Trim(string(data), `"`))
+	if err != nil {
+		return fmt.Errorf("failed to parse Met Office time: %w", err)
+	}
+	t.Time = parsed
+	return nil
+}
+
+type hourlyStep struct {
+	Time                   apiTime `json:"time"`
+	ScreenTemperature      float64 `json:"screenTemperature"`
+	FeelsLikeTemperature   float64 `json:"feelsLikeTemperature"`
+	WindSpeed10m           float64 `json:"windSpeed10m"`
+	WindGustSpeed10m       float64 `json:"windGustSpeed10m"`
+	WindDirectionFrom10m   float64 `json:"windDirectionFrom10m"`
+	ScreenRelativeHumidity float64 `json:"screenRelativeHumidity"`
+	MSLP                   float64 `json:"mslp"`
+	TotalPrecipAmount      float64 `json:"totalPrecipAmount"`
+	SignificantWeatherCode int     `json:"significantWeatherCode"`
+	UVIndex                float64 `json:"uvIndex"`
+}
+
+// dailyStep is a day of the daily forecast. The values of the day part are missing for the current day
+// once it has passed.
+type dailyStep struct {
+	Time                            apiTime  `json:"time"`
+	DayMaxScreenTemperature         *float64 `json:"dayMaxScreenTemperature"`
+	NightMinScreenTemperature       *float64 `json:"nightMinScreenTemperature"`
+	DaySignificantWeatherCode       *int     `json:"daySignificantWeatherCode"`
+	NightSignificantWeatherCode     *int     `json:"nightSignificantWeatherCode"`
+	DayProbabilityOfPrecipitation   *float64 `json:"dayProbabilityOfPrecipitation"`
+	NightProbabilityOfPrecipitation *float64 `json:"nightProbabilityOfPrecipitation"`
+}
+
+// featureCollection is the GeoJSON response of the API with the time series of type T.
+type featureCollection[T any] struct {
+	Features []struct {
+		Geometry struct {
+			// Longitude, latitude and elevation of the forecast site
+			Coordinates []float64 `json:"coordinates"`
+		} `json:"geometry"`
+		Properties struct {
+			TimeSeries []T `json:"timeSeries"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+type warningsFeed struct {
+	Channel struct {
+		Items []struct {
+			Title string `xml:"title"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// New returns a new Met Office backend. The warnings are taken from the RSS feed of the given region.
+func New(client *http.Client, apikey, region string, codes map[string]int) *MetOffice {
+	return &MetOffice{http: client, apikey: apikey, region: region, codes: codes}
+}
+
+func (m *MetOffice) Name() string {
+	return name
+}
+
+func (m *MetOffice) Endpoint() string {
+	return APIEndpoint
+}
+
+func (m *MetOffice) Capabilities() forecast.Capabilities {
+	return forecast.Capabilities{Alerts: true}
+}
+
+// Forecast returns the forecast for the given coordinates as Open-Meteo compatible response body. The
+// current conditions are taken from the current hour of the hourly forecast, since the API doesn't provide
+// observations.
+func (m *MetOffice) Forecast(ctx context.Context, lat, lon float64, zone *time.Location,
+	opts *omgo.Options,
+) ([]byte, error) {
+	var hourly featureCollection[hourlyStep]
+	if err := m.get(ctx, "hourly", lat, lon, &hourly); err != nil {
+		return nil, err
+	}
+	var daily featureCollection[dailyStep]
+	if err := m.get(ctx, "daily", lat, lon, &daily); err != nil {
+		return nil, err
+	}
+	if len(hourly.Features) == 0 || len(hourly.Features[0].Properties.TimeSeries) == 0 {
+		return nil, errors.New("Met Office API did not return hourly forecast data")
+	}
+
+	data := forecast.Data{Latitude: lat, Longitude: lon}
+	if coordinates := hourly.Features[0].Geometry.Coordinates; len(coordinates) > 2 {
+		data.Elevation = coordinates[2]
+	}
+	steps := hourly.Features[0].Properties.TimeSeries
+	for i, step := range steps {
+		// The precipitation amount is the total of the preceding hour
+		precipitation := 0.0
+		if i+1 < len(steps) {
+			precipitation = steps[i+1].TotalPrecipAmount
+		}
+		data.Hours = append(data.Hours, forecast.Hour{
+			Time:                step.Time.Time,
+			Temperature:         step.ScreenTemperature,
+			ApparentTemperature: step.FeelsLikeTemperature,
+			WeatherCode:         m.weatherCode(step.SignificantWeatherCode),
+			WindSpeed:           step.WindSpeed10m * 3.6,
+			WindGust:            max(step.WindGustSpeed10m, step.WindSpeed10m) * 3.6,
+			WindDirection:       step.WindDirectionFrom10m,
+			Humidity:            step.ScreenRelativeHumidity,
+			PressureMSL:         step.MSLP / 100,
+			SurfacePressure:     math.NaN(),
+			FreezingLevel:       math.NaN(),
+			Precipitation:       precipitation,
+			UVIndex:             step.UVIndex,
+		})
+	}
+	now := time.Now()
+	data.Current = forecast.Current{Time: data.Hours[0].Time}
+	for _, hour := range data.Hours {
+		if hour.Time.After(now) {
+			break
+		}
+		data.Current = forecast.Current{
+			Time:          hour.Time,
+			Temperature:   hour.Temperature,
+			WeatherCode:   hour.WeatherCode,
+			WindSpeed:     hour.WindSpeed,
+			WindDirection: hour.WindDirection,
+		}
+	}
+	if len(daily.Features) > 0 {
+		data.Days = m.days(daily.Features[0].Properties.TimeSeries, zone)
+	}
+	return data.Body(zone, opts)
+}
+
+// Alerts returns the severe weather warnings of the configured region. The feed doesn't state the end of a
+// warning, so a warning is shown until it is removed from the feed.
+func (m *MetOffice) Alerts(ctx context.Context, _, _ float64) ([]forecast.Alert, error) {
+	ctx, cancel := context.WithTimeout(ctx, APITimeout)
+	defer cancel()
+	request, err := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet,
+		WarningsEndpoint+"/"+url.PathEscape(m.region), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Met Office warnings request: %w", err)
+	}
+	request.Header.Set("User-Agent", http.UserAgent)
+	response, err := m.http.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Met Office warnings: %w", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != stdhttp.StatusOK {
+		return nil, fmt.Errorf("failed to get Met Office warnings: unexpected status: %s", response.Status)
+	}
+
+	var feed warningsFeed
+	if err = xml.NewDecoder(response.Body).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("failed to decode Met Office warnings: %w", err)
+	}
+	alerts := make([]forecast.Alert, 0, len(feed.Channel.Items))
+	for _, item := range feed.Channel.Items {
+		// The titles start with the color of the warning, e.g. "Yellow warning of wind affecting ..."
+		severity, _, _ := strings.Cut(item.Title, " ")
+		alerts = append(alerts, forecast.Alert{
+			Headline: strings.TrimSpace(item.Title),
+			Severity: strings.ToLower(severity),
+		})
+	}
+	return alerts, nil
+}
+
+// get requests the forecast of the given time step for the coordinates.
+func (m *MetOffice) get(ctx context.Context, step string, lat, lon float64, target any) error {
+	query := url.Values{
+		"latitude":                 {fmt.Sprintf("%.4f", lat)},
+		"longitude":                {fmt.Sprintf("%.4f", lon)},
+		"excludeParameterMetadata": {"true"},
+	}
+	apiURL := fmt.Sprintf("%s/point/%s?%s", APIEndpoint, step, query.Encode())
+	if _, err := m.http.GetWithTimeout(ctx, apiURL, target, map[string]string{"apikey": m.apikey},
+		APITimeout); err != nil {
+		return fmt.Errorf("failed to get %s forecast from Met Office API: %w", step, err)
+	}
+	return nil
+}
+
+// days returns the daily forecast of the time series. Values of the day part that have passed are taken
+// from the night part.
+func (m *MetOffice) days(steps []dailyStep, zone *time.Location) []forecast.Day {
+	result := make([]forecast.Day, 0, len(steps))
+	for _, step := range steps {
+		if step.NightMinScreenTemperature == nil {
+			continue
+		}
+		day := forecast.Day{
+			Date:           time.Date(step.Time.Year(), step.Time.Month(), step.Time.Day(), 0, 0, 0, 0, zone),
+			TemperatureMax: *step.NightMinScreenTemperature,
+			TemperatureMin: *step.NightMinScreenTemperature,
+		}
+		if step.DayMaxScreenTemperature != nil {
+			day.TemperatureMax = *step.DayMaxScreenTemperature
+		}
+		switch {
+		case step.DaySignificantWeatherCode != nil:
+			day.WeatherCode = m.weatherCode(*step.DaySignificantWeatherCode)
+		case step.NightSignificantWeatherCode != nil:
+			day.WeatherCode = m.weatherCode(*step.NightSignificantWeatherCode)
+		}
+		for _, probability := range []*float64{step.DayProbabilityOfPrecipitation,
+			step.NightProbabilityOfPrecipitation} {
+			if probability != nil {
+				day.PrecipitationProbability = max(day.PrecipitationProbability, *probability)
+			}
+		}
+		result = append(result, day)
+	}
+	return result
+}
+
+// weatherCode returns the WMO weather code of the significant weather code.
+func (m *MetOffice) weatherCode(code int) int {
+	return m.codes[strconv.Itoa(code)]
+}
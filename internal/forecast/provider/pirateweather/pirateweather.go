@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package pirateweather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hectormalot/omgo"
+
+	"github.com/wneessen/waybar-weather/internal/forecast"
+	"github.com/wneessen/waybar-weather/internal/http"
+)
+
+const (
+	APIEndpoint = "https://api.pirateweather.net/forecast"
+	APITimeout  = time.Second * 10
+	name        = "pirateweather"
+)
+
+// PirateWeather fetches the forecast from the Pirate Weather API, which is compatible with the former Dark
+// Sky API.
+type PirateWeather struct {
+	http   *http.Client
+	apikey string
+	// codes maps the icons of the API to WMO weather codes, rain and snow are refined by the summary
+	codes map[string]int
+}
+
+type dataPoint struct {
+	Time                int64   `json:"time"`
+	Summary             string  `json:"summary"`
+	Icon                string  `json:"icon"`
+	PrecipIntensity     float64 `json:"precipIntensity"`
+	PrecipProbability   float64 `json:"precipProbability"`
+	Temperature         float64 `json:"temperature"`
+	ApparentTemperature float64 `json:"apparentTemperature"`
+	TemperatureMax      float64 `json:"temperatureMax"`
+	TemperatureMin      float64 `json:"temperatureMin"`
+	Humidity            float64 `json:"humidity"`
+	Pressure            float64 `json:"pressure"`
+	WindSpeed           float64 `json:"windSpeed"`
+	WindGust            float64 `json:"windGust"`
+	WindBearing         float64 `json:"windBearing"`
+	UVIndex             float64 `json:"uvIndex"`
+}
+
+type forecastResponse struct {
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Elevation float64   `json:"elevation"`
+	Currently dataPoint `json:"currently"`
+	Hourly    struct {
+		Data []dataPoint `json:"data"`
+	} `json:"hourly"`
+	Daily struct {
+		Data []dataPoint `json:"data"`
+	} `json:"daily"`
+}
+
+func New(client *http.Client, apikey string, codes map[string]int) *PirateWeather {
+	return &PirateWeather{http: client, apikey: apikey, codes: codes}
+}
+
+func (p *PirateWeather) Name() string {
+	return name
+}
+
+func (p *PirateWeather) Endpoint() string {
+	return APIEndpoint
+}
+
+func (p *PirateWeather) Capabilities() forecast.Capabilities {
+	return forecast.Capabilities{}
+}
+
+// Forecast returns the forecast for the given coordinates as Open-Meteo compatible response body. The
+// forecast is requested in the "ca" units of the API, which match the metric units of Open-Meteo.
+func (p *PirateWeather) Forecast(ctx context.Context, lat, lon float64, zone *time.Location,
+	opts *omgo.Options,
+) ([]byte, error) {
+	query := url.Values{
+		"units":   {"ca"},
+		"extend":  {"hourly"},
+		"exclude": {"minutely,alerts,flags"},
+	}
+	apiURL := fmt.Sprintf("%s/%s/%.4f,%.4f?%s", APIEndpoint, url.PathEscape(p.apikey), lat, lon, query.Encode())
+	var response forecastResponse
+	if _, err := p.http.GetWithTimeout(ctx, apiURL, &response, nil, APITimeout); err != nil {
+		return nil, fmt.Errorf("failed to get forecast from Pirate Weather API: %w", err)
+	}
+	if response.Currently.Time == 0 {
+		return nil, errors.New("Pirate Weather API did not return current weather data")
+	}
+
+	current := response.Currently
+	data := forecast.Data{
+		Latitude:  lat,
+		Longitude: lon,
+		Elevation: response.Elevation,
+		Current: forecast.Current{
+			Time:          time.Unix(current.Time, 0),
+			Temperature:   current.Temperature,
+			WeatherCode:   p.weatherCode(current),
+			WindSpeed:     current.WindSpeed,
+			WindDirection: current.WindBearing,
+		},
+	}
+	for _, hour := range response.Hourly.Data {
+		data.Hours = append(data.Hours, forecast.Hour{
+			Time:                time.Unix(hour.Time, 0),
+			Temperature:         hour.Temperature,
+			ApparentTemperature: hour.ApparentTemperature,
+			WeatherCode:         p.weatherCode(hour),
+			WindSpeed:           hour.WindSpeed,
+			WindGust:            max(hour.WindGust, hour.WindSpeed),
+			WindDirection:       hour.WindBearing,
+			Humidity:            hour.Humidity * 100,
+			PressureMSL:         hour.Pressure,
+			SurfacePressure:     math.NaN(),
+			FreezingLevel:       math.NaN(),
+			// The intensity is given in mm/h, so it equals the amount during the hour
+			Precipitation: hour.PrecipIntensity,
+			UVIndex:       hour.UVIndex,
+		})
+	}
+	for _, day := range response.Daily.Data {
+		start := time.Unix(day.Time, 0).In(zone)
+		data.Days = append(data.Days, forecast.Day{
+			Date:                     time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, zone),
+			TemperatureMax:           day.TemperatureMax,
+			TemperatureMin:           day.TemperatureMin,
+			WeatherCode:              p.weatherCode(day),
+			PrecipitationProbability: day.PrecipProbability * 100,
+		})
+	}
+	return data.Body(zone, opts)
+}
+
+// weatherCode returns the WMO weather code of the data point. The icons of the API don't distinguish the
+// intensity of rain and snow or thunderstorms, which are taken from the summary instead.
+func (p *PirateWeather) weatherCode(point dataPoint) int {
+	summary := strings.ToLower(point.Summary)
+	switch {
+	case strings.Contains(summary, "thunder"):
+		return 95
+	case point.Icon == "rain" && strings.Contains(summary, "drizzle"):
+		return 53
+	case point.Icon == "rain" && strings.Contains(summary, "light"):
+		return 61
+	case point.Icon == "rain" && strings.Contains(summary, "heavy"):
+		return 65
+	case point.Icon == "snow" && (strings.Contains(summary, "flurries") || strings.Contains(summary, "light")):
+		return 71
+	case point.Icon == "snow" && strings.Contains(summary, "heavy"):
+		return 75
+	case strings.Contains(summary, "mostly clear"):
+		return 1
+	case strings.Contains(summary, "mostly cloudy"), strings.Contains(summary, "overcast"):
+		return 3
+	}
+	return p.codes[point.Icon]
+}
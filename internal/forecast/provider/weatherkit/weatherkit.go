@@ -0,0 +1,274 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package weatherkit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hectormalot/omgo"
+
+	"github.com/wneessen/waybar-weather/internal/forecast"
+	"github.com/wneessen/waybar-weather/internal/http"
+)
+
+const (
+	APIEndpoint = "https://weatherkit.apple.com/api/v1"
+	APITimeout  = time.Second * 10
+	name        = "weatherkit"
+
+	// tokenLifetime is the validity of the signed tokens, they are renewed a minute before they expire
+	tokenLifetime = time.Hour
+	// forecastDays is the number of days requested from the API, including the previous day
+	forecastDays = 8
+	// dataSets are the data sets requested from the API
+	dataSets = "currentWeather,forecastHourly,forecastDaily,forecastNextHour"
+)
+
+// WeatherKit fetches the forecast from the Apple WeatherKit REST API. The requests are authorized with
+// tokens signed by the private key of the developer account.
+type WeatherKit struct {
+	http *http.Client
+	// codes maps the condition codes of WeatherKit to WMO weather codes
+	codes     map[string]int
+	key       *ecdsa.PrivateKey
+	teamID    string
+	serviceID string
+	keyID     string
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+type weatherResponse struct {
+	CurrentWeather struct {
+		AsOf          time.Time `json:"asOf"`
+		ConditionCode string    `json:"conditionCode"`
+		Temperature   float64   `json:"temperature"`
+		WindDirection float64   `json:"windDirection"`
+		WindSpeed     float64   `json:"windSpeed"`
+	} `json:"currentWeather"`
+	ForecastHourly struct {
+		Hours []struct {
+			ForecastStart       time.Time `json:"forecastStart"`
+			ConditionCode       string    `json:"conditionCode"`
+			Temperature         float64   `json:"temperature"`
+			TemperatureApparent float64   `json:"temperatureApparent"`
+			Humidity            float64   `json:"humidity"`
+			Pressure            float64   `json:"pressure"`
+			PrecipitationAmount float64   `json:"precipitationAmount"`
+			WindDirection       float64   `json:"windDirection"`
+			WindSpeed           float64   `json:"windSpeed"`
+			WindGust            float64   `json:"windGust"`
+			UVIndex             float64   `json:"uvIndex"`
+		} `json:"hours"`
+	} `json:"forecastHourly"`
+	ForecastDaily struct {
+		Days []struct {
+			ForecastStart       time.Time `json:"forecastStart"`
+			ConditionCode       string    `json:"conditionCode"`
+			TemperatureMax      float64   `json:"temperatureMax"`
+			TemperatureMin      float64   `json:"temperatureMin"`
+			PrecipitationChance float64   `json:"precipitationChance"`
+		} `json:"days"`
+	} `json:"forecastDaily"`
+	ForecastNextHour struct {
+		Minutes []struct {
+			StartTime              time.Time `json:"startTime"`
+			PrecipitationIntensity float64   `json:"precipitationIntensity"`
+		} `json:"minutes"`
+	} `json:"forecastNextHour"`
+}
+
+// New returns a new WeatherKit backend for the given developer account. The private key is read from the
+// PKCS #8 key file downloaded from the developer account. Condition codes without a WMO equivalent, like
+// wind or heat, are expected to be mapped to mainly clear.
+func New(client *http.Client, teamID, serviceID, keyID, keyFile string, codes map[string]int) (*WeatherKit, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WeatherKit key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("WeatherKit key file does not contain a PEM encoded key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse WeatherKit key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("WeatherKit key is not an ECDSA key")
+	}
+	return &WeatherKit{http: client, codes: codes, key: key, teamID: teamID, serviceID: serviceID, keyID: keyID}, nil
+}
+
+func (w *WeatherKit) Name() string {
+	return name
+}
+
+func (w *WeatherKit) Endpoint() string {
+	return APIEndpoint
+}
+
+func (w *WeatherKit) Capabilities() forecast.Capabilities {
+	return forecast.Capabilities{Minutely: true}
+}
+
+// Forecast returns the forecast for the given coordinates as Open-Meteo compatible response body. The
+// precipitation of the current hour is taken from the minute forecast, if it is available for the location.
+func (w *WeatherKit) Forecast(ctx context.Context, lat, lon float64, zone *time.Location,
+	opts *omgo.Options,
+) ([]byte, error) {
+	token, err := w.authToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, zone)
+	end := start.AddDate(0, 0, forecastDays)
+	query := url.Values{
+		"dataSets":    {dataSets},
+		"timezone":    {forecast.TimezoneName(zone)},
+		"hourlyStart": {start.UTC().Format(time.RFC3339)},
+		"hourlyEnd":   {end.UTC().Format(time.RFC3339)},
+		"dailyStart":  {start.UTC().Format(time.RFC3339)},
+		"dailyEnd":    {end.UTC().Format(time.RFC3339)},
+	}
+	apiURL := fmt.Sprintf("%s/weather/en/%.4f/%.4f?%s", APIEndpoint, lat, lon, query.Encode())
+	var response weatherResponse
+	if _, err = w.http.GetWithTimeout(ctx, apiURL, &response, map[string]string{"Authorization": "Bearer " + token},
+		APITimeout); err != nil {
+		return nil, fmt.Errorf("failed to get forecast from WeatherKit API: %w", err)
+	}
+
+	current := response.CurrentWeather
+	if current.AsOf.IsZero() {
+		return nil, errors.New("WeatherKit API did not return current weather data")
+	}
+	data := forecast.Data{
+		Latitude:  lat,
+		Longitude: lon,
+		Current: forecast.Current{
+			Time:          current.AsOf,
+			Temperature:   current.Temperature,
+			WeatherCode:   w.codes[current.ConditionCode],
+			WindSpeed:     current.WindSpeed,
+			WindDirection: current.WindDirection,
+		},
+	}
+	for _, hour := range response.ForecastHourly.Hours {
+		data.Hours = append(data.Hours, forecast.Hour{
+			Time:                hour.ForecastStart,
+			Temperature:         hour.Temperature,
+			ApparentTemperature: hour.TemperatureApparent,
+			WeatherCode:         w.codes[hour.ConditionCode],
+			WindSpeed:           hour.WindSpeed,
+			WindGust:            max(hour.WindGust, hour.WindSpeed),
+			WindDirection:       hour.WindDirection,
+			Humidity:            hour.Humidity * 100,
+			PressureMSL:         hour.Pressure,
+			SurfacePressure:     math.NaN(),
+			FreezingLevel:       math.NaN(),
+			Precipitation:       hour.PrecipitationAmount,
+			UVIndex:             hour.UVIndex,
+		})
+	}
+	w.applyNextHour(&data, &response)
+	for _, day := range response.ForecastDaily.Days {
+		data.Days = append(data.Days, forecast.Day{
+			Date:                     day.ForecastStart.In(zone),
+			TemperatureMax:           day.TemperatureMax,
+			TemperatureMin:           day.TemperatureMin,
+			WeatherCode:              w.codes[day.ConditionCode],
+			PrecipitationProbability: day.PrecipitationChance * 100,
+		})
+	}
+	return data.Body(zone, opts)
+}
+
+// applyNextHour replaces the precipitation of the current hour with the sum of the minute forecast, which
+// is based on radar data and considerably more accurate than the hourly forecast.
+func (w *WeatherKit) applyNextHour(data *forecast.Data, response *weatherResponse) {
+	minutes := response.ForecastNextHour.Minutes
+	if len(minutes) == 0 {
+		return
+	}
+	for i, hour := range data.Hours {
+		if minutes[0].StartTime.Before(hour.Time) || !minutes[0].StartTime.Before(hour.Time.Add(time.Hour)) {
+			continue
+		}
+		// The minutes before the forecast start are taken from the hourly forecast
+		amount := hour.Precipitation * minutes[0].StartTime.Sub(hour.Time).Hours()
+		for _, minute := range minutes {
+			if !minute.StartTime.Before(hour.Time.Add(time.Hour)) {
+				break
+			}
+			amount += minute.PrecipitationIntensity / 60
+		}
+		data.Hours[i].Precipitation = amount
+		return
+	}
+}
+
+// authToken returns a signed token for the API requests. The token is renewed shortly before it expires.
+func (w *WeatherKit) authToken() (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	if w.token != "" && now.Add(time.Minute).Before(w.tokenExpiry) {
+		return w.token, nil
+	}
+
+	header, err := json.Marshal(map[string]string{
+		"alg": "ES256",
+		"kid": w.keyID,
+		"id":  w.teamID + "." + w.serviceID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode WeatherKit token header: %w", err)
+	}
+	expiry := now.Add(tokenLifetime)
+	claims, err := json.Marshal(map[string]any{
+		"iss": w.teamID,
+		"sub": w.serviceID,
+		"iat": now.Unix(),
+		"exp": expiry.Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode WeatherKit token claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, w.key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign WeatherKit token: %w", err)
+	}
+	// JWS expects the fixed-size concatenation of r and s instead of the ASN.1 encoding
+	size := (w.key.Curve.Params().BitSize + 7) / 8
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+
+	w.token = signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+	w.tokenExpiry = expiry
+	return w.token, nil
+}
@@ -0,0 +1,200 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package forecastdiff compares the daily forecast with the first forecast of the day, which is persisted
+// to a state file, so that notable changes of the forecast since the morning can be shown.
+package forecastdiff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	dateFormat = "2006-01-02"
+
+	// maxDistance is the distance in degrees the location may move before the reference forecast is
+	// replaced, since it no longer describes the same place
+	maxDistance = 0.1
+
+	// Kinds of changes
+	KindWarmer = "warmer"
+	KindCooler = "cooler"
+	KindWetter = "wetter"
+	KindDrier  = "drier"
+)
+
+// Day represents the daily forecast of a date.
+type Day struct {
+	Date                     string  `json:"date"`
+	TemperatureMax           float64 `json:"temperature_max"`
+	TemperatureMin           float64 `json:"temperature_min"`
+	PrecipitationProbability float64 `json:"precipitation_probability"`
+}
+
+// Snapshot represents a forecast at the time it has been fetched.
+type Snapshot struct {
+	Time      time.Time `json:"time"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	// TemperatureUnit of the temperatures, the snapshots of different units are not compared
+	TemperatureUnit string `json:"temperature_unit"`
+	Days            []Day  `json:"days"`
+}
+
+// Thresholds are the minimum changes that are reported, 0 disables the comparison.
+type Thresholds struct {
+	// Temperature is the change of the daily high temperature
+	Temperature float64
+	// PrecipitationProbability is the change of the precipitation probability in percentage points
+	PrecipitationProbability float64
+}
+
+// Change represents a notable change of the forecast of a date.
+type Change struct {
+	Date     string
+	Kind     string
+	Previous float64
+	Current  float64
+	// Since is the time of the forecast the current forecast is compared with
+	Since time.Time
+}
+
+// Tracker keeps the first forecast of the day as reference for the later forecasts of the day.
+type Tracker struct {
+	mu         sync.Mutex
+	path       string
+	thresholds Thresholds
+	reference  *Snapshot
+}
+
+// New returns a new Tracker. If path is not empty, the reference forecast is restored from and persisted to
+// the file at the given path, so that a restart of the service keeps the reference. If the state file can't
+// be restored, the Tracker is returned with the error and starts without a reference.
+func New(path string, thresholds Thresholds) (*Tracker, error) {
+	tracker := &Tracker{path: path, thresholds: thresholds}
+	return tracker, tracker.load()
+}
+
+// Update compares the forecast with the reference forecast and returns the changes that exceed the
+// thresholds. The forecast becomes the new reference if the reference is from a previous day, of another
+// location or in other units. If the new reference can't be persisted, the error is returned.
+func (t *Tracker) Update(current Snapshot) ([]Change, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	reference := t.reference
+	if reference == nil || !sameDay(reference.Time, current.Time) ||
+		math.Abs(reference.Latitude-current.Latitude) > maxDistance ||
+		math.Abs(reference.Longitude-current.Longitude) > maxDistance ||
+		reference.TemperatureUnit != current.TemperatureUnit {
+		t.reference = &current
+		return nil, t.save()
+	}
+	return diff(reference, &current, t.thresholds), nil
+}
+
+// diff returns the changes of the days of current compared to the same days of reference.
+func diff(reference, current *Snapshot, thresholds Thresholds) []Change {
+	previous := make(map[string]Day, len(reference.Days))
+	for _, day := range reference.Days {
+		previous[day.Date] = day
+	}
+
+	var changes []Change
+	add := func(date, kind string, before, after float64) {
+		changes = append(changes, Change{Date: date, Kind: kind, Previous: before, Current: after,
+			Since: reference.Time})
+	}
+	for _, day := range current.Days {
+		before, ok := previous[day.Date]
+		if !ok {
+			continue
+		}
+		temperature := day.TemperatureMax - before.TemperatureMax
+		switch {
+		case thresholds.Temperature <= 0:
+		case temperature >= thresholds.Temperature:
+			add(day.Date, KindWarmer, before.TemperatureMax, day.TemperatureMax)
+		case -temperature >= thresholds.Temperature:
+			add(day.Date, KindCooler, before.TemperatureMax, day.TemperatureMax)
+		}
+		probability := day.PrecipitationProbability - before.PrecipitationProbability
+		switch {
+		case thresholds.PrecipitationProbability <= 0:
+		case probability >= thresholds.PrecipitationProbability:
+			add(day.Date, KindWetter, before.PrecipitationProbability, day.PrecipitationProbability)
+		case -probability >= thresholds.PrecipitationProbability:
+			add(day.Date, KindDrier, before.PrecipitationProbability, day.PrecipitationProbability)
+		}
+	}
+	return changes
+}
+
+// sameDay returns true if both times are on the same date in the time zone of b.
+func sameDay(a, b time.Time) bool {
+	return a.In(b.Location()).Format(dateFormat) == b.Format(dateFormat)
+}
+
+// load restores the reference forecast from the state file. A missing state file is not an error.
+func (t *Tracker) load() error {
+	if t.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(t.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read forecast state file: %w", err)
+	}
+	var reference Snapshot
+	if err = json.Unmarshal(data, &reference); err != nil {
+		return fmt.Errorf("failed to parse forecast state file: %w", err)
+	}
+	if !reference.Time.IsZero() {
+		t.reference = &reference
+	}
+	return nil
+}
+
+// save persists the reference forecast to the state file. The file is replaced atomically, so that an
+// interrupted write doesn't leave a corrupt state file. The caller must hold the lock.
+func (t *Tracker) save() error {
+	if t.path == "" || t.reference == nil {
+		return nil
+	}
+	data, err := json.Marshal(t.reference)
+	if err != nil {
+		return fmt.Errorf("failed to encode forecast state: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(t.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create forecast state directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(t.path), filepath.Base(t.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create forecast state file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write forecast state file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close forecast state file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), t.path); err != nil {
+		return fmt.Errorf("failed to replace forecast state file: %w", err)
+	}
+	return nil
+}
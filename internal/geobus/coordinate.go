@@ -19,19 +19,36 @@ type Coordinate struct {
 	Lon float64
 	Alt float64
 	Acc float64
+	// HasAlt is false if the altitude is unknown
+	HasAlt bool
 }
 
 // PosHasSignificantChange checks if the geographic position differs significantly from
-// another based on the distance threshold. We are using the Haversine formula to calculate
-// great-circle distance between two points on a sphere (in our case: Earth).
+// another based on the distance threshold.
 func (c Coordinate) PosHasSignificantChange(other Coordinate) bool {
+	return c.DistanceTo(other) > DistanceThreshold
+}
+
+// DistanceTo returns the distance in meters to another geographic position. We are using the
+// Haversine formula to calculate great-circle distance between two points on a sphere (in our
+// case: Earth).
+func (c Coordinate) DistanceTo(other Coordinate) float64 {
 	dLat := (c.Lat - other.Lat) * math.Pi / 180
 	dLon := (c.Lon - other.Lon) * math.Pi / 180
 	lat1 := c.Lat * math.Pi / 180
 	lat2 := other.Lat * math.Pi / 180
 	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
 		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
-	distance := 2 * EarthRadius * math.Asin(math.Sqrt(h))
+	return 2 * EarthRadius * math.Asin(math.Sqrt(h))
+}
 
-	return distance > DistanceThreshold
+// Valid returns true if the coordinate is a finite position on Earth and its altitude and accuracy are
+// finite as well.
+func (c Coordinate) Valid() bool {
+	for _, v := range []float64{c.Lat, c.Lon, c.Alt, c.Acc} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return c.Lat >= -90 && c.Lat <= 90 && c.Lon >= -180 && c.Lon <= 180
 }
@@ -6,6 +6,8 @@ package geobus
 
 import (
 	"context"
+	"log/slog"
+	"maps"
 	"math"
 	"sync"
 	"time"
@@ -35,11 +37,18 @@ type Provider interface {
 	LookupStream(ctx context.Context, key string) <-chan Result
 }
 
+// Prober is implemented by providers that can check cheaply whether they are usable, e.g. whether their
+// API is reachable or their service is running.
+type Prober interface {
+	Probe(ctx context.Context) error
+}
+
 // GeoBus coordinates the publishing and subscribing of geolocation results between providers and consumers.
 type GeoBus struct {
 	mu          sync.RWMutex
 	logger      *logger.Logger
 	best        map[string]Result
+	latest      map[string]Result
 	subscribers map[string]map[chan Result]struct{}
 	globalSubs  map[chan Result]struct{}
 }
@@ -53,6 +62,26 @@ type Result struct {
 	Source         string
 	At             time.Time
 	TTL            time.Duration
+
+	// HasAlt is false if the provider doesn't report the altitude in meters
+	HasAlt bool
+
+	// Motion data and the time of the fix, if reported by the provider. Speed is in meters per second and
+	// the heading in degrees clockwise from north.
+	Speed      float64
+	HasSpeed   bool
+	Heading    float64
+	HasHeading bool
+	SourceTime time.Time
+}
+
+// Altitude returns the altitude in meters, or nil if the provider didn't report one.
+func (r Result) Altitude() *float64 {
+	if !r.HasAlt {
+		return nil
+	}
+	altitude := r.Alt
+	return &altitude
 }
 
 // BetterThan compares two Result objects to determine if the current instance is better than the provided one.
@@ -84,6 +113,7 @@ func New(logger *logger.Logger) *GeoBus {
 	return &GeoBus{
 		logger:      logger,
 		best:        make(map[string]Result),
+		latest:      make(map[string]Result),
 		subscribers: make(map[string]map[chan Result]struct{}),
 		globalSubs:  make(map[chan Result]struct{}),
 	}
@@ -149,10 +179,15 @@ func (b *GeoBus) Publish(r Result) {
 	if r.AccuracyMeters == 0 {
 		return
 	}
+	if !(Coordinate{Lat: r.Lat, Lon: r.Lon, Alt: r.Alt, Acc: r.AccuracyMeters}).Valid() {
+		b.logger.Warn("ignoring invalid geolocation result", slog.String("source", r.Source))
+		return
+	}
 	if r.At.IsZero() {
 		r.At = time.Now()
 	}
 	b.mu.Lock()
+	b.latest[r.Source] = r
 	prev, have := b.best[r.Key]
 	if !have || prev.IsExpired() || r.BetterThan(prev) {
 		b.best[r.Key] = r
@@ -185,6 +220,13 @@ func (b *GeoBus) Best(key string) (Result, bool) {
 	return r, ok && !r.IsExpired()
 }
 
+// Latest returns the most recent result of each provider, keyed by the name of the provider.
+func (b *GeoBus) Latest() map[string]Result {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return maps.Clone(b.latest)
+}
+
 func sleepOrDone(ctx context.Context, d time.Duration) bool {
 	t := time.NewTimer(d)
 	defer t.Stop()
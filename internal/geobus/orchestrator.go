@@ -6,7 +6,19 @@ package geobus
 
 import (
 	"context"
+	"log/slog"
 	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	// probeTimeout is the maximum time a provider probe may take
+	probeTimeout = 10 * time.Second
+	// initialExclusion and maxExclusion define the backoff for providers that failed the probe
+	initialExclusion = time.Minute
+	maxExclusion     = 30 * time.Minute
 )
 
 // Orchestrator coordinates the tracking and publication of geolocation results from multiple
@@ -14,32 +26,36 @@ import (
 type Orchestrator struct {
 	Bus       *GeoBus
 	Providers []Provider
+	// Workers limits the number of providers tracked at the same time (0 = one worker per provider)
+	Workers int
+
+	mu       sync.RWMutex
+	excluded map[string]time.Time
+	usable   map[string]bool
+	workers  map[string]*WorkerStatus
+	first    map[string]time.Time
 }
 
-// Track initiates concurrent geolocation tracking for a given key across multiple providers in the Orchestrator.
+// Track initiates geolocation tracking for a given key across multiple providers in the Orchestrator.
+// The providers are run on a bounded pool of supervised workers.
 func (o *Orchestrator) Track(ctx context.Context, key string) {
-	var wg sync.WaitGroup
-	for _, p := range o.Providers {
-		wg.Add(1)
-		go func(p Provider) {
-			defer wg.Done()
-			o.trackProvider(ctx, p, key)
-		}(p)
-	}
-	<-ctx.Done()
-	wg.Wait()
+	o.runPool(ctx, key)
 }
 
 // trackProvider continuously tracks a Provider for geolocation data, publishing results to
-// the GeoBus and implementing backoff.
+// the GeoBus and implementing backoff. Providers that fail their probe are excluded until they pass it.
 func (o *Orchestrator) trackProvider(ctx context.Context, p Provider, key string) {
 	backoff := initialBackoff
+lookup:
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
 		}
+		if !o.awaitUsable(ctx, p) {
+			return
+		}
 
 		lookupChan := o.safeLookup(ctx, p, key)
 		if lookupChan == nil {
@@ -56,22 +72,117 @@ func (o *Orchestrator) trackProvider(ctx context.Context, p Provider, key string
 				return
 			case r, ok := <-lookupChan:
 				if !ok {
+					// The lookup has ended, so the provider is probed and looked up again
 					if !sleepOrDone(ctx, backoff) {
 						return
 					}
 					backoff = nextBackoff(backoff)
-					break
+					continue lookup
 				}
 				o.Bus.Publish(r)
+				o.recordFirstResult(p.Name())
 				backoff = initialBackoff
 			}
 		}
 	}
 }
 
-// safeLookup safely invokes the LookupStream method on a Provider and recovers from potential panics.
-// Returns a read-only channel of Result or nil if the operation fails.
+// awaitUsable probes the provider, if it supports probing, and excludes it with an increasing backoff until
+// the probe succeeds. Returns false if the context ends.
+func (o *Orchestrator) awaitUsable(ctx context.Context, p Provider) bool {
+	prober, ok := p.(Prober)
+	if !ok {
+		return true
+	}
+
+	exclusion := initialExclusion
+	for {
+		ctxProbe, cancelProbe := context.WithTimeout(ctx, probeTimeout)
+		err := prober.Probe(ctxProbe)
+		cancelProbe()
+		if err == nil {
+			o.setExcluded(p.Name(), time.Time{})
+			// Providers are probed again after every lookup and on every turn in the worker pool
+			if o.setUsable(p.Name(), true) {
+				o.Bus.logger.Info("geolocation provider is usable", slog.String("provider", p.Name()))
+			}
+			return true
+		}
+		o.setUsable(p.Name(), false)
+		if ctx.Err() != nil {
+			return false
+		}
+
+		o.setExcluded(p.Name(), time.Now().Add(exclusion))
+		o.Bus.logger.Warn("geolocation provider is not usable, excluding it", slog.String("provider", p.Name()),
+			logger.Err(err), slog.Duration("retry_in", exclusion))
+		if !sleepOrDone(ctx, exclusion) {
+			return false
+		}
+		if exclusion *= 2; exclusion > maxExclusion {
+			exclusion = maxExclusion
+		}
+	}
+}
+
+// setExcluded sets the time until which the provider is excluded. The zero time removes the exclusion.
+func (o *Orchestrator) setExcluded(name string, until time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.excluded == nil {
+		o.excluded = make(map[string]time.Time)
+	}
+	if until.IsZero() {
+		delete(o.excluded, name)
+		return
+	}
+	o.excluded[name] = until
+}
+
+// setUsable records whether the provider passed its last probe. Returns true if the state has changed.
+func (o *Orchestrator) setUsable(name string, usable bool) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.usable == nil {
+		o.usable = make(map[string]bool)
+	}
+	previous, ok := o.usable[name]
+	o.usable[name] = usable
+	return !ok || previous != usable
+}
+
+// ExcludedUntil returns the time until which the provider is excluded because it failed its probe.
+func (o *Orchestrator) ExcludedUntil(name string) (time.Time, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	until, ok := o.excluded[name]
+	return until, ok
+}
+
+// recordFirstResult records the time of the first result of the provider.
+func (o *Orchestrator) recordFirstResult(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.first == nil {
+		o.first = make(map[string]time.Time)
+	}
+	if _, ok := o.first[name]; !ok {
+		o.first[name] = time.Now()
+	}
+}
+
+// FirstResult returns the time the provider delivered its first result.
+func (o *Orchestrator) FirstResult(name string) (time.Time, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	at, ok := o.first[name]
+	return at, ok
+}
+
+// safeLookup safely invokes the LookupStream method on a Provider and recovers from potential panics of the
+// call. Panics in the goroutines started by LookupStream are not covered, see runWorker. Returns a read-only
+// channel of Result or nil if the operation fails.
 func (o *Orchestrator) safeLookup(ctx context.Context, provider Provider, key string) (ch <-chan Result) {
-	defer func() { _ = recover() }()
+	defer o.Bus.logger.RecoverPanic(provider.Name())
 	return provider.LookupStream(ctx, key)
 }
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package geobus
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// WorkerState describes the lifecycle state of a provider in the worker pool.
+type WorkerState string
+
+const (
+	// WorkerQueued means the provider waits for a free worker
+	WorkerQueued WorkerState = "queued"
+	// WorkerRunning means the provider is run by a worker
+	WorkerRunning WorkerState = "running"
+	// WorkerRestarting means the provider crashed and waits for its restart
+	WorkerRestarting WorkerState = "restarting"
+	// WorkerStopped means the provider has been stopped
+	WorkerStopped WorkerState = "stopped"
+)
+
+// WorkerStatus represents the lifecycle of a provider in the worker pool.
+type WorkerStatus struct {
+	State       WorkerState
+	Started     time.Time
+	Restarts    int
+	LastPanic   string
+	LastPanicAt time.Time
+}
+
+// rotationSlice is the time a provider is tracked before it hands its worker to the next provider, if
+// there are fewer workers than providers
+const rotationSlice = 5 * time.Minute
+
+// runPool runs the providers on a bounded pool of workers until the context ends. A provider that panics
+// is handed back to the pool after an increasing backoff. If there are fewer workers than providers, the
+// providers take turns: each one is tracked for the rotation slice and then queued again behind the
+// waiting providers.
+func (o *Orchestrator) runPool(ctx context.Context, key string) {
+	size := o.Workers
+	if size <= 0 || size > len(o.Providers) {
+		size = len(o.Providers)
+	}
+	rotate := size < len(o.Providers)
+
+	// The queue holds every provider at most once, so sends never block
+	queue := make(chan Provider, len(o.Providers))
+	for _, p := range o.Providers {
+		o.setWorker(p.Name(), func(w *WorkerStatus) { w.State = WorkerQueued })
+		queue <- p
+	}
+
+	var wg sync.WaitGroup
+	var restarts sync.WaitGroup
+	for range size {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case p := <-queue:
+					runCtx, cancelRun := ctx, context.CancelFunc(func() {})
+					if rotate {
+						runCtx, cancelRun = context.WithTimeout(ctx, rotationSlice)
+					}
+					panicked := o.runWorker(runCtx, p, key)
+					cancelRun()
+					if !panicked {
+						if rotate && ctx.Err() == nil {
+							o.setWorker(p.Name(), func(w *WorkerStatus) { w.State = WorkerQueued })
+							queue <- p
+						}
+						continue
+					}
+					restarts.Add(1)
+					go func() {
+						defer restarts.Done()
+						o.requeue(ctx, p, queue)
+					}()
+				}
+			}
+		}()
+	}
+	<-ctx.Done()
+	wg.Wait()
+	restarts.Wait()
+	for _, p := range o.Providers {
+		o.setWorker(p.Name(), func(w *WorkerStatus) { w.State = WorkerStopped })
+	}
+}
+
+// runWorker tracks the provider and recovers from a potential panic. Returns true if the provider panicked.
+// Only panics in the goroutine of the worker are recovered, i.e. in the probe and in LookupStream itself.
+// A panic in a goroutine started by the provider can't be recovered here and terminates the process, so
+// providers have to recover in their own goroutines, as the built-in providers do.
+func (o *Orchestrator) runWorker(ctx context.Context, p Provider, key string) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			o.Bus.logger.LogPanic(p.Name(), r)
+			o.setWorker(p.Name(), func(w *WorkerStatus) {
+				w.State = WorkerRestarting
+				w.Restarts++
+				w.LastPanic = fmt.Sprint(r)
+				w.LastPanicAt = time.Now()
+			})
+			panicked = true
+		}
+	}()
+
+	o.setWorker(p.Name(), func(w *WorkerStatus) {
+		w.State = WorkerRunning
+		w.Started = time.Now()
+	})
+	o.trackProvider(ctx, p, key)
+	o.setWorker(p.Name(), func(w *WorkerStatus) { w.State = WorkerStopped })
+	return false
+}
+
+// requeue hands a crashed provider back to the pool after a backoff that grows with its restarts.
+func (o *Orchestrator) requeue(ctx context.Context, p Provider, queue chan<- Provider) {
+	status, _ := o.Worker(p.Name())
+	backoff := initialBackoff
+	for i := 1; i < status.Restarts && backoff < maxBackoff; i++ {
+		backoff = nextBackoff(backoff)
+	}
+	o.Bus.logger.Info("restarting geolocation provider after panic", slog.String("provider", p.Name()),
+		slog.Duration("retry_in", backoff))
+	if !sleepOrDone(ctx, backoff) {
+		return
+	}
+	o.setWorker(p.Name(), func(w *WorkerStatus) { w.State = WorkerQueued })
+	queue <- p
+}
+
+// setWorker updates the worker status of the provider with the given name.
+func (o *Orchestrator) setWorker(name string, update func(*WorkerStatus)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.workers == nil {
+		o.workers = make(map[string]*WorkerStatus)
+	}
+	status, ok := o.workers[name]
+	if !ok {
+		status = new(WorkerStatus)
+		o.workers[name] = status
+	}
+	update(status)
+}
+
+// Worker returns the worker status of the provider with the given name.
+func (o *Orchestrator) Worker(name string) (WorkerStatus, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	status, ok := o.workers[name]
+	if !ok {
+		return WorkerStatus{}, false
+	}
+	return *status, true
+}
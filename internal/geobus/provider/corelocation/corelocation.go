@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package corelocation implements a geolocation provider for macOS, which queries the CoreLocation
+// framework via the CoreLocationCLI command line tool.
+package corelocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	// Command is the CoreLocationCLI executable, which has to be in the PATH
+	Command = "CoreLocationCLI"
+	// lookupTimeout is the maximum time CoreLocation may take to determine the location
+	lookupTimeout = 30 * time.Second
+	// outputFormat makes CoreLocationCLI print latitude, longitude, horizontal accuracy, altitude and
+	// vertical accuracy, which is negative if the altitude is unknown
+	outputFormat = "%latitude %longitude %h_accuracy %altitude %v_accuracy"
+)
+
+// GeolocationCoreLocationProvider periodically determines the location with CoreLocation, which uses
+// the Wi-Fi networks, GPS and the IP address available to the Mac.
+type GeolocationCoreLocationProvider struct {
+	name   string
+	logger *logger.Logger
+	period time.Duration
+	ttl    time.Duration
+}
+
+// NewGeolocationCoreLocationProvider returns a new GeolocationCoreLocationProvider.
+func NewGeolocationCoreLocationProvider(log *logger.Logger) *GeolocationCoreLocationProvider {
+	return &GeolocationCoreLocationProvider{
+		logger: log,
+		name:   "CoreLocation",
+		period: 5 * time.Minute,
+		ttl:    15 * time.Minute,
+	}
+}
+
+// Name returns the name of the GeolocationCoreLocationProvider instance.
+func (p *GeolocationCoreLocationProvider) Name() string {
+	return p.name
+}
+
+// Probe checks whether CoreLocationCLI is installed.
+func (p *GeolocationCoreLocationProvider) Probe(context.Context) error {
+	if _, err := exec.LookPath(Command); err != nil {
+		return fmt.Errorf("failed to find %s: %w", Command, err)
+	}
+	return nil
+}
+
+// LookupStream periodically queries CoreLocation and emits the location whenever it changed.
+func (p *GeolocationCoreLocationProvider) LookupStream(ctx context.Context, key string) <-chan geobus.Result {
+	out := make(chan geobus.Result)
+	go func() {
+		defer close(out)
+		defer p.logger.RecoverPanic(p.name)
+		state := geobus.GeolocationState{}
+
+		for {
+			coord, err := p.locate(ctx)
+			if err != nil {
+				p.logger.Debug("failed to query CoreLocation", logger.Err(err))
+			}
+			if err == nil && state.HasChanged(coord) {
+				state.Update(coord)
+				select {
+				case <-ctx.Done():
+					return
+				case out <- p.createResult(key, coord):
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.period):
+			}
+		}
+	}()
+	return out
+}
+
+// locate runs CoreLocationCLI once and parses the reported location.
+func (p *GeolocationCoreLocationProvider) locate(ctx context.Context) (geobus.Coordinate, error) {
+	ctxLookup, cancelLookup := context.WithTimeout(ctx, lookupTimeout)
+	defer cancelLookup()
+
+	output, err := exec.CommandContext(ctxLookup, Command, "-once", "-format", outputFormat).Output()
+	if err != nil {
+		return geobus.Coordinate{}, fmt.Errorf("failed to run %s: %w", Command, err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) != 5 {
+		return geobus.Coordinate{}, errors.New("unexpected output of CoreLocationCLI")
+	}
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		if values[i], err = strconv.ParseFloat(field, 64); err != nil {
+			return geobus.Coordinate{}, fmt.Errorf("failed to parse CoreLocationCLI output: %w", err)
+		}
+	}
+	coord := geobus.Coordinate{
+		Lat:    geobus.Truncate(values[0], geobus.TruncPrecision),
+		Lon:    geobus.Truncate(values[1], geobus.TruncPrecision),
+		Acc:    values[2],
+		Alt:    values[3],
+		HasAlt: values[4] >= 0,
+	}
+	if !coord.Valid() || coord.Acc <= 0 {
+		return geobus.Coordinate{}, errors.New("CoreLocation reported an invalid location")
+	}
+	p.logger.Debug("received CoreLocation location", slog.Float64("accuracy", coord.Acc))
+	return coord, nil
+}
+
+// createResult composes and returns a Result using provided geolocation data and metadata.
+func (p *GeolocationCoreLocationProvider) createResult(key string, coord geobus.Coordinate) geobus.Result {
+	return geobus.Result{
+		Key:            key,
+		Lat:            coord.Lat,
+		Lon:            coord.Lon,
+		Alt:            coord.Alt,
+		HasAlt:         coord.HasAlt,
+		AccuracyMeters: coord.Acc,
+		Source:         p.name,
+		At:             time.Now(),
+		TTL:            p.ttl,
+	}
+}
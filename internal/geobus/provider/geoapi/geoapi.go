@@ -12,6 +12,7 @@ import (
 
 	"github.com/wneessen/waybar-weather/internal/geobus"
 	"github.com/wneessen/waybar-weather/internal/http"
+	"github.com/wneessen/waybar-weather/internal/logger"
 )
 
 const (
@@ -22,6 +23,7 @@ const (
 type GeolocationGeoAPIProvider struct {
 	name   string
 	http   *http.Client
+	logger *logger.Logger
 	period time.Duration
 	ttl    time.Duration
 }
@@ -42,8 +44,9 @@ type APIResult struct {
 	} `json:"location"`
 }
 
-func NewGeolocationGeoAPIProvider(http *http.Client) *GeolocationGeoAPIProvider {
+func NewGeolocationGeoAPIProvider(http *http.Client, log *logger.Logger) *GeolocationGeoAPIProvider {
 	return &GeolocationGeoAPIProvider{
+		logger: log,
 		name:   "geoapi",
 		http:   http,
 		period: 10 * time.Minute,
@@ -55,12 +58,18 @@ func (p *GeolocationGeoAPIProvider) Name() string {
 	return p.name
 }
 
+// Probe checks whether the GeoAPI API is reachable.
+func (p *GeolocationGeoAPIProvider) Probe(ctx context.Context) error {
+	return p.http.Probe(ctx, APIEndpoint)
+}
+
 // LookupStream continuously streams geolocation results from a file, emitting updates when data changes
 // or context ends.
 func (p *GeolocationGeoAPIProvider) LookupStream(ctx context.Context, key string) <-chan geobus.Result {
 	out := make(chan geobus.Result)
 	go func() {
 		defer close(out)
+		defer p.logger.RecoverPanic(p.name)
 		state := geobus.GeolocationState{}
 
 		for {
@@ -0,0 +1,347 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// The D-Bus library requires cgo on FreeBSD, so GeoClue is not supported there.
+
+//go:build !freebsd
+
+package geoclue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	dbusService          = "org.freedesktop.GeoClue2"
+	dbusManagerPath      = "/org/freedesktop/GeoClue2/Manager"
+	dbusManagerInterface = "org.freedesktop.GeoClue2.Manager"
+	dbusClientInterface  = "org.freedesktop.GeoClue2.Client"
+	dbusLocationIface    = "org.freedesktop.GeoClue2.Location"
+	dbusLocationSignal   = "LocationUpdated"
+	dbusInterface        = "org.freedesktop.DBus"
+	dbusOwnerSignal      = "NameOwnerChanged"
+
+	// accuracyLevelExact is the GeoClue accuracy level for the most exact location available
+	accuracyLevelExact = uint32(8)
+	// distanceThreshold is the minimum distance in meters before GeoClue reports a new location
+	distanceThreshold = uint32(1000)
+	signalBufferSize  = 8
+)
+
+// motion holds the speed, heading and time of a GeoClue location, which are not part of the coordinate.
+type motion struct {
+	speed      float64
+	hasSpeed   bool
+	heading    float64
+	hasHeading bool
+	timestamp  time.Time
+}
+
+// errServiceRestarted is returned when the GeoClue service has been restarted and the client
+// needs to be registered again.
+var errServiceRestarted = errors.New("GeoClue service has been restarted")
+
+// GeolocationGeoClueProvider looks up the location via the GeoClue2 D-Bus service. GeoClue is
+// optional: if the service is not available or the registration of the client fails, the provider
+// logs a warning and retries the registration in the background.
+type GeolocationGeoClueProvider struct {
+	name      string
+	desktopID string
+	logger    *logger.Logger
+	period    time.Duration
+	ttl       time.Duration
+}
+
+// NewGeolocationGeoClueProvider returns a new GeoClue provider that registers with the given desktop ID.
+func NewGeolocationGeoClueProvider(desktopID string, log *logger.Logger) *GeolocationGeoClueProvider {
+	return &GeolocationGeoClueProvider{
+		name:      "geoclue",
+		desktopID: desktopID,
+		logger:    log,
+		period:    time.Minute * 2,
+		ttl:       time.Minute * 30,
+	}
+}
+
+func (p *GeolocationGeoClueProvider) Name() string {
+	return p.name
+}
+
+// Probe checks whether the GeoClue service is running or can be activated on the system bus.
+func (p *GeolocationGeoClueProvider) Probe(context.Context) error {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to system bus: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	var names []string
+	if err = conn.BusObject().Call(dbusInterface+".ListActivatableNames", 0).Store(&names); err != nil {
+		return fmt.Errorf("failed to list activatable D-Bus services: %w", err)
+	}
+	if slices.Contains(names, dbusService) {
+		return nil
+	}
+	var hasOwner bool
+	if err = conn.BusObject().Call(dbusInterface+".NameHasOwner", 0, dbusService).Store(&hasOwner); err != nil {
+		return fmt.Errorf("failed to look up GeoClue service: %w", err)
+	}
+	if !hasOwner {
+		return errors.New("GeoClue service is not installed")
+	}
+	return nil
+}
+
+// LookupStream registers a GeoClue client and streams the location updates reported by GeoClue.
+// If the registration fails, it is retried periodically until the context ends. Only the first failure
+// is logged as warning, the retries are logged at debug level until a client has been registered. If
+// the GeoClue service is restarted, the client is registered again right away.
+func (p *GeolocationGeoClueProvider) LookupStream(ctx context.Context, key string) <-chan geobus.Result {
+	out := make(chan geobus.Result)
+	go func() {
+		defer close(out)
+		defer p.logger.RecoverPanic(p.name)
+
+		warned := false
+		for {
+			err := p.track(ctx, key, out, func() { warned = false })
+			if errors.Is(err, errServiceRestarted) {
+				p.logger.Info("GeoClue service has been restarted, registering new client")
+				continue
+			}
+			if err != nil {
+				logFunc := p.logger.Warn
+				if warned {
+					logFunc = p.logger.Debug
+				}
+				logFunc("GeoClue is not available, retrying in background", logger.Err(err),
+					slog.Duration("retry_in", p.period))
+				warned = true
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.period):
+			}
+		}
+	}()
+	return out
+}
+
+// track connects to the system bus, registers a GeoClue client, calls registered once the client has been
+// started and emits location updates until the context ends or the connection is lost. Since GeoClue invalidates all clients when it exits,
+// the owner of the GeoClue bus name is monitored and errServiceRestarted is returned once the service
+// is available again.
+func (p *GeolocationGeoClueProvider) track(ctx context.Context, key string, out chan<- geobus.Result,
+	registered func(),
+) error {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to system bus: %w", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			p.logger.Error("failed to close system bus connection", logger.Err(err))
+		}
+	}()
+
+	client, err := p.registerClient(conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = client.Call(dbusClientInterface+".Stop", 0).Err
+	}()
+
+	if err = conn.AddMatchSignal(dbus.WithMatchObjectPath(client.Path()),
+		dbus.WithMatchInterface(dbusClientInterface), dbus.WithMatchMember(dbusLocationSignal)); err != nil {
+		return fmt.Errorf("failed to subscribe to GeoClue location updates: %w", err)
+	}
+	if err = conn.AddMatchSignal(dbus.WithMatchSender(dbusInterface), dbus.WithMatchInterface(dbusInterface),
+		dbus.WithMatchMember(dbusOwnerSignal), dbus.WithMatchArg(0, dbusService)); err != nil {
+		return fmt.Errorf("failed to subscribe to GeoClue service changes: %w", err)
+	}
+	sigCh := make(chan *dbus.Signal, signalBufferSize)
+	conn.Signal(sigCh)
+	defer conn.RemoveSignal(sigCh)
+
+	if err = client.Call(dbusClientInterface+".Start", 0).Err; err != nil {
+		return fmt.Errorf("failed to start GeoClue client: %w", err)
+	}
+	p.logger.Debug("registered GeoClue client", slog.String("path", string(client.Path())))
+	registered()
+
+	state := geobus.GeolocationState{}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case sig, ok := <-sigCh:
+			if !ok {
+				return fmt.Errorf("GeoClue signal channel closed")
+			}
+			if sig.Name == dbusInterface+"."+dbusOwnerSignal {
+				if err = p.ownerChanged(sig); err != nil {
+					return err
+				}
+				continue
+			}
+			if sig.Name != dbusClientInterface+"."+dbusLocationSignal || len(sig.Body) != 2 {
+				continue
+			}
+			path, ok := sig.Body[1].(dbus.ObjectPath)
+			if !ok {
+				continue
+			}
+			coord, err := p.location(conn, path)
+			if err != nil {
+				p.logger.Error("failed to read GeoClue location", logger.Err(err))
+				continue
+			}
+			if !state.HasChanged(coord) {
+				continue
+			}
+			state.Update(coord)
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case out <- p.createResult(key, coord, p.motion(conn, path)):
+			}
+		}
+	}
+}
+
+// ownerChanged handles a change of the owner of the GeoClue bus name. If the service has exited, we
+// wait for it to come back. Once it has a new owner, errServiceRestarted is returned.
+func (p *GeolocationGeoClueProvider) ownerChanged(sig *dbus.Signal) error {
+	if len(sig.Body) != 3 {
+		return nil
+	}
+	name, _ := sig.Body[0].(string)
+	newOwner, _ := sig.Body[2].(string)
+	if name != dbusService {
+		return nil
+	}
+	if newOwner == "" {
+		p.logger.Warn("GeoClue service has exited, waiting for it to restart")
+		return nil
+	}
+	return errServiceRestarted
+}
+
+// registerClient requests a new client from the GeoClue manager and configures it.
+func (p *GeolocationGeoClueProvider) registerClient(conn *dbus.Conn) (dbus.BusObject, error) {
+	var clientPath dbus.ObjectPath
+	manager := conn.Object(dbusService, dbusManagerPath)
+	if err := manager.Call(dbusManagerInterface+".GetClient", 0).Store(&clientPath); err != nil {
+		return nil, fmt.Errorf("failed to register GeoClue client: %w", err)
+	}
+
+	client := conn.Object(dbusService, clientPath)
+	if err := client.SetProperty(dbusClientInterface+".DesktopId", dbus.MakeVariant(p.desktopID)); err != nil {
+		return nil, fmt.Errorf("failed to set GeoClue desktop ID: %w", err)
+	}
+	if err := client.SetProperty(dbusClientInterface+".RequestedAccuracyLevel",
+		dbus.MakeVariant(accuracyLevelExact)); err != nil {
+		return nil, fmt.Errorf("failed to set GeoClue accuracy level: %w", err)
+	}
+	if err := client.SetProperty(dbusClientInterface+".DistanceThreshold",
+		dbus.MakeVariant(distanceThreshold)); err != nil {
+		return nil, fmt.Errorf("failed to set GeoClue distance threshold: %w", err)
+	}
+	return client, nil
+}
+
+// location reads the coordinates of the GeoClue location object at the given path.
+func (p *GeolocationGeoClueProvider) location(conn *dbus.Conn, path dbus.ObjectPath) (geobus.Coordinate, error) {
+	obj := conn.Object(dbusService, path)
+	var coord geobus.Coordinate
+	for prop, target := range map[string]*float64{
+		"Latitude":  &coord.Lat,
+		"Longitude": &coord.Lon,
+		"Altitude":  &coord.Alt,
+		"Accuracy":  &coord.Acc,
+	} {
+		value, err := obj.GetProperty(dbusLocationIface + "." + prop)
+		if err != nil {
+			return coord, fmt.Errorf("failed to read GeoClue location property %q: %w", prop, err)
+		}
+		if err = value.Store(target); err != nil {
+			return coord, fmt.Errorf("failed to parse GeoClue location property %q: %w", prop, err)
+		}
+	}
+
+	// GeoClue reports -DBL_MAX for an unknown altitude
+	coord.HasAlt = coord.Alt > -math.MaxFloat64
+	if !coord.HasAlt {
+		coord.Alt = 0
+	}
+	coord.Lat = geobus.Truncate(coord.Lat, geobus.TruncPrecision)
+	coord.Lon = geobus.Truncate(coord.Lon, geobus.TruncPrecision)
+	coord.Alt = geobus.Truncate(coord.Alt, geobus.TruncPrecision)
+	coord.Acc = geobus.Truncate(coord.Acc, geobus.TruncPrecision)
+	return coord, nil
+}
+
+// motion reads the speed, heading and timestamp of the GeoClue location object at the given path. GeoClue
+// reports -1 for an unknown speed or heading. Since these properties are optional, read errors are ignored.
+func (p *GeolocationGeoClueProvider) motion(conn *dbus.Conn, path dbus.ObjectPath) motion {
+	obj := conn.Object(dbusService, path)
+	var m motion
+	if value, err := obj.GetProperty(dbusLocationIface + ".Speed"); err == nil {
+		if err = value.Store(&m.speed); err == nil && m.speed >= 0 {
+			m.hasSpeed = true
+		}
+	}
+	if value, err := obj.GetProperty(dbusLocationIface + ".Heading"); err == nil {
+		if err = value.Store(&m.heading); err == nil && m.heading >= 0 {
+			m.hasHeading = true
+		}
+	}
+	if value, err := obj.GetProperty(dbusLocationIface + ".Timestamp"); err == nil {
+		var timestamp struct {
+			Seconds      uint64
+			Microseconds uint64
+		}
+		if err = value.Store(&timestamp); err == nil && timestamp.Seconds > 0 {
+			m.timestamp = time.Unix(int64(timestamp.Seconds), int64(timestamp.Microseconds)*1000) //nolint:gosec
+		}
+	}
+	return m
+}
+
+// createResult composes and returns a Result using provided geolocation data and metadata.
+func (p *GeolocationGeoClueProvider) createResult(key string, coord geobus.Coordinate, m motion) geobus.Result {
+	return geobus.Result{
+		Key:            key,
+		Lat:            coord.Lat,
+		Lon:            coord.Lon,
+		Alt:            coord.Alt,
+		HasAlt:         coord.HasAlt,
+		AccuracyMeters: coord.Acc,
+		Source:         p.name,
+		At:             time.Now(),
+		TTL:            p.ttl,
+		Speed:          m.speed,
+		HasSpeed:       m.hasSpeed,
+		Heading:        m.heading,
+		HasHeading:     m.hasHeading,
+		SourceTime:     m.timestamp,
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/wneessen/waybar-weather/internal/geobus"
 	"github.com/wneessen/waybar-weather/internal/http"
+	"github.com/wneessen/waybar-weather/internal/logger"
 )
 
 const (
@@ -21,6 +22,7 @@ const (
 type GeolocationGeoIPProvider struct {
 	name   string
 	http   *http.Client
+	logger *logger.Logger
 	period time.Duration
 	ttl    time.Duration
 }
@@ -39,8 +41,9 @@ type APIResult struct {
 	MetroCode   int     `json:"metro_code"`
 }
 
-func NewGeolocationGeoIPProvider(http *http.Client) *GeolocationGeoIPProvider {
+func NewGeolocationGeoIPProvider(http *http.Client, log *logger.Logger) *GeolocationGeoIPProvider {
 	return &GeolocationGeoIPProvider{
+		logger: log,
 		name:   "geoip",
 		http:   http,
 		period: 30 * time.Minute,
@@ -52,12 +55,18 @@ func (p *GeolocationGeoIPProvider) Name() string {
 	return p.name
 }
 
+// Probe checks whether the GeoIP API is reachable.
+func (p *GeolocationGeoIPProvider) Probe(ctx context.Context) error {
+	return p.http.Probe(ctx, APIEndpoint)
+}
+
 // LookupStream continuously streams geolocation results from a file, emitting updates when data changes
 // or context ends.
 func (p *GeolocationGeoIPProvider) LookupStream(ctx context.Context, key string) <-chan geobus.Result {
 	out := make(chan geobus.Result)
 	go func() {
 		defer close(out)
+		defer p.logger.RecoverPanic(p.name)
 		state := geobus.GeolocationState{}
 
 		for {
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
 )
 
 // Accuracy is the default accuracy value for geolocation data. We consider geolocation file data as
@@ -26,14 +27,16 @@ const Accuracy = 5
 type GeolocationFileProvider struct {
 	name   string
 	path   string
+	logger *logger.Logger
 	period time.Duration
 	ttl    time.Duration
 }
 
 // NewGeolocationFileProvider initializes a GeolocationFileProvider with a file path and default update
 // interval and TTL settings.
-func NewGeolocationFileProvider(path string) *GeolocationFileProvider {
+func NewGeolocationFileProvider(path string, log *logger.Logger) *GeolocationFileProvider {
 	return &GeolocationFileProvider{
+		logger: log,
 		name:   "GeolocationFile",
 		path:   path,
 		period: 2 * time.Minute,
@@ -46,12 +49,21 @@ func (p *GeolocationFileProvider) Name() string {
 	return p.name
 }
 
+// Probe checks whether the geolocation file exists.
+func (p *GeolocationFileProvider) Probe(context.Context) error {
+	if _, err := os.Stat(p.path); err != nil {
+		return fmt.Errorf("failed to access geolocation file: %w", err)
+	}
+	return nil
+}
+
 // LookupStream continuously streams geolocation results from a file, emitting updates when data changes
 // or context ends.
 func (p *GeolocationFileProvider) LookupStream(ctx context.Context, key string) <-chan geobus.Result {
 	out := make(chan geobus.Result)
 	go func() {
 		defer close(out)
+		defer p.logger.RecoverPanic(p.name)
 		state := geobus.GeolocationState{}
 
 		for {
@@ -116,13 +128,16 @@ func (p *GeolocationFileProvider) readFile() (lat, lon float64, err error) {
 	if len(coords) != 2 {
 		return 0, 0, fmt.Errorf("geolocation file %q contains invalid coordinates", p.path)
 	}
-	lat, err = strconv.ParseFloat(coords[0], 64)
+	lat, err = strconv.ParseFloat(strings.TrimSpace(coords[0]), 64)
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to parse latitude from geolocation file %q: %w", p.path, err)
 	}
-	lon, err = strconv.ParseFloat(coords[1], 64)
+	lon, err = strconv.ParseFloat(strings.TrimSpace(coords[1]), 64)
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to parse longitude from geolocation file %q: %w", p.path, err)
 	}
+	if !(geobus.Coordinate{Lat: lat, Lon: lon}).Valid() {
+		return 0, 0, fmt.Errorf("geolocation file %q contains coordinates out of range", p.path)
+	}
 	return lat, lon, nil
 }
@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package geolocation_file
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+func FuzzGeolocationFileProvider_readFile(f *testing.F) {
+	for _, seed := range []string{
+		"52.52,13.405", " 52.52 , 13.405 \n", "52.52", "52.52,", "52.52,13.405,34", "NaN,13.405",
+		"52.52,+Inf", "91,13.405", "52.52,-180.1", "0x1p-2,1e2", "52.52,13.4\x00", "\xff\xfe,\xfd", "",
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		path := filepath.Join(t.TempDir(), "geolocation")
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatalf("failed to write geolocation file: %s", err)
+		}
+		provider := NewGeolocationFileProvider(path, logger.NewLogger(slog.LevelError))
+		lat, lon, err := provider.readFile()
+		if err != nil {
+			return
+		}
+		if !(geobus.Coordinate{Lat: lat, Lon: lon}).Valid() {
+			t.Errorf("invalid coordinates %f,%f parsed from %q", lat, lon, data)
+		}
+	})
+}
@@ -7,10 +7,12 @@ package gpsd
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net"
 	"time"
 
 	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
 
 	"github.com/stratoberry/go-gpsd"
 )
@@ -18,16 +20,20 @@ import (
 const (
 	host = "localhost"
 	port = "2947"
+	// probeTimeout is the time we wait for gpsd to accept a connection when probing
+	probeTimeout = 2 * time.Second
 )
 
 type GeolocationGPSDProvider struct {
 	name   string
+	logger *logger.Logger
 	period time.Duration
 	ttl    time.Duration
 }
 
-func NewGeolocationGPSDProvider() *GeolocationGPSDProvider {
+func NewGeolocationGPSDProvider(log *logger.Logger) *GeolocationGPSDProvider {
 	return &GeolocationGPSDProvider{
+		logger: log,
 		name:   "gpsd",
 		period: time.Second * 30,
 		ttl:    time.Minute * 2,
@@ -38,11 +44,24 @@ func (p *GeolocationGPSDProvider) Name() string {
 	return p.name
 }
 
+// Probe checks whether gpsd accepts connections.
+func (p *GeolocationGPSDProvider) Probe(ctx context.Context) error {
+	var dialer net.Dialer
+	ctxDial, cancelDial := context.WithTimeout(ctx, probeTimeout)
+	defer cancelDial()
+	conn, err := dialer.DialContext(ctxDial, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return fmt.Errorf("failed to connect to gpsd: %w", err)
+	}
+	return conn.Close()
+}
+
 func (p *GeolocationGPSDProvider) LookupStream(ctx context.Context, key string) <-chan geobus.Result {
 	out := make(chan geobus.Result)
 
 	go func() {
 		defer close(out)
+		defer p.logger.RecoverPanic(p.name)
 		state := geobus.GeolocationState{}
 
 		for {
@@ -58,8 +77,7 @@ func (p *GeolocationGPSDProvider) LookupStream(ctx context.Context, key string)
 			session, err := gpsd.Dial(addr)
 			if err != nil {
 				// gpsd unavailable — log and retry after a delay
-				// (Replace with your logger if you have one.)
-				fmt.Printf("GeolocationGPSD: failed to connect to gpsd at %q: %s\n", addr, err)
+				p.logger.Debug("failed to connect to gpsd", slog.String("address", addr), logger.Err(err))
 
 				select {
 				case <-ctx.Done():
@@ -71,6 +89,7 @@ func (p *GeolocationGPSDProvider) LookupStream(ctx context.Context, key string)
 
 			// Install TPV filter: this gets called for every TPV report
 			session.AddFilter("TPV", func(r interface{}) {
+				defer p.logger.RecoverPanic(p.name)
 				tpv, ok := r.(*gpsd.TPVReport)
 				if !ok {
 					return
@@ -85,7 +104,8 @@ func (p *GeolocationGPSDProvider) LookupStream(ctx context.Context, key string)
 					geobus.Truncate(tpv.Lon, geobus.TruncPrecision),
 					geobus.Truncate(tpv.Alt, geobus.TruncPrecision),
 					geobus.Truncate((tpv.Lat+tpv.Lon)/2, geobus.TruncPrecision)
-				coord := geobus.Coordinate{Lat: lat, Lon: lon, Alt: alt, Acc: acc}
+				// The altitude is only known with a 3D fix
+				coord := geobus.Coordinate{Lat: lat, Lon: lon, Alt: alt, Acc: acc, HasAlt: tpv.Mode >= gpsd.Mode3D}
 
 				// Only emit if values changed or it's the first fix
 				if !state.HasChanged(coord) {
@@ -132,6 +152,8 @@ func (p *GeolocationGPSDProvider) createResult(key string, coord geobus.Coordina
 		Key:            key,
 		Lat:            coord.Lat,
 		Lon:            coord.Lon,
+		Alt:            coord.Alt,
+		HasAlt:         coord.HasAlt,
 		AccuracyMeters: coord.Acc,
 		Source:         p.name,
 		At:             time.Now(),
@@ -14,6 +14,7 @@ import (
 
 	"github.com/wneessen/waybar-weather/internal/geobus"
 	"github.com/wneessen/waybar-weather/internal/http"
+	"github.com/wneessen/waybar-weather/internal/logger"
 
 	"github.com/mdlayher/wifi"
 )
@@ -26,6 +27,7 @@ const (
 type GeolocationICHNAEAProvider struct {
 	name   string
 	http   *http.Client
+	logger *logger.Logger
 	wlan   *wifi.Client
 	period time.Duration
 	ttl    time.Duration
@@ -45,7 +47,7 @@ type WirelessNetwork struct {
 	SignalStrength int32  `json:"signalStrength"`
 }
 
-func NewGeolocationICHNAEAProvider(http *http.Client) (*GeolocationICHNAEAProvider, error) {
+func NewGeolocationICHNAEAProvider(http *http.Client, log *logger.Logger) (*GeolocationICHNAEAProvider, error) {
 	wlan, err := wifi.New()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create wifi client: %w", err)
@@ -53,6 +55,7 @@ func NewGeolocationICHNAEAProvider(http *http.Client) (*GeolocationICHNAEAProvid
 	return &GeolocationICHNAEAProvider{
 		name:   "ichnaea",
 		http:   http,
+		logger: log,
 		wlan:   wlan,
 		period: 5 * time.Minute,
 		ttl:    10 * time.Minute,
@@ -63,12 +66,25 @@ func (p *GeolocationICHNAEAProvider) Name() string {
 	return p.name
 }
 
+// Probe checks whether wireless networks are visible and the ICHNAEA API is reachable.
+func (p *GeolocationICHNAEAProvider) Probe(ctx context.Context) error {
+	networks, err := p.wifiList()
+	if err != nil {
+		return fmt.Errorf("failed to scan wireless networks: %w", err)
+	}
+	if len(networks) == 0 {
+		return fmt.Errorf("no wireless networks visible")
+	}
+	return p.http.Probe(ctx, APIEndpoint)
+}
+
 // LookupStream continuously streams geolocation results from a file, emitting updates when data changes
 // or context ends.
 func (p *GeolocationICHNAEAProvider) LookupStream(ctx context.Context, key string) <-chan geobus.Result {
 	out := make(chan geobus.Result)
 	go func() {
 		defer close(out)
+		defer p.logger.RecoverPanic(p.name)
 		state := geobus.GeolocationState{}
 
 		for {
@@ -0,0 +1,162 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package winlocation implements a geolocation provider for Windows, which queries the Geolocator of the
+// Windows.Devices.Geolocation WinRT API via Windows PowerShell.
+package winlocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	// Command is the Windows PowerShell executable, which ships with every Windows installation and
+	// provides access to the WinRT APIs
+	Command = "powershell.exe"
+	// lookupTimeout is the maximum time the Geolocator may take to determine the location
+	lookupTimeout = 30 * time.Second
+)
+
+// script awaits the position of the WinRT Geolocator and prints latitude, longitude, accuracy, altitude
+// and whether the altitude is known, formatted independently of the locale of the user. The altitude
+// accuracy is null if the position has no altitude.
+const script = `$ErrorActionPreference = 'Stop'
+Add-Type -AssemblyName System.Runtime.WindowsRuntime
+$null = [Windows.Devices.Geolocation.Geolocator, Windows.Devices.Geolocation, ContentType = WindowsRuntime]
+$asTask = [System.WindowsRuntimeSystemExtensions].GetMethods() | Where-Object {
+    $_.Name -eq 'AsTask' -and $_.GetParameters().Count -eq 1 -and
+    $_.GetParameters()[0].ParameterType.Name -eq 'IAsyncOperation` + "`" + `1'
+} | Select-Object -First 1
+$operation = (New-Object Windows.Devices.Geolocation.Geolocator).GetGeopositionAsync()
+$task = $asTask.MakeGenericMethod([Windows.Devices.Geolocation.Geoposition]).Invoke($null, @($operation))
+$null = $task.Wait(-1)
+$coordinate = $task.Result.Coordinate
+[string]::Format([cultureinfo]::InvariantCulture, '{0} {1} {2} {3} {4}',
+    $coordinate.Point.Position.Latitude, $coordinate.Point.Position.Longitude,
+    $coordinate.Accuracy, $coordinate.Point.Position.Altitude, [int]($null -ne $coordinate.AltitudeAccuracy))`
+
+// GeolocationWindowsProvider periodically determines the location with the Windows location service,
+// which uses the Wi-Fi networks, GPS and the IP address available to the computer.
+type GeolocationWindowsProvider struct {
+	name   string
+	logger *logger.Logger
+	period time.Duration
+	ttl    time.Duration
+}
+
+// NewGeolocationWindowsProvider returns a new GeolocationWindowsProvider.
+func NewGeolocationWindowsProvider(log *logger.Logger) *GeolocationWindowsProvider {
+	return &GeolocationWindowsProvider{
+		logger: log,
+		name:   "Windows",
+		period: 5 * time.Minute,
+		ttl:    15 * time.Minute,
+	}
+}
+
+// Name returns the name of the GeolocationWindowsProvider instance.
+func (p *GeolocationWindowsProvider) Name() string {
+	return p.name
+}
+
+// Probe checks whether Windows PowerShell is available.
+func (p *GeolocationWindowsProvider) Probe(context.Context) error {
+	if _, err := exec.LookPath(Command); err != nil {
+		return fmt.Errorf("failed to find %s: %w", Command, err)
+	}
+	return nil
+}
+
+// LookupStream periodically queries the Windows location service and emits the location whenever it
+// changed.
+func (p *GeolocationWindowsProvider) LookupStream(ctx context.Context, key string) <-chan geobus.Result {
+	out := make(chan geobus.Result)
+	go func() {
+		defer close(out)
+		defer p.logger.RecoverPanic(p.name)
+		state := geobus.GeolocationState{}
+
+		for {
+			coord, err := p.locate(ctx)
+			if err != nil {
+				p.logger.Debug("failed to query Windows location service", logger.Err(err))
+			}
+			if err == nil && state.HasChanged(coord) {
+				state.Update(coord)
+				select {
+				case <-ctx.Done():
+					return
+				case out <- p.createResult(key, coord):
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.period):
+			}
+		}
+	}()
+	return out
+}
+
+// locate runs the Geolocator script once and parses the reported location. Fails if the location
+// service is disabled or the access to the location has been denied in the privacy settings.
+func (p *GeolocationWindowsProvider) locate(ctx context.Context) (geobus.Coordinate, error) {
+	ctxLookup, cancelLookup := context.WithTimeout(ctx, lookupTimeout)
+	defer cancelLookup()
+
+	output, err := exec.CommandContext(ctxLookup, Command, "-NoProfile", "-NonInteractive", "-Command",
+		script).Output()
+	if err != nil {
+		return geobus.Coordinate{}, fmt.Errorf("failed to run Geolocator: %w", err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) != 5 {
+		return geobus.Coordinate{}, errors.New("unexpected output of Geolocator")
+	}
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		if values[i], err = strconv.ParseFloat(field, 64); err != nil {
+			return geobus.Coordinate{}, fmt.Errorf("failed to parse Geolocator output: %w", err)
+		}
+	}
+	coord := geobus.Coordinate{
+		Lat:    geobus.Truncate(values[0], geobus.TruncPrecision),
+		Lon:    geobus.Truncate(values[1], geobus.TruncPrecision),
+		Acc:    values[2],
+		Alt:    values[3],
+		HasAlt: values[4] == 1,
+	}
+	if !coord.Valid() || coord.Acc <= 0 {
+		return geobus.Coordinate{}, errors.New("Windows location service reported an invalid location")
+	}
+	p.logger.Debug("received Windows location", slog.Float64("accuracy", coord.Acc))
+	return coord, nil
+}
+
+// createResult composes and returns a Result using provided geolocation data and metadata.
+func (p *GeolocationWindowsProvider) createResult(key string, coord geobus.Coordinate) geobus.Result {
+	return geobus.Result{
+		Key:            key,
+		Lat:            coord.Lat,
+		Lon:            coord.Lon,
+		Alt:            coord.Alt,
+		HasAlt:         coord.HasAlt,
+		AccuracyMeters: coord.Acc,
+		Source:         p.name,
+		At:             time.Now(),
+		TTL:            p.ttl,
+	}
+}
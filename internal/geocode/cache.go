@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package geocode
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wneessen/waybar-weather/internal/cache"
+)
+
+// cachePrecision is the amount of decimal places of the coordinates used as cache key (~11m)
+const cachePrecision = 4
+
+// Cache wraps a Geocoder and caches the addresses per location, so that the geocoding API is not queried
+// again for a location that has been looked up recently.
+type Cache struct {
+	geocoder Geocoder
+	store    *cache.Store
+}
+
+// NewCache returns a new Cache for the given Geocoder, which caches the addresses in the store.
+func NewCache(geocoder Geocoder, store *cache.Store) *Cache {
+	return &Cache{geocoder: geocoder, store: store}
+}
+
+// Name returns the name of the underlying Geocoder.
+func (c *Cache) Name() string {
+	return c.geocoder.Name()
+}
+
+// Reverse returns the cached address for the given coordinates or queries the underlying Geocoder if the
+// location has not been looked up recently.
+func (c *Cache) Reverse(ctx context.Context, lat, lon float64) (Address, error) {
+	key := fmt.Sprintf("%s/%.*f,%.*f", c.geocoder.Name(), cachePrecision, lat, cachePrecision, lon)
+	var address Address
+	if c.store.Get(key, &address) {
+		return address, nil
+	}
+
+	address, err := c.geocoder.Reverse(ctx, lat, lon)
+	if err != nil {
+		return address, err
+	}
+	if address.AddressFound {
+		_ = c.store.Set(key, address)
+	}
+	return address, nil
+}
+
+// Quota returns the request quota of the underlying Geocoder, if it reports one.
+func (c *Cache) Quota() Quota {
+	if reporter, ok := c.geocoder.(QuotaReporter); ok {
+		return reporter.Quota()
+	}
+	return Quota{}
+}
@@ -4,7 +4,12 @@
 
 package geocode
 
-import "context"
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+)
 
 type Address struct {
 	AddressFound bool
@@ -27,3 +32,40 @@ type Geocoder interface {
 	Name() string
 	Reverse(ctx context.Context, lat, lon float64) (Address, error)
 }
+
+// Quota represents the request quota of a geocoding provider as reported by its API.
+type Quota struct {
+	Known     bool
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// QuotaReporter is implemented by geocoders that are able to report the remaining request quota
+// of the account in use.
+type QuotaReporter interface {
+	Quota() Quota
+}
+
+// Sanitized returns a copy of the address with invalid UTF-8 sequences and control characters removed
+// from its text fields, so that a malformed geocoder response can't break the rendered output.
+func (a Address) Sanitized() Address {
+	for _, field := range []*string{
+		&a.DisplayName, &a.Country, &a.State, &a.Municipality, &a.CityDistrict, &a.Postcode, &a.City,
+		&a.Suburb, &a.Street, &a.HouseNumber,
+	} {
+		*field = sanitizeText(*field)
+	}
+	return a
+}
+
+// sanitizeText replaces invalid UTF-8 sequences with the replacement character and removes control
+// characters.
+func sanitizeText(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(text, "�"))
+}
@@ -7,7 +7,10 @@ package opencage
 import (
 	"context"
 	"fmt"
+	nethttp "net/http"
 	"net/url"
+	"strconv"
+	"sync"
 	"time"
 
 	"golang.org/x/text/language"
@@ -26,13 +29,24 @@ type OpenCage struct {
 	apikey string
 	http   *http.Client
 	lang   language.Tag
+
+	quotaLock sync.RWMutex
+	quota     geocode.Quota
 }
 
 type Response struct {
+	Rate         *Rate    `json:"rate,omitempty"`
 	Results      []Result `json:"results"`
 	TotalResults int      `json:"total_results"`
 }
 
+// Rate is the rate limit information returned by the OpenCage API for free trial accounts
+type Rate struct {
+	Limit     int   `json:"limit"`
+	Remaining int   `json:"remaining"`
+	Reset     int64 `json:"reset"`
+}
+
 type Result struct {
 	Components  Components `json:"components"`
 	DisplayName string     `json:"formatted"`
@@ -88,7 +102,9 @@ func (o *OpenCage) Reverse(ctx context.Context, lat, lon float64) (geocode.Addre
 	query.Set("language", o.lang.String())
 	apiUrl.RawQuery = query.Encode()
 
-	if _, err = o.http.GetWithTimeout(ctx, apiUrl.String(), &response, nil, APITimeout); err != nil {
+	_, headers, err := o.http.GetWithResponseHeaders(ctx, apiUrl.String(), &response, nil, APITimeout)
+	o.updateQuota(headers, response.Rate)
+	if err != nil {
 		return geocode.Address{}, fmt.Errorf("failed to address details from OpenCage API: %w", err)
 	}
 	if response.TotalResults != 1 {
@@ -116,3 +132,34 @@ func (o *OpenCage) Reverse(ctx context.Context, lat, lon float64) (geocode.Addre
 
 	return address, nil
 }
+
+// Quota returns the request quota of the API key as reported with the last API response.
+func (o *OpenCage) Quota() geocode.Quota {
+	o.quotaLock.RLock()
+	defer o.quotaLock.RUnlock()
+	return o.quota
+}
+
+// updateQuota updates the request quota from the X-RateLimit response headers or, if the headers are
+// not present, from the rate information in the response body.
+func (o *OpenCage) updateQuota(headers nethttp.Header, rate *Rate) {
+	quota := geocode.Quota{}
+	limit, errLimit := strconv.Atoi(headers.Get("X-RateLimit-Limit"))
+	remaining, errRemaining := strconv.Atoi(headers.Get("X-RateLimit-Remaining"))
+	reset, errReset := strconv.ParseInt(headers.Get("X-RateLimit-Reset"), 10, 64)
+	switch {
+	case errLimit == nil && errRemaining == nil && errReset == nil:
+		quota = geocode.Quota{Known: true, Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}
+	case rate != nil:
+		quota = geocode.Quota{
+			Known: true, Limit: rate.Limit, Remaining: rate.Remaining,
+			Reset: time.Unix(rate.Reset, 0),
+		}
+	default:
+		return
+	}
+
+	o.quotaLock.Lock()
+	o.quota = quota
+	o.quotaLock.Unlock()
+}
@@ -0,0 +1,175 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	budgetDateFormat = "2006-01-02"
+	// budgetSaveInterval limits how often the counters are written to the state file
+	budgetSaveInterval = time.Minute
+)
+
+// ErrBudgetExceeded is returned if a request would exceed the daily request budget of an API.
+var ErrBudgetExceeded = errors.New("daily API request budget exceeded")
+
+// Budget tracks the daily amount of requests per external API (identified by its host) and refuses
+// requests that would exceed the configured daily limit. The counters are reset at local midnight
+// and persisted to a state file, so that a restart of the service does not reset the budget. The state
+// file is written at most once per minute and on Flush.
+type Budget struct {
+	mu        sync.Mutex
+	limits    map[string]uint
+	fallback  uint
+	path      string
+	day       string
+	counts    map[string]uint
+	exhausted bool
+	dirty     bool
+	saved     time.Time
+}
+
+type budgetState struct {
+	Day    string          `json:"day"`
+	Counts map[string]uint `json:"counts"`
+}
+
+// NewBudget returns a new Budget with the given per-host limits. A limit of 0 means unlimited.
+// fallback is used for all hosts without a dedicated limit. If path is not empty, the counters
+// are restored from and persisted to the file at the given path.
+func NewBudget(limits map[string]uint, fallback uint, path string) *Budget {
+	budget := &Budget{
+		limits:   limits,
+		fallback: fallback,
+		path:     path,
+		day:      time.Now().Format(budgetDateFormat),
+		counts:   make(map[string]uint),
+	}
+	budget.load()
+	return budget
+}
+
+// Allow checks if another request to the given host fits into the daily budget and counts it
+// if it does. Returns ErrBudgetExceeded otherwise.
+func (b *Budget) Allow(host string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+
+	if limit := b.Limit(host); limit > 0 && b.counts[host] >= limit {
+		b.exhausted = true
+		return fmt.Errorf("%w: %d requests to %s", ErrBudgetExceeded, b.counts[host], host)
+	}
+	b.counts[host]++
+	b.dirty = true
+	if time.Since(b.saved) >= budgetSaveInterval {
+		b.save()
+	}
+	return nil
+}
+
+// Flush writes pending changes of the counters to the state file.
+func (b *Budget) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.dirty {
+		b.save()
+	}
+}
+
+// Limit returns the daily request limit of the given host, 0 means unlimited.
+func (b *Budget) Limit(host string) uint {
+	if limit, ok := b.limits[host]; ok {
+		return limit
+	}
+	return b.fallback
+}
+
+// Usage returns a copy of the request counters of the current day.
+func (b *Budget) Usage() map[string]uint {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+
+	usage := make(map[string]uint, len(b.counts))
+	for host, count := range b.counts {
+		usage[host] = count
+	}
+	return usage
+}
+
+// Exhausted returns true if a request has been refused during the current day.
+func (b *Budget) Exhausted() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+	return b.exhausted
+}
+
+// rollover resets the counters if the day has changed. The caller must hold the lock.
+func (b *Budget) rollover() {
+	today := time.Now().Format(budgetDateFormat)
+	if today == b.day {
+		return
+	}
+	b.day = today
+	b.counts = make(map[string]uint)
+	b.exhausted = false
+}
+
+// load restores the counters of the current day from the state file.
+func (b *Budget) load() {
+	if b.path == "" {
+		return
+	}
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return
+	}
+	var state budgetState
+	if err = json.Unmarshal(data, &state); err != nil || state.Day != b.day || state.Counts == nil {
+		return
+	}
+	b.counts = state.Counts
+}
+
+// save persists the counters to the state file. The caller must hold the lock.
+func (b *Budget) save() {
+	if b.path == "" {
+		return
+	}
+	b.dirty, b.saved = false, time.Now()
+	data, err := json.Marshal(budgetState{Day: b.day, Counts: b.counts})
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(b.path), 0o700); err != nil {
+		return
+	}
+	_ = os.WriteFile(b.path, data, 0o600)
+}
+
+// budgetTransport is a http.RoundTripper that enforces the request Budget before passing the
+// request on to the underlying transport.
+type budgetTransport struct {
+	budget *Budget
+	next   http.RoundTripper
+}
+
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.budget.Allow(req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
@@ -21,6 +21,9 @@ import (
 const (
 	// DefaultTimeout is the default timeout value for the HTTPClient
 	DefaultTimeout = time.Second * 10
+
+	// idleConnTimeout is the time after which an idle keep-alive connection is closed
+	idleConnTimeout = time.Second * 90
 )
 
 var (
@@ -32,6 +35,13 @@ var (
 		runtime.GOARCH,
 		version,
 	)
+
+	// transport is shared by all clients, so that the keep-alive connections of short-lived clients
+	// (e.g. the probes) are reused and closed once idle, instead of accumulating with every new client
+	transport = &http.Transport{
+		TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS12},
+		IdleConnTimeout: idleConnTimeout,
+	}
 )
 
 // Client is a type wrapper for the Go stdlib http.Client and the Config
@@ -40,12 +50,13 @@ type Client struct {
 	logger *logger.Logger
 }
 
-// New returns a new HTTP client
-func New(logger *logger.Logger) *Client {
-	tlsConfig := &tls.Config{
-		MinVersion: tls.VersionTLS12,
+// New returns a new HTTP client. If budget is not nil, all requests are subject to the
+// daily request budget
+func New(logger *logger.Logger, budget *Budget) *Client {
+	var httpTransport http.RoundTripper = transport
+	if budget != nil {
+		httpTransport = &budgetTransport{budget: budget, next: httpTransport}
 	}
-	httpTransport := &http.Transport{TLSClientConfig: tlsConfig}
 	httpClient := &http.Client{
 		Timeout:   DefaultTimeout,
 		Transport: httpTransport,
@@ -62,8 +73,15 @@ func (h *Client) Get(ctx context.Context, url string, target any, headers map[st
 // GetWithTimeout performs a HTTP GET request for the given URL and timeout and JSON-unmarshals
 // the response into target
 func (h *Client) GetWithTimeout(ctx context.Context, url string, target any, headers map[string]string, timeout time.Duration) (int, error) {
+	code, _, err := h.GetWithResponseHeaders(ctx, url, target, headers, timeout)
+	return code, err
+}
+
+// GetWithResponseHeaders performs a HTTP GET request for the given URL and timeout, JSON-unmarshals
+// the response into target and returns the response headers
+func (h *Client) GetWithResponseHeaders(ctx context.Context, url string, target any, headers map[string]string, timeout time.Duration) (int, http.Header, error) {
 	if target == nil {
-		return 0, errors.New("target must not be nil")
+		return 0, nil, errors.New("target must not be nil")
 	}
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
@@ -71,7 +89,7 @@ func (h *Client) GetWithTimeout(ctx context.Context, url string, target any, hea
 	// Prepare HTTP request
 	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return 0, fmt.Errorf("failed create new HTTP request with context: %w", err)
+		return 0, nil, fmt.Errorf("failed create new HTTP request with context: %w", err)
 	}
 	request.Header.Set("User-Agent", UserAgent)
 	for k, v := range headers {
@@ -81,12 +99,12 @@ func (h *Client) GetWithTimeout(ctx context.Context, url string, target any, hea
 	response, err := h.Do(request)
 	if err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-			return 0, err
+			return 0, nil, err
 		}
-		return 0, fmt.Errorf("failed to perform HTTP request: %w", err)
+		return 0, nil, fmt.Errorf("failed to perform HTTP request: %w", err)
 	}
 	if response == nil {
-		return 0, errors.New("nil response received")
+		return 0, nil, errors.New("nil response received")
 	}
 	defer func(body io.ReadCloser) {
 		if err := body.Close(); err != nil {
@@ -96,10 +114,10 @@ func (h *Client) GetWithTimeout(ctx context.Context, url string, target any, hea
 
 	// Unmarshal the JSON API response into target
 	if err = json.NewDecoder(response.Body).Decode(target); err != nil {
-		return response.StatusCode, fmt.Errorf("failed to decode JSON: %w", err)
+		return response.StatusCode, response.Header, fmt.Errorf("failed to decode JSON: %w", err)
 	}
 
-	return response.StatusCode, nil
+	return response.StatusCode, response.Header, nil
 }
 
 // Post performs a HTTP POST request for the given URL and json-unmarshals the response
@@ -150,3 +168,40 @@ func (h *Client) PostWithTimeout(ctx context.Context, url string, target any, bo
 
 	return response.StatusCode, nil
 }
+
+// Head performs a HTTP HEAD request for the given URL and returns the status code. It is used to check
+// whether an API is reachable without transferring a response body.
+func (h *Client) Head(ctx context.Context, url string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed create new HTTP request with context: %w", err)
+	}
+	request.Header.Set("User-Agent", UserAgent)
+	response, err := h.Do(request)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return 0, err
+		}
+		return 0, fmt.Errorf("failed to perform HTTP request: %w", err)
+	}
+	if err = response.Body.Close(); err != nil {
+		h.logger.Error("failed to close HTTP request body", logger.Err(err))
+	}
+	return response.StatusCode, nil
+}
+
+// Probe checks whether the API at the given URL is reachable. Since some APIs don't support HEAD requests,
+// every response that is not a server error is considered as reachable.
+func (h *Client) Probe(ctx context.Context, url string) error {
+	code, err := h.Head(ctx, url)
+	if err != nil {
+		return err
+	}
+	if code >= http.StatusInternalServerError {
+		return fmt.Errorf("API responded with HTTP status %d", code)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// WithEndpoints returns a copy of the client that sends the requests for the given API hosts to the
+// mapped base URLs instead, e.g. to a local mock server. The path of a base URL is prepended to the
+// request path.
+func (h *Client) WithEndpoints(endpoints map[string]string) (*Client, error) {
+	if len(endpoints) == 0 {
+		return h, nil
+	}
+	bases := make(map[string]*url.URL, len(endpoints))
+	for host, endpoint := range endpoints {
+		base, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse endpoint for %s: %w", host, err)
+		}
+		bases[host] = base
+	}
+
+	transport := h.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	client := *h.Client
+	client.Transport = &endpointTransport{bases: bases, next: transport}
+	return &Client{&client, h.logger}, nil
+}
+
+// endpointTransport is a http.RoundTripper that redirects the requests for the mapped hosts to
+// their base URLs before passing them on to the underlying transport.
+type endpointTransport struct {
+	bases map[string]*url.URL
+	next  http.RoundTripper
+}
+
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base, ok := t.bases[req.URL.Host]
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.URL.Scheme = base.Scheme
+	req.URL.Host = base.Host
+	req.URL.Path = base.Path + req.URL.Path
+	req.URL.RawPath = ""
+	req.Host = ""
+	return t.next.RoundTrip(req)
+}
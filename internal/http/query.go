@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package http
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// WithQuery returns a copy of the client that adds the given parameters to the query of every request,
+// e.g. for API options the client library doesn't support. Parameters already set by the request take
+// precedence.
+func (h *Client) WithQuery(params url.Values) *Client {
+	if len(params) == 0 {
+		return h
+	}
+	transport := h.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	client := *h.Client
+	client.Transport = &queryTransport{params: params, next: transport}
+	return &Client{&client, h.logger}
+}
+
+// queryTransport is a http.RoundTripper that adds query parameters to the requests before passing them
+// on to the underlying transport.
+type queryTransport struct {
+	params url.Values
+	next   http.RoundTripper
+}
+
+func (t *queryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	query := req.URL.Query()
+	for key, values := range t.params {
+		if !query.Has(key) {
+			query[key] = values
+		}
+	}
+	req.URL.RawQuery = query.Encode()
+	return t.next.RoundTrip(req)
+}
@@ -26,6 +26,9 @@ func New(loc string) (*spreak.Localizer, error) {
 			tag = language.English // Unable to detect locale, fallback to English
 		}
 	}
+	if tag == language.Und {
+		tag = language.English // Locale-less environments like "C" or "POSIX", fallback to English
+	}
 
 	localeFS, err := fs.Sub(locales, "locale")
 	if err != nil {
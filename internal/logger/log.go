@@ -5,17 +5,93 @@
 package logger
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
+	"runtime/debug"
+	"strings"
+)
+
+const (
+	// PrivacyExact logs exact coordinates and full addresses
+	PrivacyExact = "exact"
+	// PrivacyRound logs coordinates rounded to roughly 1km and the city and country only
+	PrivacyRound = "round"
+	// PrivacyRedact does not log any coordinates or addresses
+	PrivacyRedact = "redact"
+
+	redacted = "[redacted]"
 )
 
 type Logger struct {
 	*slog.Logger
+	privacy string
+	records *records
 }
 
 func NewLogger(level slog.Level) *Logger {
 	output := os.Stderr
-	return &Logger{slog.New(slog.NewTextHandler(output, &slog.HandlerOptions{Level: level}))}
+	recs := new(records)
+	handler := &recordingHandler{
+		Handler: slog.NewTextHandler(output, &slog.HandlerOptions{Level: level}),
+		records: recs,
+	}
+	return &Logger{
+		Logger:  slog.New(handler),
+		privacy: PrivacyRound,
+		records: recs,
+	}
+}
+
+// SetPrivacy sets the privacy mode that controls how coordinates and addresses are logged.
+func (l *Logger) SetPrivacy(mode string) {
+	l.privacy = mode
+}
+
+// Coordinates returns a log attribute for the given coordinates, which are rounded or redacted
+// depending on the privacy mode.
+func (l *Logger) Coordinates(lat, lon float64) slog.Attr {
+	switch l.privacy {
+	case PrivacyExact:
+		return slog.String("coordinates", fmt.Sprintf("%f,%f", lat, lon))
+	case PrivacyRedact:
+		return slog.String("coordinates", redacted)
+	default:
+		return slog.String("coordinates", fmt.Sprintf("%.2f,%.2f", lat, lon))
+	}
+}
+
+// Address returns a log attribute for the given address, which is reduced to the city and country
+// or redacted depending on the privacy mode.
+func (l *Logger) Address(displayName, city, country string) slog.Attr {
+	switch l.privacy {
+	case PrivacyExact:
+		return slog.String("address", displayName)
+	case PrivacyRedact:
+		return slog.String("address", redacted)
+	default:
+		var parts []string
+		for _, part := range []string{city, country} {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+		return slog.String("address", strings.Join(parts, ", "))
+	}
+}
+
+// RecoverPanic recovers from a panic in the calling goroutine and logs it with its stack trace, so
+// that a single failing subsystem does not take down the whole service. It must be called via defer.
+func (l *Logger) RecoverPanic(subsystem string) {
+	if r := recover(); r != nil {
+		l.LogPanic(subsystem, r)
+	}
+}
+
+// LogPanic logs a recovered panic value with the stack trace of the calling goroutine.
+func (l *Logger) LogPanic(subsystem string, r any) {
+	l.Error("recovered from panic", slog.String("subsystem", subsystem), slog.Any("panic", r),
+		slog.String("stack", string(debug.Stack())))
 }
 
 func Err(err error) slog.Attr {
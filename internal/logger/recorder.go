@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recordLimit is the number of recent warnings and errors that are kept
+const recordLimit = 10
+
+// Record represents a logged warning or error.
+type Record struct {
+	Time    time.Time  `json:"time"`
+	Level   slog.Level `json:"level"`
+	Message string     `json:"message"`
+	Attrs   string     `json:"attrs,omitempty"`
+}
+
+// records keeps the most recent warnings and errors, shared by all handlers derived from the logger.
+type records struct {
+	mu      sync.Mutex
+	entries []Record
+}
+
+// add appends the record and drops the oldest one if the limit is exceeded.
+func (r *records) add(record Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, record)
+	if len(r.entries) > recordLimit {
+		r.entries = r.entries[len(r.entries)-recordLimit:]
+	}
+}
+
+// recordingHandler is a slog.Handler that keeps the recent warnings and errors before passing the
+// records on to the wrapped handler.
+type recordingHandler struct {
+	slog.Handler
+	records *records
+	attrs   []slog.Attr
+}
+
+func (h *recordingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn {
+		attrs := make([]string, 0, len(h.attrs)+r.NumAttrs())
+		for _, attr := range h.attrs {
+			attrs = append(attrs, attr.String())
+		}
+		r.Attrs(func(attr slog.Attr) bool {
+			attrs = append(attrs, attr.String())
+			return true
+		})
+		h.records.add(Record{Time: r.Time, Level: r.Level, Message: r.Message, Attrs: strings.Join(attrs, " ")})
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *recordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &recordingHandler{
+		Handler: h.Handler.WithAttrs(attrs),
+		records: h.records,
+		attrs:   append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...),
+	}
+}
+
+func (h *recordingHandler) WithGroup(name string) slog.Handler {
+	return &recordingHandler{Handler: h.Handler.WithGroup(name), records: h.records, attrs: h.attrs}
+}
+
+// RecentErrors returns the most recent logged warnings and errors, oldest first.
+func (l *Logger) RecentErrors() []Record {
+	l.records.mu.Lock()
+	defer l.records.mu.Unlock()
+	return append([]Record(nil), l.records.entries...)
+}
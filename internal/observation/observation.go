@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package observation
+
+import (
+	"context"
+	"time"
+)
+
+// Observation represents the latest measured conditions of a weather station close to the
+// current location. All values are reported in metric units.
+type Observation struct {
+	StationID   string
+	StationName string
+	ObservedAt  time.Time
+	Temperature float64
+	Humidity    float64
+}
+
+// Observer is implemented by all providers that are able to look up actual weather observations
+// for a given set of coordinates.
+type Observer interface {
+	Name() string
+	Nearest(ctx context.Context, lat, lon float64) (Observation, error)
+}
@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package nws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/http"
+	"github.com/wneessen/waybar-weather/internal/observation"
+)
+
+const (
+	APIEndpoint = "https://api.weather.gov"
+	APITimeout  = time.Second * 10
+	name        = "nws"
+)
+
+// headers are sent with every request to the NWS API, which requires a GeoJSON accept header.
+var headers = map[string]string{"Accept": "application/geo+json"}
+
+// NWS looks up the latest observation of the nearest station of the US National Weather Service.
+// The station lookup is cached for the last requested coordinates.
+type NWS struct {
+	http *http.Client
+
+	mu          sync.Mutex
+	lat, lon    float64
+	stationID   string
+	stationName string
+}
+
+type pointResponse struct {
+	Properties struct {
+		ObservationStations string `json:"observationStations"`
+	} `json:"properties"`
+}
+
+type stationsResponse struct {
+	Features []struct {
+		Properties struct {
+			StationIdentifier string `json:"stationIdentifier"`
+			Name              string `json:"name"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+type quantity struct {
+	UnitCode string   `json:"unitCode"`
+	Value    *float64 `json:"value"`
+}
+
+type observationResponse struct {
+	Properties struct {
+		Timestamp        time.Time `json:"timestamp"`
+		Temperature      quantity  `json:"temperature"`
+		RelativeHumidity quantity  `json:"relativeHumidity"`
+	} `json:"properties"`
+}
+
+func New(client *http.Client) *NWS {
+	return &NWS{http: client}
+}
+
+func (n *NWS) Name() string {
+	return name
+}
+
+// Nearest returns the latest observation of the station closest to the given coordinates.
+func (n *NWS) Nearest(ctx context.Context, lat, lon float64) (observation.Observation, error) {
+	stationID, stationName, err := n.station(ctx, lat, lon)
+	if err != nil {
+		return observation.Observation{}, err
+	}
+
+	var response observationResponse
+	apiUrl := fmt.Sprintf("%s/stations/%s/observations/latest", APIEndpoint, stationID)
+	if _, err = n.http.GetWithTimeout(ctx, apiUrl, &response, headers, APITimeout); err != nil {
+		return observation.Observation{}, fmt.Errorf("failed to get latest observation from NWS API: %w", err)
+	}
+	if response.Properties.Temperature.Value == nil {
+		return observation.Observation{}, fmt.Errorf("station %s did not report a temperature", stationID)
+	}
+
+	obs := observation.Observation{
+		StationID:   stationID,
+		StationName: stationName,
+		ObservedAt:  response.Properties.Timestamp,
+		Temperature: *response.Properties.Temperature.Value,
+	}
+	if response.Properties.RelativeHumidity.Value != nil {
+		obs.Humidity = *response.Properties.RelativeHumidity.Value
+	}
+
+	return obs, nil
+}
+
+// station resolves the nearest observation station for the given coordinates. The result is
+// cached until the coordinates change.
+func (n *NWS) station(ctx context.Context, lat, lon float64) (string, string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stationID != "" && n.lat == lat && n.lon == lon {
+		return n.stationID, n.stationName, nil
+	}
+
+	var point pointResponse
+	apiUrl := fmt.Sprintf("%s/points/%.4f,%.4f", APIEndpoint, lat, lon)
+	if _, err := n.http.GetWithTimeout(ctx, apiUrl, &point, headers, APITimeout); err != nil {
+		return "", "", fmt.Errorf("failed to get grid point from NWS API: %w", err)
+	}
+	if point.Properties.ObservationStations == "" {
+		return "", "", errors.New("no observation stations available for coordinates")
+	}
+
+	var stations stationsResponse
+	if _, err := n.http.GetWithTimeout(ctx, point.Properties.ObservationStations, &stations, headers,
+		APITimeout); err != nil {
+		return "", "", fmt.Errorf("failed to get observation stations from NWS API: %w", err)
+	}
+	if len(stations.Features) == 0 {
+		return "", "", errors.New("no observation stations available for coordinates")
+	}
+
+	n.lat, n.lon = lat, lon
+	n.stationID = stations.Features[0].Properties.StationIdentifier
+	n.stationName = stations.Features[0].Properties.Name
+	return n.stationID, n.stationName, nil
+}
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package output
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+// Async is a non-blocking Writer that hands the values to another Writer in the background. If the
+// reader of the output stops reading (e.g. because Waybar is suspended), writes would block. In that
+// case, the values are queued in a bounded queue and the oldest, superseded values are dropped, so that
+// the latest state is written once the reader continues.
+type Async struct {
+	writer Writer
+	logger *logger.Logger
+	queue  chan any
+
+	mu      sync.Mutex
+	err     error
+	dropped uint
+}
+
+// NewAsync returns a new Async writer that writes to w with a queue of the given size. The background
+// writer stops when the context ends.
+func NewAsync(ctx context.Context, w Writer, log *logger.Logger, size int) *Async {
+	a := &Async{
+		writer: w,
+		logger: log,
+		queue:  make(chan any, max(size, 1)),
+	}
+	go a.run(ctx)
+	return a
+}
+
+// Write queues v for writing and returns immediately. If the queue is full, the oldest value is
+// dropped. Returns the error of the last failed write, so that a closed output is noticed.
+func (a *Async) Write(v any) error {
+	for {
+		select {
+		case a.queue <- v:
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			return a.err
+		default:
+		}
+
+		select {
+		case <-a.queue:
+			a.mu.Lock()
+			if a.dropped == 0 {
+				a.logger.Warn("output reader is stalled, dropping superseded output")
+			}
+			a.dropped++
+			a.mu.Unlock()
+		default:
+		}
+	}
+}
+
+// run writes the queued values until the context ends.
+func (a *Async) run(ctx context.Context) {
+	defer a.logger.RecoverPanic("output")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case v := <-a.queue:
+			err := a.writer.Write(v)
+
+			a.mu.Lock()
+			a.err = err
+			if err == nil && a.dropped > 0 {
+				a.logger.Info("output reader has resumed", slog.Uint64("dropped", uint64(a.dropped)))
+				a.dropped = 0
+			}
+			a.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package output implements the writers for the module output that is read by Waybar.
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// ErrBrokenPipe is returned if the reader of the output has gone away, e.g. because Waybar has been
+// restarted.
+var ErrBrokenPipe = errors.New("output reader has gone away")
+
+// Writer is implemented by all output writers.
+type Writer interface {
+	Write(v any) error
+}
+
+// flusher is implemented by writers that buffer their output.
+type flusher interface {
+	Flush() error
+}
+
+// JSONLines writes each value as one complete JSON object per line, as expected by Waybar. It is safe
+// for concurrent use.
+type JSONLines struct {
+	mu      sync.Mutex
+	w       io.Writer
+	partial bool
+}
+
+// NewJSONLines returns a new JSONLines writer that writes to w.
+func NewJSONLines(w io.Writer) *JSONLines {
+	return &JSONLines{w: w}
+}
+
+// Write encodes v as JSON and writes it as a single line. The value is encoded completely before it is
+// written, so that an encoding error never results in a partial line. If a previous write was only
+// partially successful, the broken line is terminated first, so the reader is able to resync with the
+// next line. If the writer buffers its output, it is flushed after each line.
+func (j *JSONLines) Write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	return j.writeLine(data)
+}
+
+// writeLine writes data followed by a line break.
+func (j *JSONLines) writeLine(data []byte) error {
+	data = append(data, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.partial {
+		data = append([]byte{'\n'}, data...)
+	}
+	n, err := j.w.Write(data)
+	j.partial = err != nil && n > 0 && n < len(data)
+	if err == nil {
+		if f, ok := j.w.(flusher); ok {
+			err = f.Flush()
+		}
+	}
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrClosedPipe) {
+		return fmt.Errorf("%w: %w", ErrBrokenPipe, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// TextLines writes the text of each value as a single plain text line, e.g. for status bars that only
+// display the standard output of a command. The values have to implement fmt.Stringer.
+type TextLines struct {
+	lines *JSONLines
+}
+
+// NewTextLines returns a new TextLines writer that writes to w.
+func NewTextLines(w io.Writer) *TextLines {
+	return &TextLines{lines: NewJSONLines(w)}
+}
+
+// Write writes the text of v as a single line, line breaks within the text are replaced by spaces.
+func (t *TextLines) Write(v any) error {
+	stringer, ok := v.(fmt.Stringer)
+	if !ok {
+		return fmt.Errorf("failed to write output: %T has no text representation", v)
+	}
+	return t.lines.writeLine([]byte(strings.ReplaceAll(stringer.String(), "\n", " ")))
+}
+
+// PolybarLines writes the text of each value as a single line for the custom/script module of polybar. The
+// text is wrapped in an action tag, so that a left click on the module runs the given command, e.g. to
+// cycle the display modes. The values have to implement fmt.Stringer.
+type PolybarLines struct {
+	lines   *JSONLines
+	onClick string
+}
+
+// NewPolybarLines returns a new PolybarLines writer that writes to w. If onClick is empty, no action tag
+// is written.
+func NewPolybarLines(w io.Writer, onClick string) *PolybarLines {
+	// Colons terminate the command of an action tag, so they have to be escaped
+	return &PolybarLines{lines: NewJSONLines(w), onClick: strings.ReplaceAll(onClick, ":", `\:`)}
+}
+
+// Write writes the text of v as a single line, line breaks within the text are replaced by spaces.
+func (p *PolybarLines) Write(v any) error {
+	stringer, ok := v.(fmt.Stringer)
+	if !ok {
+		return fmt.Errorf("failed to write output: %T has no text representation", v)
+	}
+	text := strings.ReplaceAll(stringer.String(), "\n", " ")
+	if p.onClick != "" {
+		text = "%{A1:" + p.onClick + ":}" + text + "%{A}"
+	}
+	return p.lines.writeLine([]byte(text))
+}
+
+// Discard is a Writer that discards all values, e.g. if the daemon only serves attached instances.
+var Discard Writer = discard{}
+
+// discard implements the Discard writer.
+type discard struct{}
+
+// Write discards v.
+func (discard) Write(any) error {
+	return nil
+}
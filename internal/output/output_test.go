@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package output
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"syscall"
+	"testing"
+)
+
+// partialWriter writes at most limit bytes of the next write and fails with err, subsequent writes
+// succeed.
+type partialWriter struct {
+	bytes.Buffer
+	limit int
+	err   error
+}
+
+func (p *partialWriter) Write(data []byte) (int, error) {
+	if p.err == nil {
+		return p.Buffer.Write(data)
+	}
+	err := p.err
+	p.err = nil
+	n, _ := p.Buffer.Write(data[:min(p.limit, len(data))])
+	return n, err
+}
+
+// flushWriter counts the flushes of the written data.
+type flushWriter struct {
+	bytes.Buffer
+	flushes int
+	err     error
+}
+
+func (f *flushWriter) Flush() error {
+	f.flushes++
+	return f.err
+}
+
+type text string
+
+func (t text) String() string {
+	return string(t)
+}
+
+func TestJSONLines_Write(t *testing.T) {
+	t.Run("each value is written as one line", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		lines := NewJSONLines(buf)
+		for _, v := range []any{map[string]string{"text": "a"}, map[string]string{"text": "b\nc"}} {
+			if err := lines.Write(v); err != nil {
+				t.Fatalf("failed to write value: %s", err)
+			}
+		}
+		want := "{\"text\":\"a\"}\n{\"text\":\"b\\nc\"}\n"
+		if buf.String() != want {
+			t.Errorf("expected output %q, got %q", want, buf.String())
+		}
+	})
+	t.Run("a partial write is terminated before the next line", func(t *testing.T) {
+		writer := &partialWriter{limit: 5, err: syscall.EAGAIN}
+		lines := NewJSONLines(writer)
+		if err := lines.Write(map[string]string{"text": "first"}); err == nil {
+			t.Fatal("expected the partial write to fail")
+		}
+		if err := lines.Write(map[string]string{"text": "second"}); err != nil {
+			t.Fatalf("failed to write value: %s", err)
+		}
+		want := "{\"tex\n{\"text\":\"second\"}\n"
+		if writer.String() != want {
+			t.Errorf("expected output %q, got %q", want, writer.String())
+		}
+	})
+	t.Run("a failed write without written bytes is not terminated", func(t *testing.T) {
+		writer := &partialWriter{limit: 0, err: syscall.EAGAIN}
+		lines := NewJSONLines(writer)
+		if err := lines.Write(map[string]string{"text": "first"}); err == nil {
+			t.Fatal("expected the write to fail")
+		}
+		if err := lines.Write(map[string]string{"text": "second"}); err != nil {
+			t.Fatalf("failed to write value: %s", err)
+		}
+		want := "{\"text\":\"second\"}\n"
+		if writer.String() != want {
+			t.Errorf("expected output %q, got %q", want, writer.String())
+		}
+	})
+	t.Run("an encoding error writes nothing", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		lines := NewJSONLines(buf)
+		if err := lines.Write(map[string]float64{"value": math.NaN()}); err == nil {
+			t.Fatal("expected the encoding to fail")
+		}
+		if buf.Len() != 0 {
+			t.Errorf("expected no output, got %q", buf.String())
+		}
+	})
+	t.Run("a broken pipe is reported", func(t *testing.T) {
+		for _, err := range []error{syscall.EPIPE, io.ErrClosedPipe} {
+			lines := NewJSONLines(&partialWriter{limit: 3, err: err})
+			if werr := lines.Write("value"); !errors.Is(werr, ErrBrokenPipe) || !errors.Is(werr, err) {
+				t.Errorf("expected error to wrap %s and %s, got %s", ErrBrokenPipe, err, werr)
+			}
+		}
+	})
+	t.Run("buffered output is flushed after each line", func(t *testing.T) {
+		writer := &flushWriter{}
+		lines := NewJSONLines(writer)
+		for range 2 {
+			if err := lines.Write("value"); err != nil {
+				t.Fatalf("failed to write value: %s", err)
+			}
+		}
+		if writer.flushes != 2 {
+			t.Errorf("expected 2 flushes, got %d", writer.flushes)
+		}
+	})
+	t.Run("a failed flush is reported", func(t *testing.T) {
+		lines := NewJSONLines(&flushWriter{err: syscall.EPIPE})
+		if err := lines.Write("value"); !errors.Is(err, ErrBrokenPipe) {
+			t.Errorf("expected error to wrap %s, got %s", ErrBrokenPipe, err)
+		}
+	})
+}
+
+func TestTextLines_Write(t *testing.T) {
+	t.Run("line breaks are replaced", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		if err := NewTextLines(buf).Write(text("sunny\n21°C")); err != nil {
+			t.Fatalf("failed to write value: %s", err)
+		}
+		if buf.String() != "sunny 21°C\n" {
+			t.Errorf("expected output %q, got %q", "sunny 21°C\n", buf.String())
+		}
+	})
+	t.Run("a partial write is terminated before the next line", func(t *testing.T) {
+		writer := &partialWriter{limit: 2, err: syscall.EAGAIN}
+		lines := NewTextLines(writer)
+		if err := lines.Write(text("first")); err == nil {
+			t.Fatal("expected the partial write to fail")
+		}
+		if err := lines.Write(text("second")); err != nil {
+			t.Fatalf("failed to write value: %s", err)
+		}
+		if writer.String() != "fi\nsecond\n" {
+			t.Errorf("expected output %q, got %q", "fi\nsecond\n", writer.String())
+		}
+	})
+	t.Run("values without text are rejected", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		if err := NewTextLines(buf).Write(42); err == nil {
+			t.Error("expected the write to fail")
+		}
+		if buf.Len() != 0 {
+			t.Errorf("expected no output, got %q", buf.String())
+		}
+	})
+}
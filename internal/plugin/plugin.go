@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// Result represents the output of a plugin. Tooltip lines and CSS classes returned by the plugin
+// are merged into the module output.
+type Result struct {
+	Tooltip []string `json:"tooltip"`
+	Class   []string `json:"class"`
+}
+
+// Plugin is an external executable that receives the current weather state as JSON on stdin and
+// returns a Result as JSON on stdout.
+type Plugin struct {
+	name    string
+	command string
+	timeout time.Duration
+}
+
+// New returns a new Plugin that runs the given command via /bin/sh.
+func New(name, command string, timeout time.Duration) *Plugin {
+	return &Plugin{
+		name:    name,
+		command: command,
+		timeout: timeout,
+	}
+}
+
+// Name returns the name of the plugin.
+func (p *Plugin) Name() string {
+	return p.name
+}
+
+// Run spawns the plugin, sends the JSON encoded state to its stdin and decodes the Result from
+// its stdout. The plugin is killed if it doesn't finish within the timeout.
+func (p *Plugin) Run(ctx context.Context, state any) (Result, error) {
+	var result Result
+	input, err := json.Marshal(state)
+	if err != nil {
+		return result, fmt.Errorf("failed to encode plugin input: %w", err)
+	}
+
+	ctxRun, cancelRun := context.WithTimeout(ctx, p.timeout)
+	defer cancelRun()
+
+	stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(ctxRun, "/bin/sh", "-c", p.command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err = cmd.Run(); err != nil {
+		return result, fmt.Errorf("failed to run plugin: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return result, nil
+	}
+	if err = json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return result, fmt.Errorf("failed to decode plugin output: %w", err)
+	}
+	return result, nil
+}
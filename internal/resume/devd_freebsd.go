@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package resume
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	// devdSocket is the socket devd(8) publishes the device events on
+	devdSocket = "/var/run/devd.pipe"
+	// devdResumeEvent identifies the notification about the resume of the system
+	devdResumeEvent = "system=ACPI subsystem=Resume"
+
+	reconnectDelay = 5 * time.Second
+)
+
+// New returns the resume monitor of the platform.
+func New(log *logger.Logger) Monitor {
+	return &Devd{logger: log}
+}
+
+// Devd detects a resume by the ACPI notifications of devd(8).
+type Devd struct {
+	logger *logger.Logger
+}
+
+// Name returns the name of the Devd monitor.
+func (d *Devd) Name() string {
+	return "devd"
+}
+
+// Watch reads the events of devd and calls resumed whenever the system woke up. Returns an error if
+// the devd socket is not available, it reconnects if the connection is lost later on.
+func (d *Devd) Watch(ctx context.Context, resumed func()) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", devdSocket)
+	if err != nil {
+		return fmt.Errorf("failed to connect to devd: %w", err)
+	}
+	for {
+		stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "!") && strings.Contains(scanner.Text(), devdResumeEvent) {
+				resumed()
+			}
+		}
+		stop()
+		if err = conn.Close(); err != nil && ctx.Err() == nil {
+			d.logger.Debug("failed to close devd connection", logger.Err(err))
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(reconnectDelay):
+			}
+			if conn, err = dialer.DialContext(ctx, "unix", devdSocket); err == nil {
+				break
+			}
+			d.logger.Debug("failed to reconnect to devd", logger.Err(err))
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package resume
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	dbusInterface   = "org.freedesktop.login1.Manager"
+	dbusWatchMember = "PrepareForSleep"
+
+	signalBufferSize = 8
+
+	busReconnectDelay   = 5 * time.Second
+	reconnectDelay      = 2 * time.Second
+	subscribeRetryDelay = 10 * time.Second
+)
+
+// New returns the resume monitor of the platform.
+func New(log *logger.Logger) Monitor {
+	return &Logind{logger: log}
+}
+
+// Logind detects a resume by the PrepareForSleep signal of systemd-logind (or elogind) on the system
+// D-Bus.
+type Logind struct {
+	logger *logger.Logger
+}
+
+// Name returns the name of the Logind monitor.
+func (l *Logind) Name() string {
+	return "logind"
+}
+
+// Watch subscribes to the PrepareForSleep signal and calls resumed whenever the system woke up. It
+// reconnects to the system bus as needed until the context ends.
+func (l *Logind) Watch(ctx context.Context, resumed func()) error {
+	for {
+		conn := l.connectToSystemBus(ctx)
+		if conn == nil {
+			return nil // the context was cancelled, exit
+		}
+
+		// try to reconnect or exit if we can't if the context was cancelled
+		if !l.setupSleepMonitoring(ctx, conn) {
+			continue
+		}
+
+		sigCh := make(chan *dbus.Signal, signalBufferSize)
+		conn.Signal(sigCh)
+		l.logger.Debug("subscribed to dbus signal", slog.String("interface", dbusInterface),
+			slog.String("member", dbusWatchMember))
+
+		l.handleSleepSignals(ctx, sigCh, resumed)
+
+		// Clean up before reconnect
+		conn.RemoveSignal(sigCh)
+		if err := conn.Close(); err != nil {
+			l.logger.Error("failed to close system bus connection", logger.Err(err))
+		}
+
+		// If we're here because of ctx cancel, exit; otherwise reconnect
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// connectToSystemBus establishes a connection to the system D-Bus with automatic reconnection handling
+// on failure. It continuously retries on connection failures until the provided context is canceled.
+// The caller is responsible for closing the connection.
+func (l *Logind) connectToSystemBus(ctx context.Context) *dbus.Conn {
+	for {
+		conn, err := dbus.ConnectSystemBus()
+		if err != nil {
+			select {
+			case <-time.After(busReconnectDelay):
+				continue
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return conn
+	}
+}
+
+// setupSleepMonitoring configures sleep monitoring by subscribing to specific dbus signals and
+// handles error retries.
+func (l *Logind) setupSleepMonitoring(ctx context.Context, conn *dbus.Conn) bool {
+	if err := conn.AddMatchSignal(dbus.WithMatchInterface(dbusInterface),
+		dbus.WithMatchMember(dbusWatchMember),
+	); err != nil {
+		l.logger.Error("failed to subscribe to dbus signal", slog.String("interface", dbusInterface),
+			slog.String("member", dbusWatchMember), logger.Err(err))
+		if err = conn.Close(); err != nil {
+			l.logger.Error("failed to close system bus connection", logger.Err(err))
+		}
+		select {
+		case <-time.After(subscribeRetryDelay):
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// handleSleepSignals listens for sleep-related signals on the provided signal channel and calls resumed
+// for every signal announcing the wake-up of the system.
+func (l *Logind) handleSleepSignals(ctx context.Context, sigCh chan *dbus.Signal, resumed func()) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sgn, ok := <-sigCh:
+			if !ok {
+				// connection likely closed; try to reconnect
+				return
+			}
+			if len(sgn.Body) != 1 {
+				continue
+			}
+			if sleeping, ok := sgn.Body[0].(bool); ok && !sleeping {
+				resumed()
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package resume detects the resume of the system from sleep. New returns the monitor of the platform,
+// e.g. logind on Linux, while the ClockJump monitor is usable on every platform.
+package resume
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// clockJumpInterval is the interval in which the ClockJump monitor checks the wall clock
+	clockJumpInterval = 10 * time.Second
+	// clockJumpThreshold is the delay of a check above which the ClockJump monitor assumes a resume
+	clockJumpThreshold = 30 * time.Second
+)
+
+// Monitor detects the resume of the system from sleep.
+type Monitor interface {
+	// Name returns the name of the monitor.
+	Name() string
+	// Watch calls resumed on every resume of the system until the context ends. Returns an error if the
+	// monitor is not usable on the system.
+	Watch(ctx context.Context, resumed func()) error
+}
+
+// ClockJump detects a resume by the jump of the wall clock, since the checks are not run while the
+// system sleeps. Setting the system time forward is detected as resume as well.
+type ClockJump struct {
+	interval  time.Duration
+	threshold time.Duration
+}
+
+// NewClockJump returns a new ClockJump monitor.
+func NewClockJump() *ClockJump {
+	return &ClockJump{interval: clockJumpInterval, threshold: clockJumpThreshold}
+}
+
+// Name returns the name of the ClockJump monitor.
+func (c *ClockJump) Name() string {
+	return "clock"
+}
+
+// Watch checks the wall clock in the configured interval and calls resumed if a check is considerably
+// later than expected.
+func (c *ClockJump) Watch(ctx context.Context, resumed func()) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	// Strip the monotonic clock reading, it doesn't advance during sleep on every platform
+	last := time.Now().Round(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			now := time.Now().Round(0)
+			if now.Sub(last) > c.interval+c.threshold {
+				resumed()
+				now = time.Now().Round(0)
+			}
+			last = now
+		}
+	}
+}
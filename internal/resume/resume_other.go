@@ -0,0 +1,17 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build !linux && !freebsd
+
+package resume
+
+import (
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+// New returns the resume monitor of the platform. On macOS, caffeinate can only prevent the sleep but
+// not report the wake-up, so the resume is detected by the jump of the wall clock, as on Windows.
+func New(*logger.Logger) Monitor {
+	return NewClockJump()
+}
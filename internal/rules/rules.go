@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package rules implements a small expression syntax to derive CSS classes from the weather state.
+// A rule has the form "<expression> -> <class>", where the expression consists of comparisons of a
+// state variable with a number (e.g. "temp < 0"), which can be combined with "&&" and "||", negated
+// with "!" and grouped with parentheses. As usual, "!" takes precedence over "&&", which takes
+// precedence over "||".
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Variables lists the state variables available in the rules. Boolean values are represented as 1 and 0.
+var Variables = []string{
+	"temp", "apparent_temp", "humidity", "pressure", "wind_speed", "wind_gust", "wind_direction", "weather_code",
+	"is_day", "precipitation", "forecast_temp", "today_max", "today_min", "sun_elevation",
+}
+
+// State is a snapshot of the weather state the rules are evaluated against.
+type State map[string]float64
+
+// tokenRegex matches the next token of an expression: an operator, a parenthesis, a variable or a number.
+var tokenRegex = regexp.MustCompile(`^\s*(&&|\|\||<=|>=|==|!=|<|>|!|\(|\)|[a-z_]+|-?[0-9]+(?:\.[0-9]+)?)`)
+
+// classRegex matches valid CSS class names.
+var classRegex = regexp.MustCompile(`^-?[_a-zA-Z][_a-zA-Z0-9-]*$`)
+
+// node is a node of the syntax tree of an expression.
+type node interface {
+	eval(state State) bool
+}
+
+type comparison struct {
+	variable string
+	operator string
+	value    float64
+}
+
+// logical combines the operands with "&&" or, if any is true, with "||".
+type logical struct {
+	any      bool
+	operands []node
+}
+
+type negation struct {
+	operand node
+}
+
+// Rule is a parsed class rule.
+type Rule struct {
+	expression node
+	class      string
+}
+
+// Parse parses a class rule.
+func Parse(rule string) (Rule, error) {
+	idx := strings.LastIndex(rule, "->")
+	if idx == -1 {
+		return Rule{}, fmt.Errorf("invalid rule %q: missing class", rule)
+	}
+	class := strings.TrimSpace(rule[idx+2:])
+	if !classRegex.MatchString(class) {
+		return Rule{}, fmt.Errorf("invalid rule %q: invalid class name %q", rule, class)
+	}
+	parsed, err := ParseExpression(rule[:idx])
+	if err != nil {
+		return parsed, fmt.Errorf("invalid rule %q: %w", rule, err)
+	}
+	parsed.class = class
+	return parsed, nil
+}
+
+// ParseExpression parses an expression without class, e.g. the condition of a hook. The returned rule
+// has no class.
+func ParseExpression(expression string) (Rule, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return Rule{}, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return Rule{}, err
+	}
+	if p.pos < len(p.tokens) {
+		return Rule{}, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return Rule{expression: root}, nil
+}
+
+// tokenize splits the expression into its tokens.
+func tokenize(expression string) ([]string, error) {
+	var tokens []string
+	rest := strings.TrimSpace(expression)
+	for rest != "" {
+		matches := tokenRegex.FindStringSubmatch(rest)
+		if matches == nil {
+			return nil, fmt.Errorf("invalid expression %q at %q", strings.TrimSpace(expression), rest)
+		}
+		tokens = append(tokens, matches[1])
+		rest = strings.TrimSpace(rest[len(matches[0]):])
+	}
+	return tokens, nil
+}
+
+// parser is a recursive descent parser of the tokens of an expression.
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+// peek returns the current token, or an empty string at the end of the expression.
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// next returns the current token and advances to the next one.
+func (p *parser) next() string {
+	token := p.peek()
+	if token != "" {
+		p.pos++
+	}
+	return token
+}
+
+// parseOr parses operands combined with "||".
+func (p *parser) parseOr() (node, error) {
+	return p.parseLogical("||", true, p.parseAnd)
+}
+
+// parseAnd parses operands combined with "&&".
+func (p *parser) parseAnd() (node, error) {
+	return p.parseLogical("&&", false, p.parseUnary)
+}
+
+// parseLogical parses operands parsed by operand and combined with operator.
+func (p *parser) parseLogical(operator string, anyOperand bool, operand func() (node, error)) (node, error) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	combined := logical{any: anyOperand, operands: []node{first}}
+	for p.peek() == operator {
+		p.next()
+		next, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		combined.operands = append(combined.operands, next)
+	}
+	if len(combined.operands) == 1 {
+		return first, nil
+	}
+	return combined, nil
+}
+
+// parseUnary parses a negation, a group in parentheses or a comparison.
+func (p *parser) parseUnary() (node, error) {
+	switch p.peek() {
+	case "!":
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negation{operand: operand}, nil
+	case "(":
+		p.next()
+		group, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if token := p.next(); token != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis, got %q", token)
+		}
+		return group, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses a comparison of a variable with a number.
+func (p *parser) parseComparison() (node, error) {
+	variable := p.next()
+	if !slices.Contains(Variables, variable) {
+		if variable == "" {
+			return nil, fmt.Errorf("unexpected end of expression")
+		}
+		return nil, fmt.Errorf("unknown variable %q", variable)
+	}
+	operator := p.next()
+	if !slices.Contains([]string{"<", "<=", ">", ">=", "==", "!="}, operator) {
+		return nil, fmt.Errorf("invalid comparison operator %q after %q", operator, variable)
+	}
+	number := p.next()
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q: %w", number, err)
+	}
+	return comparison{variable: variable, operator: operator, value: value}, nil
+}
+
+// Class returns the CSS class of the rule.
+func (r Rule) Class() string {
+	return r.class
+}
+
+// Matches returns true if the expression of the rule is true for the given state.
+func (r Rule) Matches(state State) bool {
+	return r.expression != nil && r.expression.eval(state)
+}
+
+func (l logical) eval(state State) bool {
+	for _, operand := range l.operands {
+		if operand.eval(state) == l.any {
+			return l.any
+		}
+	}
+	return !l.any
+}
+
+func (n negation) eval(state State) bool {
+	return !n.operand.eval(state)
+}
+
+func (c comparison) eval(state State) bool {
+	value := state[c.variable]
+	switch c.operator {
+	case "<":
+		return value < c.value
+	case "<=":
+		return value <= c.value
+	case ">":
+		return value > c.value
+	case ">=":
+		return value >= c.value
+	case "==":
+		return value == c.value
+	case "!=":
+		return value != c.value
+	}
+	return false
+}
+
+// Classes returns the CSS classes of all rules that match the given state.
+func Classes(rules []Rule, state State) []string {
+	var classes []string
+	for _, rule := range rules {
+		if rule.Matches(state) && !slices.Contains(classes, rule.class) {
+			classes = append(classes, rule.class)
+		}
+	}
+	return classes
+}
@@ -0,0 +1,307 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package satellite predicts the passes of satellites over an observer from their mean orbital elements,
+// as published by CelesTrak in the JSON OMM format. The orbits are propagated with the secular
+// perturbations of the Earth's oblateness and the decay of the orbit instead of the full SGP4 model. For
+// the ISS and elements that are up to three days old, the rise, peak and set times are within 5 seconds of
+// SGP4, except for passes that barely rise above the horizon.
+package satellite
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	// Equatorial radius in km, gravitational parameter in km³/s² and second zonal harmonic of the Earth
+	earthRadius = 6378.137
+	earthMu     = 398600.4418
+	j2          = 1.08262668e-3
+	// flattening of the WGS-84 ellipsoid
+	flattening = 1 / 298.257223563
+	// astronomicalUnit in km
+	astronomicalUnit = 149597870.7
+
+	minutesPerDay = 1440
+	degree        = math.Pi / 180
+
+	// searchStep is the interval of the search for passes, which is short enough not to miss a pass of a
+	// low earth orbit above the minimum elevation
+	searchStep = 30 * time.Second
+	// maxPassDuration limits how long a pass in progress at the end of the search is followed, passes of
+	// the low earth orbits take only a few minutes
+	maxPassDuration = time.Hour
+	// precision of the rise, peak and set times
+	precision = time.Second
+	// darkElevation is the elevation of the sun below which the sky is dark enough to see a satellite
+	darkElevation = -6.0
+
+	// epochLayout is the time format of the epoch of the OMM format, which is given in UTC
+	epochLayout = "2006-01-02T15:04:05.999999"
+)
+
+// Elements represents the mean orbital elements of a satellite in the JSON OMM format of CelesTrak.
+type Elements struct {
+	Name  string `json:"OBJECT_NAME"`
+	ID    int    `json:"NORAD_CAT_ID"`
+	Epoch Epoch  `json:"EPOCH"`
+	// MeanMotion in revolutions per day and half of its first derivative in revolutions per day²
+	MeanMotion    float64 `json:"MEAN_MOTION"`
+	MeanMotionDot float64 `json:"MEAN_MOTION_DOT"`
+	Eccentricity  float64 `json:"ECCENTRICITY"`
+	// Angles in degrees
+	Inclination     float64 `json:"INCLINATION"`
+	AscendingNode   float64 `json:"RA_OF_ASC_NODE"`
+	ArgumentPerigee float64 `json:"ARG_OF_PERICENTER"`
+	MeanAnomaly     float64 `json:"MEAN_ANOMALY"`
+}
+
+// Epoch is the epoch of the orbital elements, which doesn't conform to RFC 3339.
+type Epoch struct {
+	time.Time
+}
+
+func (e Epoch) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + e.UTC().Format(epochLayout) + `"`), nil
+}
+
+func (e *Epoch) UnmarshalJSON(data []byte) error {
+	parsed, err := time.Parse(epochLayout, strings.Trim(string(data), `"`))
+	if err != nil {
+		return fmt.Errorf("failed to parse epoch: %w", err)
+	}
+	e.Time = parsed
+	return nil
+}
+
+// Observer represents the position of the observer. The altitude is given in meters.
+type Observer struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+}
+
+// Pass represents a pass of a satellite above the horizon of the observer. The azimuths are given in
+// degrees clockwise from north.
+type Pass struct {
+	Name         string
+	Rise         time.Time
+	Peak         time.Time
+	Set          time.Time
+	RiseAzimuth  float64
+	SetAzimuth   float64
+	MaxElevation float64
+	// Visible is true if the satellite is lit by the sun while the sky of the observer is dark during
+	// the pass
+	Visible bool
+}
+
+// vector is a position in the Earth-centered inertial frame in km.
+type vector struct {
+	x, y, z float64
+}
+
+func (v vector) sub(other vector) vector {
+	return vector{v.x - other.x, v.y - other.y, v.z - other.z}
+}
+
+func (v vector) dot(other vector) float64 {
+	return v.x*other.x + v.y*other.y + v.z*other.z
+}
+
+func (v vector) length() float64 {
+	return math.Sqrt(v.dot(v))
+}
+
+// Passes returns the passes of the satellite between start and end that reach the minimum elevation in
+// degrees. A pass in progress at start begins at start, a pass in progress at end is followed until it
+// sets, unless it takes longer than an hour.
+func Passes(elements Elements, observer Observer, start, end time.Time, minElevation float64) []Pass {
+	var passes []Pass
+	var current *Pass
+	for at := start; !at.After(end) || current != nil && at.Before(end.Add(maxPassDuration)); at = at.Add(searchStep) {
+		_, elevation := observer.look(elements.position(at), at)
+		switch {
+		case elevation > 0 && current == nil:
+			current = &Pass{Name: elements.Name, Rise: at}
+			if at.After(start) {
+				current.Rise = elements.crossing(observer, at.Add(-searchStep), at)
+			}
+			current.RiseAzimuth, _ = observer.look(elements.position(current.Rise), current.Rise)
+			fallthrough
+		case elevation > 0:
+			if elevation > current.MaxElevation {
+				current.Peak, current.MaxElevation = at, elevation
+			}
+			current.Visible = current.Visible || observer.visible(elements.position(at), at)
+		case current != nil:
+			current.Set = elements.crossing(observer, at, at.Add(-searchStep))
+			current.SetAzimuth, _ = observer.look(elements.position(current.Set), current.Set)
+			current.Peak = elements.peak(observer, current.Peak.Add(-searchStep), current.Peak.Add(searchStep))
+			_, current.MaxElevation = observer.look(elements.position(current.Peak), current.Peak)
+			if current.MaxElevation >= minElevation {
+				passes = append(passes, *current)
+			}
+			current = nil
+		}
+	}
+	return passes
+}
+
+// crossing returns the time between below and above at which the satellite crosses the horizon.
+func (e Elements) crossing(observer Observer, below, above time.Time) time.Time {
+	for below.Sub(above).Abs() > precision {
+		middle := below.Add(above.Sub(below) / 2)
+		if _, elevation := observer.look(e.position(middle), middle); elevation > 0 {
+			above = middle
+		} else {
+			below = middle
+		}
+	}
+	return above
+}
+
+// peak returns the time of the highest elevation of the satellite between start and end by a ternary
+// search.
+func (e Elements) peak(observer Observer, start, end time.Time) time.Time {
+	for end.Sub(start) > precision {
+		third := end.Sub(start) / 3
+		first, second := start.Add(third), end.Add(-third)
+		_, firstElevation := observer.look(e.position(first), first)
+		_, secondElevation := observer.look(e.position(second), second)
+		if firstElevation < secondElevation {
+			start = first
+		} else {
+			end = second
+		}
+	}
+	return start.Add(end.Sub(start) / 2)
+}
+
+// position returns the position of the satellite at the given time. The ascending node and the argument
+// of perigee drift due to the oblateness of the Earth, and the mean motion increases due to the drag.
+func (e Elements) position(at time.Time) vector {
+	minutes := at.Sub(e.Epoch.Time).Minutes()
+	days := minutes / minutesPerDay
+	eccentricity, inclination := e.Eccentricity, e.Inclination*degree
+	sinInclination2 := math.Pow(math.Sin(inclination), 2)
+	root := math.Sqrt(1 - eccentricity*eccentricity)
+
+	// The mean motion of the elements is the Kozai mean motion of SGP4, which includes a part of the
+	// oblateness. It is converted to the Brouwer mean motion like SGP4 does, otherwise the satellite runs
+	// ahead by about 10 seconds per day.
+	meanMotion := e.MeanMotion * 2 * math.Pi / minutesPerDay
+	kozai := math.Cbrt(earthMu/math.Pow(meanMotion/60, 2)) / earthRadius
+	oblateness := 0.75 * j2 * (2 - 3*sinInclination2) / (root * root * root)
+	delta := oblateness / (kozai * kozai)
+	delta = oblateness / math.Pow(kozai*(1-delta*delta-delta*(1.0/3+134*delta*delta/81)), 2)
+	meanMotion /= 1 + delta
+	// Semi-major axis from the mean motion in radians per second
+	semiMajor := math.Cbrt(earthMu / math.Pow(meanMotion/60, 2))
+	drift := 1.5 * j2 * math.Pow(earthRadius/(semiMajor*root*root), 2) * meanMotion
+
+	node := e.AscendingNode*degree - drift*math.Cos(inclination)*minutes
+	perigee := e.ArgumentPerigee*degree + drift*(2-2.5*sinInclination2)*minutes
+	anomaly := e.MeanAnomaly*degree + (meanMotion+drift*root*(1-1.5*sinInclination2))*minutes +
+		2*math.Pi*e.MeanMotionDot*days*days
+
+	// Solve Kepler's equation for the eccentric anomaly
+	eccentric := anomaly
+	for range 10 {
+		eccentric -= (eccentric - eccentricity*math.Sin(eccentric) - anomaly) /
+			(1 - eccentricity*math.Cos(eccentric))
+	}
+	px := semiMajor * (math.Cos(eccentric) - eccentricity)
+	py := semiMajor * root * math.Sin(eccentric)
+
+	cosNode, sinNode := math.Cos(node), math.Sin(node)
+	cosPerigee, sinPerigee := math.Cos(perigee), math.Sin(perigee)
+	cosInclination, sinInclination := math.Cos(inclination), math.Sin(inclination)
+	return vector{
+		x: px*(cosNode*cosPerigee-sinNode*sinPerigee*cosInclination) -
+			py*(cosNode*sinPerigee+sinNode*cosPerigee*cosInclination),
+		y: px*(sinNode*cosPerigee+cosNode*sinPerigee*cosInclination) +
+			py*(cosNode*cosPerigee*cosInclination-sinNode*sinPerigee),
+		z: px*sinPerigee*sinInclination + py*cosPerigee*sinInclination,
+	}
+}
+
+// look returns the azimuth and the elevation in degrees of the target as seen by the observer.
+func (o Observer) look(target vector, at time.Time) (float64, float64) {
+	latitude := o.Latitude * degree
+	sidereal := siderealTime(at) + o.Longitude*degree
+	distance := target.sub(o.position(sidereal))
+	cosLatitude, sinLatitude := math.Cos(latitude), math.Sin(latitude)
+	cosSidereal, sinSidereal := math.Cos(sidereal), math.Sin(sidereal)
+
+	south := sinLatitude*cosSidereal*distance.x + sinLatitude*sinSidereal*distance.y - cosLatitude*distance.z
+	east := -sinSidereal*distance.x + cosSidereal*distance.y
+	zenith := cosLatitude*cosSidereal*distance.x + cosLatitude*sinSidereal*distance.y + sinLatitude*distance.z
+	elevation := math.Asin(zenith / distance.length())
+	azimuth := math.Atan2(east, -south)
+	return math.Mod(azimuth/degree+360, 360), elevation / degree
+}
+
+// position returns the position of the observer at the given local sidereal angle in radians.
+func (o Observer) position(sidereal float64) vector {
+	latitude := o.Latitude * degree
+	eccentricity2 := flattening * (2 - flattening)
+	normal := earthRadius / math.Sqrt(1-eccentricity2*math.Pow(math.Sin(latitude), 2))
+	altitude := o.Altitude / 1000
+	radius := (normal + altitude) * math.Cos(latitude)
+	return vector{
+		x: radius * math.Cos(sidereal),
+		y: radius * math.Sin(sidereal),
+		z: (normal*(1-eccentricity2) + altitude) * math.Sin(latitude),
+	}
+}
+
+// visible returns true if the satellite at the given position is lit by the sun while the sun is far
+// enough below the horizon of the observer.
+func (o Observer) visible(target vector, at time.Time) bool {
+	sun := sunPosition(at)
+	if _, elevation := o.look(sun, at); elevation > darkElevation {
+		return false
+	}
+	// The shadow of the Earth is approximated by a cylinder
+	direction := sun.length()
+	along := target.dot(sun) / direction
+	if along > 0 {
+		return true
+	}
+	perpendicular := math.Sqrt(math.Max(target.dot(target)-along*along, 0))
+	return perpendicular > earthRadius
+}
+
+// siderealTime returns the Greenwich mean sidereal time in radians.
+func siderealTime(at time.Time) float64 {
+	days := julianDate(at) - 2451545
+	centuries := days / 36525
+	angle := 280.46061837 + 360.98564736629*days + 0.000387933*centuries*centuries -
+		centuries*centuries*centuries/38710000
+	return math.Mod(angle, 360) * degree
+}
+
+// sunPosition returns the low-precision position of the sun.
+func sunPosition(at time.Time) vector {
+	days := julianDate(at) - 2451545
+	longitude := 280.460 + 0.9856474*days
+	anomaly := (357.528 + 0.9856003*days) * degree
+	ecliptic := (longitude + 1.915*math.Sin(anomaly) + 0.020*math.Sin(2*anomaly)) * degree
+	obliquity := (23.439 - 0.0000004*days) * degree
+	distance := (1.00014 - 0.01671*math.Cos(anomaly) - 0.00014*math.Cos(2*anomaly)) * astronomicalUnit
+	return vector{
+		x: distance * math.Cos(ecliptic),
+		y: distance * math.Cos(obliquity) * math.Sin(ecliptic),
+		z: distance * math.Sin(obliquity) * math.Sin(ecliptic),
+	}
+}
+
+// julianDate returns the Julian date of the given time.
+func julianDate(at time.Time) float64 {
+	return float64(at.UnixNano())/float64(24*time.Hour) + 2440587.5
+}
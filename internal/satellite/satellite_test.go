@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package satellite
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	// passTolerance is the maximum difference of the rise, peak and set times from SGP4
+	passTolerance = 5 * time.Second
+	// elevationTolerance is the maximum difference of the maximum elevation from SGP4 in degrees
+	elevationTolerance = 1.0
+)
+
+// TestPasses compares the passes of the ISS over Berlin with the passes predicted by the SGP4 model of
+// Vallado et al. ("Revisiting Spacetrack Report #3", 2006) for the same elements, over the three days
+// after their epoch. The SGP4 times are rounded to the second.
+func TestPasses(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "iss.json"))
+	if err != nil {
+		t.Fatalf("failed to read elements: %s", err)
+	}
+	var elements []Elements
+	if err = json.Unmarshal(data, &elements); err != nil {
+		t.Fatalf("failed to decode elements: %s", err)
+	}
+	if len(elements) != 1 || elements[0].Name != "ISS (ZARYA)" {
+		t.Fatalf("expected the elements of the ISS, got %+v", elements)
+	}
+	iss := elements[0]
+	berlin := Observer{Latitude: 52.52, Longitude: 13.405, Altitude: 34}
+
+	want := []struct {
+		rise, peak, set string
+		maxElevation    float64
+	}{
+		{"2019-12-10T10:58:09Z", "2019-12-10T11:03:12Z", "2019-12-10T11:08:18Z", 24.2},
+		{"2019-12-10T12:34:12Z", "2019-12-10T12:39:36Z", "2019-12-10T12:45:01Z", 65.1},
+		{"2019-12-10T14:10:50Z", "2019-12-10T14:16:16Z", "2019-12-10T14:21:42Z", 72.5},
+		{"2019-12-10T15:47:31Z", "2019-12-10T15:52:45Z", "2019-12-10T15:57:59Z", 31.0},
+		{"2019-12-11T10:10:06Z", "2019-12-11T10:14:54Z", "2019-12-11T10:19:45Z", 17.4},
+		{"2019-12-11T11:45:48Z", "2019-12-11T11:51:09Z", "2019-12-11T11:56:33Z", 53.5},
+		{"2019-12-11T13:22:22Z", "2019-12-11T13:27:48Z", "2019-12-11T13:33:15Z", 77.8},
+		{"2019-12-11T14:59:03Z", "2019-12-11T15:04:22Z", "2019-12-11T15:09:42Z", 41.1},
+		{"2019-12-11T16:35:59Z", "2019-12-11T16:40:28Z", "2019-12-11T16:44:57Z", 11.9},
+		{"2019-12-12T09:22:12Z", "2019-12-12T09:26:39Z", "2019-12-12T09:31:08Z", 12.1},
+		{"2019-12-12T10:57:27Z", "2019-12-12T11:02:45Z", "2019-12-12T11:08:05Z", 41.6},
+		{"2019-12-12T12:33:54Z", "2019-12-12T12:39:19Z", "2019-12-12T12:44:46Z", 77.9},
+		{"2019-12-12T14:10:35Z", "2019-12-12T14:15:58Z", "2019-12-12T14:21:21Z", 52.9},
+		{"2019-12-12T15:47:22Z", "2019-12-12T15:52:13Z", "2019-12-12T15:57:03Z", 17.2},
+	}
+
+	start := time.Date(2019, 12, 9, 18, 0, 0, 0, time.UTC)
+	passes := Passes(iss, berlin, start, iss.Epoch.AddDate(0, 0, 3), 10)
+	if len(passes) != len(want) {
+		t.Fatalf("expected %d passes, got %d", len(want), len(passes))
+	}
+	for i, pass := range passes {
+		for _, check := range []struct {
+			name     string
+			got      time.Time
+			expected string
+		}{
+			{"rise", pass.Rise, want[i].rise},
+			{"peak", pass.Peak, want[i].peak},
+			{"set", pass.Set, want[i].set},
+		} {
+			expected, err := time.Parse(time.RFC3339, check.expected)
+			if err != nil {
+				t.Fatalf("failed to parse expected time: %s", err)
+			}
+			if check.got.Sub(expected).Abs() > passTolerance {
+				t.Errorf("pass %d: expected %s at %s, got %s", i, check.name, expected, check.got)
+			}
+		}
+		if math.Abs(pass.MaxElevation-want[i].maxElevation) > elevationTolerance {
+			t.Errorf("pass %d: expected max elevation of %.1f°, got %.1f°", i, want[i].maxElevation,
+				pass.MaxElevation)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/forecast"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+const WeatherAlertClass = "weather-alert"
+
+// alertState holds the weather warnings of the active weather backend.
+type alertState struct {
+	mu     sync.RWMutex
+	alerts []forecast.Alert
+}
+
+// fetchAlerts fetches the weather warnings for the current location, if the active weather backend provides
+// them. If the lookup fails, the previous warnings are kept, so that a warning doesn't disappear because of
+// a temporary error. Must be called with the location and backend locks held.
+func (s *Service) fetchAlerts(ctx context.Context) {
+	provider, ok := s.providers[s.backend].(forecast.AlertProvider)
+	if !ok || !s.backendCapabilities(s.backend).Alerts {
+		s.alerts.mu.Lock()
+		defer s.alerts.mu.Unlock()
+		s.alerts.alerts = nil
+		return
+	}
+	var alerts []forecast.Alert
+	cacheKey := fmt.Sprintf("%s/%.4f,%.4f", s.backend, s.coordinates.Lat, s.coordinates.Lon)
+	if s.alertCache == nil || !s.alertCache.Get(cacheKey, &alerts) {
+		var err error
+		if alerts, err = provider.Alerts(ctx, s.coordinates.Lat, s.coordinates.Lon); err != nil {
+			s.logger.Error("failed to get weather alerts", logger.Err(err), slog.String("backend", s.backend))
+			return
+		}
+		if s.alertCache != nil {
+			if err = s.alertCache.Set(cacheKey, alerts); err != nil {
+				s.logger.Warn("failed to cache weather alerts", logger.Err(err))
+			}
+		}
+	}
+
+	s.alerts.mu.Lock()
+	defer s.alerts.mu.Unlock()
+	s.alerts.alerts = alerts
+}
+
+// weatherAlerts returns the weather warnings that have not expired at the given time in its time zone.
+func (s *Service) weatherAlerts(now time.Time) []template.AlertData {
+	s.alerts.mu.RLock()
+	defer s.alerts.mu.RUnlock()
+	var alerts []template.AlertData
+	for _, alert := range s.alerts.alerts {
+		if !alert.Expires.IsZero() && !alert.Expires.After(now) {
+			continue
+		}
+		data := template.AlertData{Headline: alert.Headline, Severity: alert.Severity}
+		if !alert.Expires.IsZero() {
+			data.Expires = alert.Expires.In(now.Location())
+		}
+		alerts = append(alerts, data)
+	}
+	return alerts
+}
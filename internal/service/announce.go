@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+// announceSyncTag makes the notification servers replace the previous announcement instead of stacking them
+const announceSyncTag = "waybar-weather-announcement"
+
+// announceState keeps track of the last announced weather state, so that every change is announced once.
+type announceState struct {
+	mu                 sync.Mutex
+	category           string
+	precipitationStart time.Time
+	precipitationEnd   time.Time
+}
+
+// announceChanges announces significant weather changes in short, spoken-friendly sentences for users of
+// screen readers: a change of the condition category and the upcoming start or end of precipitation.
+func (s *Service) announceChanges(ctx context.Context, data *template.DisplayData) {
+	if !s.config.Accessibility.Announcements {
+		return
+	}
+
+	s.announcements.mu.Lock()
+	defer s.announcements.mu.Unlock()
+
+	now := time.Now()
+	var messages []string
+	category := s.conditions.Category(data.Current.WeatherCode)
+	if s.announcements.category != "" && s.announcements.category != category {
+		messages = append(messages, s.t.Getf("Weather changed to %s, %.0f%s", data.Current.Condition,
+			template.Round(data.Current.Temperature, 0), data.TempUnit))
+	}
+	s.announcements.category = category
+
+	start := data.PrecipitationStart
+	if start.Expected && !start.Time.Equal(s.announcements.precipitationStart) &&
+		start.Time.Sub(now) <= s.config.Accessibility.LeadTime {
+		s.announcements.precipitationStart = start.Time
+		messages = append(messages, s.t.Getf("%s starting in about %d minutes", start.Condition,
+			minutesUntil(now, start.Time)))
+	}
+	end := data.PrecipitationEnd
+	if end.IsActive && end.EndKnown && !end.Time.Equal(s.announcements.precipitationEnd) &&
+		end.Time.Sub(now) <= s.config.Accessibility.LeadTime {
+		s.announcements.precipitationEnd = end.Time
+		messages = append(messages, s.t.Getf("%s ending in about %d minutes", data.Current.Condition,
+			minutesUntil(now, end.Time)))
+	}
+
+	for _, message := range messages {
+		s.announce(ctx, message, data)
+	}
+}
+
+// announce delivers the message via the configured command or a transient notification.
+func (s *Service) announce(ctx context.Context, message string, data *template.DisplayData) {
+	s.logger.Debug("announcing weather change", slog.String("message", message))
+	if s.config.Accessibility.Command != "" {
+		s.runHook(ctx, "announcement", s.config.Accessibility.Command,
+			append(s.weatherEnv(data), "WAYBAR_WEATHER_ANNOUNCEMENT="+message))
+		return
+	}
+
+	if s.quietHours() {
+		return
+	}
+	hints := notifyHints{
+		"urgency":                         notifyUrgencyLow,
+		"transient":                       true,
+		"x-canonical-private-synchronous": announceSyncTag,
+	}
+	if _, err := sendNotification(0, message, "", notifyDefaultTimeout, hints); err != nil {
+		s.logger.Error("failed to send announcement", logger.Err(err))
+	}
+}
+
+// minutesUntil returns the minutes from now until the given time, rounded to 5 minutes, since the hourly
+// forecast is not more precise anyway.
+func minutesUntil(now, t time.Time) int {
+	minutes := math.Round(t.Sub(now).Minutes()/5) * 5
+	return int(math.Max(minutes, 0))
+}
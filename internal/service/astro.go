@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/nathan-osman/go-sunrise"
+)
+
+const (
+	// astroRetryInterval is the wait for weather data before the next sun transition can be computed
+	astroRetryInterval = time.Minute
+	// astroMaxWait limits the wait for a transition, since the timer doesn't advance while the system is
+	// suspended and the location may change in the meantime
+	astroMaxWait = 15 * time.Minute
+	// astroLookaheadDays is the number of days searched for the next sunrise or sunset
+	astroLookaheadDays = 2
+)
+
+// monitorSunTransitions re-renders the output at sunrise and sunset, so that the day/night icons and the
+// sunrise and sunset hooks switch on time, regardless of the output interval. The next transition is
+// re-computed after every wait, so that a change of the location, the time zone or a DST transition
+// between now and the transition doesn't shift it.
+func (s *Service) monitorSunTransitions(ctx context.Context) {
+	for {
+		wait := astroRetryInterval
+		transition := false
+		if snap, ok := s.snapshot(); ok {
+			now := s.localNow()
+			next := nextSunTransition(snap.weather.Latitude, snap.weather.Longitude, now)
+			if dst := nextOffsetChange(now, next); !dst.IsZero() {
+				s.logger.Debug("offset change of the time zone before the next sun transition",
+					slog.Time("offset_change", dst), slog.Time("transition", next))
+				next = dst
+			}
+			wait = next.Sub(now)
+			transition = wait <= astroMaxWait
+			wait = min(wait, astroMaxWait)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.sunWake:
+			continue
+		case <-time.After(wait):
+		}
+		if transition {
+			s.printWeather(ctx)
+		}
+	}
+}
+
+// nextSunTransition returns the next sunrise or sunset after now at the given coordinates. If the sun
+// doesn't rise or set within the lookahead, e.g. during the polar night, the next local midnight is
+// returned instead, since the day/night state is re-evaluated for every day.
+func nextSunTransition(latitude, longitude float64, now time.Time) time.Time {
+	for offset := range astroLookaheadDays + 1 {
+		day := time.Date(now.Year(), now.Month(), now.Day()+offset, 12, 0, 0, 0, now.Location())
+		sunriseTime, sunsetTime := sunrise.SunriseSunset(latitude, longitude, day.Year(), day.Month(), day.Day())
+		for _, transition := range []time.Time{sunriseTime, sunsetTime} {
+			if !transition.IsZero() && transition.After(now) {
+				return transition.In(now.Location())
+			}
+		}
+	}
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+}
+
+// nextOffsetChange returns the first instant between now and until at which the UTC offset of the time
+// zone of now changes, e.g. a DST transition. Returns the zero time if the offset doesn't change.
+func nextOffsetChange(now, until time.Time) time.Time {
+	_, start := now.Zone()
+	if _, end := until.In(now.Location()).Zone(); end == start {
+		return time.Time{}
+	}
+	low, high := now, until
+	for high.Sub(low) > time.Second {
+		mid := low.Add(high.Sub(low) / 2)
+		if _, offset := mid.In(now.Location()).Zone(); offset == start {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return high
+}
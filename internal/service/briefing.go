@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+
+	"github.com/wneessen/waybar-weather/internal/config"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+// briefingState keeps the day the morning briefing was sent last, so that it is sent at most once a day,
+// even if the job is caught up after a resume.
+type briefingState struct {
+	mu       sync.Mutex
+	lastSent string
+}
+
+// createBriefingJob schedules the morning briefing at the configured time on the configured weekdays.
+func (s *Service) createBriefingJob(ctx context.Context) error {
+	at, err := time.Parse("15:04", s.config.Briefing.Time)
+	if err != nil {
+		return fmt.Errorf("failed to parse briefing time: %w", err)
+	}
+	atTimes := gocron.NewAtTimes(gocron.NewAtTime(uint(at.Hour()), uint(at.Minute()), 0))
+
+	definition := gocron.DailyJob(1, atTimes)
+	if len(s.config.Briefing.Weekdays) > 0 {
+		weekdays := make([]time.Weekday, 0, len(s.config.Briefing.Weekdays))
+		for _, day := range s.config.Briefing.Weekdays {
+			weekdays = append(weekdays, time.Weekday(slices.Index(config.Weekdays, day)))
+		}
+		definition = gocron.WeeklyJob(1, gocron.NewWeekdays(weekdays[0], weekdays[1:]...), atTimes)
+	}
+
+	return s.addJob(ctx, briefingJobName, definition, s.sendBriefing)
+}
+
+// sendBriefing sends a desktop notification with the weather summary of the day, unless it has been sent
+// today already.
+func (s *Service) sendBriefing(context.Context) {
+	s.briefing.mu.Lock()
+	defer s.briefing.mu.Unlock()
+	today := s.localNow().Format(time.DateOnly)
+	if s.briefing.lastSent == today {
+		s.logger.Debug("skipping morning briefing, it has been sent today already")
+		return
+	}
+	if s.quietHours() {
+		s.logger.Debug("skipping morning briefing during quiet hours")
+		return
+	}
+	displayData := new(template.DisplayData)
+	if !s.fillDisplayData(displayData) {
+		s.logger.Warn("no weather data available, skipping morning briefing")
+		return
+	}
+	s.templates.ApplyPrecision(displayData)
+
+	body := bytes.NewBuffer(nil)
+	if err := s.templates.Briefing.Execute(body, displayData); err != nil {
+		s.logger.Error("failed to render briefing template", logger.Err(err))
+		return
+	}
+
+	summary := s.t.Get("Today's weather")
+	if displayData.Address.AddressFound {
+		summary += ": " + displayData.Address.City
+	}
+	if _, err := sendNotification(0, summary, body.String(), notifyDefaultTimeout, nil); err != nil {
+		s.logger.Error("failed to send morning briefing", logger.Err(err))
+		return
+	}
+	s.briefing.lastSent = today
+}
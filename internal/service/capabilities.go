@@ -0,0 +1,31 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"github.com/wneessen/waybar-weather/internal/forecast"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+// backendCapabilities returns the optional data sets of the given weather backend. The Open-Meteo compatible
+// backends provide none of them, since the service requests only the hourly and daily forecast from them.
+func (s *Service) backendCapabilities(backend string) forecast.Capabilities {
+	if provider, ok := s.providers[backend]; ok {
+		return provider.Capabilities()
+	}
+	return forecast.Capabilities{}
+}
+
+// capabilityData returns the optional data sets of the weather backend the weather data of the snapshot
+// has been fetched from, so that the templates show only the sections the backend provides data for.
+func (s *Service) capabilityData(snap *stateSnapshot) template.CapabilityData {
+	capabilities := s.backendCapabilities(snap.weatherSource)
+	return template.CapabilityData{
+		Minutely:   capabilities.Minutely,
+		Alerts:     capabilities.Alerts,
+		AirQuality: capabilities.AirQuality,
+		Marine:     capabilities.Marine,
+	}
+}
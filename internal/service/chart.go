@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"slices"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+// chartLabelStep is the number of bars per hour label of the hourly charts
+const chartLabelStep = 3
+
+// hourlyCharts returns the temperature sparkline and the precipitation bar chart of the configured number
+// of hours, starting with the current hour. The precipitation chart is only available if precipitation
+// is expected within these hours.
+func (s *Service) hourlyCharts(snap *stateSnapshot, nowIdx int, loc *time.Location) (template.ChartData,
+	template.ChartData,
+) {
+	var tempChart, precipChart template.ChartData
+	temperature := snap.weather.HourlyMetrics["temperature_2m"]
+	precipitation := snap.weather.HourlyMetrics["precipitation"]
+	if nowIdx == -1 {
+		return tempChart, precipChart
+	}
+	// The precipitation of an hour is the sum of the preceding hour, so the precipitation during an hour
+	// is found at the index of the following hour
+	end := min(nowIdx+s.config.Templates.ChartHours, len(temperature), len(precipitation)-1)
+	if end <= nowIdx {
+		return tempChart, precipChart
+	}
+
+	times := make([]time.Time, 0, end-nowIdx)
+	for _, hour := range snap.weather.HourlyTimes[nowIdx:end] {
+		times = append(times, hour.In(loc))
+	}
+	labels := template.ChartLabels(times, chartLabelStep)
+
+	temps := temperature[nowIdx:end]
+	tempChart = template.ChartData{
+		Available: true,
+		Min:       slices.Min(temps),
+		Max:       slices.Max(temps),
+		Labels:    labels,
+	}
+	tempChart.Bars = template.Sparkline(temps, tempChart.Min, tempChart.Max, false)
+
+	amounts := precipitation[nowIdx+1 : end+1]
+	threshold := precipitationThreshold[s.precipitationUnit()]
+	precipChart = template.ChartData{
+		Available: slices.ContainsFunc(amounts, func(amount float64) bool { return amount >= threshold }),
+		Min:       0,
+		Max:       slices.Max(amounts),
+		Labels:    labels,
+	}
+	if precipChart.Available {
+		precipChart.Bars = template.Sparkline(amounts, 0, precipChart.Max, true)
+	}
+	return tempChart, precipChart
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	clockSyncPollInterval = 15 * time.Second
+	clockSyncTimeout      = 10 * time.Minute
+)
+
+// waitForClockSync checks if the system clock is synchronized via NTP. Right after boot or resume the
+// clock of some machines is off until NTP has synchronized it, so while the clock is unsynchronized the
+// day/night calculations are based on the time of the weather data instead of the system clock. Since
+// some systems don't use NTP at all, the system clock is trusted again after clockSyncTimeout.
+func (s *Service) waitForClockSync(ctx context.Context) {
+	if !s.clockCheckRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer s.clockCheckRunning.Store(false)
+
+	deadline := time.Now().Add(clockSyncTimeout)
+	for {
+		synced, err := ntpSynchronized()
+		if err != nil {
+			s.logger.Debug("failed to determine clock synchronization state", logger.Err(err))
+			synced = true
+		}
+		if synced || time.Now().After(deadline) {
+			if s.clockUnsynced.Swap(false) {
+				s.logger.Info("system clock is synchronized")
+				if !s.onDemand() {
+					s.printWeather(ctx)
+				}
+			}
+			return
+		}
+		if !s.clockUnsynced.Swap(true) {
+			s.logger.Warn("system clock is not synchronized, displayed times are approximate")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(clockSyncPollInterval):
+		}
+	}
+}
@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import "errors"
+
+// errNoDBus is returned by the desktop services on FreeBSD, where the D-Bus library requires cgo and is
+// therefore not part of the build.
+var errNoDBus = errors.New("D-Bus is not supported on FreeBSD")
+
+// sendNotification is not supported on FreeBSD.
+func sendNotification(uint32, string, string, int32, notifyHints) (uint32, error) {
+	return 0, errNoDBus
+}
+
+// ntpSynchronized is not supported on FreeBSD.
+func ntpSynchronized() (bool, error) {
+	return false, errNoDBus
+}
+
+// subscribeTimezoneChanges is not supported on FreeBSD, the time zone is checked periodically instead.
+func subscribeTimezoneChanges() (<-chan struct{}, func() error, error) {
+	return nil, nil, errNoDBus
+}
+
+// networkOnline is not supported on FreeBSD.
+func (s *Service) networkOnline() (bool, error) {
+	return false, errNoDBus
+}
+
+// connectedSSID is not supported on FreeBSD.
+func (s *Service) connectedSSID() (string, error) {
+	return "", errNoDBus
+}
@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build !freebsd
+
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	notifyService   = "org.freedesktop.Notifications"
+	notifyPath      = "/org/freedesktop/Notifications"
+	notifyInterface = "org.freedesktop.Notifications"
+	notifyAppName   = "waybar-weather"
+
+	timedateService     = "org.freedesktop.timedate1"
+	timedatePath        = "/org/freedesktop/timedate1"
+	timedateInterface   = "org.freedesktop.timedate1"
+	ntpSyncProperty     = "NTPSynchronized"
+	propertiesInterface = "org.freedesktop.DBus.Properties"
+	propertiesSignal    = "PropertiesChanged"
+	signalBufferSize    = 8
+
+	nmService          = "org.freedesktop.NetworkManager"
+	nmPath             = "/org/freedesktop/NetworkManager"
+	nmInterface        = "org.freedesktop.NetworkManager"
+	nmActiveConnection = "org.freedesktop.NetworkManager.Connection.Active"
+	nmAccessPoint      = "org.freedesktop.NetworkManager.AccessPoint"
+	nmWirelessConnType = "802-11-wireless"
+)
+
+// sendNotification sends a desktop notification via the notification server on the session bus. If
+// replacesID is not 0, the notification with that ID is updated in place. Returns the ID of the
+// notification.
+func sendNotification(replacesID uint32, summary, body string, timeout int32, hints notifyHints) (uint32, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to session bus: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	variants := make(map[string]dbus.Variant, len(hints))
+	for key, value := range hints {
+		variants[key] = dbus.MakeVariant(value)
+	}
+	var id uint32
+	obj := conn.Object(notifyService, notifyPath)
+	if err = obj.Call(notifyInterface+".Notify", 0, notifyAppName, replacesID, "", summary, body,
+		[]string{}, variants, timeout).Store(&id); err != nil {
+		return 0, fmt.Errorf("failed to send notification: %w", err)
+	}
+	return id, nil
+}
+
+// ntpSynchronized returns the NTP synchronization state reported by systemd-timedated.
+func ntpSynchronized() (bool, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to system bus: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	value, err := conn.Object(timedateService, timedatePath).GetProperty(timedateInterface + "." + ntpSyncProperty)
+	if err != nil {
+		return false, fmt.Errorf("failed to read NTP synchronization state: %w", err)
+	}
+	var synced bool
+	if err = value.Store(&synced); err != nil {
+		return false, fmt.Errorf("failed to parse NTP synchronization state: %w", err)
+	}
+	return synced, nil
+}
+
+// subscribeTimezoneChanges subscribes to the property changes of systemd-timedated. The returned channel
+// receives a value for every change, until the returned function closes the subscription.
+func subscribeTimezoneChanges() (<-chan struct{}, func() error, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to system bus: %w", err)
+	}
+	if err = conn.AddMatchSignal(dbus.WithMatchObjectPath(timedatePath),
+		dbus.WithMatchInterface(propertiesInterface), dbus.WithMatchMember(propertiesSignal),
+		dbus.WithMatchArg(0, timedateInterface)); err != nil {
+		return nil, nil, errors.Join(fmt.Errorf("failed to subscribe to time zone changes: %w", err), conn.Close())
+	}
+
+	// The signal channel is closed with the connection, which ends the forwarding
+	sigCh := make(chan *dbus.Signal, signalBufferSize)
+	conn.Signal(sigCh)
+	changes := make(chan struct{}, 1)
+	go func() {
+		for range sigCh {
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return changes, conn.Close, nil
+}
+
+// networkOnline returns true if NetworkManager reports full connectivity.
+func (s *Service) networkOnline() (bool, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to system bus: %w", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			s.logger.Error("failed to close system bus connection", logger.Err(err))
+		}
+	}()
+
+	var connectivity uint32
+	if err = getProperty(conn, nmPath, nmInterface, "Connectivity", &connectivity); err != nil {
+		return false, err
+	}
+	return connectivity == nmConnectivityFull, nil
+}
+
+// connectedSSID returns the SSID of the Wi-Fi network of the primary NetworkManager connection.
+// Returns an empty string if the primary connection is not a Wi-Fi connection.
+func (s *Service) connectedSSID() (string, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to system bus: %w", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			s.logger.Error("failed to close system bus connection", logger.Err(err))
+		}
+	}()
+
+	var primary dbus.ObjectPath
+	if err = getProperty(conn, nmPath, nmInterface, "PrimaryConnection", &primary); err != nil {
+		return "", err
+	}
+	if primary == "/" {
+		return "", nil
+	}
+
+	var connType string
+	if err = getProperty(conn, primary, nmActiveConnection, "Type", &connType); err != nil {
+		return "", err
+	}
+	if connType != nmWirelessConnType {
+		return "", nil
+	}
+
+	var accessPoint dbus.ObjectPath
+	if err = getProperty(conn, primary, nmActiveConnection, "SpecificObject", &accessPoint); err != nil {
+		return "", err
+	}
+	if accessPoint == "/" {
+		return "", errors.New("no access point for the active Wi-Fi connection")
+	}
+	var ssid []byte
+	if err = getProperty(conn, accessPoint, nmAccessPoint, "Ssid", &ssid); err != nil {
+		return "", err
+	}
+	return string(ssid), nil
+}
+
+// getProperty reads a NetworkManager D-Bus property into target.
+func getProperty(conn *dbus.Conn, path dbus.ObjectPath, iface, property string, target any) error {
+	value, err := conn.Object(nmService, path).GetProperty(iface + "." + property)
+	if err != nil {
+		return fmt.Errorf("failed to read NetworkManager property %s: %w", property, err)
+	}
+	if err = value.Store(target); err != nil {
+		return fmt.Errorf("failed to parse NetworkManager property %s: %w", property, err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"math"
+
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+// Indicators for the thermostat setpoints
+const (
+	heatingIndicator     = "🔥"
+	coolingIndicator     = "❄️"
+	openWindowsIndicator = "🪟"
+)
+
+// degreeDays returns the heating and cooling degree days of today and yesterday, based on the configured
+// thermostat setpoints, and whether the current temperature is outside of them. If the current temperature
+// is between both setpoints, it's a good time to open the windows. The degree days are derived from the
+// mean of the daily maximum and minimum temperatures.
+func (s *Service) degreeDays(snap *stateSnapshot, data *template.DisplayData) template.DegreeDayData {
+	heating, cooling := s.config.Weather.HeatingSetpoint, s.config.Weather.CoolingSetpoint
+	if heating == 0 && cooling == 0 {
+		return template.DegreeDayData{}
+	}
+
+	result := template.DegreeDayData{
+		Available:       true,
+		HeatingSetpoint: heating,
+		CoolingSetpoint: cooling,
+	}
+	yesterday := s.dailyData(snap, data.LocalTime.AddDate(0, 0, -1))
+	if heating != 0 {
+		result.HeatingToday = heatingDegreeDays(data.Today, heating)
+		result.HeatingYesterday = heatingDegreeDays(yesterday, heating)
+		result.NeedsHeating = data.Current.Temperature < heating
+	}
+	if cooling != 0 {
+		result.CoolingToday = coolingDegreeDays(data.Today, cooling)
+		result.CoolingYesterday = coolingDegreeDays(yesterday, cooling)
+		result.NeedsCooling = data.Current.Temperature > cooling
+	}
+	result.OpenWindows = heating != 0 && cooling != 0 && !result.NeedsHeating && !result.NeedsCooling
+
+	switch {
+	case result.NeedsHeating:
+		result.Indicator = heatingIndicator
+	case result.NeedsCooling:
+		result.Indicator = coolingIndicator
+	case result.OpenWindows:
+		result.Indicator = openWindowsIndicator
+	}
+	return result
+}
+
+// heatingDegreeDays returns the heating degree days of the day for the given base temperature.
+func heatingDegreeDays(day template.DailyData, base float64) float64 {
+	if !day.Available {
+		return 0
+	}
+	return math.Max(0, base-(day.TemperatureMax+day.TemperatureMin)/2)
+}
+
+// coolingDegreeDays returns the cooling degree days of the day for the given base temperature.
+func coolingDegreeDays(day template.DailyData, base float64) float64 {
+	if !day.Available {
+		return 0
+	}
+	return math.Max(0, (day.TemperatureMax+day.TemperatureMin)/2-base)
+}
@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+const (
+	// DeltaAlertClass is the CSS class emitted while a delta alert is active
+	DeltaAlertClass = "delta-alert"
+
+	// Kinds of delta alerts, also used as CSS class
+	deltaTemperatureDrop = "temperature-drop"
+	deltaTemperatureRise = "temperature-rise"
+	deltaWindIncrease    = "wind-increase"
+	deltaPressureFall    = "pressure-fall"
+)
+
+// deltaState keeps track of the active delta alerts, so that a notification is only sent when an alert is
+// raised.
+type deltaState struct {
+	mu     sync.Mutex
+	active map[string]bool
+}
+
+// deltaAlerts compares the current conditions with the forecast at the end of the configured window and
+// returns an alert for every change that exceeds its threshold, based on the given state snapshot.
+func (s *Service) deltaAlerts(snap *stateSnapshot, nowIdx int, now time.Time) []template.DeltaAlertData {
+	conf := s.config.DeltaAlerts
+	hours := int(conf.Window.Hours())
+	laterIdx := nowIdx + hours
+	if nowIdx == -1 || laterIdx >= len(snap.weather.HourlyTimes) {
+		return nil
+	}
+	later := now.Add(time.Duration(hours) * time.Hour).Truncate(time.Hour)
+	metric := func(name string) float64 {
+		return snap.weather.HourlyMetrics[name][laterIdx] - snap.weather.HourlyMetrics[name][nowIdx]
+	}
+	pressure := "pressure_msl"
+	if s.config.Pressure == "surface" {
+		pressure = "surface_pressure"
+	}
+	pressureUnit := pressureUnits[s.config.PressureUnit]
+	tempUnit := snap.weather.HourlyUnits["temperature_2m"]
+
+	var alerts []template.DeltaAlertData
+	add := func(kind string, change float64, message string) {
+		alerts = append(alerts, template.DeltaAlertData{Kind: kind, Change: change, Time: later, Message: message})
+	}
+	temperature := metric("temperature_2m")
+	if conf.TemperatureDrop > 0 && -temperature >= conf.TemperatureDrop {
+		add(deltaTemperatureDrop, -temperature, s.t.Getf("Temperature will drop %.0f%s within %dh",
+			-temperature, tempUnit, hours))
+	}
+	if conf.TemperatureRise > 0 && temperature >= conf.TemperatureRise {
+		add(deltaTemperatureRise, temperature, s.t.Getf("Temperature will rise %.0f%s within %dh",
+			temperature, tempUnit, hours))
+	}
+	wind := s.windSpeed(snap.weather.HourlyMetrics["wind_speed_10m"][laterIdx]) -
+		s.windSpeed(snap.weather.HourlyMetrics["wind_speed_10m"][nowIdx])
+	if conf.WindIncrease > 0 && wind >= conf.WindIncrease {
+		add(deltaWindIncrease, wind, s.t.Getf("Wind will pick up %.0f %s within %dh", wind, s.windSpeedUnit(),
+			hours))
+	}
+	fall := -metric(pressure) * pressureUnit.perHectopascal
+	if conf.PressureFall > 0 && fall >= conf.PressureFall {
+		add(deltaPressureFall, fall, s.t.Getf("Pressure will fall %s %s within %dh",
+			s.templates.NumberFormat(fall, int(math.Max(float64(pressureUnit.precision), 1))), pressureUnit.symbol,
+			hours))
+	}
+	return alerts
+}
+
+// notifyDeltaAlerts sends a notification for every delta alert that has been raised since the last update,
+// if enabled.
+func (s *Service) notifyDeltaAlerts(data *template.DisplayData) {
+	s.deltas.mu.Lock()
+	defer s.deltas.mu.Unlock()
+
+	active := make(map[string]bool, len(data.DeltaAlerts))
+	for _, alert := range data.DeltaAlerts {
+		active[alert.Kind] = true
+		if s.deltas.active[alert.Kind] || !s.config.DeltaAlerts.Notify || s.quietHours() {
+			continue
+		}
+		s.logger.Debug("delta alert raised", slog.String("kind", alert.Kind), slog.Float64("change", alert.Change))
+		hints := notifyHints{"x-canonical-private-synchronous": alert.Kind}
+		if _, err := sendNotification(0, alert.Message, "", notifyDefaultTimeout, hints); err != nil {
+			s.logger.Error("failed to send delta alert notification", logger.Err(err))
+		}
+	}
+	s.deltas.active = active
+}
+
+// deltaAlertClasses returns the CSS classes of the active delta alerts.
+func deltaAlertClasses(alerts []template.DeltaAlertData) []string {
+	if len(alerts) == 0 {
+		return nil
+	}
+	classes := []string{DeltaAlertClass}
+	for _, alert := range alerts {
+		classes = append(classes, alert.Kind)
+	}
+	return classes
+}
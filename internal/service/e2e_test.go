@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+
+package service
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// e2eTimeout is the time the service has to react to an event in the end-to-end tests.
+const e2eTimeout = 30 * time.Second
+
+// channelWriter sends every output of the service to a channel.
+type channelWriter chan Output
+
+func (c channelWriter) Write(v any) error {
+	if out, ok := v.(Output); ok {
+		c <- out
+	}
+	return nil
+}
+
+// TestService_EndToEnd runs the service against a private D-Bus with fake GeoClue, logind and notification
+// services and fake Open-Meteo and Nominatim APIs.
+func TestService_EndToEnd(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping end-to-end test in short mode")
+	}
+	address := startTestBus(t)
+	desktop := startFakeDesktop(t, address, 52.52, 13.405, 10)
+	apis := startFakeAPIs(t, 61)
+
+	conf := newHarnessConfig(t, apis)
+	conf.WeatherCard.Enabled = true
+	serv := newTestService(t, conf)
+	outputs := make(channelWriter, 64)
+	serv.SetOutput(outputs)
+
+	runTestService(t, serv)
+
+	t.Run("the weather of the GeoClue location is written", func(t *testing.T) {
+		out := waitForOutput(t, outputs, func(out Output) bool { return strings.Contains(out.Tooltip, "Berlin") })
+		if !strings.Contains(out.Text, "21.4") {
+			t.Errorf("expected the current temperature in the text, got %q", out.Text)
+		}
+		if !strings.Contains(out.Tooltip, "Slight rain") {
+			t.Errorf("expected the weather condition in the tooltip, got %q", out.Tooltip)
+		}
+		serv.locationLock.RLock()
+		source := serv.geoSource
+		serv.locationLock.RUnlock()
+		if source != "geoclue" {
+			t.Errorf("expected the location from GeoClue, got %q", source)
+		}
+	})
+	t.Run("the weather card is sent as notification", func(t *testing.T) {
+		select {
+		case card := <-desktop.notified:
+			if !strings.Contains(card.Summary, "21.4") {
+				t.Errorf("expected the current temperature in the summary, got %q", card.Summary)
+			}
+		case <-time.After(e2eTimeout):
+			t.Fatal("no weather card notification received")
+		}
+	})
+	t.Run("the weather is updated after resume", func(t *testing.T) {
+		forecasts := apis.forecasts.Load()
+		desktop.resume(t)
+		deadline := time.Now().Add(e2eTimeout)
+		for apis.forecasts.Load() == forecasts {
+			if time.Now().After(deadline) {
+				t.Fatal("the weather has not been updated after resume")
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	})
+}
+
+// TestService_OnDemand checks that in on-demand mode, the output is only written on request via the output
+// signal, but not after the weather or location updates.
+func TestService_OnDemand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping end-to-end test in short mode")
+	}
+	sig, ok := realtimeSignal(8)
+	if !ok {
+		t.Skip("real-time signals are not supported on this platform")
+	}
+	address := startTestBus(t)
+	desktop := startFakeDesktop(t, address, 52.52, 13.405, 10)
+	apis := startFakeAPIs(t, 0)
+
+	conf := newHarnessConfig(t, apis)
+	conf.Intervals.OutputSignal = 8
+	serv := newTestService(t, conf)
+	outputs := make(channelWriter, 64)
+	serv.SetOutput(outputs)
+	runTestService(t, serv)
+
+	t.Run("weather and location updates are not written", func(t *testing.T) {
+		waitForWeather(t, serv)
+		desktop.move(t, 48.137, 11.575)
+		desktop.resume(t)
+		select {
+		case out := <-outputs:
+			t.Fatalf("unexpected output without request: %q", out.Text)
+		case <-time.After(time.Second):
+		}
+	})
+	t.Run("the output is written on request", func(t *testing.T) {
+		process, err := os.FindProcess(os.Getpid())
+		if err != nil {
+			t.Fatalf("failed to find own process: %s", err)
+		}
+		if err = process.Signal(sig); err != nil {
+			t.Fatalf("failed to send output signal: %s", err)
+		}
+		out := waitForOutput(t, outputs, func(Output) bool { return true })
+		if !strings.Contains(out.Text, "21.4") {
+			t.Errorf("expected the current temperature in the text, got %q", out.Text)
+		}
+	})
+}
+
+// runTestService runs the service until the end of the test.
+func runTestService(t *testing.T, serv *Service) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serv.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("service failed: %s", err)
+			}
+		case <-time.After(e2eTimeout):
+			t.Error("service did not stop")
+		}
+	})
+}
+
+// waitForWeather waits until the service has fetched the weather.
+func waitForWeather(t *testing.T, serv *Service) {
+	t.Helper()
+	deadline := time.Now().Add(e2eTimeout)
+	for {
+		serv.weatherLock.RLock()
+		isSet := serv.weatherIsSet
+		serv.weatherLock.RUnlock()
+		if isSet {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the weather has not been fetched")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// waitForOutput returns the first output of the service that matches.
+func waitForOutput(t *testing.T, outputs <-chan Output, matches func(Output) bool) Output {
+	t.Helper()
+	timeout := time.After(e2eTimeout)
+	for {
+		select {
+		case out := <-outputs:
+			if matches(out) {
+				return out
+			}
+		case <-timeout:
+			t.Fatal("no matching output received")
+			return Output{}
+		}
+	}
+}
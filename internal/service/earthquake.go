@@ -0,0 +1,208 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+const (
+	// usgsURL and emscURL are the FDSN event endpoints of the earthquake feeds
+	usgsURL = "https://earthquake.usgs.gov/fdsnws/event/1/query"
+	emscURL = "https://www.seismicportal.eu/fdsnws/event/1/query"
+
+	earthquakeJobName = "earthquake_update_job"
+	// kmPerDegree is the length of a degree of latitude, the EMSC feed expects the radius in degrees
+	kmPerDegree = 111.195
+	// earthquakeNotifyTag makes the notification servers replace the previous earthquake notification
+	earthquakeNotifyTag = "earthquake"
+)
+
+// earthquakeState holds the recent earthquakes around the current location and the IDs of the earthquakes
+// that have been notified.
+type earthquakeState struct {
+	mu       sync.RWMutex
+	quakes   []template.EarthquakeData
+	notified map[string]bool
+}
+
+// usgsResponse represents the GeoJSON response of the USGS feed. The time is given in milliseconds since
+// the epoch.
+type usgsResponse struct {
+	Features []struct {
+		ID         string `json:"id"`
+		Properties struct {
+			Mag   float64 `json:"mag"`
+			Place string  `json:"place"`
+			Time  int64   `json:"time"`
+			URL   string  `json:"url"`
+		} `json:"properties"`
+		Geometry struct {
+			// Longitude, latitude and depth
+			Coordinates []float64 `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"features"`
+}
+
+// emscResponse represents the GeoJSON response of the EMSC feed.
+type emscResponse struct {
+	Features []struct {
+		ID         string `json:"id"`
+		Properties struct {
+			Mag         float64   `json:"mag"`
+			FlynnRegion string    `json:"flynn_region"`
+			Time        time.Time `json:"time"`
+			Lat         float64   `json:"lat"`
+			Lon         float64   `json:"lon"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+// createEarthquakeJob schedules the earthquake feed monitor, starting immediately.
+func (s *Service) createEarthquakeJob(ctx context.Context) error {
+	return s.addJob(ctx, earthquakeJobName, gocron.DurationJob(s.config.Earthquakes.Interval), s.fetchEarthquakes,
+		gocron.WithStartAt(gocron.WithStartImmediately()))
+}
+
+// fetchEarthquakes fetches the recent earthquakes within the configured radius of the current location and
+// sends a notification for every earthquake that has not been notified yet. Earthquakes that occurred
+// before the service started are not notified. On failure, the previous earthquakes are kept.
+func (s *Service) fetchEarthquakes(ctx context.Context) {
+	s.locationLock.RLock()
+	position, isSet := s.coordinates, s.locationIsSet
+	s.locationLock.RUnlock()
+	if !isSet {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, FetchTimeout)
+	defer cancel()
+	quakes, err := s.earthquakeFeed(ctx, position)
+	if err != nil {
+		s.logger.Error("failed to get earthquakes", logger.Err(err),
+			slog.String("source", s.config.Earthquakes.Source))
+		return
+	}
+
+	s.quakes.mu.Lock()
+	defer s.quakes.mu.Unlock()
+	s.quakes.quakes = quakes
+	if s.quakes.notified == nil {
+		s.quakes.notified = make(map[string]bool)
+	}
+	for _, quake := range quakes {
+		if s.quakes.notified[quake.ID] || quake.Time.Before(s.started) {
+			continue
+		}
+		s.quakes.notified[quake.ID] = true
+		if s.quietHours() {
+			continue
+		}
+		s.logger.Debug("earthquake nearby", slog.String("id", quake.ID), slog.Float64("magnitude", quake.Magnitude))
+		summary := s.t.Getf("Earthquake of magnitude %.1f, %.0f %s away", quake.Magnitude, quake.Distance,
+			quake.DistanceUnit)
+		body := fmt.Sprintf("%s\n%s", quake.Place, quake.Time.In(s.localNow().Location()).Format("15:04"))
+		hints := notifyHints{"x-canonical-private-synchronous": earthquakeNotifyTag}
+		if _, err = sendNotification(0, summary, body, notifyDefaultTimeout, hints); err != nil {
+			s.logger.Error("failed to send earthquake notification", logger.Err(err))
+		}
+	}
+}
+
+// earthquakeFeed requests the earthquakes of the configured period above the configured magnitude within the
+// configured radius of the position, sorted by time with the latest first.
+func (s *Service) earthquakeFeed(ctx context.Context, position geobus.Coordinate) ([]template.EarthquakeData, error) {
+	conf := s.config.Earthquakes
+	start := s.clock().Add(-conf.MaxAge).UTC().Format("2006-01-02T15:04:05")
+	client := newHTTPClient(s.config, s.logger, s.budget)
+
+	var quakes []template.EarthquakeData
+	switch conf.Source {
+	case "usgs":
+		query := url.Values{
+			"format":       {"geojson"},
+			"latitude":     {fmt.Sprintf("%f", position.Lat)},
+			"longitude":    {fmt.Sprintf("%f", position.Lon)},
+			"maxradiuskm":  {fmt.Sprintf("%f", conf.Radius)},
+			"minmagnitude": {fmt.Sprintf("%f", conf.MinMagnitude)},
+			"starttime":    {start},
+		}
+		var response usgsResponse
+		if _, err := client.Get(ctx, usgsURL+"?"+query.Encode(), &response, nil); err != nil {
+			return nil, fmt.Errorf("failed to request USGS earthquakes: %w", err)
+		}
+		for _, feature := range response.Features {
+			if len(feature.Geometry.Coordinates) < 2 {
+				continue
+			}
+			quakes = append(quakes, template.EarthquakeData{
+				ID:        feature.ID,
+				Magnitude: feature.Properties.Mag,
+				Place:     feature.Properties.Place,
+				Time:      time.UnixMilli(feature.Properties.Time),
+				Latitude:  feature.Geometry.Coordinates[1],
+				Longitude: feature.Geometry.Coordinates[0],
+				URL:       feature.Properties.URL,
+			})
+		}
+	case "emsc":
+		query := url.Values{
+			"format":    {"json"},
+			"lat":       {fmt.Sprintf("%f", position.Lat)},
+			"lon":       {fmt.Sprintf("%f", position.Lon)},
+			"maxradius": {fmt.Sprintf("%f", conf.Radius/kmPerDegree)},
+			"minmag":    {fmt.Sprintf("%f", conf.MinMagnitude)},
+			"start":     {start},
+		}
+		var response emscResponse
+		status, err := client.Get(ctx, emscURL+"?"+query.Encode(), &response, nil)
+		// The feed responds with 204 No Content if there are no earthquakes
+		if err != nil && status != 204 {
+			return nil, fmt.Errorf("failed to request EMSC earthquakes: %w", err)
+		}
+		for _, feature := range response.Features {
+			quakes = append(quakes, template.EarthquakeData{
+				ID:        feature.ID,
+				Magnitude: feature.Properties.Mag,
+				Place:     feature.Properties.FlynnRegion,
+				Time:      feature.Properties.Time,
+				Latitude:  feature.Properties.Lat,
+				Longitude: feature.Properties.Lon,
+				URL:       "https://www.seismicportal.eu/eventdetails.html?unid=" + url.QueryEscape(feature.ID),
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported earthquake source: %s", conf.Source)
+	}
+
+	for i := range quakes {
+		meters := position.DistanceTo(geobus.Coordinate{Lat: quakes[i].Latitude, Lon: quakes[i].Longitude})
+		quakes[i].Distance, quakes[i].DistanceUnit = meters/1000, "km"
+		if s.config.Units == "imperial" {
+			quakes[i].Distance, quakes[i].DistanceUnit = meters/metersPerMile, "mi"
+		}
+	}
+	slices.SortFunc(quakes, func(a, b template.EarthquakeData) int { return b.Time.Compare(a.Time) })
+	return quakes, nil
+}
+
+// earthquakeData returns the recent earthquakes around the current location.
+func (s *Service) earthquakeData() []template.EarthquakeData {
+	s.quakes.mu.RLock()
+	defer s.quakes.mu.RUnlock()
+	return slices.Clone(s.quakes.quakes)
+}
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+// ensembleURL is the endpoint of the Open-Meteo Ensemble API
+const ensembleURL = "https://ensemble-api.open-meteo.com/v1/ensemble"
+
+// ensembleState holds the uncertainty range of tomorrow's temperatures.
+type ensembleState struct {
+	mu   sync.RWMutex
+	data template.EnsembleData
+}
+
+// ensembleResponse represents the hourly temperatures of all ensemble members. The temperatures of the
+// members are keyed by temperature_2m_memberNN, the control run by temperature_2m.
+type ensembleResponse struct {
+	UTCOffsetSeconds int                        `json:"utc_offset_seconds"`
+	Hourly           map[string]json.RawMessage `json:"hourly"`
+}
+
+// fetchEnsemble fetches the ensemble forecast of the current location and computes the likely range of
+// tomorrow's high and low from the members. The caller is expected to hold the location lock.
+func (s *Service) fetchEnsemble(ctx context.Context) {
+	if !s.config.Ensemble.Enabled {
+		return
+	}
+	data, err := s.ensembleForecast(ctx)
+	if err != nil {
+		s.logger.Error("failed to get ensemble forecast", logger.Err(err))
+	}
+
+	s.ensemble.mu.Lock()
+	defer s.ensemble.mu.Unlock()
+	s.ensemble.data = data
+}
+
+// ensembleForecast requests the hourly temperatures of the ensemble members and returns the range between
+// the configured percentiles of the members' highs and lows of tomorrow.
+func (s *Service) ensembleForecast(ctx context.Context) (template.EnsembleData, error) {
+	query := url.Values{
+		"latitude":      {fmt.Sprintf("%f", s.coordinates.Lat)},
+		"longitude":     {fmt.Sprintf("%f", s.coordinates.Lon)},
+		"hourly":        {"temperature_2m"},
+		"models":        {s.config.Ensemble.Model},
+		"timezone":      {"auto"},
+		"forecast_days": {"3"},
+	}
+	if s.config.Units == "imperial" {
+		query.Set("temperature_unit", "fahrenheit")
+	}
+	var response ensembleResponse
+	client := newHTTPClient(s.config, s.logger, s.budget)
+	if _, err := client.Get(ctx, ensembleURL+"?"+query.Encode(), &response, nil); err != nil {
+		return template.EnsembleData{}, fmt.Errorf("failed to request ensemble forecast: %w", err)
+	}
+
+	var times []string
+	if err := json.Unmarshal(response.Hourly["time"], &times); err != nil {
+		return template.EnsembleData{}, fmt.Errorf("failed to parse ensemble forecast times: %w", err)
+	}
+	zone := time.FixedZone("", response.UTCOffsetSeconds)
+	tomorrow := s.clock().In(zone).AddDate(0, 0, 1).Format(time.DateOnly)
+
+	var highs, lows []float64
+	for key, raw := range response.Hourly {
+		if key != "temperature_2m" && !strings.HasPrefix(key, "temperature_2m_member") {
+			continue
+		}
+		var temperatures []*float64
+		if err := json.Unmarshal(raw, &temperatures); err != nil {
+			return template.EnsembleData{}, fmt.Errorf("failed to parse ensemble member %s: %w", key, err)
+		}
+		high, low, ok := dayExtremes(times, temperatures, tomorrow)
+		if !ok {
+			continue
+		}
+		highs = append(highs, high)
+		lows = append(lows, low)
+	}
+	if len(highs) < 2 {
+		return template.EnsembleData{}, fmt.Errorf("ensemble forecast has too few members for %s", tomorrow)
+	}
+
+	lower, upper := s.config.Ensemble.LowerPercentile, s.config.Ensemble.UpperPercentile
+	return template.EnsembleData{
+		Available:          true,
+		Members:            len(highs),
+		TemperatureMaxLow:  percentile(highs, lower),
+		TemperatureMaxHigh: percentile(highs, upper),
+		TemperatureMinLow:  percentile(lows, lower),
+		TemperatureMinHigh: percentile(lows, upper),
+	}, nil
+}
+
+// dayExtremes returns the highest and lowest temperature of the given day (YYYY-MM-DD). Returns false if
+// a member has no complete data for the day, i.e. a value is missing or the hours don't span the day from
+// midnight to the last hour. The number of hours isn't checked, since a day with a daylight saving time
+// change has 23 or 25 hours.
+func dayExtremes(times []string, temperatures []*float64, day string) (float64, float64, bool) {
+	high, low := math.Inf(-1), math.Inf(1)
+	var first, last string
+	for i, hour := range times {
+		if !strings.HasPrefix(hour, day) {
+			continue
+		}
+		if i >= len(temperatures) || temperatures[i] == nil {
+			return 0, 0, false
+		}
+		high, low = max(high, *temperatures[i]), min(low, *temperatures[i])
+		if first == "" {
+			first = hour
+		}
+		last = hour
+	}
+	return high, low, strings.HasPrefix(first, day+"T00:") && strings.HasPrefix(last, day+"T23:")
+}
+
+// percentile returns the p-th percentile (0-100) of the values, interpolating between the closest ranks.
+func percentile(values []float64, p float64) float64 {
+	sorted := slices.Sorted(slices.Values(values))
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// ensembleData returns the uncertainty range of tomorrow's temperatures.
+func (s *Service) ensembleData() template.EnsembleData {
+	s.ensemble.mu.RLock()
+	defer s.ensemble.mu.RUnlock()
+	return s.ensemble.data
+}
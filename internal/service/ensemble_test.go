@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestDayExtremes(t *testing.T) {
+	// hours returns the given number of hourly times and temperatures of the day from midnight, without
+	// the skipped hours
+	hours := func(day string, count int, skip ...int) ([]string, []*float64) {
+		var times []string
+		var temperatures []*float64
+		for hour := range count {
+			if len(skip) > 0 && hour == skip[0] {
+				skip = skip[1:]
+				continue
+			}
+			temperature := float64(hour)
+			times = append(times, fmt.Sprintf("%sT%02d:00", day, hour))
+			temperatures = append(temperatures, &temperature)
+		}
+		return times, temperatures
+	}
+	// fallBack returns the hours of a day on which the clocks are set back, with the hour 02:00 twice
+	fallBack := func(day string) ([]string, []*float64) {
+		times, temperatures := hours(day, 24)
+		return slices.Insert(times, 3, times[2]), slices.Insert(temperatures, 3, temperatures[2])
+	}
+	springForward := func(day string) ([]string, []*float64) { return hours(day, 24, 2) }
+	truncated := func(day string) ([]string, []*float64) { return hours(day, 18) }
+	regular := func(day string) ([]string, []*float64) { return hours(day, 24) }
+
+	tests := []struct {
+		name     string
+		hours    func(string) ([]string, []*float64)
+		complete bool
+	}{
+		{"a regular day is complete", regular, true},
+		{"a day with 23 hours is complete", springForward, true},
+		{"a day with 25 hours is complete", fallBack, true},
+		{"a truncated day is not complete", truncated, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, beforeTemps := regular("2026-10-24")
+			times, temperatures := tt.hours("2026-10-25")
+			times, temperatures = append(before, times...), append(beforeTemps, temperatures...)
+			high, low, complete := dayExtremes(times, temperatures, "2026-10-25")
+			if complete != tt.complete {
+				t.Fatalf("expected complete to be %t, got %t", tt.complete, complete)
+			}
+			if complete && (low != 0 || high != 23) {
+				t.Errorf("expected low 0 and high 23, got %f and %f", low, high)
+			}
+		})
+	}
+	t.Run("a missing value is not complete", func(t *testing.T) {
+		times, temperatures := regular("2026-10-25")
+		temperatures[5] = nil
+		if _, _, complete := dayExtremes(times, temperatures, "2026-10-25"); complete {
+			t.Error("expected the day not to be complete")
+		}
+	})
+}
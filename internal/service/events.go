@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/event"
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	// eventQueueSize is the buffer size of the event subscriptions
+	eventQueueSize = 8
+
+	nmConnectivityFull   = 4
+	networkCheckInterval = 30 * time.Second
+)
+
+// subscribeEvents subscribes the subsystems to the events they react on. Every subscriber runs
+// independently, so a slow weather fetch doesn't delay the output or the hooks.
+func (s *Service) subscribeEvents(ctx context.Context) {
+	s.subscribe(ctx, "weather_events", func(ctx context.Context, _ event.Event) {
+		s.fetchWeather(ctx)
+	}, event.LocationChanged, event.SleepResume, event.NetworkUp)
+
+	// In on-demand mode, the output is only written on request via the output signal
+	if !s.onDemand() {
+		s.subscribe(ctx, "output_events", func(ctx context.Context, _ event.Event) {
+			s.printWeather(ctx)
+		}, event.LocationChanged, event.WeatherUpdated, event.ConfigReloaded, event.TimeChanged)
+	}
+
+	s.subscribe(ctx, "clock_events", func(ctx context.Context, _ event.Event) {
+		s.goSupervised(ctx, "clock_sync", s.waitForClockSync)
+	}, event.SleepResume)
+
+	s.subscribe(ctx, "resume_events", func(ctx context.Context, _ event.Event) {
+		s.catchUpAfterResume(ctx)
+	}, event.SleepResume)
+
+	s.subscribe(ctx, "time_events", func(context.Context, event.Event) {
+		select {
+		case s.sunWake <- struct{}{}:
+		default:
+		}
+	}, event.TimeChanged)
+
+	if len(s.plugins) > 0 {
+		s.subscribe(ctx, "plugin_events", func(ctx context.Context, _ event.Event) {
+			s.runPlugins(ctx)
+		}, event.WeatherUpdated)
+	}
+	if s.config.Satellites.Enabled {
+		s.subscribe(ctx, "satellite_events", func(ctx context.Context, _ event.Event) {
+			if s.satellitePassesMoved() {
+				s.predictSatellitePasses(ctx)
+			}
+		}, event.LocationChanged)
+	}
+	if s.config.WeatherCard.Enabled {
+		s.subscribe(ctx, "weather_card_events", func(ctx context.Context, _ event.Event) {
+			s.updateWeatherCard(ctx)
+		}, event.WeatherUpdated)
+	}
+	if len(s.config.Hooks.Events) > 0 {
+		s.subscribe(ctx, "hook_events", func(ctx context.Context, ev event.Event) {
+			if command, ok := s.config.Hooks.Events[string(ev.Kind)]; ok {
+				s.runHook(ctx, string(ev.Kind), command, []string{"WAYBAR_WEATHER_EVENT_SOURCE=" + ev.Source})
+			}
+		}, event.Kinds...)
+	}
+}
+
+// subscribe subscribes the handler to the given event kinds and runs it as a supervised subsystem
+// until the context ends.
+func (s *Service) subscribe(ctx context.Context, name string, handler func(context.Context, event.Event),
+	kinds ...event.Kind,
+) {
+	events, unsub := s.events.Subscribe(eventQueueSize, kinds...)
+	context.AfterFunc(ctx, unsub)
+	s.goSupervised(ctx, name, func(ctx context.Context) {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				s.logger.Debug("handling event", slog.String("event", string(ev.Kind)),
+					slog.String("subscriber", name), slog.String("source", ev.Source))
+				handler(ctx, ev)
+			}
+		}
+	})
+}
+
+// monitorNetwork periodically checks the connectivity reported by NetworkManager and publishes an
+// event once full connectivity is (re-)established.
+func (s *Service) monitorNetwork(ctx context.Context) {
+	connected := true
+	for {
+		online, err := s.networkOnline()
+		if err != nil {
+			s.logger.Debug("failed to determine network connectivity", logger.Err(err))
+		} else {
+			if online && !connected {
+				s.logger.Info("network connection is up")
+				s.events.Publish(event.NetworkUp, "networkmanager")
+			}
+			connected = online
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(networkCheckInterval):
+		}
+	}
+}
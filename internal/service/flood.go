@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+const (
+	// floodURL is the endpoint of the Open-Meteo Flood API
+	floodURL = "https://flood-api.open-meteo.com/v1/flood"
+	// floodForecastDays is the number of days of the river discharge forecast
+	floodForecastDays = 7
+	floodJobName      = "flood_update_job"
+	floodSource       = "open-meteo-flood"
+
+	FloodWarningClass = "flood-warning"
+	FloodDangerClass  = "flood-danger"
+
+	// Flood levels
+	floodWarning = "warning"
+	floodDanger  = "danger"
+)
+
+// floodState holds the river discharge forecast of the configured river point.
+type floodState struct {
+	mu   sync.RWMutex
+	data template.FloodData
+}
+
+// floodResponse represents the daily river discharge of the Open-Meteo Flood API. Days without data are
+// null.
+type floodResponse struct {
+	Daily struct {
+		Time           []string   `json:"time"`
+		RiverDischarge []*float64 `json:"river_discharge"`
+	} `json:"daily"`
+}
+
+// createFloodJob schedules the update of the river discharge forecast, starting immediately.
+func (s *Service) createFloodJob(ctx context.Context) error {
+	return s.addJob(ctx, floodJobName, gocron.DurationJob(s.config.Flood.Interval), s.fetchFlood,
+		gocron.WithStartAt(gocron.WithStartImmediately()))
+}
+
+// fetchFlood fetches the river discharge forecast of the configured river point. On failure, the previous
+// forecast is kept. The forecast is shown with the next output.
+func (s *Service) fetchFlood(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, FetchTimeout)
+	defer cancel()
+	data, err := s.floodForecast(ctx)
+	if err != nil {
+		s.logger.Error("failed to get river discharge forecast", logger.Err(err))
+		return
+	}
+
+	s.flood.mu.Lock()
+	defer s.flood.mu.Unlock()
+	s.flood.data = data
+}
+
+// floodForecast requests the daily river discharge and determines the highest discharge of the forecast
+// and the flood level it reaches.
+func (s *Service) floodForecast(ctx context.Context) (template.FloodData, error) {
+	conf := s.config.Flood
+	query := url.Values{
+		"latitude":      {fmt.Sprintf("%f", conf.Latitude)},
+		"longitude":     {fmt.Sprintf("%f", conf.Longitude)},
+		"daily":         {"river_discharge"},
+		"timezone":      {"auto"},
+		"forecast_days": {fmt.Sprintf("%d", floodForecastDays)},
+	}
+	var response floodResponse
+	client := newHTTPClient(s.config, s.logger, s.budget)
+	if _, err := client.Get(ctx, floodURL+"?"+query.Encode(), &response, nil); err != nil {
+		return template.FloodData{}, fmt.Errorf("failed to request river discharge: %w", err)
+	}
+
+	data := template.FloodData{Name: conf.Name}
+	now := s.localNow()
+	today := now.Format(time.DateOnly)
+	for i, day := range response.Daily.Time {
+		if i >= len(response.Daily.RiverDischarge) || response.Daily.RiverDischarge[i] == nil || day < today {
+			continue
+		}
+		date, err := time.ParseInLocation(time.DateOnly, day, now.Location())
+		if err != nil {
+			return template.FloodData{}, fmt.Errorf("failed to parse river discharge date: %w", err)
+		}
+		discharge := *response.Daily.RiverDischarge[i]
+		if !data.Available {
+			data.Available, data.Discharge = true, discharge
+		}
+		if discharge > data.Max || data.MaxDate.IsZero() {
+			data.Max, data.MaxDate = discharge, date
+		}
+	}
+	if !data.Available {
+		return data, fmt.Errorf("no river discharge data at %f, %f", conf.Latitude, conf.Longitude)
+	}
+	switch {
+	case conf.Danger > 0 && data.Max >= conf.Danger:
+		data.Level = floodDanger
+	case conf.Warning > 0 && data.Max >= conf.Warning:
+		data.Level = floodWarning
+	}
+	return data, nil
+}
+
+// floodData returns the river discharge forecast of the configured river point.
+func (s *Service) floodData() template.FloodData {
+	s.flood.mu.RLock()
+	defer s.flood.mu.RUnlock()
+	return s.flood.data
+}
+
+// floodClass returns the CSS class of the flood level, or an empty string if no level is reached.
+func floodClass(data template.FloodData) string {
+	switch data.Level {
+	case floodDanger:
+		return FloodDangerClass
+	case floodWarning:
+		return FloodWarningClass
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hectormalot/omgo"
+
+	"github.com/wneessen/waybar-weather/internal/forecastdiff"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+// forecastDiffState holds the changes of the latest forecast compared to the first forecast of the day.
+type forecastDiffState struct {
+	mu      sync.RWMutex
+	changes []forecastdiff.Change
+}
+
+// updateForecastDiff compares the daily forecast of today and the following days with the first forecast
+// of the day. The caller is expected to hold the location lock.
+func (s *Service) updateForecastDiff(forecast *omgo.Forecast) {
+	if s.forecastTracker == nil {
+		return
+	}
+	now := s.localNow()
+	today := now.Format(time.DateOnly)
+	snapshot := forecastdiff.Snapshot{
+		Time:            now,
+		Latitude:        s.coordinates.Lat,
+		Longitude:       s.coordinates.Lon,
+		TemperatureUnit: forecast.HourlyUnits["temperature_2m"],
+	}
+	for i, date := range forecast.DailyTimes {
+		if date.Format(time.DateOnly) < today {
+			continue
+		}
+		snapshot.Days = append(snapshot.Days, forecastdiff.Day{
+			Date:                     date.Format(time.DateOnly),
+			TemperatureMax:           forecast.DailyMetrics["temperature_2m_max"][i],
+			TemperatureMin:           forecast.DailyMetrics["temperature_2m_min"][i],
+			PrecipitationProbability: forecast.DailyMetrics["precipitation_probability_max"][i],
+		})
+	}
+	changes, err := s.forecastTracker.Update(snapshot)
+	if err != nil {
+		s.logger.Error("failed to save forecast reference", logger.Err(err))
+	}
+
+	s.forecastDiff.mu.Lock()
+	defer s.forecastDiff.mu.Unlock()
+	s.forecastDiff.changes = changes
+}
+
+// forecastChanges returns the changes of today's and tomorrow's forecast with their messages.
+func (s *Service) forecastChanges(now time.Time, tempUnit string) []template.ForecastChangeData {
+	s.forecastDiff.mu.RLock()
+	defer s.forecastDiff.mu.RUnlock()
+
+	today := now.Format(time.DateOnly)
+	tomorrow := now.AddDate(0, 0, 1).Format(time.DateOnly)
+	var result []template.ForecastChangeData
+	for _, change := range s.forecastDiff.changes {
+		if change.Date != today && change.Date != tomorrow {
+			continue
+		}
+		date, _ := time.ParseInLocation(time.DateOnly, change.Date, now.Location())
+		since := change.Since.In(now.Location())
+		difference := template.Round(change.Current-change.Previous, 0)
+		isToday := change.Date == today
+		var message string
+		switch {
+		case change.Kind == forecastdiff.KindWarmer && isToday:
+			message = s.t.Getf("Today's high now %.0f%s warmer than forecast at %s", difference, tempUnit,
+				since.Format("15:04"))
+		case change.Kind == forecastdiff.KindWarmer:
+			message = s.t.Getf("Tomorrow's high now %.0f%s warmer than forecast at %s", difference, tempUnit,
+				since.Format("15:04"))
+		case change.Kind == forecastdiff.KindCooler && isToday:
+			message = s.t.Getf("Today's high now %.0f%s cooler than forecast at %s", -difference, tempUnit,
+				since.Format("15:04"))
+		case change.Kind == forecastdiff.KindCooler:
+			message = s.t.Getf("Tomorrow's high now %.0f%s cooler than forecast at %s", -difference, tempUnit,
+				since.Format("15:04"))
+		case isToday:
+			message = s.t.Getf("Precipitation probability today now %.0f%% instead of %.0f%%", change.Current,
+				change.Previous)
+		default:
+			message = s.t.Getf("Precipitation probability tomorrow now %.0f%% instead of %.0f%%", change.Current,
+				change.Previous)
+		}
+		result = append(result, template.ForecastChangeData{
+			Kind:     change.Kind,
+			Date:     date,
+			Previous: change.Previous,
+			Current:  change.Current,
+			Since:    since,
+			Message:  message,
+		})
+	}
+	return result
+}
@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+// compositorQueryTimeout is the maximum runtime of a query of the compositor IPC
+const compositorQueryTimeout = 5 * time.Second
+
+// errNoCompositor is returned if neither Sway nor Hyprland is running.
+var errNoCompositor = errors.New("no supported compositor found")
+
+// swayNode is a node of the Sway layout tree, reduced to the fields needed to find a focused
+// fullscreen window.
+type swayNode struct {
+	Focused        bool       `json:"focused"`
+	FullscreenMode int        `json:"fullscreen_mode"`
+	Nodes          []swayNode `json:"nodes"`
+	FloatingNodes  []swayNode `json:"floating_nodes"`
+}
+
+// monitorFullscreen periodically queries the compositor for a focused fullscreen window, e.g. a game or a
+// presentation, and reschedules the jobs with the reduced fullscreen intervals while it is focused.
+func (s *Service) monitorFullscreen(ctx context.Context) {
+	for {
+		fullscreen, err := s.fullscreenActive(ctx)
+		if errors.Is(err, errNoCompositor) {
+			s.logger.Debug("fullscreen detection is not available", logger.Err(err))
+			return
+		}
+		if err != nil {
+			s.logger.Debug("failed to query compositor for fullscreen windows", logger.Err(err))
+		}
+		if err == nil {
+			s.setFullscreen(ctx, fullscreen)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.config.Fullscreen.CheckInterval):
+		}
+	}
+}
+
+// setFullscreen applies the intervals for the given fullscreen state, if it changed.
+func (s *Service) setFullscreen(ctx context.Context, fullscreen bool) {
+	s.profileLock.Lock()
+	if s.fullscreen == fullscreen {
+		s.profileLock.Unlock()
+		return
+	}
+	s.fullscreen = fullscreen
+	s.profileLock.Unlock()
+
+	if fullscreen {
+		s.logger.Info("fullscreen window is focused, reducing updates",
+			slog.Duration("weather_update", s.config.Fullscreen.WeatherUpdate),
+			slog.Duration("output", s.config.Fullscreen.Output))
+	} else {
+		s.logger.Info("fullscreen window has been left, resuming regular updates")
+	}
+	s.applyIntervals(ctx)
+}
+
+// fullscreenActive returns true if the focused window of the running compositor is in fullscreen mode.
+func (s *Service) fullscreenActive(ctx context.Context) (bool, error) {
+	ctxQuery, cancelQuery := context.WithTimeout(ctx, compositorQueryTimeout)
+	defer cancelQuery()
+
+	switch {
+	case os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "":
+		output, err := exec.CommandContext(ctxQuery, "hyprctl", "activewindow", "-j").Output()
+		if err != nil {
+			return false, fmt.Errorf("failed to query hyprctl: %w", err)
+		}
+		return hyprlandFullscreen(output)
+	case os.Getenv("SWAYSOCK") != "":
+		output, err := exec.CommandContext(ctxQuery, "swaymsg", "-t", "get_tree", "-r").Output()
+		if err != nil {
+			return false, fmt.Errorf("failed to query swaymsg: %w", err)
+		}
+		var root swayNode
+		if err = json.Unmarshal(output, &root); err != nil {
+			return false, fmt.Errorf("failed to decode sway tree: %w", err)
+		}
+		_, fullscreen := root.focusedFullscreen(false)
+		return fullscreen, nil
+	default:
+		return false, errNoCompositor
+	}
+}
+
+// hyprlandFullscreen returns true if the active window reported by hyprctl is in fullscreen mode. Older
+// versions of Hyprland report the mode as boolean, newer ones as number.
+func hyprlandFullscreen(output []byte) (bool, error) {
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 || bytes.Equal(output, []byte("{}")) || bytes.Equal(output, []byte("Invalid")) {
+		return false, nil // no active window
+	}
+	var window struct {
+		Fullscreen json.RawMessage `json:"fullscreen"`
+	}
+	if err := json.Unmarshal(output, &window); err != nil {
+		return false, fmt.Errorf("failed to decode active window: %w", err)
+	}
+	var state bool
+	if err := json.Unmarshal(window.Fullscreen, &state); err == nil {
+		return state, nil
+	}
+	var mode int
+	if err := json.Unmarshal(window.Fullscreen, &mode); err != nil {
+		return false, fmt.Errorf("failed to decode fullscreen state: %w", err)
+	}
+	return mode > 0, nil
+}
+
+// focusedFullscreen searches the tree for the focused node and returns whether it has been found and
+// whether it or one of its parents is in fullscreen mode.
+func (n swayNode) focusedFullscreen(parentFullscreen bool) (bool, bool) {
+	fullscreen := parentFullscreen || n.FullscreenMode > 0
+	if n.Focused {
+		return true, fullscreen
+	}
+	for _, children := range [][]swayNode{n.Nodes, n.FloatingNodes} {
+		for _, child := range children {
+			if found, childFullscreen := child.focusedFullscreen(fullscreen); found {
+				return true, childFullscreen
+			}
+		}
+	}
+	return false, false
+}
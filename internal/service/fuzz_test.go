@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"math"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hectormalot/omgo"
+)
+
+func FuzzDecodeForecast(f *testing.F) {
+	zone, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		f.Fatalf("failed to load time zone: %s", err)
+	}
+	now := time.Date(2026, 6, 15, 14, 30, 0, 0, zone)
+	opts := &omgo.Options{TemperatureUnit: "celsius", WindspeedUnit: "kmh", PrecipitationUnit: "mm"}
+	body, err := encodeForecast(goldenForecast(now, 3, "metric"), zone, opts)
+	if err != nil {
+		f.Fatalf("failed to encode forecast: %s", err)
+	}
+	f.Add(body)
+	f.Add(body[:len(body)/2])
+	f.Add(bytes.Replace(body, []byte(`"temperature":21.4`), []byte(`"temperature":1e999`), 1))
+	f.Add(bytes.Replace(body, []byte(`"timezone":"Europe/Berlin"`), []byte(`"timezone":"\xff"`), 1))
+	f.Add([]byte(`{"utc_offset_seconds":-2147483648,"hourly":{"time":["2026-06-15T00:00"]}}`))
+	f.Add([]byte(`null`))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		forecast, err := omgo.ParseBody(body)
+		if err != nil {
+			return
+		}
+		var meta forecastMeta
+		if err = json.Unmarshal(body, &meta); err != nil {
+			return
+		}
+		timezone := localizeForecast(forecast, meta)
+		serv := newGoldenService(t, "json", "en", "metric", 3)
+		if err = serv.validateForecast(forecast); err != nil {
+			return
+		}
+		serv.weather, serv.timezone = forecast, timezone
+		buf := bytes.NewBuffer(nil)
+		serv.output = serv.formatWriter(buf)
+		serv.printWeather(context.Background())
+		checkRenderedOutput(t, buf.Bytes())
+	})
+}
+
+func FuzzRenderOutput(f *testing.F) {
+	f.Add("Berlin", 21.4, 14.2)
+	f.Add("", math.NaN(), math.Inf(1))
+	f.Add("S\xe3o Paulo", math.Inf(-1), -0.0)
+	f.Add("Zürich\n\x00{{.Address.City}}", 1e308, math.SmallestNonzeroFloat64)
+	f.Add("\"</span><script>", -273.15, 1e-9)
+
+	f.Fuzz(func(t *testing.T, city string, temperature, windSpeed float64) {
+		for _, format := range []string{"json", "eww"} {
+			serv := newGoldenService(t, format, "de", "metric", 61)
+			serv.address.City, serv.address.DisplayName = city, city
+			serv.address = serv.address.Sanitized()
+			serv.weather.CurrentWeather.Temperature = temperature
+			serv.weather.CurrentWeather.WindSpeed = windSpeed
+			buf := bytes.NewBuffer(nil)
+			serv.output = serv.formatWriter(buf)
+			serv.printWeather(context.Background())
+			checkRenderedOutput(t, buf.Bytes())
+		}
+	})
+}
+
+// encodeForecast encodes the forecast as API response with the wall clock times of the time zone zone and the
+// units requested by opts. The values are not converted.
+func encodeForecast(forecast *omgo.Forecast, zone *time.Location, opts *omgo.Options) ([]byte, error) {
+	hourly := map[string]any{}
+	times := make([]string, 0, len(forecast.HourlyTimes))
+	for _, t := range forecast.HourlyTimes {
+		times = append(times, t.In(zone).Format("2006-01-02T15:04"))
+	}
+	hourly["time"] = times
+	for metric, values := range forecast.HourlyMetrics {
+		hourly[metric] = values
+	}
+	daily := map[string]any{}
+	dates := make([]string, 0, len(forecast.DailyTimes))
+	for _, t := range forecast.DailyTimes {
+		dates = append(dates, t.In(zone).Format(time.DateOnly))
+	}
+	daily["time"] = dates
+	for metric, values := range forecast.DailyMetrics {
+		daily[metric] = values
+	}
+
+	units := maps.Clone(expectedUnits["metric"])
+	if opts.TemperatureUnit == "fahrenheit" {
+		units = maps.Clone(expectedUnits["imperial"])
+	}
+	for _, wind := range windUnits {
+		if wind.param == opts.WindspeedUnit {
+			units["wind_speed_10m"], units["wind_gusts_10m"] = wind.unit, wind.unit
+		}
+	}
+	units["precipitation"] = opts.PrecipitationUnit
+
+	current := forecast.CurrentWeather.Time.In(zone)
+	abbreviation, offset := current.Zone()
+	return json.Marshal(map[string]any{
+		"latitude": forecast.Latitude, "longitude": forecast.Longitude, "elevation": forecast.Elevation,
+		"utc_offset_seconds": offset, "timezone": zone.String(), "timezone_abbreviation": abbreviation,
+		"current_weather": map[string]any{
+			"time": current.Format("2006-01-02T15:04"), "temperature": forecast.CurrentWeather.Temperature,
+			"weathercode": forecast.CurrentWeather.WeatherCode, "windspeed": forecast.CurrentWeather.WindSpeed,
+			"winddirection": forecast.CurrentWeather.WindDirection,
+		},
+		"hourly": hourly, "hourly_units": units, "daily": daily,
+	})
+}
+
+// checkRenderedOutput fails the test if the rendered output is not a valid UTF-8 encoded JSON object.
+func checkRenderedOutput(t *testing.T, data []byte) {
+	t.Helper()
+	if len(data) == 0 {
+		return
+	}
+	if !utf8.Valid(data) {
+		t.Fatalf("output is not valid UTF-8: %q", data)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("output is not a valid JSON object: %s: %q", err, data)
+	}
+}
@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+// geofenceHysteresis is the factor of the radius a location has to be outside of a geofence before it is
+// considered as left. This prevents flapping at the border due to inaccurate location updates.
+const geofenceHysteresis = 1.1
+
+// checkGeofences determines which of the configured geofences contain the location of the geolocation
+// update and runs the enter and exit commands of the geofences that have been entered or left. The
+// first update only determines the initial state, so no commands are run on startup. If the backend of the
+// smallest geofence that selects one changes, the weather backend is selected again.
+func (s *Service) checkGeofences(ctx context.Context, result geobus.Result) {
+	if len(s.config.Geofences) == 0 {
+		return
+	}
+
+	s.geofenceLock.Lock()
+	position := geobus.Coordinate{Lat: result.Lat, Lon: result.Lon}
+	initial := s.geofenceInside == nil
+	if initial {
+		s.geofenceInside = make(map[string]bool, len(s.config.Geofences))
+	}
+
+	// Geofences are processed ordered by their radius, so the label of the smallest geofence wins
+	names := slices.SortedFunc(maps.Keys(s.config.Geofences), func(a, b string) int {
+		if c := cmp.Compare(s.config.Geofences[a].Radius, s.config.Geofences[b].Radius); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	s.place = ""
+	backend := ""
+	for _, name := range names {
+		fence := s.config.Geofences[name]
+		distance := position.DistanceTo(geobus.Coordinate{Lat: fence.Latitude, Lon: fence.Longitude})
+		wasInside := s.geofenceInside[name]
+		inside := distance <= fence.Radius || (wasInside && distance <= fence.Radius*geofenceHysteresis)
+		s.geofenceInside[name] = inside
+		if inside && s.place == "" {
+			s.place = fence.Label
+		}
+		if inside && backend == "" {
+			backend = fence.Backend
+		}
+		if initial || inside == wasInside {
+			continue
+		}
+
+		event, command := "geofence_exit", fence.OnExit
+		if inside {
+			event, command = "geofence_enter", fence.OnEnter
+		}
+		s.logger.Info("geofence changed", slog.String("geofence", name), slog.String("event", event))
+		if command != "" {
+			s.runHook(ctx, event, command, []string{
+				"WAYBAR_WEATHER_GEOFENCE=" + name,
+				"WAYBAR_WEATHER_GEOFENCE_LABEL=" + fence.Label,
+				"WAYBAR_WEATHER_DISTANCE=" + strconv.FormatFloat(distance, 'f', 0, 64),
+			})
+		}
+	}
+	changed := backend != s.placeBackend
+	s.placeBackend = backend
+	s.geofenceLock.Unlock()
+
+	// The backend is switched outside of the geofence lock, since the switch waits for a running weather
+	// update. The weather is fetched from the new backend with the location update.
+	if changed {
+		s.selectBackend()
+	}
+}
+
+// selectBackend switches to the weather backend of the smallest geofence that selects one, or else to
+// the backend of the active profile or the configured one. It is only called if the selection has
+// changed, so a backend switched to via the control socket is kept until then.
+func (s *Service) selectBackend() {
+	s.geofenceLock.Lock()
+	backend := s.placeBackend
+	s.geofenceLock.Unlock()
+	if backend == "" {
+		s.profileLock.RLock()
+		backend = s.config.Profiles[s.activeProfile].Backend
+		s.profileLock.RUnlock()
+	}
+	if backend == "" {
+		backend = s.config.Weather.Backend
+	}
+
+	s.backendLock.RLock()
+	current := s.backend
+	s.backendLock.RUnlock()
+	if backend == current {
+		return
+	}
+	if err := s.setBackend(backend); err != nil {
+		s.logger.Error("failed to switch weather backend", logger.Err(err))
+	}
+}
+
+// currentPlace returns the label of the geofence the location is in.
+func (s *Service) currentPlace() string {
+	s.geofenceLock.Lock()
+	defer s.geofenceLock.Unlock()
+	return s.place
+}
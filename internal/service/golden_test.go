@@ -0,0 +1,200 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hectormalot/omgo"
+
+	"github.com/wneessen/waybar-weather/internal/config"
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/geocode"
+	"github.com/wneessen/waybar-weather/internal/i18n"
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// goldenWeatherCodes are the WMO weather codes the output is rendered for: clear sky, overcast, rain,
+// snow and thunderstorm.
+var goldenWeatherCodes = []int{0, 3, 61, 71, 95}
+
+// TestOutput_Golden renders the output of every output format for a matrix of locales, unit systems and
+// weather codes and compares it with the golden files in testdata/golden. Run the tests with -update to
+// write the golden files after an intended change of the output.
+func TestOutput_Golden(t *testing.T) {
+	for _, format := range config.OutputFormats {
+		for _, locale := range []string{"en", "de"} {
+			for _, units := range []string{"metric", "imperial"} {
+				name := fmt.Sprintf("%s_%s_%s", format, locale, units)
+				t.Run(name, func(t *testing.T) {
+					buf := bytes.NewBuffer(nil)
+					for _, code := range goldenWeatherCodes {
+						serv := newGoldenService(t, format, locale, units, code)
+						serv.output = serv.formatWriter(buf)
+						serv.printWeather(context.Background())
+					}
+					compareGolden(t, filepath.Join("testdata", "golden", name+".golden"), buf.Bytes())
+				})
+			}
+		}
+	}
+}
+
+// compareGolden compares the output with the golden file at path, or writes the golden file if the
+// -update flag is set.
+func compareGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create golden file directory: %s", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("failed to write golden file: %s", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file, run the tests with -update to create it: %s", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from golden file %s\n got: %s\nwant: %s", path, got, want)
+	}
+}
+
+// newGoldenService returns a service with a fixed location, clock and weather, whose current weather and
+// forecast have the given weather code.
+func newGoldenService(t *testing.T, format, locale, units string, code int) *Service {
+	t.Helper()
+	zone, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %s", err)
+	}
+	now := time.Date(2026, 6, 15, 14, 30, 0, 0, zone)
+
+	conf := newTestConfig(t)
+	conf.Locale = locale
+	conf.Units = units
+	conf.OutputFormat = format
+	serv := newTestService(t, conf)
+	serv.SetClock(func() time.Time { return now })
+	serv.localZone = zone
+
+	weather := goldenForecast(now, code, units)
+	serv.timezone = zone
+	serv.weather, serv.weatherIsSet, serv.weatherSource = weather, true, "api"
+	serv.coordinates = geobus.Coordinate{Lat: 52.52, Lon: 13.405, Acc: 10}
+	serv.locationIsSet, serv.geoSource = true, "file"
+	serv.address = geocode.Address{
+		AddressFound: true, Latitude: 52.52, Longitude: 13.405, DisplayName: "Berlin, Germany",
+		Country: "Germany", City: "Berlin",
+	}
+	return serv
+}
+
+// newTestConfig returns the default config with all state and cache files in a temporary directory.
+func newTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	conf, err := config.New()
+	if err != nil {
+		t.Fatalf("failed to load default config: %s", err)
+	}
+	dir := t.TempDir()
+	conf.Socket = filepath.Join(dir, "control.sock")
+	conf.APIBudget.StateFile = filepath.Join(dir, "api-usage.json")
+	conf.GeoLocation.LocationCache = filepath.Join(dir, "location.json")
+	conf.Cache.Dir = filepath.Join(dir, "cache")
+	return conf
+}
+
+// newTestService returns a new service for the config, which only logs errors.
+func newTestService(t *testing.T, conf *config.Config) *Service {
+	t.Helper()
+	localizer, err := i18n.New(conf.Locale)
+	if err != nil {
+		t.Fatalf("failed to initialize localizer: %s", err)
+	}
+	serv, err := New(conf, logger.NewLogger(slog.LevelError+1), localizer)
+	if err != nil {
+		t.Fatalf("failed to create service: %s", err)
+	}
+	return serv
+}
+
+// goldenForecast returns a forecast of two days around now with the given weather code in the given unit
+// system. The times are absolute points in time, as after localizeForecast. Metrics without a value
+// below are zero.
+func goldenForecast(now time.Time, code int, units string) *omgo.Forecast {
+	temperature := func(celsius float64) float64 {
+		if units == "imperial" {
+			return celsius*9/5 + 32
+		}
+		return celsius
+	}
+	values := map[string]func(hour int) float64{
+		"temperature_2m":       func(hour int) float64 { return temperature(15 + float64(hour)/2) },
+		"apparent_temperature": func(hour int) float64 { return temperature(14 + float64(hour)/2) },
+		"weather_code":         func(int) float64 { return float64(code) },
+		"wind_speed_10m":       func(int) float64 { return 14.2 },
+		"wind_gusts_10m":       func(int) float64 { return 28.5 },
+		"is_day": func(hour int) float64 {
+			if hour >= 5 && hour < 21 {
+				return 1
+			}
+			return 0
+		},
+		"wind_direction_10m":    func(int) float64 { return 250 },
+		"relative_humidity_2m":  func(int) float64 { return 60 },
+		"pressure_msl":          func(int) float64 { return 1013.2 },
+		"surface_pressure":      func(int) float64 { return 1009.1 },
+		"freezing_level_height": func(int) float64 { return 3200 },
+		"precipitation":         func(int) float64 { return 0.4 },
+	}
+
+	data := &omgo.Forecast{
+		Latitude: 52.52, Longitude: 13.405, Elevation: 34,
+		CurrentWeather: omgo.CurrentWeather{
+			Time: omgo.ApiTime{Time: now.Truncate(time.Hour).UTC()}, Temperature: temperature(21.4),
+			WeatherCode: float64(code), WindSpeed: 14.2, WindDirection: 250,
+		},
+		HourlyUnits:   maps.Clone(expectedUnits[units]),
+		HourlyMetrics: make(map[string][]float64),
+		DailyMetrics:  make(map[string][]float64),
+	}
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for i := range 48 {
+		data.HourlyTimes = append(data.HourlyTimes, midnight.Add(time.Duration(i)*time.Hour).UTC())
+		for _, metric := range slices.Concat(hourlyMetrics, optionalHourlyMetrics) {
+			var value float64
+			if valueAt, ok := values[metric]; ok {
+				value = valueAt(i % 24)
+			}
+			data.HourlyMetrics[metric] = append(data.HourlyMetrics[metric], value)
+		}
+	}
+	daily := map[string]float64{
+		"temperature_2m_max": temperature(26.5), "temperature_2m_min": temperature(15),
+		"weather_code": float64(code), "precipitation_probability_max": 40,
+	}
+	for i := range 2 {
+		data.DailyTimes = append(data.DailyTimes, midnight.AddDate(0, 0, i))
+		for _, metric := range dailyMetrics {
+			data.DailyMetrics[metric] = append(data.DailyMetrics[metric], daily[metric])
+		}
+	}
+	return data
+}
@@ -0,0 +1,345 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/prop"
+	"github.com/hectormalot/omgo"
+
+	"github.com/wneessen/waybar-weather/internal/config"
+)
+
+// busConfig is the configuration of the private dbus-daemon, which allows every connection to own any
+// name and to send messages to any destination.
+const busConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+const (
+	geoclueManagerPath  = dbus.ObjectPath("/org/freedesktop/GeoClue2/Manager")
+	geoclueClientPath   = dbus.ObjectPath("/org/freedesktop/GeoClue2/Client/1")
+	geoclueLocationPath = dbus.ObjectPath("/org/freedesktop/GeoClue2/Location/1")
+	logindInterface     = "org.freedesktop.login1.Manager"
+)
+
+// startTestBus starts a private dbus-daemon and points the system and session bus of the process to it.
+// The test is skipped if dbus-daemon is not installed.
+func startTestBus(t *testing.T) string {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "bus.conf")
+	if err = os.WriteFile(configFile, fmt.Appendf(nil, busConfig, filepath.Join(dir, "bus")), 0o600); err != nil {
+		t.Fatalf("failed to write D-Bus config: %s", err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+configFile, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("failed to get dbus-daemon output: %s", err)
+	}
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("failed to start dbus-daemon: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read D-Bus address: %s", err)
+	}
+	address = strings.TrimSpace(address)
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", address)
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", address)
+	return address
+}
+
+// fakeDesktop provides the GeoClue, logind and notification services on the private bus.
+type fakeDesktop struct {
+	conn     *dbus.Conn
+	location *prop.Properties
+	mu       sync.Mutex
+	inhibit  []*os.File
+	notified chan notification
+}
+
+// notification is a notification received by the fake notification server.
+type notification struct {
+	ReplacesID uint32
+	Summary    string
+	Body       string
+}
+
+// startFakeDesktop connects to the private bus at address and registers the fake services, which report
+// the given location via GeoClue.
+func startFakeDesktop(t *testing.T, address string, lat, lon, acc float64) *fakeDesktop {
+	t.Helper()
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("failed to connect to private bus: %s", err)
+	}
+	desktop := &fakeDesktop{conn: conn, notified: make(chan notification, 16)}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		desktop.mu.Lock()
+		defer desktop.mu.Unlock()
+		for _, file := range desktop.inhibit {
+			_ = file.Close()
+		}
+	})
+
+	manager := &fakeGeoClueManager{}
+	client := &fakeGeoClueClient{conn: conn}
+	exports := []struct {
+		value any
+		path  dbus.ObjectPath
+		iface string
+	}{
+		{manager, geoclueManagerPath, "org.freedesktop.GeoClue2.Manager"},
+		{client, geoclueClientPath, "org.freedesktop.GeoClue2.Client"},
+		{&fakeLogind{desktop: desktop}, logindPath, logindInterface},
+		{&fakeNotifications{desktop: desktop}, notifyPath, notifyInterface},
+	}
+	for _, export := range exports {
+		if err = conn.Export(export.value, export.path, export.iface); err != nil {
+			t.Fatalf("failed to export %s: %s", export.iface, err)
+		}
+	}
+	if _, err = prop.Export(conn, geoclueClientPath, prop.Map{"org.freedesktop.GeoClue2.Client": {
+		"DesktopId":              {Value: "", Writable: true, Emit: prop.EmitFalse},
+		"RequestedAccuracyLevel": {Value: uint32(0), Writable: true, Emit: prop.EmitFalse},
+		"DistanceThreshold":      {Value: uint32(0), Writable: true, Emit: prop.EmitFalse},
+	}}); err != nil {
+		t.Fatalf("failed to export GeoClue client properties: %s", err)
+	}
+	if desktop.location, err = prop.Export(conn, geoclueLocationPath, prop.Map{"org.freedesktop.GeoClue2.Location": {
+		"Latitude":  {Value: lat, Emit: prop.EmitFalse},
+		"Longitude": {Value: lon, Emit: prop.EmitFalse},
+		"Altitude":  {Value: -math.MaxFloat64, Emit: prop.EmitFalse},
+		"Accuracy":  {Value: acc, Emit: prop.EmitFalse},
+		"Speed":     {Value: float64(-1), Emit: prop.EmitFalse},
+		"Heading":   {Value: float64(-1), Emit: prop.EmitFalse},
+	}}); err != nil {
+		t.Fatalf("failed to export GeoClue location properties: %s", err)
+	}
+
+	for _, name := range []string{"org.freedesktop.GeoClue2", logindService, notifyService} {
+		reply, err := conn.RequestName(name, dbus.NameFlagDoNotQueue)
+		if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+			t.Fatalf("failed to own bus name %s: %v", name, err)
+		}
+	}
+	return desktop
+}
+
+// move reports a new GeoClue location.
+func (d *fakeDesktop) move(t *testing.T, lat, lon float64) {
+	t.Helper()
+	d.location.SetMust("org.freedesktop.GeoClue2.Location", "Latitude", lat)
+	d.location.SetMust("org.freedesktop.GeoClue2.Location", "Longitude", lon)
+	if err := d.conn.Emit(geoclueClientPath, "org.freedesktop.GeoClue2.Client.LocationUpdated",
+		dbus.ObjectPath("/"), geoclueLocationPath); err != nil {
+		t.Fatalf("failed to emit location update: %s", err)
+	}
+}
+
+// restartGeoClue releases and re-acquires the GeoClue bus name, as a restart of the GeoClue service does.
+func (d *fakeDesktop) restartGeoClue(t *testing.T) {
+	t.Helper()
+	if _, err := d.conn.ReleaseName("org.freedesktop.GeoClue2"); err != nil {
+		t.Fatalf("failed to release GeoClue bus name: %s", err)
+	}
+	if _, err := d.conn.RequestName("org.freedesktop.GeoClue2", dbus.NameFlagDoNotQueue); err != nil {
+		t.Fatalf("failed to own GeoClue bus name: %s", err)
+	}
+}
+
+// resume emits the PrepareForSleep signal of logind announcing the wake-up of the system.
+func (d *fakeDesktop) resume(t *testing.T) {
+	t.Helper()
+	if err := d.conn.Emit(logindPath, logindInterface+".PrepareForSleep", false); err != nil {
+		t.Fatalf("failed to emit resume signal: %s", err)
+	}
+}
+
+type fakeGeoClueManager struct{}
+
+func (m *fakeGeoClueManager) GetClient() (dbus.ObjectPath, *dbus.Error) {
+	return geoclueClientPath, nil
+}
+
+type fakeGeoClueClient struct {
+	conn *dbus.Conn
+}
+
+// Start reports the location right away, as GeoClue does once the client is started.
+func (c *fakeGeoClueClient) Start() *dbus.Error {
+	if err := c.conn.Emit(geoclueClientPath, "org.freedesktop.GeoClue2.Client.LocationUpdated",
+		dbus.ObjectPath("/"), geoclueLocationPath); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+func (c *fakeGeoClueClient) Stop() *dbus.Error {
+	return nil
+}
+
+type fakeLogind struct {
+	desktop *fakeDesktop
+}
+
+// Inhibit returns a pipe as inhibitor lock. The pipe of the previous call is closed, since its file
+// descriptor has been passed to the caller with the reply already.
+func (l *fakeLogind) Inhibit(_, _, _, _ string) (dbus.UnixFD, *dbus.Error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return -1, dbus.MakeFailedError(err)
+	}
+	l.desktop.mu.Lock()
+	defer l.desktop.mu.Unlock()
+	for _, file := range l.desktop.inhibit {
+		_ = file.Close()
+	}
+	l.desktop.inhibit = []*os.File{reader, writer}
+	return dbus.UnixFD(reader.Fd()), nil //nolint:gosec
+}
+
+type fakeNotifications struct {
+	desktop *fakeDesktop
+}
+
+func (n *fakeNotifications) Notify(_ string, replacesID uint32, _, summary, body string, _ []string,
+	_ map[string]dbus.Variant, _ int32,
+) (uint32, *dbus.Error) {
+	select {
+	case n.desktop.notified <- notification{ReplacesID: replacesID, Summary: summary, Body: body}:
+	default:
+	}
+	return 1, nil
+}
+
+// newHarnessConfig returns a test config, which uses the fake APIs and GeoClue as the only geolocation
+// provider.
+func newHarnessConfig(t *testing.T, apis *fakeAPIs) *config.Config {
+	t.Helper()
+	conf := newTestConfig(t)
+	conf.Endpoints = apis.endpoints()
+	conf.Cache.Weather = 0
+	conf.Icons.Glyphs = "emoji"
+	conf.GeoLocation.DisableGeoIP = true
+	conf.GeoLocation.DisableGeoAPI = true
+	conf.GeoLocation.DisableGeolocationFile = true
+	conf.GeoLocation.DisableICHNAEA = true
+	conf.GeoLocation.DisableGPSD = true
+	conf.GeoLocation.DisableLocationCache = true
+	return conf
+}
+
+// fakeAPIs mimics the Open-Meteo forecast and the Nominatim reverse geocoding API.
+type fakeAPIs struct {
+	openMeteo *httptest.Server
+	nominatim *httptest.Server
+	forecasts atomic.Int32
+	code      int
+}
+
+// startFakeAPIs starts the fake APIs, which report the weather code for the current day.
+func startFakeAPIs(t *testing.T, code int) *fakeAPIs {
+	t.Helper()
+	apis := &fakeAPIs{code: code}
+	apis.openMeteo = httptest.NewServer(http.HandlerFunc(apis.serveForecast))
+	apis.nominatim = httptest.NewServer(http.HandlerFunc(apis.serveReverse))
+	t.Cleanup(apis.openMeteo.Close)
+	t.Cleanup(apis.nominatim.Close)
+	return apis
+}
+
+// endpoints returns the endpoints config, which redirects the APIs to the fake servers.
+func (a *fakeAPIs) endpoints() map[string]string {
+	return map[string]string{
+		"api.open-meteo.com":          a.openMeteo.URL,
+		"nominatim.openstreetmap.org": a.nominatim.URL,
+	}
+}
+
+func (a *fakeAPIs) serveForecast(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodHead {
+		return
+	}
+	if r.URL.Path != "/v1/forecast" {
+		http.NotFound(w, r)
+		return
+	}
+	a.forecasts.Add(1)
+	query := r.URL.Query()
+	zone, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	opts := &omgo.Options{
+		TemperatureUnit:   query.Get("temperature_unit"),
+		WindspeedUnit:     query.Get("windspeed_unit"),
+		PrecipitationUnit: query.Get("precipitation_unit"),
+	}
+	units := "metric"
+	if opts.TemperatureUnit == "fahrenheit" {
+		units = "imperial"
+	}
+	body, err := encodeForecast(goldenForecast(time.Now().In(zone), a.code, units), zone, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+func (a *fakeAPIs) serveReverse(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/reverse" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"lat":          r.URL.Query().Get("lat"),
+		"lon":          r.URL.Query().Get("lon"),
+		"display_name": "Berlin, Germany",
+		"address":      map[string]string{"city": "Berlin", "country": "Germany"},
+	})
+}
@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+// hookState keeps track of the last observed weather state, so that hooks are only run on changes.
+type hookState struct {
+	mu           sync.Mutex
+	category     string
+	pending      string
+	pendingSince time.Time
+	isDaytime    bool
+}
+
+// runHooks checks the displayed weather data for a changed condition category or a transition between
+// day and night and runs the configured hook commands. A new condition category has to persist for the
+// configured debounce time before its hook is run, so that short showers don't trigger a wallpaper change.
+// The first weather data after the start only determines the initial state, so that a restart doesn't run
+// the hooks again.
+func (s *Service) runHooks(ctx context.Context, data *template.DisplayData) {
+	if len(s.config.Hooks.Conditions) == 0 && s.config.Hooks.Sunrise == "" && s.config.Hooks.Sunset == "" {
+		return
+	}
+
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+
+	now := time.Now()
+	category := s.conditions.Category(data.Current.WeatherCode)
+	if s.hooks.category == "" {
+		s.hooks.category = category
+		s.hooks.isDaytime = data.Current.IsDaytime
+		return
+	}
+	switch {
+	case category == s.hooks.category:
+		s.hooks.pending = ""
+	case category != s.hooks.pending:
+		s.hooks.pending = category
+		s.hooks.pendingSince = now
+	}
+	if s.hooks.pending != "" && now.Sub(s.hooks.pendingSince) >= s.config.Hooks.Debounce {
+		s.hooks.category = s.hooks.pending
+		s.hooks.pending = ""
+		if command, ok := s.config.Hooks.Conditions[category]; ok {
+			s.runHook(ctx, "condition", command, s.weatherEnv(data))
+		}
+	}
+
+	if s.hooks.isDaytime != data.Current.IsDaytime {
+		s.hooks.isDaytime = data.Current.IsDaytime
+		if data.Current.IsDaytime && s.config.Hooks.Sunrise != "" {
+			s.runHook(ctx, "sunrise", s.config.Hooks.Sunrise, s.weatherEnv(data))
+		}
+		if !data.Current.IsDaytime && s.config.Hooks.Sunset != "" {
+			s.runHook(ctx, "sunset", s.config.Hooks.Sunset, s.weatherEnv(data))
+		}
+	}
+}
+
+// weatherEnv returns the environment variables carrying the current weather state to the hook commands.
+func (s *Service) weatherEnv(data *template.DisplayData) []string {
+	return []string{
+		"WAYBAR_WEATHER_CATEGORY=" + s.conditions.Category(data.Current.WeatherCode),
+		"WAYBAR_WEATHER_CONDITION=" + data.Current.Condition,
+		"WAYBAR_WEATHER_CODE=" + strconv.FormatFloat(data.Current.WeatherCode, 'f', 0, 64),
+		"WAYBAR_WEATHER_TEMPERATURE=" + strconv.FormatFloat(data.Current.Temperature, 'f', 1, 64),
+		"WAYBAR_WEATHER_TEMP_UNIT=" + data.TempUnit,
+		"WAYBAR_WEATHER_IS_DAY=" + strconv.FormatBool(data.Current.IsDaytime),
+		"WAYBAR_WEATHER_CITY=" + data.Address.City,
+		"WAYBAR_WEATHER_COUNTRY=" + data.Address.Country,
+	}
+}
+
+// runHook executes the hook command in the background using the shell. The event and the given
+// environment variables are passed to the command.
+func (s *Service) runHook(ctx context.Context, event, command string, vars []string) {
+	if s.quietHours() {
+		s.logger.Debug("skipping hook during quiet hours", slog.String("event", event))
+		return
+	}
+	env := append(os.Environ(), "WAYBAR_WEATHER_EVENT="+event)
+	env = append(env, vars...)
+
+	go func() {
+		defer s.logger.RecoverPanic("hook_" + event)
+
+		ctxHook, cancelHook := context.WithTimeout(ctx, s.config.Hooks.Timeout)
+		defer cancelHook()
+
+		cmd := exec.CommandContext(ctxHook, "/bin/sh", "-c", command)
+		cmd.Env = env
+		s.logger.Debug("running hook", slog.String("event", event), slog.String("command", command))
+		if output, err := cmd.CombinedOutput(); err != nil {
+			s.logger.Error("failed to run hook", logger.Err(fmt.Errorf("%w: %s", err, output)),
+				slog.String("event", event), slog.String("command", command))
+		}
+	}()
+}
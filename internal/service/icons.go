@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/vorlif/spreak/localize"
+	"github.com/wneessen/go-moonphase"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+const (
+	// fontQueryTimeout is the maximum runtime of the fontconfig query for an emoji font
+	fontQueryTimeout = 5 * time.Second
+	// emojiCharset is the code point of a weather emoji, 🌤, that an emoji font has to cover
+	emojiCharset = ":charset=1f324"
+)
+
+// conditionIcon returns the emoji for the WMO weather code or, if no emoji font is available, the
+// localized condition. If configured, a clear night shows the current moon phase instead of the moon.
+func (s *Service) conditionIcon(code float64, isDaytime bool) string {
+	if s.textIcons {
+		return s.conditionText(code)
+	}
+	if !isDaytime && code <= 1 && s.config.Icons.MoonAtNight {
+		return MoonPhaseIcon[moonphase.New(s.clock()).PhaseName()]
+	}
+	return s.conditions.Icon(code, isDaytime)
+}
+
+// conditionText returns the localized description of the WMO weather code.
+func (s *Service) conditionText(code float64) string {
+	return s.t.Get(localize.MsgID(s.conditions.Description(code)))
+}
+
+// temperatureIcon returns the icon of the temperature band of the configured ramp the temperature is in.
+func (s *Service) temperatureIcon(temperature float64) string {
+	band, _ := slices.BinarySearch(s.config.Icons.TemperatureBreakpoints, temperature)
+	return s.config.Icons.TemperatureRamp[band]
+}
+
+// iconWithSpace returns the icon followed by the space needed to separate it from the following text.
+func (s *Service) iconWithSpace(icon string) string {
+	if s.textIcons && icon == "" {
+		return ""
+	}
+	if s.textIcons {
+		return icon + " "
+	}
+	return s.templates.EmojiWithSpace(icon)
+}
+
+// emojiSupported returns whether the condition icons can be rendered as emoji. In auto mode, fontconfig is
+// queried for a font with weather emoji. If fontconfig is not available, emoji are assumed to work.
+func (s *Service) emojiSupported(ctx context.Context) bool {
+	switch s.config.Icons.Glyphs {
+	case "emoji":
+		return true
+	case "text":
+		return false
+	}
+
+	ctxQuery, cancelQuery := context.WithTimeout(ctx, fontQueryTimeout)
+	defer cancelQuery()
+	output, err := exec.CommandContext(ctxQuery, "fc-list", emojiCharset, "family").Output()
+	if err != nil {
+		s.logger.Debug("failed to query fontconfig for an emoji font", logger.Err(err))
+		return true
+	}
+	if len(bytes.TrimSpace(output)) == 0 {
+		s.logger.Warn("no emoji font found, showing the weather conditions as text")
+		return false
+	}
+	return true
+}
+
+// iconPath returns the path of the image icon for the WMO weather code in the configured icon directory.
+// Returns an empty string if no icon directory is configured.
+func (s *Service) iconPath(code float64, isDaytime bool) string {
+	if s.config.Icons.Path == "" {
+		return ""
+	}
+	return filepath.Join(s.config.Icons.Path, s.conditions.ImageName(code, isDaytime)+"."+s.config.Icons.Extension)
+}
+
+// writeIconFile writes the path of the current condition icon to the configured icon file, so that rich
+// bars and widgets can display the image. The file is only written if the icon has changed.
+func (s *Service) writeIconFile(data *template.DisplayData) {
+	if s.config.Icons.File == "" {
+		return
+	}
+
+	s.iconFileLock.Lock()
+	defer s.iconFileLock.Unlock()
+	if data.Current.ConditionIconPath == s.iconFilePath {
+		return
+	}
+	if err := writeFileAtomic(s.config.Icons.File, []byte(data.Current.ConditionIconPath+"\n"), 0o644); err != nil {
+		s.logger.Error("failed to write icon file", logger.Err(err))
+		return
+	}
+	s.iconFilePath = data.Current.ConditionIconPath
+}
+
+// writeFileAtomic writes the data to a temporary file and renames it to the given path, so that readers
+// never see a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename temporary file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	logindService  = "org.freedesktop.login1"
+	logindPath     = "/org/freedesktop/login1"
+	logindInhibit  = "org.freedesktop.login1.Manager.Inhibit"
+	inhibitWhat    = "sleep"
+	inhibitMode    = "delay"
+	inhibitAppName = "waybar-weather"
+)
+
+// sleepInhibitor keeps the system bus connection for the inhibitor locks, so that it is not set up again
+// for every weather update. The connection is established on first use and again after it was lost.
+type sleepInhibitor struct {
+	mu   sync.Mutex
+	conn *dbus.Conn
+}
+
+// inhibitSleep takes a delay inhibitor lock from systemd-logind (or elogind), so that a suspend waits
+// until the returned release function has been called, at most for the InhibitDelayMaxSec of logind. If
+// no lock can be taken, e.g. without logind, the release function does nothing.
+func (s *Service) inhibitSleep(why string) func() {
+	fd, err := s.inhibitor.lock(why)
+	if err != nil {
+		s.logger.Debug("failed to take sleep inhibitor lock", logger.Err(err))
+		return func() {}
+	}
+	return func() {
+		if err := syscall.Close(int(fd)); err != nil {
+			s.logger.Error("failed to release sleep inhibitor lock", logger.Err(err))
+		}
+	}
+}
+
+// lock requests the delay inhibitor lock and returns its file descriptor, which holds the lock until it
+// is closed.
+func (i *sleepInhibitor) lock(why string) (dbus.UnixFD, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.conn == nil || !i.conn.Connected() {
+		conn, err := dbus.ConnectSystemBus()
+		if err != nil {
+			return -1, fmt.Errorf("failed to connect to system bus: %w", err)
+		}
+		i.conn = conn
+	}
+
+	var fd dbus.UnixFD
+	if err := i.conn.Object(logindService, logindPath).Call(logindInhibit, 0, inhibitWhat, inhibitAppName, why,
+		inhibitMode).Store(&fd); err != nil {
+		return -1, fmt.Errorf("failed to request inhibitor lock: %w", err)
+	}
+	return fd, nil
+}
+
+// close closes the system bus connection, if established.
+func (i *sleepInhibitor) close() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.conn == nil {
+		return nil
+	}
+	err := i.conn.Close()
+	i.conn = nil
+	return err
+}
@@ -0,0 +1,21 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build !linux
+
+package service
+
+// sleepInhibitor is not needed without inhibitor lock support.
+type sleepInhibitor struct{}
+
+// inhibitSleep takes a sleep inhibitor lock and returns its release function. Inhibitor locks are only
+// supported with systemd-logind on Linux, so the release function does nothing.
+func (s *Service) inhibitSleep(string) func() {
+	return func() {}
+}
+
+// close does nothing without inhibitor lock support.
+func (i *sleepInhibitor) close() error {
+	return nil
+}
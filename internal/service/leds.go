@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/rules"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+// ledClassPath is the sysfs directory of the LEDs
+const ledClassPath = "/sys/class/leds"
+
+// ledState keeps track of the lit LEDs and the settings they had before, which are restored once their
+// condition is false again.
+type ledState struct {
+	mu    sync.Mutex
+	rules map[string]rules.Rule
+	lit   map[string]ledSettings
+}
+
+// ledSettings are the brightness and, for multicolor LEDs, the channel intensities of a LED.
+type ledSettings struct {
+	brightness string
+	color      string
+}
+
+// runLEDHooks lights the LEDs whose condition became true and restores the LEDs whose condition became
+// false. LEDs are only changed on transitions, so that a manually changed brightness is kept.
+func (s *Service) runLEDHooks(ctx context.Context, data *template.DisplayData) {
+	if len(s.config.Hooks.LEDs) == 0 {
+		return
+	}
+
+	s.leds.mu.Lock()
+	defer s.leds.mu.Unlock()
+	state := classState(data)
+	for _, name := range slices.Sorted(maps.Keys(s.config.Hooks.LEDs)) {
+		hook := s.config.Hooks.LEDs[name]
+		_, lit := s.leds.lit[name]
+		matches := s.leds.rules[name].Matches(state)
+		switch {
+		case matches && !lit && s.quietHours():
+			continue
+		case matches && !lit:
+			previous, err := s.lightLED(ctx, hook.Device, hook.Brightness, hook.Color)
+			if err != nil {
+				s.logger.Error("failed to light LED", logger.Err(err), slog.String("hook", name))
+				continue
+			}
+			s.logger.Debug("lit LED", slog.String("hook", name), slog.String("device", hook.Device))
+			s.leds.lit[name] = previous
+		case !matches && lit:
+			if err := s.setLED(ctx, hook.Device, s.leds.lit[name]); err != nil {
+				s.logger.Error("failed to restore LED", logger.Err(err), slog.String("hook", name))
+			}
+			delete(s.leds.lit, name)
+		}
+	}
+}
+
+// restoreLEDs restores all lit LEDs, e.g. when the service stops.
+func (s *Service) restoreLEDs(ctx context.Context) {
+	s.leds.mu.Lock()
+	defer s.leds.mu.Unlock()
+	for name, previous := range s.leds.lit {
+		if err := s.setLED(ctx, s.config.Hooks.LEDs[name].Device, previous); err != nil {
+			s.logger.Error("failed to restore LED", logger.Err(err), slog.String("hook", name))
+		}
+		delete(s.leds.lit, name)
+	}
+}
+
+// lightLED sets the LED to the given brightness and color and returns its previous settings. A brightness
+// of 0 lights the LED with its maximum brightness.
+func (s *Service) lightLED(ctx context.Context, device string, brightness int, color string) (ledSettings, error) {
+	var previous ledSettings
+	var err error
+	if previous.brightness, err = readLED(device, "brightness"); err != nil {
+		return previous, err
+	}
+	settings := ledSettings{brightness: strconv.Itoa(brightness), color: color}
+	if brightness == 0 {
+		if settings.brightness, err = readLED(device, "max_brightness"); err != nil {
+			return previous, err
+		}
+	}
+	if color != "" {
+		if previous.color, err = readLED(device, "multi_intensity"); err != nil {
+			return previous, err
+		}
+	}
+	return previous, s.setLED(ctx, device, settings)
+}
+
+// setLED writes the color and the brightness of the LED. If the brightness can't be written, e.g. due to
+// missing permissions, it is set with brightnessctl, which falls back to systemd-logind.
+func (s *Service) setLED(ctx context.Context, device string, settings ledSettings) error {
+	if settings.color != "" {
+		if err := os.WriteFile(filepath.Join(ledClassPath, device, "multi_intensity"), []byte(settings.color),
+			0o644); err != nil {
+			return fmt.Errorf("failed to set LED color: %w", err)
+		}
+	}
+	err := os.WriteFile(filepath.Join(ledClassPath, device, "brightness"), []byte(settings.brightness), 0o644)
+	if err == nil {
+		return nil
+	}
+
+	ctxCmd, cancelCmd := context.WithTimeout(ctx, s.config.Hooks.Timeout)
+	defer cancelCmd()
+	output, cmdErr := exec.CommandContext(ctxCmd, "brightnessctl", "--quiet", "--class=leds",
+		"--device="+device, "set", settings.brightness).CombinedOutput()
+	if cmdErr != nil {
+		return fmt.Errorf("failed to set LED brightness: %w (brightnessctl: %w: %s)", err, cmdErr,
+			strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// readLED returns the value of the given sysfs attribute of the LED.
+func readLED(device, attribute string) (string, error) {
+	value, err := os.ReadFile(filepath.Join(ledClassPath, device, attribute))
+	if err != nil {
+		return "", fmt.Errorf("failed to read LED %s: %w", attribute, err)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
@@ -0,0 +1,19 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/geobus/provider/corelocation"
+)
+
+// platformProviders returns the geolocation providers that use the location service of the platform,
+// which is CoreLocation on macOS.
+func (s *Service) platformProviders() []geobus.Provider {
+	if s.config.GeoLocation.DisableCoreLocation {
+		return nil
+	}
+	return []geobus.Provider{corelocation.NewGeolocationCoreLocationProvider(s.logger)}
+}
@@ -0,0 +1,13 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import "github.com/wneessen/waybar-weather/internal/geobus"
+
+// platformProviders returns no geolocation providers on FreeBSD, since GeoClue is only available via the
+// D-Bus library, which requires cgo on FreeBSD.
+func (s *Service) platformProviders() []geobus.Provider {
+	return nil
+}
@@ -0,0 +1,21 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build !darwin && !windows && !freebsd
+
+package service
+
+import (
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/geobus/provider/geoclue"
+)
+
+// platformProviders returns the geolocation providers that use the location service of the platform,
+// which is GeoClue on Linux.
+func (s *Service) platformProviders() []geobus.Provider {
+	if s.config.GeoLocation.DisableGeoClue {
+		return nil
+	}
+	return []geobus.Provider{geoclue.NewGeolocationGeoClueProvider(DesktopID, s.logger)}
+}
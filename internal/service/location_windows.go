@@ -0,0 +1,19 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/geobus/provider/winlocation"
+)
+
+// platformProviders returns the geolocation providers that use the location service of the platform,
+// which is the WinRT Geolocator on Windows.
+func (s *Service) platformProviders() []geobus.Provider {
+	if s.config.GeoLocation.DisableWindowsLocation {
+		return nil
+	}
+	return []geobus.Provider{winlocation.NewGeolocationWindowsProvider(s.logger)}
+}
@@ -4,7 +4,11 @@
 
 package service
 
-import "github.com/vorlif/spreak/localize"
+import (
+	"math"
+
+	"github.com/vorlif/spreak/localize"
+)
 
 // MoonPhaseIcon is a map where moon phase names are keys and their corresponding emoji representations are values.
 var MoonPhaseIcon = map[string]string{
@@ -18,150 +22,91 @@ var MoonPhaseIcon = map[string]string{
 	"Waning Crescent": "🌘",
 }
 
-// WMOWeatherCodes maps WMO weather code integers to their descriptions
-var WMOWeatherCodes = map[float64]localize.MsgID{
-	0:  "Clear sky",
-	1:  "Mainly clear",
-	2:  "Partly cloudy",
-	3:  "Overcast",
-	45: "Fog",
-	48: "Depositing rime fog",
-	51: "Light drizzle",
-	53: "Moderate drizzle",
-	55: "Dense drizzle",
-	56: "Light freezing drizzle",
-	57: "Dense freezing drizzle",
-	61: "Slight rain",
-	63: "Moderate rain",
-	65: "Heavy rain",
-	66: "Light freezing rain",
-	67: "Heavy freezing rain",
-	71: "Slight snow fall",
-	73: "Moderate snow fall",
-	75: "Heavy snow fall",
-	77: "Snow grains",
-	80: "Slight rain showers",
-	81: "Moderate rain showers",
-	82: "Violent rain showers",
-	85: "Slight snow showers",
-	86: "Heavy snow showers",
-	95: "Thunderstorm",
-	96: "Thunderstorm with slight hail",
-	99: "Thunderstorm with heavy hail",
+// SourceAttribution maps the names of the data providers to the attribution line required by their terms of use.
+var SourceAttribution = map[string]string{
+	"open-meteo":       "Weather data by Open-Meteo.com (CC BY 4.0)",
+	"met.no":           "Weather data by MET Norway via Open-Meteo.com (CC BY 4.0)",
+	"weatherkit":       "Weather data by Apple Weather (https://developer.apple.com/weatherkit/data-source-attribution/)",
+	"pirateweather":    "Weather data by Pirate Weather",
+	"brightsky":        "Weather data by Deutscher Wetterdienst via Bright Sky (CC BY 4.0)",
+	"metoffice":        "Contains public sector information licensed under the Open Government Licence (Met Office)",
+	"envcanada":        "Weather data by Environment and Climate Change Canada (Open Government Licence - Canada)",
+	"osm-nominatim":    "Geocoding by Nominatim, © OpenStreetMap contributors (ODbL)",
+	"opencage":         "Geocoding by OpenCage Data",
+	"ichnaea":          "Geolocation by beaconDB",
+	"geoip":            "Geolocation by reallyfreegeoip.org",
+	"geoapi":           "Geolocation by GeoAPI.info",
+	"nws":              "Observations by the U.S. National Weather Service",
+	"open-elevation":   "Elevation data by Open-Elevation",
+	floodSource:        "River discharge by GloFAS via Open-Meteo.com (CC BY 4.0)",
+	satelliteSource:    "Orbital elements by CelesTrak",
+	"earthquakes-usgs": "Earthquakes by U.S. Geological Survey",
+	"earthquakes-emsc": "Earthquakes by EMSC-CSEM",
+}
+
+// TwilightPhases maps the twilight phases to the sun elevation in degrees that ends the phase and to their
+// localized names.
+var TwilightPhases = map[string]struct {
+	Elevation float64
+	Name      localize.MsgID
+}{
+	"civil":        {-6, "Civil twilight"},
+	"nautical":     {-12, "Nautical twilight"},
+	"astronomical": {-18, "Astronomical twilight"},
+}
+
+// CompassPoints lists the abbreviations, full words and arrows of the eight compass points, starting in
+// the north and going clockwise. The arrows point in the direction the wind blows to.
+var CompassPoints = []struct {
+	Abbreviation localize.MsgID
+	Word         localize.MsgID
+	Arrow        string
+}{
+	{"N", "North", "↓"},
+	{"NE", "Northeast", "↙"},
+	{"E", "East", "←"},
+	{"SE", "Southeast", "↖"},
+	{"S", "South", "↑"},
+	{"SW", "Southwest", "↗"},
+	{"W", "West", "→"},
+	{"NW", "Northwest", "↘"},
+}
+
+// BeaufortScale lists the upper wind speed limits in km/h of the Beaufort scale and their descriptions.
+var BeaufortScale = []struct {
+	MaxSpeed    float64
+	Description localize.MsgID
+}{
+	{1, "Calm"},
+	{6, "Light air"},
+	{12, "Light breeze"},
+	{20, "Gentle breeze"},
+	{29, "Moderate breeze"},
+	{39, "Fresh breeze"},
+	{50, "Strong breeze"},
+	{62, "Near gale"},
+	{75, "Gale"},
+	{89, "Strong gale"},
+	{103, "Storm"},
+	{118, "Violent storm"},
+	{math.Inf(1), "Hurricane"},
+}
+
+// precipitationThreshold maps the precipitation unit to the minimum hourly precipitation considered as rain
+// or snow.
+var precipitationThreshold = map[string]float64{
+	"mm":   0.1,
+	"inch": 0.004,
 }
 
-// WMOWeatherIcons maps WMO weather codes to single emoji icons for day (1) and night (0)
-var WMOWeatherIcons = map[float64]map[bool]string{
-	0: {
-		true:  "☀️", // Clear sky (day)
-		false: "🌙",
-	},
-	1: {
-		true:  "🌤️", // Mainly clear (day)
-		false: "🌙",
-	},
-	2: {
-		true:  "⛅", // Partly cloudy
-		false: "☁️",
-	},
-	3: {
-		true:  "☁️", // Overcast
-		false: "☁️",
-	},
-	45: {
-		true:  "🌫️", // Fog
-		false: "🌫️",
-	},
-	48: {
-		true:  "🌫️", // Depositing rime fog
-		false: "🌫️",
-	},
-	51: {
-		true:  "🌦️", // Drizzle: Light
-		false: "🌧️",
-	},
-	53: {
-		true:  "🌧️", // Drizzle: Moderate
-		false: "🌧️",
-	},
-	55: {
-		true:  "🌧️", // Drizzle: Dense intensity
-		false: "🌧️",
-	},
-	56: {
-		true:  "🌨️", // Freezing drizzle: Light
-		false: "🌨️",
-	},
-	57: {
-		true:  "🌨️", // Freezing drizzle: Dense intensity
-		false: "🌨️",
-	},
-	61: {
-		true:  "🌦️", // Rain: Slight
-		false: "🌧️",
-	},
-	63: {
-		true:  "🌧️", // Rain: Moderate
-		false: "🌧️",
-	},
-	65: {
-		true:  "🌧️", // Rain: Heavy
-		false: "🌧️",
-	},
-	66: {
-		true:  "🌨️", // Freezing rain: Light
-		false: "🌨️",
-	},
-	67: {
-		true:  "🌨️", // Freezing rain: Heavy
-		false: "🌨️",
-	},
-	71: {
-		true:  "🌨️", // Snow fall: Slight
-		false: "🌨️",
-	},
-	73: {
-		true:  "🌨️", // Snow fall: Moderate
-		false: "🌨️",
-	},
-	75: {
-		true:  "🌨️", // Snow fall: Heavy
-		false: "🌨️",
-	},
-	77: {
-		true:  "🌨️", // Snow grains
-		false: "🌨️",
-	},
-	80: {
-		true:  "🌦️", // Rain showers: Slight
-		false: "🌧️",
-	},
-	81: {
-		true:  "🌧️", // Rain showers: Moderate
-		false: "🌧️",
-	},
-	82: {
-		true:  "🌧️", // Rain showers: Violent
-		false: "🌧️",
-	},
-	85: {
-		true:  "🌨️", // Snow showers: Slight
-		false: "🌨️",
-	},
-	86: {
-		true:  "🌨️", // Snow showers: Heavy
-		false: "🌨️",
-	},
-	95: {
-		true:  "🌩️", // Thunderstorm: Slight or moderate
-		false: "🌩️",
-	},
-	96: {
-		true:  "⛈️", // Thunderstorm with slight hail
-		false: "⛈️",
-	},
-	99: {
-		true:  "⛈️", // Thunderstorm with heavy hail
-		false: "⛈️",
-	},
+// PrecipitationIntensities lists the upper limits of the hourly precipitation in mm and the descriptions
+// of the precipitation intensity.
+var PrecipitationIntensities = []struct {
+	MaxAmount   float64
+	Description localize.MsgID
+}{
+	{2.5, "Light"},
+	{7.6, "Moderate"},
+	{50, "Heavy"},
+	{math.Inf(1), "Violent"},
 }
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build !nometrics
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	nethttp "net/http"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+// metricsReadTimeout limits the time a client has to send its request to the metrics endpoint.
+const metricsReadTimeout = 5 * time.Second
+
+// serveMetrics serves the metrics in the Prometheus text format on the configured address until the
+// context ends.
+func (s *Service) serveMetrics(ctx context.Context) {
+	mux := nethttp.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w nethttp.ResponseWriter, _ *nethttp.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		s.writeMetrics(w)
+	})
+	server := &nethttp.Server{
+		Addr:              s.config.MetricsListen,
+		Handler:           mux,
+		ReadHeaderTimeout: metricsReadTimeout,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	s.logger.Debug("serving metrics", slog.String("address", s.config.MetricsListen))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
+		s.logger.Error("failed to serve metrics", logger.Err(err))
+	}
+}
+
+// writeMetrics writes the metrics in the Prometheus text format to w.
+func (s *Service) writeMetrics(w io.Writer) {
+	budget := s.budgetStatus()
+	_, _ = fmt.Fprintln(w, "# HELP waybar_weather_api_requests Requests of the current day per API host.")
+	_, _ = fmt.Fprintln(w, "# TYPE waybar_weather_api_requests gauge")
+	for _, host := range budget.Hosts {
+		_, _ = fmt.Fprintf(w, "waybar_weather_api_requests{host=%q} %d\n", host.Host, host.Requests)
+	}
+	_, _ = fmt.Fprintln(w, "# HELP waybar_weather_api_request_limit Daily request limit per API host, 0 is unlimited.")
+	_, _ = fmt.Fprintln(w, "# TYPE waybar_weather_api_request_limit gauge")
+	for _, host := range budget.Hosts {
+		_, _ = fmt.Fprintf(w, "waybar_weather_api_request_limit{host=%q} %d\n", host.Host, host.Limit)
+	}
+	exhausted := 0
+	if budget.Exhausted {
+		exhausted = 1
+	}
+	_, _ = fmt.Fprintln(w, "# HELP waybar_weather_api_budget_exhausted Whether a request has been refused today.")
+	_, _ = fmt.Fprintln(w, "# TYPE waybar_weather_api_budget_exhausted gauge")
+	_, _ = fmt.Fprintf(w, "waybar_weather_api_budget_exhausted %d\n", exhausted)
+
+	if quota, ok := s.geocoderQuota(); ok {
+		_, _ = fmt.Fprintln(w, "# HELP waybar_weather_geocoder_quota_remaining Remaining requests of the geocoder quota.")
+		_, _ = fmt.Fprintln(w, "# TYPE waybar_weather_geocoder_quota_remaining gauge")
+		_, _ = fmt.Fprintf(w, "waybar_weather_geocoder_quota_remaining{geocoder=%q} %d\n", s.geocoder.Name(),
+			quota.Remaining)
+		_, _ = fmt.Fprintln(w, "# HELP waybar_weather_geocoder_quota_limit Request limit of the geocoder quota.")
+		_, _ = fmt.Fprintln(w, "# TYPE waybar_weather_geocoder_quota_limit gauge")
+		_, _ = fmt.Fprintf(w, "waybar_weather_geocoder_quota_limit{geocoder=%q} %d\n", s.geocoder.Name(), quota.Limit)
+	}
+}
@@ -0,0 +1,16 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build nometrics
+
+package service
+
+import (
+	"context"
+)
+
+// serveMetrics is a no-op, since the metrics endpoint is not part of builds with the nometrics tag.
+func (s *Service) serveMetrics(context.Context) {
+	s.logger.Warn("metrics endpoint is not available in this build, rebuild without the nometrics tag")
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+const (
+	// modelMetaURL is the metadata endpoint of an Open-Meteo weather model
+	modelMetaURL = "https://api.open-meteo.com/data/%s/static/meta.json"
+
+	// StaleModelClass is the CSS class emitted if the run of the weather model is older than the max age
+	StaleModelClass = "stale-model"
+)
+
+// metaModels maps the names of the forecast models to the names of their metadata at Open-Meteo, where
+// they differ. The seamless models are mapped to the global model they are based on.
+var metaModels = map[string]string{
+	"icon_seamless":            "dwd_icon",
+	"icon_global":              "dwd_icon",
+	"icon_eu":                  "dwd_icon_eu",
+	"icon_d2":                  "dwd_icon_d2",
+	"gfs_seamless":             "ncep_gfs025",
+	"gfs_global":               "ncep_gfs025",
+	"gfs_hrrr":                 "ncep_hrrr_conus",
+	"ecmwf_aifs025":            "ecmwf_aifs025_single",
+	"gem_seamless":             "cmc_gem_gdps",
+	"gem_global":               "cmc_gem_gdps",
+	"gem_regional":             "cmc_gem_rdps",
+	"gem_hrdps_continental":    "cmc_gem_hrdps",
+	"jma_seamless":             "jma_gsm",
+	"metno_seamless":           "metno_nordic_pp",
+	"metno_nordic":             "metno_nordic_pp",
+	"meteofrance_seamless":     "meteofrance_arpege_world025",
+	"meteofrance_arpege_world": "meteofrance_arpege_world025",
+	"meteofrance_arome_france": "meteofrance_arome_france0025",
+	"ukmo_seamless":            "ukmo_global_deterministic_10km",
+}
+
+// metaModel returns the name of the metadata of the given forecast model at Open-Meteo.
+func metaModel(model string) string {
+	if meta, ok := metaModels[model]; ok {
+		return meta
+	}
+	return model
+}
+
+// modelMeta represents the metadata of an Open-Meteo weather model, reduced to its latest run.
+type modelMeta struct {
+	InitialisationTime int64 `json:"last_run_initialisation_time"`
+	AvailabilityTime   int64 `json:"last_run_availability_time"`
+}
+
+// modelRunState holds the latest run of the configured weather model.
+type modelRunState struct {
+	mu   sync.RWMutex
+	data template.ModelRunData
+}
+
+// fetchModelRun fetches the metadata of the latest run of the weather model the forecast is requested
+// from. If Open-Meteo selects the model or the backend doesn't support the model selection, there is no
+// single model run to look up. If the lookup fails, the model run is reported as not available rather than
+// showing a run that might be outdated. The caller is expected to hold the backend lock.
+func (s *Service) fetchModelRun(ctx context.Context) {
+	model := s.weatherModel(s.backend)
+	if model == "" || model == defaultWeatherModel {
+		s.modelRun.mu.Lock()
+		s.modelRun.data = template.ModelRunData{}
+		s.modelRun.mu.Unlock()
+		return
+	}
+	data := template.ModelRunData{Model: model}
+	var meta modelMeta
+	client := newHTTPClient(s.config, s.logger, s.budget)
+	metaURL := fmt.Sprintf(modelMetaURL, url.PathEscape(metaModel(model)))
+	if _, err := client.Get(ctx, metaURL, &meta, nil); err != nil {
+		s.logger.Error("failed to get weather model metadata", logger.Err(err))
+	} else if meta.InitialisationTime > 0 {
+		data.Available = true
+		data.Initialized = time.Unix(meta.InitialisationTime, 0)
+		data.Published = time.Unix(meta.AvailabilityTime, 0)
+	}
+
+	s.modelRun.mu.Lock()
+	defer s.modelRun.mu.Unlock()
+	s.modelRun.data = data
+}
+
+// modelRunData returns the latest run of the weather model in the local time zone with its age at the
+// given time.
+func (s *Service) modelRunData(now time.Time) template.ModelRunData {
+	s.modelRun.mu.RLock()
+	defer s.modelRun.mu.RUnlock()
+	data := s.modelRun.data
+	if !data.Available {
+		return data
+	}
+	data.Initialized = data.Initialized.In(now.Location())
+	data.Published = data.Published.In(now.Location())
+	// The system time may be set before the model run, which must not result in a negative age
+	age := max(now.Sub(data.Initialized), 0)
+	data.AgeHours = age.Hours()
+	data.Stale = age > s.config.Weather.ModelRunMaxAge
+	return data
+}
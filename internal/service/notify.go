@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+const (
+	// notifyDefaultTimeout lets the notification server decide when the notification expires
+	notifyDefaultTimeout = int32(-1)
+	// notifyNoTimeout keeps the notification until it is dismissed
+	notifyNoTimeout = int32(0)
+	// notifyUrgencyLow is the urgency level for notifications that are not important
+	notifyUrgencyLow = byte(0)
+)
+
+// notifyHints are the hints of a desktop notification, like the urgency or the synchronous tag.
+type notifyHints map[string]any
+
+// updateWeatherCard pushes the weather card notification, which shows the weather details as a
+// persistent notification (e.g. in the SwayNC control center). The notification is updated in place
+// on every weather update.
+func (s *Service) updateWeatherCard(ctx context.Context) {
+	defer s.logger.RecoverPanic("weather_card")
+	if ctx.Err() != nil {
+		return
+	}
+
+	displayData := new(template.DisplayData)
+	if !s.fillDisplayData(displayData) {
+		return
+	}
+	s.templates.ApplyPrecision(displayData)
+
+	summary := bytes.NewBuffer(nil)
+	if err := s.templates.Text.Execute(summary, displayData); err != nil {
+		s.logger.Error("failed to render text template", logger.Err(err))
+		return
+	}
+	body := bytes.NewBuffer(nil)
+	if err := s.templates.Card.Execute(body, displayData); err != nil {
+		s.logger.Error("failed to render weather card template", logger.Err(err))
+		return
+	}
+
+	s.cardLock.Lock()
+	defer s.cardLock.Unlock()
+	hints := notifyHints{"urgency": notifyUrgencyLow, "resident": true}
+	id, err := sendNotification(s.cardID, summary.String(), body.String(), notifyNoTimeout, hints)
+	if err != nil {
+		s.logger.Error("failed to update weather card", logger.Err(err))
+		return
+	}
+	s.cardID = id
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"slices"
+	"sync"
+)
+
+// outputState keeps the last output of the module and of each attached instance.
+type outputState struct {
+	mu   sync.Mutex
+	last map[string]Output
+}
+
+// outputChanged returns true if the output differs from the last output of the instance, which is empty
+// for the output of the module itself. The values are formatted at display precision when they are rendered,
+// so an unchanged output means that nothing visible has changed and Waybar doesn't have to lay out the module
+// again. Always returns true if emit_unchanged is set and in on-demand mode, where every output has been
+// requested explicitly.
+func (s *Service) outputChanged(instance string, result Output) bool {
+	if s.config.EmitUnchanged || s.onDemand() {
+		return true
+	}
+	s.outputs.mu.Lock()
+	defer s.outputs.mu.Unlock()
+	last, ok := s.outputs.last[instance]
+	return !ok || !last.equal(result)
+}
+
+// recordOutput records the output as the last output of the instance. It is only called once the output has
+// been written successfully, so that an output that failed to be written is written again on the next update
+// instead of being considered unchanged.
+func (s *Service) recordOutput(instance string, result Output) {
+	s.outputs.mu.Lock()
+	defer s.outputs.mu.Unlock()
+	if s.outputs.last == nil {
+		s.outputs.last = make(map[string]Output)
+	}
+	s.outputs.last[instance] = result
+}
+
+// equal returns true if both outputs render identically.
+func (o Output) equal(other Output) bool {
+	if o.Text != other.Text || o.Alt != other.Alt || o.Tooltip != other.Tooltip ||
+		!slices.Equal(o.Class, other.Class) {
+		return false
+	}
+	if o.Percentage == nil || other.Percentage == nil {
+		return o.Percentage == other.Percentage
+	}
+	return *o.Percentage == *other.Percentage
+}
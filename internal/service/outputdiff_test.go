@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// failingWriter records the outputs written to it and fails the first fail writes.
+type failingWriter struct {
+	fail    int
+	written []Output
+}
+
+func (f *failingWriter) Write(v any) error {
+	if f.fail > 0 {
+		f.fail--
+		return errors.New("write failed")
+	}
+	f.written = append(f.written, v.(Output))
+	return nil
+}
+
+func TestService_printWeather_unchanged(t *testing.T) {
+	t.Run("an unchanged output is not written again", func(t *testing.T) {
+		serv := newGoldenService(t, "json", "en", "metric", 61)
+		writer := &failingWriter{}
+		serv.output = writer
+		serv.printWeather(context.Background())
+		serv.printWeather(context.Background())
+		if len(writer.written) != 1 {
+			t.Errorf("expected the output to be written once, got %d writes", len(writer.written))
+		}
+	})
+	t.Run("an output that failed to be written is written again", func(t *testing.T) {
+		serv := newGoldenService(t, "json", "en", "metric", 61)
+		writer := &failingWriter{fail: 1}
+		serv.output = writer
+		serv.printWeather(context.Background())
+		serv.printWeather(context.Background())
+		if len(writer.written) != 1 {
+			t.Errorf("expected the output to be written on the second update, got %d writes", len(writer.written))
+		}
+	})
+}
@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/plugin"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+// runPlugins sends the current weather state to all configured plugins and stores their results, which
+// are merged into the module output. A failing plugin keeps its previous result.
+func (s *Service) runPlugins(ctx context.Context) {
+	defer s.logger.RecoverPanic("plugins")
+
+	state := new(template.DisplayData)
+	if !s.fillDisplayData(state) {
+		return
+	}
+
+	results := make(map[string]plugin.Result, len(s.plugins))
+	for _, p := range s.plugins {
+		result, err := p.Run(ctx, state)
+		if err != nil {
+			s.logger.Error("failed to run plugin", logger.Err(err), slog.String("plugin", p.Name()))
+			continue
+		}
+		results[p.Name()] = result
+	}
+
+	s.pluginLock.Lock()
+	defer s.pluginLock.Unlock()
+	if s.pluginResults == nil {
+		s.pluginResults = make(map[string]plugin.Result, len(results))
+	}
+	for name, result := range results {
+		s.pluginResults[name] = result
+	}
+}
+
+// pluginOutput returns the tooltip lines and CSS classes returned by the plugins, in the order of
+// the plugin names.
+func (s *Service) pluginOutput() ([]string, []string) {
+	s.pluginLock.RLock()
+	defer s.pluginLock.RUnlock()
+
+	var tooltip, classes []string
+	for _, p := range s.plugins {
+		result, ok := s.pluginResults[p.Name()]
+		if !ok {
+			continue
+		}
+		tooltip = append(tooltip, result.Tooltip...)
+		classes = append(classes, result.Class...)
+	}
+	return tooltip, classes
+}
@@ -0,0 +1,37 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import "github.com/wneessen/waybar-weather/internal/template"
+
+// pressureUnit describes a configurable unit of the air pressure.
+type pressureUnit struct {
+	symbol string
+	// perHectopascal is the factor to convert the pressure from hPa, as returned by the weather API
+	perHectopascal float64
+	// precision is the number of decimals of the formatted pressure
+	precision int
+}
+
+// pressureUnits maps the configurable pressure units to their symbol, conversion factor and precision.
+var pressureUnits = map[string]pressureUnit{
+	"hpa":  {"hPa", 1, 0},
+	"inhg": {"inHg", 0.0295300, 2},
+	"mmhg": {"mmHg", 0.750062, 0},
+}
+
+// fillPressure sets the air pressure of the weather data at the given index of the hourly metrics in
+// the configured unit. The pressure selected by the configuration, i. e. at sea level or at the surface,
+// is additionally formatted with the separators of the language.
+func (s *Service) fillPressure(snap *stateSnapshot, target *template.WeatherData, idx int) {
+	unit := pressureUnits[s.config.PressureUnit]
+	target.PressureMSL = snap.weather.HourlyMetrics["pressure_msl"][idx] * unit.perHectopascal
+	target.SurfacePressure = snap.weather.HourlyMetrics["surface_pressure"][idx] * unit.perHectopascal
+	target.Pressure = target.PressureMSL
+	if s.config.Pressure == "surface" {
+		target.Pressure = target.SurfacePressure
+	}
+	target.PressureText = s.templates.NumberFormat(target.Pressure, unit.precision)
+}
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/event"
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	profileCheckInterval = time.Minute
+)
+
+// monitorProfile periodically determines the active config profile and applies it if it changed.
+// If a profile is configured manually, it is applied once. Otherwise, the profile is selected by
+// the SSID of the Wi-Fi network NetworkManager is connected to.
+func (s *Service) monitorProfile(ctx context.Context) {
+	if s.config.Profile != "" {
+		s.applyProfile(ctx, s.config.Profile)
+		return
+	}
+
+	for {
+		ssid, err := s.connectedSSID()
+		if err != nil {
+			s.logger.Debug("failed to determine connected Wi-Fi network", logger.Err(err))
+		}
+		s.applyProfile(ctx, s.profileForSSID(ssid))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(profileCheckInterval):
+		}
+	}
+}
+
+// profileForSSID returns the name of the first profile that lists the given SSID, or an empty
+// string if no profile matches.
+func (s *Service) profileForSSID(ssid string) string {
+	if ssid == "" {
+		return ""
+	}
+	names := make([]string, 0, len(s.config.Profiles))
+	for name := range s.config.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if slices.Contains(s.config.Profiles[name].SSIDs, ssid) {
+			return name
+		}
+	}
+	return ""
+}
+
+// applyProfile activates the given profile (or the defaults if the name is empty), if it is not
+// already active. The intervals are rescheduled, the weather backend is selected and the location is
+// updated accordingly.
+func (s *Service) applyProfile(ctx context.Context, name string) {
+	s.profileLock.Lock()
+	previous := s.activeProfile
+	if previous == name {
+		s.profileLock.Unlock()
+		return
+	}
+	s.activeProfile = name
+	s.profileLock.Unlock()
+	s.logger.Info("activating config profile", slog.String("profile", name))
+	defer s.events.Publish(event.ConfigReloaded, "profile")
+
+	profile := s.config.Profiles[name]
+	s.applyIntervals(ctx)
+	// The weather is fetched from the new backend with the location update
+	if profile.Backend != s.config.Profiles[previous].Backend {
+		s.selectBackend()
+	}
+
+	// Use the static location of the profile or fall back to the best geobus result
+	if profile.HasLocation() {
+		if err := s.updateLocation(ctx, profile.Latitude, profile.Longitude, nil, 0, "profile"); err != nil {
+			s.logger.Error("failed to apply profile location", logger.Err(err))
+		}
+		return
+	}
+	if best, ok := s.geobus.Best(DesktopID); ok {
+		if err := s.updateLocation(ctx, best.Lat, best.Lon, best.Altitude(), best.AccuracyMeters, best.Source); err != nil {
+			s.logger.Error("failed to apply geo update", logger.Err(err), slog.String("source", best.Source))
+		}
+	}
+}
+
+// applyIntervals reschedules the output and weather update jobs with the intervals of the active
+// profile. While a fullscreen window is focused, the reduced fullscreen intervals apply instead, unless
+// the profile intervals are even longer. In on-demand mode, there are no jobs to reschedule.
+func (s *Service) applyIntervals(ctx context.Context) {
+	if s.onDemand() {
+		return
+	}
+	s.profileLock.RLock()
+	profile, fullscreen := s.config.Profiles[s.activeProfile], s.fullscreen
+	s.profileLock.RUnlock()
+
+	outputInterval, weatherInterval := s.config.Intervals.Output, s.config.Intervals.WeatherUpdate
+	if profile.Intervals.Output > 0 {
+		outputInterval = profile.Intervals.Output
+	}
+	if profile.Intervals.WeatherUpdate > 0 {
+		weatherInterval = profile.Intervals.WeatherUpdate
+	}
+	if fullscreen {
+		outputInterval = max(outputInterval, s.config.Fullscreen.Output)
+		weatherInterval = max(weatherInterval, s.config.Fullscreen.WeatherUpdate)
+	}
+	if err := s.rescheduleJob(ctx, outputInterval, s.printWeather, outputJobName); err != nil {
+		s.logger.Error("failed to apply output interval", logger.Err(err))
+	}
+	if err := s.rescheduleJob(ctx, weatherInterval, s.fetchWeather, weatherUpdateJobName); err != nil {
+		s.logger.Error("failed to apply weather update interval", logger.Err(err))
+	}
+}
+
+// profileHasLocation returns true if the active profile defines a static location.
+func (s *Service) profileHasLocation() bool {
+	s.profileLock.RLock()
+	defer s.profileLock.RUnlock()
+	return s.activeProfile != "" && s.config.Profiles[s.activeProfile].HasLocation()
+}
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/config"
+)
+
+// quietHours returns true if notifications and hooks are currently suppressed by the configured quiet
+// hours. The quiet hours apply in the time zone of the weather location, falling back to the system time
+// zone until the weather data is available.
+func (s *Service) quietHours() bool {
+	now := s.localNow()
+	s.weatherLock.RLock()
+	if s.timezone != nil {
+		now = now.In(s.timezone)
+	}
+	s.weatherLock.RUnlock()
+	return isQuiet(s.config, now)
+}
+
+// isQuiet returns true if the given time is within the quiet hours of the config. A range that passes
+// midnight belongs to the weekday it starts on.
+func isQuiet(conf *config.Config, now time.Time) bool {
+	quiet := conf.QuietHours
+	if slices.Contains(quiet.Dates, now.Format(time.DateOnly)) || slices.Contains(quiet.Dates, now.Format("01-02")) ||
+		slices.Contains(quiet.AllDay, config.Weekdays[now.Weekday()]) {
+		return true
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	today := len(quiet.Weekdays) == 0 || slices.Contains(quiet.Weekdays, config.Weekdays[now.Weekday()])
+	yesterday := len(quiet.Weekdays) == 0 || slices.Contains(quiet.Weekdays, config.Weekdays[(now.Weekday()+6)%7])
+	for _, period := range quiet.Ranges {
+		startText, endText, _ := strings.Cut(period, "-")
+		start, errStart := time.Parse("15:04", strings.TrimSpace(startText))
+		end, errEnd := time.Parse("15:04", strings.TrimSpace(endText))
+		if errStart != nil || errEnd != nil {
+			continue
+		}
+		from, until := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+		switch {
+		case from <= until && today && minute >= from && minute < until:
+			return true
+		case from > until && ((today && minute >= from) || (yesterday && minute < until)):
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/nathan-osman/go-sunrise"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/satellite"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+const (
+	celestrakURL         = "https://celestrak.org/NORAD/elements/gp.php"
+	satelliteJobName     = "satellite_update_job"
+	satellitePassJobName = "satellite_pass_job"
+	satelliteSource      = "celestrak"
+	// satelliteRecompute is the interval in which the passes are predicted again in the background, since
+	// the prediction of large groups of satellites is expensive
+	satelliteRecompute = 10 * time.Minute
+	// satelliteMaxMove is the distance in degrees the location may move before the passes no longer apply
+	// and are predicted again
+	satelliteMaxMove = 0.01
+)
+
+// satelliteState holds the orbital elements of the configured satellites and the passes predicted for the
+// location of the last prediction. The prediction runs in the background, so predictLock serializes the
+// predictions without blocking the output, which only reads the predicted passes.
+type satelliteState struct {
+	mu          sync.RWMutex
+	predictLock sync.Mutex
+	elements    []satellite.Elements
+	passes      []satellite.Pass
+	coordinate  geobus.Coordinate
+}
+
+// createSatelliteJob schedules the update of the orbital elements, starting immediately, and the periodic
+// prediction of the passes.
+func (s *Service) createSatelliteJob(ctx context.Context) error {
+	if err := s.addJob(ctx, satelliteJobName, gocron.DurationJob(s.config.Satellites.Interval),
+		s.fetchSatellites, gocron.WithStartAt(gocron.WithStartImmediately())); err != nil {
+		return err
+	}
+	return s.addJob(ctx, satellitePassJobName, gocron.DurationJob(satelliteRecompute), s.predictSatellitePasses)
+}
+
+// fetchSatellites fetches the orbital elements of the configured CelesTrak group and keeps the elements of
+// the satellites whose names start with one of the configured names. On failure, the previous elements
+// are kept.
+func (s *Service) fetchSatellites(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, FetchTimeout)
+	defer cancel()
+	query := url.Values{"GROUP": {s.config.Satellites.Group}, "FORMAT": {"json"}}
+	var elements []satellite.Elements
+	if s.astronomyCache == nil || !s.astronomyCache.Get("satellites/"+s.config.Satellites.Group, &elements) {
+		client := newHTTPClient(s.config, s.logger, s.budget)
+		if _, err := client.Get(ctx, celestrakURL+"?"+query.Encode(), &elements, nil); err != nil {
+			s.logger.Error("failed to get orbital elements", logger.Err(err),
+				slog.String("group", s.config.Satellites.Group))
+			return
+		}
+		if s.astronomyCache != nil {
+			if err := s.astronomyCache.Set("satellites/"+s.config.Satellites.Group, elements); err != nil {
+				s.logger.Warn("failed to cache orbital elements", logger.Err(err))
+			}
+		}
+	}
+	elements = slices.DeleteFunc(elements, func(element satellite.Elements) bool {
+		return !slices.ContainsFunc(s.config.Satellites.Names, func(name string) bool {
+			return strings.HasPrefix(strings.ToLower(element.Name), strings.ToLower(name))
+		})
+	})
+	if len(elements) == 0 {
+		s.logger.Warn("no configured satellite found in group", slog.String("group", s.config.Satellites.Group))
+	}
+
+	s.satellites.mu.Lock()
+	s.satellites.elements = elements
+	s.satellites.mu.Unlock()
+	s.predictSatellitePasses(ctx)
+}
+
+// predictSatellitePasses predicts tonight's visible passes of the configured satellites at the current
+// location. The prediction can take a while for large groups of satellites, so it is done in the
+// background and the previous passes are served meanwhile.
+func (s *Service) predictSatellitePasses(context.Context) {
+	s.satellites.predictLock.Lock()
+	defer s.satellites.predictLock.Unlock()
+
+	s.locationLock.RLock()
+	coordinate, locationIsSet := s.coordinates, s.locationIsSet
+	s.locationLock.RUnlock()
+	s.satellites.mu.RLock()
+	elements := s.satellites.elements
+	s.satellites.mu.RUnlock()
+	if !locationIsSet || len(elements) == 0 {
+		return
+	}
+
+	now := s.localNow()
+	observer := satellite.Observer{Latitude: coordinate.Lat, Longitude: coordinate.Lon, Altitude: coordinate.Alt}
+	end := tonightEnd(coordinate.Lat, coordinate.Lon, now)
+	var passes []satellite.Pass
+	for _, element := range elements {
+		for _, pass := range satellite.Passes(element, observer, now, end, s.config.Satellites.MinElevation) {
+			if pass.Visible {
+				passes = append(passes, pass)
+			}
+		}
+	}
+	slices.SortFunc(passes, func(a, b satellite.Pass) int { return a.Rise.Compare(b.Rise) })
+
+	s.satellites.mu.Lock()
+	defer s.satellites.mu.Unlock()
+	s.satellites.passes, s.satellites.coordinate = passes, coordinate
+}
+
+// satellitePassesMoved returns true if the current location has moved away from the location of the last
+// prediction of the passes.
+func (s *Service) satellitePassesMoved() bool {
+	s.locationLock.RLock()
+	coordinate := s.coordinates
+	s.locationLock.RUnlock()
+	s.satellites.mu.RLock()
+	defer s.satellites.mu.RUnlock()
+	return satelliteMoved(s.satellites.coordinate, coordinate)
+}
+
+// satelliteMoved returns true if the distance between the coordinates exceeds satelliteMaxMove.
+func satelliteMoved(a, b geobus.Coordinate) bool {
+	return math.Abs(a.Lat-b.Lat) > satelliteMaxMove || math.Abs(a.Lon-b.Lon) > satelliteMaxMove
+}
+
+// satellitePasses returns tonight's remaining visible passes of the configured satellites at the given
+// coordinates, in the time zone of now. Only the predicted passes are read; if the location has moved since
+// the last prediction, no passes are returned until they have been predicted for the new location.
+func (s *Service) satellitePasses(coordinate geobus.Coordinate, now time.Time) []template.SatellitePassData {
+	s.satellites.mu.RLock()
+	defer s.satellites.mu.RUnlock()
+	if satelliteMoved(s.satellites.coordinate, coordinate) {
+		return nil
+	}
+
+	var data []template.SatellitePassData
+	for _, pass := range s.satellites.passes {
+		if pass.Set.Before(now) {
+			continue
+		}
+		data = append(data, template.SatellitePassData{
+			Name:          pass.Name,
+			Rise:          pass.Rise.In(now.Location()),
+			Peak:          pass.Peak.In(now.Location()),
+			Set:           pass.Set.In(now.Location()),
+			MaxElevation:  pass.MaxElevation,
+			RiseAzimuth:   pass.RiseAzimuth,
+			RiseDirection: s.compassAbbreviation(pass.RiseAzimuth),
+			SetAzimuth:    pass.SetAzimuth,
+			SetDirection:  s.compassAbbreviation(pass.SetAzimuth),
+		})
+		if len(data) == s.config.Satellites.MaxPasses {
+			break
+		}
+	}
+	return data
+}
+
+// tonightEnd returns the end of tonight at the given coordinates, which is the next sunrise during the
+// night and the sunrise after the next sunset during the day. Close to the poles, where the sun might not
+// rise or set, tonight ends a day from now.
+func tonightEnd(latitude, longitude float64, now time.Time) time.Time {
+	_, elevation := sunPosition(latitude, longitude, now)
+	night := elevation < sunriseElevation
+	for day := range 3 {
+		date := now.UTC().AddDate(0, 0, day)
+		sunriseTime, sunsetTime := sunrise.SunriseSunset(latitude, longitude, date.Year(), date.Month(), date.Day())
+		if night && sunriseTime.After(now) {
+			return sunriseTime
+		}
+		night = night || sunsetTime.After(now)
+	}
+	return now.Add(24 * time.Hour)
+}
@@ -6,20 +6,43 @@ package service
 
 import (
 	"bytes"
+	"cmp"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
+	"math"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/vorlif/spreak"
 
+	"github.com/wneessen/waybar-weather/internal/cache"
+	"github.com/wneessen/waybar-weather/internal/conditions"
 	"github.com/wneessen/waybar-weather/internal/config"
+	"github.com/wneessen/waybar-weather/internal/control"
+	"github.com/wneessen/waybar-weather/internal/coordinate"
+	"github.com/wneessen/waybar-weather/internal/elevation"
+	"github.com/wneessen/waybar-weather/internal/elevation/provider/openelevation"
+	elevationom "github.com/wneessen/waybar-weather/internal/elevation/provider/openmeteo"
+	"github.com/wneessen/waybar-weather/internal/event"
+	"github.com/wneessen/waybar-weather/internal/forecast"
+	"github.com/wneessen/waybar-weather/internal/forecast/provider/brightsky"
+	"github.com/wneessen/waybar-weather/internal/forecast/provider/envcanada"
+	"github.com/wneessen/waybar-weather/internal/forecast/provider/metoffice"
+	"github.com/wneessen/waybar-weather/internal/forecast/provider/pirateweather"
+	"github.com/wneessen/waybar-weather/internal/forecast/provider/weatherkit"
+	"github.com/wneessen/waybar-weather/internal/forecastdiff"
 	"github.com/wneessen/waybar-weather/internal/geobus"
 	"github.com/wneessen/waybar-weather/internal/geobus/provider/geoapi"
 	"github.com/wneessen/waybar-weather/internal/geobus/provider/geoip"
@@ -31,47 +54,243 @@ import (
 	nominatim "github.com/wneessen/waybar-weather/internal/geocode/provider/osm-nominatim"
 	"github.com/wneessen/waybar-weather/internal/http"
 	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/observation"
+	"github.com/wneessen/waybar-weather/internal/observation/provider/nws"
+	"github.com/wneessen/waybar-weather/internal/output"
+	"github.com/wneessen/waybar-weather/internal/plugin"
+	"github.com/wneessen/waybar-weather/internal/rules"
 	"github.com/wneessen/waybar-weather/internal/template"
 
 	"github.com/go-co-op/gocron/v2"
+	"github.com/google/uuid"
 	"github.com/hectormalot/omgo"
 	"github.com/nathan-osman/go-sunrise"
 	"github.com/wneessen/go-moonphase"
 )
 
 const (
-	OutputClass = "waybar-weather"
-	DesktopID   = "waybar-weather"
+	OutputClass   = "waybar-weather"
+	DegradedClass = "degraded"
+	DesktopID     = "waybar-weather"
+
+	// DayClass and NightClass are emitted depending on the current time of day
+	DayClass   = "day"
+	NightClass = "night"
+	// StaleClass is emitted if the last weather update failed and older weather data is shown
+	StaleClass = "stale"
+
+	panicRestartDelay = 5 * time.Second
+
+	// outputQueueSize is the number of outputs that are queued while the reader of the output is stalled
+	outputQueueSize = 1
+
+	// weatherProbeTimeout is the maximum time the check whether the weather API is reachable may take
+	weatherProbeTimeout = 10 * time.Second
+	// weatherExclusion and maxWeatherExclusion define the backoff for a weather backend that failed the probe
+	weatherExclusion    = time.Minute
+	maxWeatherExclusion = 30 * time.Minute
+
+	// tonightStartHour and tonightDuration define the time range considered as "tonight"
+	tonightStartHour = 18
+	tonightDuration  = 12 * time.Hour
+
+	// Conversion factors for speeds and the imperial units
+	kmhPerMeterPerSecond = 3.6
+	metersPerMile        = 1609.344
+	millimetersPerInch   = 25.4
+	feetPerMeter         = 3.28084
+
+	// precipitationLookahead is the number of hours we look ahead for the start or end of precipitation
+	precipitationLookahead = 24
+
+	outputJobName        = "weatherdata_output_job"
+	weatherUpdateJobName = "weather_update_job"
+	briefingJobName      = "morning_briefing_job"
+
+	// defaultWeatherModel is the model selection of Open-Meteo if no model is configured
+	defaultWeatherModel = "best_match"
+	clockJobName        = "clock_output_job"
+
+	// modelBackend is the only weather backend the configured weather model is requested from, since the
+	// other backends, like met.no, serve the data of their own model
+	modelBackend = "open-meteo"
 )
 
-type outputData struct {
-	Text    string `json:"text"`
-	Tooltip string `json:"tooltip"`
-	Class   string `json:"class"`
+// Output represents the module output of a single update in the format expected by Waybar.
+type Output struct {
+	Text    string   `json:"text"`
+	Alt     string   `json:"alt"`
+	Tooltip string   `json:"tooltip"`
+	Class   []string `json:"class"`
+	// Percentage is only emitted if configured, since Waybar would otherwise select the format icon by 0
+	Percentage *int `json:"percentage,omitempty"`
+}
+
+// String returns the text of the output, as written in the text output format.
+func (o Output) String() string {
+	return o.Text
+}
+
+// ewwOutput is the module output in the eww output format. The CSS classes are a single string, so that
+// they can be passed to the :class property of an eww widget as is.
+type ewwOutput struct {
+	Text       string `json:"text"`
+	Alt        string `json:"alt"`
+	Tooltip    string `json:"tooltip"`
+	Class      string `json:"class"`
+	Percentage *int   `json:"percentage,omitempty"`
+}
+
+// ewwWriter writes the outputs as JSON objects in the eww output format, one per line, for deflisten.
+type ewwWriter struct {
+	lines *output.JSONLines
+}
+
+// Write writes v, which has to be an Output, in the eww output format.
+func (e ewwWriter) Write(v any) error {
+	out, ok := v.(Output)
+	if !ok {
+		return fmt.Errorf("failed to write output: unexpected output type %T", v)
+	}
+	return e.lines.Write(ewwOutput{
+		Text: out.Text, Alt: out.Alt, Tooltip: out.Tooltip, Class: strings.Join(out.Class, " "),
+		Percentage: out.Percentage,
+	})
 }
 
 type Service struct {
+	budget       *http.Budget
 	config       *config.Config
+	elevation    elevation.Provider
+	events       *event.Bus
 	geobus       *geobus.GeoBus
 	logger       *logger.Logger
 	geocoder     geocode.Geocoder
-	omclient     omgo.Client
+	observer     observation.Observer
+	output       output.Writer
+	plugins      []*plugin.Plugin
+	classRules   []rules.Rule
 	orchestrator *geobus.Orchestrator
 	scheduler    gocron.Scheduler
 	templates    *template.Templates
 	t            *spreak.Localizer
+	stop         context.CancelFunc
 
-	locationLock  sync.RWMutex
-	address       geocode.Address
-	locationIsSet bool
-	location      omgo.Location
+	locationLock   sync.RWMutex
+	address        geocode.Address
+	addressIsStale bool
+	locationIsSet  bool
+	location       omgo.Location
+	coordinates    geobus.Coordinate
+	geoSource      string
+	// Time of the last location update
+	locationUpdated time.Time
+
+	// Most recent geolocation result, including deferred ones, for the motion data
+	fixLock sync.RWMutex
+	lastFix geobus.Result
+
+	// Content of the location cache file written last
+	locationCacheLock    sync.Mutex
+	locationCacheWritten *cachedLocation
 
 	weatherLock  sync.RWMutex
 	weatherIsSet bool
 	weather      *omgo.Forecast
+	// Weather backend the weather data was fetched from
+	weatherSource string
+	timezone      *time.Location
+	observation   *observation.Observation
+
+	// Active weather backend, locked for the duration of a weather update
+	backendLock sync.RWMutex
+	backend     string
+	omclient    omgo.Client
+	// HTTP clients of the Open-Meteo client, with and without the configured weather model
+	omHTTP, omModelHTTP *http.Client
+	// Weather backends whose API is not Open-Meteo compatible, by name
+	providers map[string]forecast.Provider
+	// Descriptions, icons and categories of the WMO weather codes
+	conditions *conditions.Table
+
+	displayModeLock sync.RWMutex
+	displayMode     int
+
+	jobLock sync.Mutex
+	jobs    map[string]scheduledJob
+
+	profileLock   sync.RWMutex
+	activeProfile string
+	fullscreen    bool
 
-	displayAltLock sync.RWMutex
-	displayAltText bool
+	hooks hookState
+	leds  ledState
+
+	pluginLock    sync.RWMutex
+	pluginResults map[string]plugin.Result
+
+	localZoneLock sync.RWMutex
+	localZone     *time.Location
+
+	// Condition icons are rendered as text, since no emoji font is available
+	textIcons bool
+
+	clockUnsynced     atomic.Bool
+	clockCheckRunning atomic.Bool
+
+	cardLock sync.Mutex
+	cardID   uint32
+
+	inhibitor sleepInhibitor
+
+	geofenceLock   sync.Mutex
+	geofenceInside map[string]bool
+	place          string
+	// Weather backend selected by the geofences, empty if none of them selects one
+	placeBackend string
+
+	iconFileLock sync.Mutex
+	iconFilePath string
+
+	// Requests to restart the geolocation providers and to re-compute the sun transitions
+	relocate chan struct{}
+	sunWake  chan struct{}
+
+	announcements announceState
+	briefing      briefingState
+	deltas        deltaState
+	trip          tripState
+	modelRun      modelRunState
+	alerts        alertState
+	outputs       outputState
+	ensemble      ensembleState
+	forecastDiff  forecastDiffState
+	flood         floodState
+	quakes        earthquakeState
+	satellites    satelliteState
+	// Tracks the first forecast of the day, nil if the forecast changes are disabled
+	forecastTracker *forecastdiff.Tracker
+
+	server         *control.Server
+	stdoutDisabled bool
+	once           bool
+	// Output, output formatter and geolocation providers set by applications that embed the service
+	customOutput    output.Writer
+	outputFormatter func(Output) Output
+	extraProviders  []geobus.Provider
+	// clock returns the current time for the displayed data, so that the output can be reproduced
+	clock func() time.Time
+
+	started time.Time
+	startup startupTrace
+	fetches fetchState
+
+	// Caches of the API results, the weather cache is nil if disabled
+	caches       []*cache.Store
+	weatherCache *cache.Store
+	// Caches of the weather warnings and the orbital elements of the satellites
+	alertCache     *cache.Store
+	astronomyCache *cache.Store
 }
 
 func New(conf *config.Config, log *logger.Logger, t *spreak.Localizer) (*Service, error) {
@@ -80,52 +299,305 @@ func New(conf *config.Config, log *logger.Logger, t *spreak.Localizer) (*Service
 		return nil, fmt.Errorf("failed to create scheduler: %w", err)
 	}
 
+	budget := http.NewBudget(conf.APIBudget.Hosts, conf.APIBudget.Daily, conf.APIBudget.StateFile)
 	omclient, err := omgo.NewClient()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Open-Meteo client: %w", err)
 	}
+	omHTTP := newHTTPClient(conf, log, budget)
+	omModelHTTP := omHTTP.WithQuery(weatherModelQuery(conf))
+	omclient.Client = omHTTP.Client
+	if conf.Weather.Backend == modelBackend {
+		omclient.Client = omModelHTTP.Client
+	}
+	if backendURL, ok := conf.WeatherBackendURL(conf.Weather.Backend); ok {
+		omclient.URL = backendURL
+	}
+	conditionTable, err := conditions.Load(conf.Conditions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load condition table: %w", err)
+	}
+	providers := make(map[string]forecast.Provider)
+	if weatherKit := conf.Weather.WeatherKit; weatherKit.KeyFile != "" {
+		provider, err := weatherkit.New(newHTTPClient(conf, log, budget), weatherKit.TeamID, weatherKit.ServiceID,
+			weatherKit.KeyID, weatherKit.KeyFile, conditionTable.Backend("weatherkit"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create WeatherKit backend: %w", err)
+		}
+		providers[provider.Name()] = provider
+	}
+	if apikey := conf.Weather.PirateWeather.APIKey; apikey != "" {
+		provider := pirateweather.New(newHTTPClient(conf, log, budget), apikey, conditionTable.Backend("pirateweather"))
+		providers[provider.Name()] = provider
+	}
+	if metOffice := conf.Weather.MetOffice; metOffice.APIKey != "" {
+		provider := metoffice.New(newHTTPClient(conf, log, budget), metOffice.APIKey, metOffice.WarningsRegion,
+			conditionTable.Backend("metoffice"))
+		providers[provider.Name()] = provider
+	}
+	brightSky := brightsky.New(newHTTPClient(conf, log, budget))
+	providers[brightSky.Name()] = brightSky
+	envCanada := envcanada.New(newHTTPClient(conf, log, budget), conditionTable.Backend("envcanada"))
+	providers[envCanada.Name()] = envCanada
 
 	tpls, err := template.NewTemplate(conf, t)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse templates: %w", err)
 	}
 
+	var cacheBackend cache.Backend = cache.NewMemory()
+	if conf.Cache.Backend == "file" {
+		if cacheBackend, err = cache.NewFile(conf.Cache.Dir); err != nil {
+			return nil, fmt.Errorf("failed to create cache: %w", err)
+		}
+	}
+	var caches []*cache.Store
+	newStore := func(namespace string, ttl time.Duration) *cache.Store {
+		if ttl == 0 {
+			return nil
+		}
+		store := cache.NewStore(cacheBackend, namespace, ttl)
+		caches = append(caches, store)
+		return store
+	}
+
 	var geocoder geocode.Geocoder
 	switch strings.ToLower(conf.GeoCoder.Provider) {
 	case "nominatim":
-		geocoder = nominatim.New(http.New(log), t.Language())
+		geocoder = nominatim.New(newHTTPClient(conf, log, budget), t.Language())
 	case "opencage":
 		if conf.GeoCoder.APIKey == "" {
 			return nil, fmt.Errorf("opencage geocoder requires an API key")
 		}
-		geocoder = opencage.New(http.New(log), t.Language(), conf.GeoCoder.APIKey)
+		geocoder = opencage.New(newHTTPClient(conf, log, budget), t.Language(), conf.GeoCoder.APIKey)
 	default:
 		return nil, fmt.Errorf("unsupported geocoder type: %s", conf.GeoCoder.Provider)
 	}
+	if store := newStore("geocode", conf.Cache.Geocode); store != nil {
+		geocoder = geocode.NewCache(geocoder, store)
+	}
+
+	var observer observation.Observer
+	switch strings.ToLower(conf.Weather.ObservationSource) {
+	case "":
+	case "nws":
+		observer = nws.New(newHTTPClient(conf, log, budget))
+	default:
+		return nil, fmt.Errorf("unsupported observation source: %s", conf.Weather.ObservationSource)
+	}
+
+	var elevationProvider elevation.Provider
+	switch strings.ToLower(conf.Weather.ElevationSource) {
+	case "":
+	case "open-meteo":
+		elevationProvider = elevationom.New(newHTTPClient(conf, log, budget))
+	case "open-elevation":
+		elevationProvider = openelevation.New(newHTTPClient(conf, log, budget))
+	default:
+		return nil, fmt.Errorf("unsupported elevation source: %s", conf.Weather.ElevationSource)
+	}
+	if elevationProvider != nil {
+		if store := newStore("elevation", conf.Cache.Elevation); store != nil {
+			elevationProvider = elevation.NewCache(elevationProvider, store)
+		}
+	}
+	weatherCache := newStore("weather", conf.Cache.Weather)
+	alertCache := newStore("alerts", conf.Cache.Alerts)
+	astronomyCache := newStore("astronomy", conf.Cache.Astronomy)
+
+	plugins := make([]*plugin.Plugin, 0, len(conf.Plugins))
+	for _, name := range slices.Sorted(maps.Keys(conf.Plugins)) {
+		plugins = append(plugins, plugin.New(name, conf.Plugins[name].Command, conf.Plugins[name].Timeout))
+	}
+
+	classRules := make([]rules.Rule, 0, len(conf.Templates.ClassRules))
+	for _, rule := range conf.Templates.ClassRules {
+		parsed, err := rules.Parse(rule)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse class rule: %w", err)
+		}
+		classRules = append(classRules, parsed)
+	}
+	ledRules := make(map[string]rules.Rule, len(conf.Hooks.LEDs))
+	for name, hook := range conf.Hooks.LEDs {
+		parsed, err := rules.ParseExpression(hook.When)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse condition of LED hook %s: %w", name, err)
+		}
+		ledRules[name] = parsed
+	}
 
 	service := &Service{
+		budget:         budget,
 		config:         conf,
+		elevation:      elevationProvider,
 		geocoder:       geocoder,
+		observer:       observer,
+		events:         event.New(log),
 		geobus:         geobus.New(log),
 		logger:         log,
+		backend:        conf.Weather.Backend,
+		caches:         caches,
+		weatherCache:   weatherCache,
+		alertCache:     alertCache,
+		astronomyCache: astronomyCache,
 		omclient:       omclient,
+		omHTTP:         omHTTP,
+		omModelHTTP:    omModelHTTP,
+		providers:      providers,
+		conditions:     conditionTable,
+		plugins:        plugins,
+		classRules:     classRules,
+		leds:           ledState{rules: ledRules, lit: make(map[string]ledSettings)},
+		relocate:       make(chan struct{}, 1),
+		sunWake:        make(chan struct{}, 1),
 		scheduler:      scheduler,
 		templates:      tpls,
 		t:              t,
-		displayAltText: false,
+		displayMode:    0,
+		jobs:           make(map[string]scheduledJob),
+		localZone:      time.Local,
+		clock:          time.Now,
+	}
+	if diff := conf.ForecastDiff; diff.Temperature > 0 || diff.PrecipitationProbability > 0 {
+		service.forecastTracker, err = forecastdiff.New(diff.StateFile, forecastdiff.Thresholds{
+			Temperature:              diff.Temperature,
+			PrecipitationProbability: diff.PrecipitationProbability,
+		})
+		if err != nil {
+			log.Warn("failed to restore forecast reference", logger.Err(err))
+		}
 	}
+	service.server = control.NewServer(conf.SocketPath(), slices.Sorted(maps.Keys(conf.Instances)),
+		func() any { return service.Status() }, service.SwitchBackend, log)
 	return service, nil
 }
 
+// newHTTPClient returns a new HTTP client subject to the request budget, which sends the requests of the
+// APIs with a configured endpoint to that endpoint.
+func newHTTPClient(conf *config.Config, log *logger.Logger, budget *http.Budget) *http.Client {
+	client := http.New(log, budget)
+	redirected, err := client.WithEndpoints(conf.Endpoints)
+	if err != nil {
+		log.Error("failed to apply API endpoints", logger.Err(err))
+		return client
+	}
+	return redirected
+}
+
+// weatherModelQuery returns the query parameters that select the configured weather model.
+func weatherModelQuery(conf *config.Config) url.Values {
+	if conf.Weather.Model == "" {
+		return nil
+	}
+	return url.Values{"models": {conf.Weather.Model}}
+}
+
+// weatherModel returns the weather model the forecast of the given backend is requested from, or an empty
+// string if the model of the backend can't be selected.
+func (s *Service) weatherModel(backend string) string {
+	if backend != modelBackend {
+		return ""
+	}
+	return cmp.Or(s.config.Weather.Model, defaultWeatherModel)
+}
+
+// SetOutput replaces the output to stdout with the given writer, which receives every update as Output.
+func (s *Service) SetOutput(w output.Writer) {
+	s.customOutput = w
+}
+
+// SetFormatter sets a function that post-processes the output of every update before it is written, both
+// to stdout and to the writer set by SetOutput. The outputs served to attached instances are not affected.
+func (s *Service) SetFormatter(format func(Output) Output) {
+	s.outputFormatter = format
+}
+
+// AddProvider adds a geolocation provider to the providers enabled in the configuration.
+func (s *Service) AddProvider(p geobus.Provider) {
+	s.extraProviders = append(s.extraProviders, p)
+}
+
+// SetClock replaces the clock the displayed data is based on, e.g. to render the output for a fixed
+// point in time.
+func (s *Service) SetClock(clock func() time.Time) {
+	s.clock = clock
+}
+
+// ExitAfterFirstOutput makes the service exit once the first output has been written, e.g. for status
+// bars that run a command periodically and read its output.
+func (s *Service) ExitAfterFirstOutput() {
+	s.once = true
+}
+
+// DisableStdout disables the output to stdout, so that the weather data is only served to the instances
+// attached via the control socket.
+func (s *Service) DisableStdout() {
+	s.stdoutDisabled = true
+}
+
 func (s *Service) Run(ctx context.Context) error {
-	// Start scheduled jobs
-	if err := s.createScheduledJob(ctx, s.config.Intervals.Output, s.printWeather,
-		"weatherdata_output_job"); err != nil {
-		return err
+	// The service stops itself if the output has been closed
+	ctx, s.stop = context.WithCancel(ctx)
+	defer s.stop()
+	defer func() {
+		if err := s.inhibitor.close(); err != nil {
+			s.logger.Error("failed to close system bus connection", logger.Err(err))
+		}
+	}()
+	s.started = time.Now()
+	s.startup.start = s.started
+
+	s.textIcons = !s.emojiSupported(ctx)
+
+	// Write errors for a closed stdout instead of terminating the process with SIGPIPE
+	signal.Notify(make(chan os.Signal, 1), syscall.SIGPIPE)
+	s.output = output.Discard
+	switch {
+	case s.customOutput != nil:
+		s.output = output.NewAsync(ctx, s.customOutput, s.logger, outputQueueSize)
+	case s.once:
+		// Write synchronously, so that the output is complete when the service exits
+		s.output = s.stdoutWriter()
+	case !s.stdoutDisabled:
+		s.output = output.NewAsync(ctx, s.stdoutWriter(), s.logger, outputQueueSize)
+	}
+
+	// Start scheduled jobs, the output and weather updates are requested by signal in on-demand mode
+	if !s.onDemand() {
+		if err := s.createScheduledJob(ctx, s.config.Intervals.Output, s.printWeather,
+			outputJobName); err != nil {
+			return err
+		}
+		if err := s.createScheduledJob(ctx, s.config.Intervals.WeatherUpdate, s.fetchWeather,
+			weatherUpdateJobName); err != nil {
+			return err
+		}
+	}
+	if s.config.Briefing.Time != "" {
+		if err := s.createBriefingJob(ctx); err != nil {
+			return err
+		}
+	}
+	if s.config.HasFlood() {
+		if err := s.createFloodJob(ctx); err != nil {
+			return err
+		}
+	}
+	if s.config.Earthquakes.Source != "" {
+		if err := s.createEarthquakeJob(ctx); err != nil {
+			return err
+		}
 	}
-	if err := s.createScheduledJob(ctx, s.config.Intervals.WeatherUpdate, s.fetchWeather,
-		"weather_update_job"); err != nil {
-		return err
+	if s.config.Satellites.Enabled {
+		if err := s.createSatelliteJob(ctx); err != nil {
+			return err
+		}
+	}
+	if s.config.Templates.Clock && !s.onDemand() {
+		if err := s.createClockJob(ctx); err != nil {
+			return err
+		}
 	}
 	s.scheduler.Start()
 
@@ -133,282 +605,1146 @@ func (s *Service) Run(ctx context.Context) error {
 	if err := s.templates.Text.Execute(bytes.NewBuffer(nil), template.DisplayData{}); err != nil {
 		return fmt.Errorf("failed to render text template: %w", err)
 	}
-	if err := s.templates.AltText.Execute(bytes.NewBuffer(nil), template.DisplayData{}); err != nil {
-		return fmt.Errorf("failed to render alt text template: %w", err)
+	for _, mode := range s.templates.Modes[1:] {
+		if err := mode.Template.Execute(bytes.NewBuffer(nil), template.DisplayData{}); err != nil {
+			return fmt.Errorf("failed to render %s template: %w", mode.Name, err)
+		}
 	}
 	if err := s.templates.Tooltip.Execute(bytes.NewBuffer(nil), template.DisplayData{}); err != nil {
 		return fmt.Errorf("failed to render tooltip template: %w", err)
 	}
+	for name, instance := range s.templates.Instances {
+		for _, mode := range instance.Modes {
+			if err := mode.Template.Execute(bytes.NewBuffer(nil), template.DisplayData{}); err != nil {
+				return fmt.Errorf("failed to render %s template of instance %s: %w", mode.Name, name, err)
+			}
+		}
+		if err := instance.Tooltip.Execute(bytes.NewBuffer(nil), template.DisplayData{}); err != nil {
+			return fmt.Errorf("failed to render tooltip template of instance %s: %w", name, err)
+		}
+	}
+	if err := s.templates.Briefing.Execute(bytes.NewBuffer(nil), template.DisplayData{}); err != nil {
+		return fmt.Errorf("failed to render briefing template: %w", err)
+	}
+	if err := s.templates.Card.Execute(bytes.NewBuffer(nil), template.DisplayData{}); err != nil {
+		return fmt.Errorf("failed to render weather card template: %w", err)
+	}
+
+	// Subscribe the subsystems to the events before any of them is published
+	s.subscribeEvents(ctx)
+
+	// Check whether the weather API is reachable, the geolocation providers are probed by the orchestrator
+	go s.probeWeatherProvider(ctx)
 
 	// Create the orchestrator
 	s.orchestrator = s.createOrchestrator()
 
 	// Subscribe to geolocation updates from the geobus
 	sub, unsub := s.geobus.Subscribe(DesktopID, 32)
-	go s.processLocationUpdates(ctx, sub)
-	go s.orchestrator.Track(ctx, DesktopID)
+	s.goSupervised(ctx, "location_updates", func(ctx context.Context) { s.processLocationUpdates(ctx, sub) })
+	s.goSupervised(ctx, "geobus_orchestrator", s.trackLocation)
 
-	// Set up signal handler for SIGUSR1 to toggle alt text display
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGUSR1)
-	go s.handleAltTextToggleSignal(ctx, sigChan)
+	// Set up signal handler for SIGUSR1 to cycle through the display modes, if the platform supports it
+	if len(displayModeSignals) > 0 {
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, displayModeSignals...)
+		s.goSupervised(ctx, "display_mode_cycle", func(ctx context.Context) {
+			s.handleDisplayModeSignal(ctx, sigChan)
+		})
+	}
+
+	// Write the output on request via the configured real-time signal
+	if s.onDemand() {
+		sig, ok := realtimeSignal(s.config.Intervals.OutputSignal)
+		if !ok {
+			return errors.New("output signal is not supported on this platform")
+		}
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, sig)
+		s.goSupervised(ctx, "output_signal", func(ctx context.Context) { s.handleOutputSignal(ctx, sigChan) })
+	}
 
 	// Detect sleep/wake events and update the weather
-	go s.monitorSleepResume(ctx)
+	s.goSupervised(ctx, "sleep_monitor", s.monitorSleepResume)
+
+	// Detect changes of the system time and re-evaluate the time-dependent output
+	s.goSupervised(ctx, "time_jump_monitor", s.monitorTimeJumps)
+
+	// Detect a (re-)established network connection and update the weather
+	s.goSupervised(ctx, "network_monitor", s.monitorNetwork)
+
+	// Don't trust the system clock for the day/night calculations until it is synchronized
+	s.goSupervised(ctx, "clock_sync", s.waitForClockSync)
+
+	// Follow changes of the system time zone
+	s.goSupervised(ctx, "timezone_monitor", s.monitorTimezone)
+
+	// Switch the day/night state at sunrise and sunset instead of the next output
+	if !s.onDemand() {
+		s.goSupervised(ctx, "sun_transitions", s.monitorSunTransitions)
+	}
+
+	// Serve the status and the output of the additional instances via the control socket
+	s.goSupervised(ctx, "control_socket", func(ctx context.Context) {
+		err := s.server.Listen(ctx)
+		if errors.Is(err, control.ErrInUse) {
+			s.logger.Warn("control socket is in use by another process, status and instances are not available",
+				logger.Err(err))
+			return
+		}
+		if err != nil {
+			s.logger.Error("failed to listen on control socket", logger.Err(err))
+		}
+	})
+
+	// Reduce the updates while a fullscreen window is focused
+	if s.config.Fullscreen.Enabled {
+		s.goSupervised(ctx, "fullscreen_monitor", s.monitorFullscreen)
+	}
+
+	// Select the config profile manually or by the connected Wi-Fi network
+	if len(s.config.Profiles) > 0 {
+		s.goSupervised(ctx, "profile_monitor", s.monitorProfile)
+	}
+
+	// Serve the metrics via HTTP
+	if s.config.MetricsListen != "" {
+		s.goSupervised(ctx, "metrics_endpoint", s.serveMetrics)
+	}
 
 	// Wait for the context to cancel
 	<-ctx.Done()
 	if unsub != nil {
 		unsub()
 	}
-	return s.scheduler.Shutdown()
+	s.budget.Flush()
+	err := s.scheduler.Shutdown()
+	s.restoreLEDs(context.Background())
+	return err
+}
+
+// probeWeatherProvider checks whether the API of the active weather backend is reachable. While the probe
+// fails, the backend is excluded from the weather updates and probed again with an increasing backoff.
+// Once the probe succeeds, the exclusion is lifted and the weather data is fetched right away.
+func (s *Service) probeWeatherProvider(ctx context.Context) {
+	defer s.logger.RecoverPanic("weather_probe")
+	exclusion := weatherExclusion
+	for {
+		s.backendLock.RLock()
+		backend, backendURL := s.backend, s.backendEndpoint()
+		s.backendLock.RUnlock()
+		ctxProbe, cancelProbe := context.WithTimeout(ctx, weatherProbeTimeout)
+		err := newHTTPClient(s.config, s.logger, s.budget).Probe(ctxProbe, backendURL)
+		cancelProbe()
+		if err == nil {
+			s.logger.Info("weather provider is usable", slog.String("provider", backend))
+			if s.setBackendExcluded(backend, time.Time{}) {
+				s.fetchWeather(ctx)
+			}
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.setBackendExcluded(backend, time.Now().Add(exclusion))
+		s.logger.Warn("weather provider is not usable, excluding it", slog.String("provider", backend),
+			logger.Err(err), slog.Duration("retry_in", exclusion))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(exclusion):
+		}
+		exclusion = min(2*exclusion, maxWeatherExclusion)
+	}
 }
 
 func (s *Service) createOrchestrator() *geobus.Orchestrator {
-	httpClient := http.New(s.logger)
+	httpClient := newHTTPClient(s.config, s.logger, s.budget)
 	var provider []geobus.Provider
 
 	if !s.config.GeoLocation.DisableGeolocationFile {
-		provider = append(provider, geolocation_file.NewGeolocationFileProvider(s.config.GeoLocation.File))
+		provider = append(provider, geolocation_file.NewGeolocationFileProvider(s.config.GeoLocation.File,
+			s.logger))
 	}
 
+	provider = append(provider, s.platformProviders()...)
+
 	if !s.config.GeoLocation.DisableGPSD {
-		provider = append(provider, gpsd.NewGeolocationGPSDProvider())
+		provider = append(provider, gpsd.NewGeolocationGPSDProvider(s.logger))
 	}
 
 	if !s.config.GeoLocation.DisableGeoIP {
-		provider = append(provider, geoip.NewGeolocationGeoIPProvider(httpClient))
+		provider = append(provider, geoip.NewGeolocationGeoIPProvider(httpClient, s.logger))
 	}
 
 	if !s.config.GeoLocation.DisableGeoAPI {
-		provider = append(provider, geoapi.NewGeolocationGeoAPIProvider(httpClient))
+		provider = append(provider, geoapi.NewGeolocationGeoAPIProvider(httpClient, s.logger))
 	}
 
 	if !s.config.GeoLocation.DisableICHNAEA {
-		mls, err := ichnaea.NewGeolocationICHNAEAProvider(httpClient)
+		mls, err := ichnaea.NewGeolocationICHNAEAProvider(httpClient, s.logger)
 		if err != nil {
 			s.logger.Error("failed to create ICHNAEA provider", logger.Err(err))
 		} else {
 			provider = append(provider, mls)
 		}
 	}
+	provider = append(provider, s.extraProviders...)
 	if len(provider) == 0 {
 		s.logger.Error(s.t.Get("no geolocation providers enabled, will not be able to fetch weather data " + "" +
 			"due to missing location"))
 	}
 
-	return s.geobus.NewOrchestrator(provider)
+	orchestrator := s.geobus.NewOrchestrator(provider)
+	orchestrator.Workers = s.config.GeoLocation.Workers
+	return orchestrator
+}
+
+// createClockJob creates the job that re-renders the output at the start of every minute, so that the
+// time shown by the templates is always up to date.
+func (s *Service) createClockJob(ctx context.Context) error {
+	return s.addJob(ctx, clockJobName, gocron.CronJob("* * * * *", false), s.printWeather)
 }
 
 func (s *Service) createScheduledJob(ctx context.Context, interval time.Duration, task func(context.Context),
 	jobName string,
 ) error {
-	_, err := s.scheduler.NewJob(
-		gocron.DurationJob(interval),
-		gocron.NewTask(task),
-		gocron.WithContext(ctx),
-		gocron.WithSingletonMode(gocron.LimitModeReschedule),
-		gocron.WithName(jobName),
-	)
+	return s.addJob(ctx, jobName, gocron.DurationJob(interval), task)
+}
+
+// addJob creates a scheduled job and registers it with its definition by name, so that it can be
+// rescheduled later.
+func (s *Service) addJob(ctx context.Context, jobName string, definition gocron.JobDefinition,
+	task func(context.Context), options ...gocron.JobOption,
+) error {
+	job, err := s.scheduler.NewJob(definition, s.jobTask(task, jobName),
+		append(s.jobOptions(ctx, jobName), options...)...)
 	if err != nil {
 		return fmt.Errorf("failed to create %s: %w", jobName, err)
 	}
+	s.jobLock.Lock()
+	s.jobs[jobName] = scheduledJob{id: job.ID(), definition: definition, task: task}
+	s.jobLock.Unlock()
+	return nil
+}
+
+// rescheduleJob updates the interval of a previously created scheduled job.
+func (s *Service) rescheduleJob(ctx context.Context, interval time.Duration, task func(context.Context),
+	jobName string,
+) error {
+	s.jobLock.Lock()
+	defer s.jobLock.Unlock()
+	scheduled, ok := s.jobs[jobName]
+	if !ok {
+		return fmt.Errorf("job %s does not exist", jobName)
+	}
+	definition := gocron.DurationJob(interval)
+	job, err := s.scheduler.Update(scheduled.id, definition, s.jobTask(task, jobName),
+		s.jobOptions(ctx, jobName)...)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", jobName, err)
+	}
+	s.jobs[jobName] = scheduledJob{id: job.ID(), definition: definition, task: task}
 	return nil
 }
 
+// scheduledJob is a job of the scheduler with its definition and task.
+type scheduledJob struct {
+	id         uuid.UUID
+	definition gocron.JobDefinition
+	task       func(context.Context)
+}
+
+// jobTask wraps the task of a scheduled job with a panic handler.
+func (s *Service) jobTask(task func(context.Context), jobName string) gocron.Task {
+	return gocron.NewTask(func(ctx context.Context) {
+		defer s.logger.RecoverPanic(jobName)
+		task(ctx)
+	})
+}
+
+// jobOptions returns the options used for all scheduled jobs.
+func (s *Service) jobOptions(ctx context.Context, jobName string) []gocron.JobOption {
+	return []gocron.JobOption{
+		gocron.WithContext(ctx),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+		gocron.WithName(jobName),
+	}
+}
+
+// goSupervised runs the given subsystem in a new goroutine. If the subsystem panics, the panic is logged
+// and the subsystem is restarted after a short delay, until the context is cancelled.
+func (s *Service) goSupervised(ctx context.Context, name string, fn func(context.Context)) {
+	go func() {
+		for s.runRecovered(ctx, name, fn) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(panicRestartDelay):
+				s.logger.Info("restarting subsystem after panic", slog.String("subsystem", name))
+			}
+		}
+	}()
+}
+
+// runRecovered runs the given subsystem and recovers from a potential panic. Returns true if the
+// subsystem panicked.
+func (s *Service) runRecovered(ctx context.Context, name string, fn func(context.Context)) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.LogPanic(name, r)
+			panicked = true
+		}
+	}()
+	fn(ctx)
+	return false
+}
+
 // printWeather outputs the current weather data to stdout if available and renders it using predefined templates.
-func (s *Service) printWeather(context.Context) {
-	if !s.weatherIsSet {
+func (s *Service) printWeather(ctx context.Context) {
+	endTrace := s.startup.begin("render")
+	displayData := new(template.DisplayData)
+	if !s.fillDisplayData(displayData) {
 		return
 	}
 
-	s.displayAltLock.RLock()
-	displayAltText := s.displayAltText
-	s.displayAltLock.RUnlock()
+	s.displayModeLock.RLock()
+	modeIdx := s.displayMode
+	s.displayModeLock.RUnlock()
+	s.runHooks(ctx, displayData)
+	s.runLEDHooks(ctx, displayData)
+	s.writeIconFile(displayData)
+	s.announceChanges(ctx, displayData)
+	s.notifyDeltaAlerts(displayData)
+	s.templates.ApplyPrecision(displayData)
 
-	displayData := new(template.DisplayData)
-	s.fillDisplayData(displayData)
+	pluginTooltip, pluginClasses := s.pluginOutput()
+	for _, name := range slices.Sorted(maps.Keys(s.templates.Instances)) {
+		instance := s.templates.Instances[name]
+		result, err := s.renderOutput(displayData, instance, modeIdx, pluginTooltip, pluginClasses)
+		if err != nil {
+			s.logger.Error("failed to render output", logger.Err(err), slog.String("instance", name))
+			continue
+		}
+		if !s.outputChanged(name, result) {
+			continue
+		}
+		if err = s.server.Publish(name, result); err != nil {
+			s.logger.Warn("failed to publish output", logger.Err(err), slog.String("instance", name))
+			continue
+		}
+		s.recordOutput(name, result)
+	}
 
-	textBuf := bytes.NewBuffer(nil)
-	if err := s.templates.Text.Execute(textBuf, displayData); err != nil {
-		s.logger.Error("failed to render text template", logger.Err(err))
+	global := template.Instance{Modes: s.templates.Modes, Tooltip: s.templates.Tooltip}
+	result, err := s.renderOutput(displayData, global, modeIdx, pluginTooltip, pluginClasses)
+	if err != nil {
+		s.logger.Error("failed to render output", logger.Err(err))
 		return
 	}
-
-	altTextBuf := bytes.NewBuffer(nil)
-	if err := s.templates.AltText.Execute(altTextBuf, displayData); err != nil {
-		s.logger.Error("failed to render alt text template", logger.Err(err))
+	if s.outputFormatter != nil {
+		result = s.outputFormatter(result)
+	}
+	endTrace("")
+	if !s.outputChanged("", result) {
+		return
+	}
+	if err = s.output.Write(result); err != nil {
+		s.logger.Error("failed to write weather data", logger.Err(err))
+		if errors.Is(err, output.ErrBrokenPipe) {
+			s.logger.Info("output has been closed, shutting down")
+			s.stop()
+		}
 		return
 	}
+	s.recordOutput("", result)
+	s.logStartupTrace()
+	if s.once {
+		s.stop()
+	}
+}
+
+// stdoutWriter returns the writer for stdout in the configured output format.
+func (s *Service) stdoutWriter() output.Writer {
+	return s.formatWriter(os.Stdout)
+}
+
+// formatWriter returns the writer for w in the configured output format.
+func (s *Service) formatWriter(w io.Writer) output.Writer {
+	switch s.config.OutputFormat {
+	case "text":
+		return output.NewTextLines(w)
+	case "polybar":
+		executable, err := os.Executable()
+		if err != nil {
+			return output.NewPolybarLines(w, "")
+		}
+		return output.NewPolybarLines(w, "pkill -USR1 -x "+filepath.Base(executable))
+	case "eww":
+		return ewwWriter{lines: output.NewJSONLines(w)}
+	}
+	return output.NewJSONLines(w)
+}
+
+// renderOutput renders the module output with the templates of the given instance and display mode.
+func (s *Service) renderOutput(displayData *template.DisplayData, instance template.Instance, modeIdx int,
+	pluginTooltip, pluginClasses []string,
+) (Output, error) {
+	mode := instance.Modes[modeIdx]
+	textBuf := bytes.NewBuffer(nil)
+	if err := mode.Template.Execute(textBuf, displayData); err != nil {
+		return Output{}, fmt.Errorf("failed to render %s template: %w", mode.Name, err)
+	}
 
 	tooltipBuf := bytes.NewBuffer(nil)
-	if err := s.templates.Tooltip.Execute(tooltipBuf, displayData); err != nil {
-		s.logger.Error("failed to render tooltip template", logger.Err(err))
-		return
+	if err := instance.Tooltip.Execute(tooltipBuf, displayData); err != nil {
+		return Output{}, fmt.Errorf("failed to render tooltip template: %w", err)
+	}
+	if len(pluginTooltip) > 0 {
+		tooltipBuf.WriteString("\n" + strings.Join(pluginTooltip, "\n"))
 	}
 
-	var displayText string
-	if displayAltText {
-		displayText = altTextBuf.String()
-	} else {
-		displayText = textBuf.String()
+	if s.config.Templates.Attribution && len(displayData.Attribution) > 0 {
+		tooltipBuf.WriteString("\n\n" + strings.Join(displayData.Attribution, "\n"))
 	}
 
-	output := outputData{
-		Text:    displayText,
+	result := Output{
+		Text:    textBuf.String(),
+		Alt:     mode.Name,
 		Tooltip: tooltipBuf.String(),
-		Class:   OutputClass,
 	}
+	if s.config.ASCIIOnly {
+		result.Text = template.ToASCII(result.Text)
+		result.Tooltip = template.ToASCII(result.Tooltip)
+	}
+	timeOfDayClass := NightClass
+	if displayData.Current.IsDaytime {
+		timeOfDayClass = DayClass
+	}
+	// The condition, time of day and stale classes are prefixed, all other classes keep their legacy names
+	category := s.conditions.Category(displayData.Current.WeatherCode)
+	prefixed := []string{category, timeOfDayClass}
+	if group := displayData.Current.ConditionGroup; group != category {
+		prefixed = append(prefixed, group)
+	}
+	if s.config.Templates.Alt == "condition_group" {
+		result.Alt = displayData.Current.ConditionGroup
+	}
+	if s.weatherStale() {
+		prefixed = append(prefixed, StaleClass)
+	}
+	result.Class = append(result.Class, OutputClass)
+	for _, class := range prefixed {
+		result.Class = append(result.Class, s.config.Templates.ClassPrefix+class)
+	}
+	if s.budget.Exhausted() {
+		result.Class = append(result.Class, DegradedClass)
+	}
+	if displayData.ModelRun.Stale {
+		result.Class = append(result.Class, StaleModelClass)
+	}
+	if displayData.Ventilation.Drying {
+		result.Class = append(result.Class, VentilationDryClass)
+	}
+	if displayData.Ventilation.Humidifying {
+		result.Class = append(result.Class, VentilationHumidClass)
+	}
+	if percentage, ok := s.percentage(displayData); ok {
+		result.Percentage = &percentage
+	}
+	if len(displayData.Alerts) > 0 {
+		result.Class = append(result.Class, WeatherAlertClass)
+	}
+	if displayData.Umbrella.Needed {
+		result.Class = append(result.Class, UmbrellaClass)
+	}
+	if class := floodClass(displayData.Flood); class != "" {
+		result.Class = append(result.Class, class)
+	}
+	result.Class = append(result.Class, deltaAlertClasses(displayData.DeltaAlerts)...)
+	result.Class = append(result.Class, pluginClasses...)
+	result.Class = append(result.Class, rules.Classes(s.classRules, classState(displayData))...)
+	return result, nil
+}
 
-	if err := json.NewEncoder(os.Stdout).Encode(output); err != nil {
-		s.logger.Error("failed to encode weather data", logger.Err(err))
+// percentage returns the configured value to emit as percentage of the output, clamped to 0 to 100.
+func (s *Service) percentage(data *template.DisplayData) (int, bool) {
+	var value float64
+	switch s.config.Templates.Percentage {
+	case "sun":
+		value = data.SunProgress
+	case "humidity":
+		value = data.Current.Humidity
+	case "precipitation_probability":
+		value = data.Today.PrecipitationProbability
+	default:
+		return 0, false
 	}
+	return int(math.Round(min(max(value, 0), 100))), true
 }
 
-// fillDisplayData populates the provided DisplayData object with details based on current or
-// forecasted weather information. It locks relevant data structures to ensure safe concurrent
-// access and conditionally fills fields based on the mode.
-func (s *Service) fillDisplayData(target *template.DisplayData) {
-	s.locationLock.RLock()
-	defer s.locationLock.RUnlock()
-	s.weatherLock.RLock()
-	defer s.weatherLock.RUnlock()
+// sunProgress returns the elapsed fraction of the daylight in percent, or of the night outside of the
+// daylight. The night is assumed to last the remainder of the day, since the sunset of the previous and
+// the sunrise of the next day differ only slightly. Returns 0 if the sun doesn't rise or set.
+func sunProgress(now, sunrise, sunset time.Time) float64 {
+	day := sunset.Sub(sunrise)
+	if day <= 0 || day >= 24*time.Hour {
+		return 0
+	}
+	night := 24*time.Hour - day
+	var progress float64
+	switch {
+	case now.Before(sunrise):
+		progress = float64(now.Sub(sunrise.Add(-night))) / float64(night)
+	case now.Before(sunset):
+		progress = float64(now.Sub(sunrise)) / float64(day)
+	default:
+		progress = float64(now.Sub(sunset)) / float64(night)
+	}
+	return 100 * min(max(progress, 0), 1)
+}
+
+// classState returns the state snapshot the class rules are evaluated against.
+func classState(data *template.DisplayData) rules.State {
+	isDay := 0.0
+	if data.Current.IsDaytime {
+		isDay = 1
+	}
+	return rules.State{
+		"temp":           data.Current.Temperature,
+		"apparent_temp":  data.Current.ApparentTemperature,
+		"humidity":       data.Current.Humidity,
+		"pressure":       data.Current.Pressure,
+		"wind_speed":     data.Current.WindSpeed,
+		"wind_gust":      data.Current.WindGust,
+		"wind_direction": data.Current.WindDirection,
+		"weather_code":   data.Current.WeatherCode,
+		"is_day":         isDay,
+		"precipitation":  data.Current.Precipitation,
+		"forecast_temp":  data.Forecast.Temperature,
+		"today_max":      data.Today.TemperatureMax,
+		"today_min":      data.Today.TemperatureMin,
+		"sun_elevation":  data.Sun.Elevation,
+	}
+}
 
+// fillDisplayData populates the provided DisplayData object with details based on current or
+// forecasted weather information. The data is filled from a snapshot of the state, so that the
+// locks are only held while the snapshot is taken. Returns false if no weather data is available yet.
+func (s *Service) fillDisplayData(target *template.DisplayData) bool {
 	// The target must not be nil
 	if target == nil {
-		return
+		return false
 	}
 
 	// We need valid weather data to fill the display data
-	if s.weather == nil {
+	snap, ok := s.snapshot()
+	if !ok {
 		s.logger.Debug("no weather data available yet, geo location might not have returned a location yet")
-		return
+		return false
 	}
 
 	// Coordinates and address data
-	target.Latitude = s.weather.Latitude
-	target.Longitude = s.weather.Longitude
-	target.Elevation = s.weather.Elevation
-	target.Address = s.address
+	target.Latitude = snap.weather.Latitude
+	target.Longitude = snap.weather.Longitude
+	target.Coordinates = coordinate.Format(target.Latitude, target.Longitude, s.config.Templates.CoordinateFormat)
+	target.Elevation = snap.weather.Elevation
+	target.Address = snap.address
+	target.AddressIsStale = snap.addressIsStale
+	target.Place = s.currentPlace()
+	target.LocationSource = snap.geoSource
+	target.LocationAccuracy = snap.coordinates.Acc
+	target.LocationAccuracyText = s.accuracyText(snap.coordinates.Acc)
+	target.LocationIsCoarse = snap.coordinates.Acc > s.config.GeoLocation.CoarseAccuracy
+	s.fillMotionData(target)
 
 	// Moon phase
-	m := moonphase.New(time.Now())
+	m := moonphase.New(s.clock())
 	target.Moonphase = m.PhaseName()
-	target.MoonphaseIcon = MoonPhaseIcon[target.Moonphase]
-	target.MoonphaseIconWithSpace = s.templates.EmojiWithSpace(target.MoonphaseIcon)
+	if !s.textIcons {
+		target.MoonphaseIcon = MoonPhaseIcon[target.Moonphase]
+	}
+	target.MoonphaseIconWithSpace = s.iconWithSpace(target.MoonphaseIcon)
 
 	// Generel weather data
-	now := time.Now()
+	now := s.localNow()
 	nowHourUTC := now.UTC().Truncate(time.Hour)
-	nowIdx := s.weatherIndexByTime(nowHourUTC)
-	target.UpdateTime = s.weather.CurrentWeather.Time.Time
-	target.TempUnit = s.weather.HourlyUnits["temperature_2m"]
-	target.PressureUnit = s.weather.HourlyUnits["pressure_msl"]
-	sunriseTimeUTC, sunsetTimeUTC := sunrise.SunriseSunset(s.weather.Latitude, s.weather.Longitude, now.Year(),
-		now.Month(), now.Day())
+	nowIdx := s.weatherIndexByTime(&snap, nowHourUTC)
+	target.UpdateTime = snap.weather.CurrentWeather.Time.In(now.Location())
+	target.LocalTime = now
+	target.Now = now
+	if snap.timezone != nil {
+		target.LocalTime = now.In(snap.timezone)
+	}
+	target.TempUnit = snap.weather.HourlyUnits["temperature_2m"]
+	target.PressureUnit = pressureUnits[s.config.PressureUnit].symbol
+	target.WindSpeedUnit = s.windSpeedUnit()
+	target.PrecipitationUnit = s.precipitationUnit()
+	target.ClockSynchronized = !s.clockUnsynced.Load()
+	dayNightTime := now
+	if !target.ClockSynchronized {
+		dayNightTime = snap.weather.CurrentWeather.Time.In(now.Location())
+	}
+	sunriseTimeUTC, sunsetTimeUTC := sunrise.SunriseSunset(snap.weather.Latitude, snap.weather.Longitude,
+		dayNightTime.Year(), dayNightTime.Month(), dayNightTime.Day())
 	target.SunriseTime, target.SunsetTime = sunriseTimeUTC.In(now.Location()), sunsetTimeUTC.In(now.Location())
-	target.Current.IsDaytime = false
-	if now.After(target.SunriseTime) && now.Before(target.SunsetTime) {
+	target.MidnightSun, target.PolarNight = polarDayNight(snap.weather.Latitude, snap.weather.Longitude,
+		dayNightTime)
+	target.Current.IsDaytime = target.MidnightSun
+	if dayNightTime.After(target.SunriseTime) && dayNightTime.Before(target.SunsetTime) {
 		target.Current.IsDaytime = true
 	}
+	target.SunProgress = sunProgress(dayNightTime, target.SunriseTime, target.SunsetTime)
+	target.Sun = s.sunData(snap.weather.Latitude, snap.weather.Longitude, dayNightTime)
+	target.Twilight = s.twilightData(snap.weather.Latitude, snap.weather.Longitude, dayNightTime)
 
 	// Current weather data
-	target.Current.Temperature = s.weather.CurrentWeather.Temperature
-	target.Current.WeatherCode = s.weather.CurrentWeather.WeatherCode
-	target.Current.WindDirection = s.weather.CurrentWeather.WindDirection
-	target.Current.WindSpeed = s.weather.CurrentWeather.WindSpeed
-	target.Current.WeatherDateForTime = s.weather.CurrentWeather.Time.Time
-	target.Current.ConditionIcon = WMOWeatherIcons[target.Current.WeatherCode][target.Current.IsDaytime]
-	target.Current.ConditionIconWithSpace = s.templates.EmojiWithSpace(target.Current.ConditionIcon)
-	target.Current.Condition = s.t.Get(WMOWeatherCodes[target.Current.WeatherCode])
+	target.Current.Temperature = snap.weather.CurrentWeather.Temperature
+	target.Current.WeatherCode = snap.weather.CurrentWeather.WeatherCode
+	target.Current.WindDirection = snap.weather.CurrentWeather.WindDirection
+	target.Current.WindSpeed = s.windSpeed(snap.weather.CurrentWeather.WindSpeed)
+	target.Current.WindGust = target.Current.WindSpeed
+	target.Current.WindDirectionText = s.windDirectionText(target.Current.WindDirection)
+	target.Current.WindDescription = s.windDescription(snap.weather.CurrentWeather.WindSpeed)
+	target.Current.WeatherDateForTime = snap.weather.CurrentWeather.Time.In(now.Location())
+	target.Current.ConditionIcon = s.conditionIcon(target.Current.WeatherCode, target.Current.IsDaytime)
+	target.Current.ConditionIconWithSpace = s.iconWithSpace(target.Current.ConditionIcon)
+	target.Current.TemperatureIcon = s.temperatureIcon(target.Current.Temperature)
+	target.Current.ConditionIconPath = s.iconPath(target.Current.WeatherCode, target.Current.IsDaytime)
+	target.Current.ConditionGroup = s.conditions.Group(target.Current.WeatherCode)
+	target.Current.Condition = s.conditionText(target.Current.WeatherCode)
 	if nowIdx != -1 {
-		target.Current.ApparentTemperature = s.weather.HourlyMetrics["apparent_temperature"][nowIdx]
-		target.Current.Humidity = s.weather.HourlyMetrics["relative_humidity_2m"][nowIdx]
-		target.Current.PressureMSL = s.weather.HourlyMetrics["pressure_msl"][nowIdx]
+		target.Current.ApparentTemperature = snap.weather.HourlyMetrics["apparent_temperature"][nowIdx]
+		target.Current.Humidity = snap.weather.HourlyMetrics["relative_humidity_2m"][nowIdx]
+		s.fillPressure(&snap, &target.Current, nowIdx)
+		target.Current.FreezingLevel = snap.weather.HourlyMetrics["freezing_level_height"][nowIdx]
+		// Wind gusts are missing from forecasts cached before they were requested
+		if gusts, ok := snap.weather.HourlyMetrics["wind_gusts_10m"]; ok {
+			target.Current.WindGust = s.windSpeed(max(gusts[nowIdx], snap.weather.CurrentWeather.WindSpeed))
+		}
+		target.Current.Precipitation = snap.weather.HourlyMetrics["precipitation"][nowIdx]
+		target.Current.PrecipitationIntensity = s.precipitationIntensity(target.Current.Precipitation)
+		target.PrecipitationEnd = s.precipitationEnd(&snap, nowIdx, target.Current.WeatherCode)
+		target.PrecipitationEnd.Time = target.PrecipitationEnd.Time.In(now.Location())
+		target.PrecipitationStart = s.precipitationStart(&snap, nowIdx, target.PrecipitationEnd)
+		target.PrecipitationStart.Time = target.PrecipitationStart.Time.In(now.Location())
+		target.Umbrella = s.umbrellaData(now, target.PrecipitationEnd, target.PrecipitationStart)
+	}
+	target.UVWindows = s.uvWindows(&snap, now)
+	target.TemperatureChart, target.PrecipitationChart = s.hourlyCharts(&snap, nowIdx, now.Location())
+	target.PrecipitationChart.Available = target.PrecipitationChart.Available && s.config.Templates.PrecipitationChart
+
+	// Elevation of the location and altitude-corrected temperatures
+	target.Current.CorrectedTemperature = target.Current.Temperature
+	if snap.coordinates.HasAlt {
+		target.LocationElevation = snap.coordinates.Alt
+		target.HasLocationElevation = true
+		target.Current.CorrectedTemperature = s.altitudeCorrected(&snap, target.Current.Temperature)
+		if nowIdx != -1 {
+			target.Current.AboveFreezingLevel = target.LocationElevation >= target.Current.FreezingLevel
+		}
 	}
 
 	// Forecast weather data
 	fcastHours := time.Duration(s.config.Weather.ForecastHours) * time.Hour //nolint:gosec
 	fcastTime := now.Add(fcastHours).Truncate(time.Hour)
 	fcastTimeUTC := now.Add(fcastHours).UTC().Truncate(time.Hour)
-	fcastIdx := s.weatherIndexByTime(fcastTimeUTC)
+	fcastIdx := s.weatherIndexByTime(&snap, fcastTimeUTC)
 	if fcastIdx != -1 {
 		target.Forecast.WeatherDateForTime = fcastTime
 		target.Forecast.IsDaytime = false
-		if s.weather.HourlyMetrics["is_day"][fcastIdx] == 1 {
+		if snap.weather.HourlyMetrics["is_day"][fcastIdx] == 1 {
 			target.Forecast.IsDaytime = true
 		}
-		target.Forecast.Temperature = s.weather.HourlyMetrics["temperature_2m"][fcastIdx]
-		target.Forecast.ApparentTemperature = s.weather.HourlyMetrics["apparent_temperature"][fcastIdx]
-		target.Forecast.Humidity = s.weather.HourlyMetrics["relative_humidity_2m"][fcastIdx]
-		target.Forecast.PressureMSL = s.weather.HourlyMetrics["pressure_msl"][fcastIdx]
-		target.Forecast.WeatherCode = s.weather.HourlyMetrics["weather_code"][fcastIdx]
-		target.Forecast.WindDirection = s.weather.HourlyMetrics["wind_direction_10m"][fcastIdx]
-		target.Forecast.WindSpeed = s.weather.HourlyMetrics["wind_speed_10m"][fcastIdx]
-		target.Forecast.ConditionIcon = WMOWeatherIcons[target.Forecast.WeatherCode][target.Forecast.IsDaytime]
-		target.Forecast.ConditionIconWithSpace = s.templates.EmojiWithSpace(target.Forecast.ConditionIcon)
-		target.Forecast.Condition = s.t.Get(WMOWeatherCodes[target.Forecast.WeatherCode])
+		target.Forecast.Temperature = snap.weather.HourlyMetrics["temperature_2m"][fcastIdx]
+		target.Forecast.ApparentTemperature = snap.weather.HourlyMetrics["apparent_temperature"][fcastIdx]
+		target.Forecast.Humidity = snap.weather.HourlyMetrics["relative_humidity_2m"][fcastIdx]
+		s.fillPressure(&snap, &target.Forecast, fcastIdx)
+		target.Forecast.WeatherCode = snap.weather.HourlyMetrics["weather_code"][fcastIdx]
+		target.Forecast.WindDirection = snap.weather.HourlyMetrics["wind_direction_10m"][fcastIdx]
+		target.Forecast.WindSpeed = s.windSpeed(snap.weather.HourlyMetrics["wind_speed_10m"][fcastIdx])
+		target.Forecast.WindDirectionText = s.windDirectionText(target.Forecast.WindDirection)
+		target.Forecast.WindDescription = s.windDescription(snap.weather.HourlyMetrics["wind_speed_10m"][fcastIdx])
+		target.Forecast.ConditionIcon = s.conditionIcon(target.Forecast.WeatherCode, target.Forecast.IsDaytime)
+		target.Forecast.ConditionIconWithSpace = s.iconWithSpace(target.Forecast.ConditionIcon)
+		target.Forecast.TemperatureIcon = s.temperatureIcon(target.Forecast.Temperature)
+		target.Forecast.ConditionIconPath = s.iconPath(target.Forecast.WeatherCode, target.Forecast.IsDaytime)
+		target.Forecast.ConditionGroup = s.conditions.Group(target.Forecast.WeatherCode)
+		target.Forecast.Condition = s.conditionText(target.Forecast.WeatherCode)
+		target.Forecast.FreezingLevel = snap.weather.HourlyMetrics["freezing_level_height"][fcastIdx]
+		target.Forecast.Precipitation = snap.weather.HourlyMetrics["precipitation"][fcastIdx]
+		target.Forecast.PrecipitationIntensity = s.precipitationIntensity(target.Forecast.Precipitation)
+		target.Forecast.CorrectedTemperature = target.Forecast.Temperature
+		if target.HasLocationElevation {
+			target.Forecast.CorrectedTemperature = s.altitudeCorrected(&snap, target.Forecast.Temperature)
+			target.Forecast.AboveFreezingLevel = target.LocationElevation >= target.Forecast.FreezingLevel
+		}
 	} else {
 		target.Forecast = target.Current
 	}
+
+	// Daily weather data for today and tomorrow at the location
+	localDate := target.LocalTime
+	target.Today = s.dailyData(&snap, localDate)
+	target.Tomorrow = s.dailyData(&snap, localDate.AddDate(0, 0, 1))
+	tonightStart := time.Date(localDate.Year(), localDate.Month(), localDate.Day(), tonightStartHour, 0, 0, 0,
+		localDate.Location())
+	target.TonightMin = s.minTemperature(&snap, tonightStart, tonightStart.Add(tonightDuration))
+	target.Weekend = s.weekendData(&snap, localDate)
+
+	// Station observation data
+	if snap.observation != nil {
+		target.Observation.Available = true
+		target.Observation.StationID = snap.observation.StationID
+		target.Observation.StationName = snap.observation.StationName
+		target.Observation.ObservedAt = snap.observation.ObservedAt.In(now.Location())
+		target.Observation.Temperature = snap.observation.Temperature
+		target.Observation.Humidity = snap.observation.Humidity
+		target.Observation.ModelTemperature = target.Current.Temperature
+		if s.config.Units == "imperial" {
+			target.Observation.Temperature = celsiusToFahrenheit(target.Observation.Temperature)
+		}
+		if s.config.Weather.PrimarySource == "observation" {
+			target.Current.Temperature = target.Observation.Temperature
+			target.Current.TemperatureIcon = s.temperatureIcon(target.Current.Temperature)
+		}
+	}
+
+	// Degree days, thermostat indicator and ventilation advice
+	target.DegreeDays = s.degreeDays(&snap, target)
+	target.Ventilation = s.ventilation(target)
+	target.DeltaAlerts = s.deltaAlerts(&snap, nowIdx, now)
+	target.Capabilities = s.capabilityData(&snap)
+	if target.Capabilities.Alerts {
+		target.Alerts = s.weatherAlerts(now)
+	}
+	target.Trip = s.tripData(now.Location())
+	target.WeatherModel = s.weatherModel(snap.weatherSource)
+	target.ModelRun = s.modelRunData(now)
+	target.Ensemble = s.ensembleData()
+	target.ForecastChanges = s.forecastChanges(now, target.TempUnit)
+	target.Flood = s.floodData()
+	target.Earthquakes = s.earthquakeData()
+	target.SatellitePasses = s.satellitePasses(snap.coordinates, now)
+
+	// Attribution of the data providers in use
+	target.Attribution = s.attribution(&snap)
+
+	// Daily API usage
+	target.APIUsage = s.budget.Usage()
+	target.GeocoderQuota, _ = s.geocoderQuota()
+	return true
+}
+
+// attribution returns the attribution lines for all data providers that contributed to the
+// currently displayed data of the given state snapshot.
+func (s *Service) attribution(snap *stateSnapshot) []string {
+	sources := []string{snap.weatherSource, snap.geoSource}
+	if snap.address.AddressFound {
+		sources = append(sources, s.geocoder.Name())
+	}
+	if snap.observation != nil && s.observer != nil {
+		sources = append(sources, s.observer.Name())
+	}
+	if s.elevation != nil && snap.coordinates.HasAlt {
+		sources = append(sources, s.elevation.Name())
+	}
+	if s.floodData().Available {
+		sources = append(sources, floodSource)
+	}
+	if s.config.Satellites.Enabled {
+		sources = append(sources, satelliteSource)
+	}
+	if len(s.earthquakeData()) > 0 {
+		sources = append(sources, "earthquakes-"+s.config.Earthquakes.Source)
+	}
+
+	var lines []string
+	seen := make(map[string]struct{})
+	for _, source := range sources {
+		line, ok := SourceAttribution[source]
+		if _, dup := seen[source]; !ok || dup {
+			continue
+		}
+		seen[source] = struct{}{}
+		lines = append(lines, line)
+	}
+	return lines
 }
 
 // updateLocation updates the service's location and address based on provided latitude and longitude.
 // It locks the location for thread-safe updates and retrieves the address information using reverse geocoding.
-// If valid coordinates are not provided, the update is skipped. The method also triggers all scheduled jobs.
-func (s *Service) updateLocation(ctx context.Context, latitude, longitude float64) error {
-	if latitude <= 0 || longitude <= 0 {
+// If valid coordinates are not provided, the update is skipped. If the reverse geocoding fails, the last
+// known address is kept and flagged as stale. The method also triggers all scheduled jobs.
+func (s *Service) updateLocation(ctx context.Context, latitude, longitude float64, altitude *float64,
+	accuracy float64, source string,
+) error {
+	if latitude == 0 && longitude == 0 {
 		s.logger.Debug("coordinates empty, skipping service geo location update")
 		return nil
 	}
 
-	address, err := s.geocoder.Reverse(ctx, latitude, longitude)
-	if err != nil {
-		return fmt.Errorf("failed reverse geocode coordinates: %w", err)
-	}
 	location, err := omgo.NewLocation(latitude, longitude)
 	if err != nil {
 		return fmt.Errorf("failed create Open-Meteo location from coordinates: %w", err)
 	}
 
+	// A failed reverse geocoding must not prevent us from displaying the weather data, we will
+	// fall back to the last known address (flagged as stale) or the coordinates instead
+	endTrace := s.startup.begin("geocode")
+	address, err := s.geocoder.Reverse(ctx, latitude, longitude)
+	endTrace(s.geocoder.Name())
+	address = address.Sanitized()
+	if err != nil {
+		s.logger.Error("failed to reverse geocode coordinates", logger.Err(err),
+			slog.String("source", s.geocoder.Name()))
+	}
+
+	// Look up the elevation of the location if the geolocation provider did not report an altitude
+	coordinates := geobus.Coordinate{Lat: latitude, Lon: longitude, Acc: accuracy}
+	if altitude != nil {
+		coordinates.Alt, coordinates.HasAlt = *altitude, true
+	} else if s.elevation != nil {
+		endTrace = s.startup.begin("elevation")
+		elevation, lookupErr := s.elevation.Lookup(ctx, latitude, longitude)
+		endTrace(s.elevation.Name())
+		if lookupErr != nil {
+			s.logger.Error("failed to look up elevation", logger.Err(lookupErr),
+				slog.String("source", s.elevation.Name()))
+		} else {
+			coordinates.Alt, coordinates.HasAlt = elevation, true
+		}
+	}
+
 	s.locationLock.Lock()
 	s.location = location
-	if address.AddressFound {
+	s.coordinates = coordinates
+	s.geoSource = source
+	s.locationUpdated = time.Now()
+	if err == nil && address.AddressFound {
 		s.address = address
+		s.addressIsStale = false
+	} else {
+		s.addressIsStale = s.address.AddressFound
 	}
 	s.locationIsSet = true
 	s.locationLock.Unlock()
-	s.logger.Debug("address successfully resolved", slog.Any("address", s.address.DisplayName),
-		slog.Any("coordinates", s.location), slog.String("source", s.geocoder.Name()))
+	s.logger.Debug("address successfully resolved",
+		s.logger.Address(s.address.DisplayName, s.address.City, s.address.Country),
+		s.logger.Coordinates(latitude, longitude), slog.String("source", s.geocoder.Name()))
+	if quota, ok := s.geocoderQuota(); ok {
+		s.logger.Debug("geocoder request quota", slog.String("source", s.geocoder.Name()),
+			slog.Int("limit", quota.Limit), slog.Int("remaining", quota.Remaining),
+			slog.Time("reset", quota.Reset))
+	}
 
-	s.fetchWeather(ctx)
-	s.printWeather(ctx)
+	s.events.Publish(event.LocationChanged, source)
 
 	return nil
 }
 
-// processLocationUpdates subscribes to geolocation updates, processes location data, and updates the
-// service state accordingly.
+// processLocationUpdates applies the geolocation updates received from the geobus. While the updates
+// imply that we are moving faster than the configured movement speed (e.g. during a road trip with a
+// GPS-fed location), the updates are deferred until the position has been stable for the configured
+// settle time, so that we don't flood the geocoding and weather APIs with requests. The movement is
+// determined per source, since the results of different providers can be far apart, and the results of
+// other sources don't end a deferral.
 func (s *Service) processLocationUpdates(ctx context.Context, sub <-chan geobus.Result) {
+	last := make(map[string]geobus.Result)
+	var pending *geobus.Result
+	var settle <-chan time.Time
+	endTrace := s.startup.begin("geolocation")
+	if r, ok := s.awaitStartupLocation(ctx, sub); ok {
+		endTrace(r.Source)
+		s.logger.Debug("received startup location", s.logger.Coordinates(r.Lat, r.Lon),
+			slog.String("source", r.Source))
+		s.recordFix(r)
+		s.checkGeofences(ctx, r)
+		last[r.Source] = r
+		s.applyLocationUpdate(ctx, r)
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-settle:
+			settle = nil
+			if pending != nil {
+				s.logger.Debug("position is stable again, applying deferred geolocation update")
+				s.applyLocationUpdate(ctx, *pending)
+				pending = nil
+			}
 		case r, ok := <-sub:
 			if !ok {
 				return
 			}
 			s.logger.Debug("received geolocation update",
-				slog.Float64("lat", r.Lat), slog.Float64("lon", r.Lon), slog.String("source", r.Source))
-			if err := s.updateLocation(ctx, r.Lat, r.Lon); err != nil {
-				s.logger.Error("failed to apply geo update", logger.Err(err), slog.String("source", r.Source))
+				s.logger.Coordinates(r.Lat, r.Lon), slog.String("source", r.Source))
+			s.recordFix(r)
+			s.checkGeofences(ctx, r)
+
+			previous, ok := last[r.Source]
+			moving := ok && s.isMoving(previous, r)
+			last[r.Source] = r
+			switch {
+			case moving:
+				s.logger.Debug("moving, deferring geolocation update until the position is stable")
+				pending = &r
+				settle = time.After(s.config.GeoLocation.MovementSettle)
+				continue
+			case pending != nil && pending.Source != r.Source:
+				s.logger.Debug("moving, ignoring geolocation update of another source",
+					slog.String("source", r.Source), slog.String("moving_source", pending.Source))
+				continue
+			}
+			pending, settle = nil, nil
+			s.applyLocationUpdate(ctx, r)
+		}
+	}
+}
+
+// isMoving returns true if the speed reported by the provider or the distance between two consecutive
+// geolocation updates of the same source implies a speed above the configured movement speed.
+func (s *Service) isMoving(previous, current geobus.Result) bool {
+	if s.config.GeoLocation.MovementSpeed == 0 {
+		return false
+	}
+	if current.HasSpeed {
+		return current.Speed*kmhPerMeterPerSecond > s.config.GeoLocation.MovementSpeed
+	}
+	elapsed := current.At.Sub(previous.At)
+	if elapsed <= 0 {
+		return false
+	}
+	distance := geobus.Coordinate{Lat: previous.Lat, Lon: previous.Lon}.DistanceTo(
+		geobus.Coordinate{Lat: current.Lat, Lon: current.Lon})
+	kmh := distance / 1000 / elapsed.Hours()
+	return kmh > s.config.GeoLocation.MovementSpeed
+}
+
+// recordFix keeps the geolocation result as the most recent fix.
+func (s *Service) recordFix(r geobus.Result) {
+	s.fixLock.Lock()
+	defer s.fixLock.Unlock()
+	s.lastFix = r
+}
+
+// applyLocationUpdate updates the location with the given geolocation update, unless the active profile
+// has a static location.
+func (s *Service) applyLocationUpdate(ctx context.Context, r geobus.Result) {
+	if s.profileHasLocation() {
+		s.logger.Debug("ignoring geolocation update, the active profile has a static location")
+		return
+	}
+	if !s.isMaterialChange(r) {
+		s.logger.Debug("ignoring geolocation update, it is within the accuracy of the current location",
+			slog.String("source", r.Source), slog.Float64("accuracy", r.AccuracyMeters))
+		return
+	}
+	if err := s.updateLocation(ctx, r.Lat, r.Lon, r.Altitude(), r.AccuracyMeters, r.Source); err != nil {
+		s.logger.Error("failed to apply geo update", logger.Err(err), slog.String("source", r.Source))
+		return
+	}
+	s.writeLocationCache(r)
+}
+
+// isMaterialChange returns true if the geolocation update is worth a new reverse geocoding and weather
+// update. Locations within the accuracy radius of the current location are only applied if their accuracy
+// is materially better, so that a repeated or coarse result doesn't replace a good location. Locations
+// outside the radius are only applied if their accuracy is comparable to the distance, so that a coarse
+// result, whose center is merely off by its own inaccuracy, doesn't replace a precise location either.
+func (s *Service) isMaterialChange(r geobus.Result) bool {
+	s.locationLock.RLock()
+	defer s.locationLock.RUnlock()
+	if !s.locationIsSet || s.coordinates.Acc == 0 {
+		return true
+	}
+	current := s.coordinates
+	if distance := current.DistanceTo(geobus.Coordinate{Lat: r.Lat, Lon: r.Lon}); distance > current.Acc {
+		return r.AccuracyMeters <= max(distance, current.Acc)
+	}
+	return r.AccuracyMeters <= current.Acc*s.config.GeoLocation.AccuracyImprovement
+}
+
+// fillMotionData fills the speed and heading of the most recent geolocation fix, if reported by the provider.
+// The speed is converted to the configured units.
+func (s *Service) fillMotionData(target *template.DisplayData) {
+	s.fixLock.RLock()
+	defer s.fixLock.RUnlock()
+	fix := s.lastFix
+	if fix.HasSpeed {
+		target.Motion.HasSpeed = true
+		target.Motion.Speed = fix.Speed * kmhPerMeterPerSecond
+		target.Motion.SpeedUnit = "km/h"
+		if s.config.Units == "imperial" {
+			target.Motion.Speed = fix.Speed * 3600 / metersPerMile
+			target.Motion.SpeedUnit = "mph"
+		}
+	}
+	target.Motion.HasHeading = fix.HasHeading
+	target.Motion.Heading = fix.Heading
+	target.Motion.FixTime = fix.SourceTime
+}
+
+// accuracyText returns the accuracy of the location in meters as human-readable text in the configured
+// units, e.g. "±15 km". Returns an empty string if the accuracy is unknown.
+func (s *Service) accuracyText(meters float64) string {
+	switch {
+	case meters <= 0:
+		return ""
+	case s.config.Units == "imperial" && meters >= metersPerMile:
+		return fmt.Sprintf("±%.0f mi", meters/metersPerMile)
+	case s.config.Units == "imperial":
+		return fmt.Sprintf("±%.0f ft", meters*feetPerMeter)
+	case meters >= 1000:
+		return fmt.Sprintf("±%.0f km", meters/1000)
+	default:
+		return fmt.Sprintf("±%.0f m", meters)
+	}
+}
+
+// dailyData returns the daily weather data of the given state snapshot for the given date at the location.
+func (s *Service) dailyData(snap *stateSnapshot, date time.Time) template.DailyData {
+	idx := -1
+	for i, t := range snap.weather.DailyTimes {
+		if t.Year() == date.Year() && t.Month() == date.Month() && t.Day() == date.Day() {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return template.DailyData{}
+	}
+
+	data := template.DailyData{
+		Available:      true,
+		Date:           time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location()),
+		TemperatureMax: snap.weather.DailyMetrics["temperature_2m_max"][idx],
+		TemperatureMin: snap.weather.DailyMetrics["temperature_2m_min"][idx],
+		WeatherCode:    snap.weather.DailyMetrics["weather_code"][idx],
+
+		PrecipitationProbability: snap.weather.DailyMetrics["precipitation_probability_max"][idx],
+	}
+	data.ConditionIcon = s.conditionIcon(data.WeatherCode, true)
+	data.ConditionIconWithSpace = s.iconWithSpace(data.ConditionIcon)
+	data.ConditionIconPath = s.iconPath(data.WeatherCode, true)
+	data.ConditionGroup = s.conditions.Group(data.WeatherCode)
+	data.Condition = s.conditionText(data.WeatherCode)
+	return data
+}
+
+// weekendData returns the daily weather data for the current weekend if the given date is on a weekend,
+// otherwise for the upcoming weekend. Days without daily data are omitted.
+func (s *Service) weekendData(snap *stateSnapshot, date time.Time) []template.DailyData {
+	saturday := date.AddDate(0, 0, int(time.Saturday-date.Weekday()))
+	if date.Weekday() == time.Sunday {
+		saturday = date.AddDate(0, 0, -1)
+	}
+
+	days := make([]template.DailyData, 0, 2)
+	for _, day := range []time.Time{saturday, saturday.AddDate(0, 0, 1)} {
+		if data := s.dailyData(snap, day); data.Available {
+			days = append(days, data)
+		}
+	}
+	return days
+}
+
+// precipitationIntensity returns the localized intensity of the hourly precipitation in the configured unit,
+// or an empty string if there is no precipitation.
+func (s *Service) precipitationIntensity(amount float64) string {
+	if amount < precipitationThreshold[s.precipitationUnit()] {
+		return ""
+	}
+	if s.precipitationUnit() == "inch" {
+		amount *= millimetersPerInch
+	}
+	for _, intensity := range PrecipitationIntensities {
+		if amount < intensity.MaxAmount {
+			return s.t.Get(intensity.Description)
+		}
+	}
+	return ""
+}
+
+// precipitationEnd determines if it is currently raining or snowing and when the precipitation is
+// expected to stop, based on the hourly precipitation data of the given state snapshot.
+func (s *Service) precipitationEnd(snap *stateSnapshot, nowIdx int,
+	weatherCode float64,
+) template.PrecipitationData {
+	threshold := precipitationThreshold[s.precipitationUnit()]
+	precipitation := snap.weather.HourlyMetrics["precipitation"]
+	data := template.PrecipitationData{IsActive: s.conditions.IsPrecipitation(weatherCode)}
+
+	// Hourly precipitation is the sum of the preceding hour, so the next hour covers the current time
+	idx := nowIdx + 1
+	if idx >= len(precipitation) {
+		return data
+	}
+	if precipitation[idx] >= threshold {
+		data.IsActive = true
+	}
+	if !data.IsActive {
+		return data
+	}
+
+	for ; idx < len(precipitation) && idx <= nowIdx+precipitationLookahead; idx++ {
+		if precipitation[idx] < threshold {
+			data.EndKnown = true
+			data.Time = snap.weather.HourlyTimes[idx-1]
+			if idx == nowIdx+1 {
+				data.Time = snap.weather.HourlyTimes[idx]
 			}
+			return data
+		}
+	}
+	return data
+}
+
+// precipitationStart determines when the next precipitation is expected to start, if it is currently dry.
+func (s *Service) precipitationStart(snap *stateSnapshot, nowIdx int,
+	end template.PrecipitationData,
+) template.PrecipitationStartData {
+	var data template.PrecipitationStartData
+	if end.IsActive {
+		return data
+	}
+
+	threshold := precipitationThreshold[s.precipitationUnit()]
+	precipitation := snap.weather.HourlyMetrics["precipitation"]
+	for idx := nowIdx + 1; idx < len(precipitation) && idx <= nowIdx+precipitationLookahead; idx++ {
+		if precipitation[idx] >= threshold {
+			// The precipitation of an hour is the sum of the preceding hour
+			data.Expected = true
+			data.Time = snap.weather.HourlyTimes[idx-1]
+			data.WeatherCode = snap.weather.HourlyMetrics["weather_code"][idx]
+			data.Condition = s.conditionText(data.WeatherCode)
+			return data
+		}
+	}
+	return data
+}
+
+// minTemperature returns the lowest hourly temperature of the given state snapshot in the given time range.
+func (s *Service) minTemperature(snap *stateSnapshot, from, to time.Time) float64 {
+	found := false
+	var lowest float64
+	for i, t := range snap.weather.HourlyTimes {
+		if t.Before(from) || !t.Before(to) {
+			continue
+		}
+		value := snap.weather.HourlyMetrics["temperature_2m"][i]
+		if !found || value < lowest {
+			lowest = value
+			found = true
 		}
 	}
+	return lowest
 }
 
-func (s *Service) weatherIndexByTime(atTime time.Time) int {
-	for i, t := range s.weather.HourlyTimes {
+func (s *Service) weatherIndexByTime(snap *stateSnapshot, atTime time.Time) int {
+	for i, t := range snap.weather.HourlyTimes {
 		if t.Equal(atTime) {
 			return i
 		}
@@ -416,17 +1752,63 @@ func (s *Service) weatherIndexByTime(atTime time.Time) int {
 	return -1
 }
 
-// handleAltTextToggleSignal toggles the module text display when a signal is received
-func (s *Service) handleAltTextToggleSignal(ctx context.Context, sigChan chan os.Signal) {
+// onDemand returns true if the output is only written on request via the output signal.
+func (s *Service) onDemand() bool {
+	return s.config.Intervals.OutputSignal > 0
+}
+
+// handleOutputSignal writes the output whenever the output signal is received. If the weather data is
+// older than the weather update interval, it is fetched first. This is the only path that writes the
+// output in on-demand mode, the weather and location updates don't.
+func (s *Service) handleOutputSignal(ctx context.Context, sigChan chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigChan:
+			s.fetches.mu.Lock()
+			lastSuccess := s.fetches.lastSuccess
+			s.fetches.mu.Unlock()
+			if time.Since(lastSuccess) >= s.config.Intervals.WeatherUpdate {
+				s.fetchWeather(ctx)
+			}
+			s.printWeather(ctx)
+		}
+	}
+}
+
+// handleDisplayModeSignal switches the module text to the next display mode when a signal is received
+func (s *Service) handleDisplayModeSignal(ctx context.Context, sigChan chan os.Signal) {
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-sigChan:
-			s.displayAltLock.Lock()
-			s.displayAltText = !s.displayAltText
-			s.displayAltLock.Unlock()
+			s.displayModeLock.Lock()
+			s.displayMode = (s.displayMode + 1) % len(s.templates.Modes)
+			s.displayModeLock.Unlock()
 			s.printWeather(ctx)
 		}
 	}
 }
+
+// trackLocation runs the geolocation providers until the context ends. The providers are restarted on
+// request, e.g. after a resume, so that they look up the location immediately.
+func (s *Service) trackLocation(ctx context.Context) {
+	for {
+		ctxTrack, cancelTrack := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-ctxTrack.Done():
+			case <-s.relocate:
+				cancelTrack()
+			}
+		}()
+		s.orchestrator.Track(ctxTrack, DesktopID)
+		cancelTrack()
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.Debug("restarting geolocation providers")
+	}
+}
@@ -0,0 +1,19 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"os"
+	"syscall"
+)
+
+// sigRTMin is SIGRTMIN as seen by programs linked against glibc like pkill and Waybar, since glibc
+// reserves the first two real-time signals of the kernel for internal use.
+const sigRTMin = 34
+
+// realtimeSignal returns the real-time signal SIGRTMIN+offset.
+func realtimeSignal(offset int) (os.Signal, bool) {
+	return syscall.Signal(sigRTMin + offset), true
+}
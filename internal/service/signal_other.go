@@ -0,0 +1,17 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build !linux
+
+package service
+
+import (
+	"os"
+)
+
+// realtimeSignal returns the real-time signal SIGRTMIN+offset. Real-time signals are only supported on
+// Linux.
+func realtimeSignal(int) (os.Signal, bool) {
+	return nil, false
+}
@@ -0,0 +1,15 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build !windows
+
+package service
+
+import (
+	"os"
+	"syscall"
+)
+
+// displayModeSignals are the signals that cycle through the display modes.
+var displayModeSignals = []os.Signal{syscall.SIGUSR1}
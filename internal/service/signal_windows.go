@@ -0,0 +1,13 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"os"
+)
+
+// displayModeSignals are the signals that cycle through the display modes. Windows has no user-defined
+// signals, so the display modes can't be cycled.
+var displayModeSignals []os.Signal
@@ -7,159 +7,149 @@ package service
 import (
 	"context"
 	"log/slog"
+	"slices"
 	"sync/atomic"
 	"time"
 
-	"github.com/godbus/dbus/v5"
+	"github.com/go-co-op/gocron/v2"
+	"github.com/google/uuid"
 
+	"github.com/wneessen/waybar-weather/internal/event"
 	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/resume"
 )
 
 const (
-	dbusInterface   = "org.freedesktop.login1.Manager"
-	dbusWatchMember = "PrepareForSleep"
-
-	debounceWindow   = 2 // seconds
-	signalBufferSize = 8
-
-	busReconnectDelay   = 5 * time.Second
-	networkWakeupDelay  = 10 * time.Second
-	reconnectDelay      = 2 * time.Second
-	subscribeRetryDelay = 10 * time.Second
+	debounceWindow = 2 // seconds
+
+	// networkWakeupTimeout is the maximum wait for the network after a resume, the weather is fetched
+	// anyway once it is exceeded
+	networkWakeupTimeout = time.Minute
+	// networkProbeDelay and maxNetworkProbeDelay define the backoff of the network probes after a resume
+	networkProbeDelay    = time.Second
+	maxNetworkProbeDelay = 15 * time.Second
 )
 
-// monitorSleepResume monitors system sleep and resume events using D-Bus signals and handles
-// reconnections as needed.
+// resumeCoveredJobs are the jobs that are not caught up after a resume, since the resume event fetches the
+// weather and writes the output anyway.
+var resumeCoveredJobs = []string{outputJobName, weatherUpdateJobName, clockJobName}
+
+// monitorSleepResume monitors system sleep and resume events with the resume monitor of the platform.
+// If the monitor is not usable, the resume is detected by the jump of the wall clock instead.
 func (s *Service) monitorSleepResume(ctx context.Context) {
 	var lastResumeUnix int64
 
-	for {
-		conn := s.connectToSystemBus(ctx)
-		if conn == nil {
-			return // the context was cancelled, exit
-		}
-
-		// try to reconnect or exit if we can't if the context was cancelled
-		if !s.setupSleepMonitoring(ctx, conn) {
-			continue
-		}
+	monitor := resume.New(s.logger)
+	watch := func(monitor resume.Monitor) error {
+		return monitor.Watch(ctx, func() { s.handleResumeEvent(ctx, &lastResumeUnix, monitor.Name()) })
+	}
+	err := watch(monitor)
+	if err == nil || ctx.Err() != nil {
+		return
+	}
 
-		sigCh := make(chan *dbus.Signal, signalBufferSize)
-		conn.Signal(sigCh)
-		s.logger.Debug("subscribed to dbus signal", slog.String("interface", dbusInterface),
-			slog.String("member", dbusWatchMember))
+	s.logger.Warn("sleep monitor is not usable, falling back to clock jump detection",
+		slog.String("monitor", monitor.Name()), logger.Err(err))
+	if err = watch(resume.NewClockJump()); err != nil {
+		s.logger.Error("failed to monitor sleep and resume", logger.Err(err))
+	}
+}
 
-		s.handleSleepSignals(ctx, sigCh, &lastResumeUnix)
+// handleResumeEvent handles the system wake-up event and triggers necessary actions to refresh weather data.
+// It ensures debouncing of multiple consecutive resume events and waits for the network to be ready.
+func (s *Service) handleResumeEvent(ctx context.Context, lastResumeUnix *int64, source string) {
+	now := time.Now().Unix()
 
-		// Clean up before reconnect
-		conn.RemoveSignal(sigCh)
-		if err := conn.Close(); err != nil {
-			s.logger.Error("failed to close system bus connection", logger.Err(err))
-		}
+	// debounce in case of multiple resume events
+	if now-atomic.LoadInt64(lastResumeUnix) < debounceWindow {
+		return
+	}
+	atomic.StoreInt64(lastResumeUnix, now)
 
-		// If we're here because of ctx cancel, exit; otherwise reconnect
-		select {
-		case <-ctx.Done():
+	// Give the system time to wake up and establish network connection
+	if !s.waitForNetwork(ctx) {
+		if ctx.Err() != nil {
 			return
-		default:
-			time.Sleep(reconnectDelay)
 		}
+		s.logger.Warn("network is not ready after resume, fetching weather data anyway",
+			slog.Duration("timeout", networkWakeupTimeout))
 	}
+
+	s.logger.Debug("resuming from sleep, fetching latest weather data", slog.String("monitor", source))
+	s.events.Publish(event.SleepResume, source)
 }
 
-// connectToSystemBus establishes a connection to the system D-Bus with automatic reconnection handling
-// on failure. It continuously retries on connection failures until the provided context is canceled.
-// On context cancellation, it ensures the connection is cleanly closed.
-func (s *Service) connectToSystemBus(ctx context.Context) *dbus.Conn {
+// waitForNetwork probes the network with an increasing backoff until it is ready or the wakeup timeout is
+// exceeded. The connectivity is taken from NetworkManager or, if it is not available, from a probe of the
+// weather API. Returns false if the network is not ready.
+func (s *Service) waitForNetwork(ctx context.Context) bool {
+	deadline := time.Now().Add(networkWakeupTimeout)
+	delay := networkProbeDelay
 	for {
-		conn, err := dbus.ConnectSystemBus()
+		online, err := s.networkOnline()
 		if err != nil {
-			select {
-			case <-time.After(busReconnectDelay):
-				continue
-			case <-ctx.Done():
-				return nil
-			}
+			s.backendLock.RLock()
+			backendURL := s.backendEndpoint()
+			s.backendLock.RUnlock()
+			ctxProbe, cancelProbe := context.WithTimeout(ctx, weatherProbeTimeout)
+			online = newHTTPClient(s.config, s.logger, s.budget).Probe(ctxProbe, backendURL) == nil
+			cancelProbe()
 		}
-
-		// Ensure cleanup on context cancellation
-		go func() {
-			<-ctx.Done()
-			if err := conn.Close(); err != nil {
-				s.logger.Error("failed to close system bus connection", logger.Err(err))
-			}
-		}()
-
-		return conn
-	}
-}
-
-// setupSleepMonitoring configures sleep monitoring by subscribing to specific dbus signals and
-// handles error retries.
-func (s *Service) setupSleepMonitoring(ctx context.Context, conn *dbus.Conn) bool {
-	if err := conn.AddMatchSignal(dbus.WithMatchInterface(dbusInterface),
-		dbus.WithMatchMember(dbusWatchMember),
-	); err != nil {
-		s.logger.Error("failed to subscribe to dbus signal", slog.String("interface", dbusInterface),
-			slog.String("member", dbusWatchMember), logger.Err(err))
-		if err = conn.Close(); err != nil {
-			s.logger.Error("failed to close system bus connection", logger.Err(err))
+		if online {
+			return true
 		}
-		select {
-		case <-time.After(subscribeRetryDelay):
+		if time.Now().Add(delay).After(deadline) {
 			return false
+		}
+		select {
 		case <-ctx.Done():
 			return false
+		case <-time.After(delay):
 		}
+		delay = min(2*delay, maxNetworkProbeDelay)
 	}
-	return true
 }
 
-// handleSleepSignals listens for sleep-related signals and processes them accordingly using the
-// provided signal channel. Takes a context to handle cancellation, a signal channel for receiving
-// dbus signals, and a timestamp pointer for updates.
-func (s *Service) handleSleepSignals(ctx context.Context, sigCh chan *dbus.Signal, lastResumeUnix *int64) {
-	for {
+// catchUpAfterResume restarts the geolocation providers, whose timers were paused during the suspend, and
+// reschedules the jobs, since their timers run on the monotonic clock, which doesn't advance during the
+// suspend either. Jobs whose run has been missed, e.g. the morning briefing, run right away, except for the
+// jobs whose work the resume event triggers anyway. The sun transitions are re-computed, since the sunrise
+// or sunset may have passed as well.
+func (s *Service) catchUpAfterResume(ctx context.Context) {
+	notify := func(ch chan struct{}) {
 		select {
-		case <-ctx.Done():
-			return
-		case sgn, ok := <-sigCh:
-			if !ok {
-				// connection likely closed; try to reconnect
-				return
-			}
-			s.processSleepSignal(ctx, sgn, lastResumeUnix)
+		case ch <- struct{}{}:
+		default:
 		}
 	}
-}
+	notify(s.relocate)
+	notify(s.sunWake)
 
-// processSleepSignal handles the sleep signal received from dbus and triggers resume event processing
-// if conditions are met.
-func (s *Service) processSleepSignal(ctx context.Context, sgn *dbus.Signal, lastResumeUnix *int64) {
-	if len(sgn.Body) != 1 {
-		return
-	}
-	sleeping, ok := sgn.Body[0].(bool)
-	if !ok || sleeping {
-		return
+	nextRuns := make(map[uuid.UUID]time.Time)
+	for _, job := range s.scheduler.Jobs() {
+		if next, err := job.NextRun(); err == nil {
+			nextRuns[job.ID()] = next
+		}
 	}
-	s.handleResumeEvent(ctx, lastResumeUnix)
-}
 
-// handleResumeEvent handles the system wake-up event and triggers necessary actions to refresh weather data.
-// It ensures debouncing of multiple consecutive resume events and provides time for network readiness.
-func (s *Service) handleResumeEvent(ctx context.Context, lastResumeUnix *int64) {
-	now := time.Now().Unix()
-
-	// debounce in case of multiple resume events
-	if now-atomic.LoadInt64(lastResumeUnix) < debounceWindow {
-		return
+	s.jobLock.Lock()
+	defer s.jobLock.Unlock()
+	now := time.Now()
+	for name, scheduled := range s.jobs {
+		options := s.jobOptions(ctx, name)
+		next := nextRuns[scheduled.id]
+		if !next.IsZero() && !next.After(now) && !slices.Contains(resumeCoveredJobs, name) {
+			s.logger.Debug("running job missed during suspend", slog.String("job", name),
+				slog.Time("scheduled", next))
+			options = append(options, gocron.WithStartAt(gocron.WithStartImmediately()))
+		}
+		job, err := s.scheduler.Update(scheduled.id, scheduled.definition, s.jobTask(scheduled.task, name),
+			options...)
+		if err != nil {
+			s.logger.Error("failed to reschedule job after resume", logger.Err(err), slog.String("job", name))
+			continue
+		}
+		scheduled.id = job.ID()
+		s.jobs[name] = scheduled
 	}
-	atomic.StoreInt64(lastResumeUnix, now)
-
-	// Give the system time to wake up and establish network connection
-	time.Sleep(networkWakeupDelay)
-
-	s.logger.Debug("resuming from sleep, fetching latest weather data")
-	s.fetchWeather(ctx)
 }
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"time"
+
+	"github.com/hectormalot/omgo"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/geocode"
+	"github.com/wneessen/waybar-weather/internal/observation"
+)
+
+// stateSnapshot is a copy of the location and weather state. The forecast and the observation are
+// replaced, but never modified, by a weather update, so the snapshot can be read without holding a lock
+// while the display data is rendered.
+type stateSnapshot struct {
+	weather        *omgo.Forecast
+	weatherSource  string
+	timezone       *time.Location
+	observation    *observation.Observation
+	address        geocode.Address
+	addressIsStale bool
+	geoSource      string
+	coordinates    geobus.Coordinate
+}
+
+// snapshot copies the location and weather state under their locks. Returns false if no weather data is
+// available yet.
+func (s *Service) snapshot() (stateSnapshot, bool) {
+	s.locationLock.RLock()
+	defer s.locationLock.RUnlock()
+	s.weatherLock.RLock()
+	defer s.weatherLock.RUnlock()
+
+	snap := stateSnapshot{
+		weather:        s.weather,
+		weatherSource:  s.weatherSource,
+		timezone:       s.timezone,
+		observation:    s.observation,
+		address:        s.address,
+		addressIsStale: s.addressIsStale,
+		geoSource:      s.geoSource,
+		coordinates:    s.coordinates,
+	}
+	return snap, s.weatherIsSet && s.weather != nil
+}
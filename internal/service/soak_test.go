@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+
+package service
+
+import (
+	"flag"
+	"runtime"
+	"testing"
+	"time"
+)
+
+var soakDuration = flag.Duration("soak", 5*time.Second, "duration of the soak test")
+
+const (
+	// soakStep is the time between two location updates of the soak test
+	soakStep = 50 * time.Millisecond
+	// soakGoroutineSlack is the number of goroutines the service may have in flight at the end of the
+	// soak test in addition to the goroutines after the warm-up
+	soakGoroutineSlack = 10
+	// soakHeapSlack is the growth of the heap in bytes the soak test tolerates
+	soakHeapSlack = 1 << 20
+)
+
+// soakLocations are the locations the soak test cycles through.
+var soakLocations = [][2]float64{{52.52, 13.405}, {48.137, 11.575}, {53.551, 9.993}, {50.938, 6.96}}
+
+// TestService_Soak runs the service against the fake desktop services and APIs with accelerated intervals,
+// moves the location, restarts GeoClue and resumes from sleep over and over, and checks that neither the
+// goroutines nor the heap accumulate. Run it with e.g. -soak=1h for a long run.
+func TestService_Soak(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping soak test in short mode")
+	}
+	address := startTestBus(t)
+	desktop := startFakeDesktop(t, address, soakLocations[0][0], soakLocations[0][1], 10)
+	apis := startFakeAPIs(t, 3)
+
+	conf := newHarnessConfig(t, apis)
+	conf.Intervals.Output = soakStep
+	conf.Intervals.WeatherUpdate = 4 * soakStep
+	conf.WeatherCard.Enabled = true
+	conf.GeoLocation.MovementSpeed = 0
+	serv := newTestService(t, conf)
+	outputs := make(channelWriter, 64)
+	serv.SetOutput(outputs)
+	go func() {
+		for range outputs {
+		}
+	}()
+	go func() {
+		for range desktop.notified {
+		}
+	}()
+
+	runTestService(t, serv)
+
+	// Run every step once before the baseline is taken, so that caches and pools are populated
+	warmup := len(soakLocations) * 10
+	var goroutines int
+	var heap uint64
+	for step := 0; ; step++ {
+		if step == warmup {
+			goroutines, heap = soakSample()
+			t.Logf("baseline: %d goroutines, %d bytes heap", goroutines, heap)
+		}
+		if step > warmup && time.Duration(step-warmup)*soakStep >= *soakDuration {
+			break
+		}
+		location := soakLocations[step%len(soakLocations)]
+		desktop.move(t, location[0], location[1])
+		switch step % 40 {
+		case 10:
+			desktop.restartGeoClue(t)
+		case 30:
+			desktop.resume(t)
+		}
+		time.Sleep(soakStep)
+	}
+
+	// Give the in-flight updates time to complete
+	time.Sleep(10 * soakStep)
+	endGoroutines, endHeap := soakSample()
+	t.Logf("end: %d goroutines, %d bytes heap, %d forecasts", endGoroutines, endHeap, apis.forecasts.Load())
+	if endGoroutines > goroutines+soakGoroutineSlack {
+		buf := make([]byte, 1<<20)
+		t.Errorf("goroutines have grown from %d to %d:\n%s", goroutines, endGoroutines,
+			buf[:runtime.Stack(buf, true)])
+	}
+	if endHeap > heap+soakHeapSlack {
+		t.Errorf("heap has grown from %d to %d bytes", heap, endHeap)
+	}
+}
+
+// soakSample returns the number of goroutines and the live heap after a garbage collection.
+func soakSample() (int, uint64) {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return runtime.NumGoroutine(), stats.HeapAlloc
+}
@@ -0,0 +1,199 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+// ipSources are the geolocation providers that locate the IP address, which is often far off.
+var ipSources = []string{"geoip", "geoapi"}
+
+// startupStage is a stage of the startup strategy, which accepts the results matching its criteria.
+type startupStage struct {
+	name    string
+	timeout time.Duration
+	accept  func(geobus.Result) bool
+}
+
+// roundedAccuracy is the accuracy in meters of coordinates rounded to two decimal places.
+const roundedAccuracy = 1000
+
+// cachedLocation represents the content of the location cache file.
+type cachedLocation struct {
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Altitude  *float64  `json:"altitude,omitempty"`
+	Accuracy  float64   `json:"accuracy"`
+	Source    string    `json:"source"`
+	At        time.Time `json:"at"`
+}
+
+// awaitStartupLocation waits for the first location with a staged strategy: first only a precise result
+// is accepted, then a result of any accuracy from a local provider, then an IP-based result. If none of
+// the stages yields a result, the cached or configured fallback location is used. Better results are
+// applied later on as usual. Returns false if no location is available.
+func (s *Service) awaitStartupLocation(ctx context.Context, sub <-chan geobus.Result) (geobus.Result, bool) {
+	stages := []startupStage{
+		{"precise", s.config.GeoLocation.StartupPreciseTimeout, func(r geobus.Result) bool {
+			return r.AccuracyMeters <= s.config.GeoLocation.StartupAccuracy
+		}},
+		{"any accuracy", s.config.GeoLocation.StartupAnyTimeout, func(r geobus.Result) bool {
+			return !slices.Contains(ipSources, r.Source)
+		}},
+		{"ip-based", s.config.GeoLocation.StartupIPTimeout, func(geobus.Result) bool { return true }},
+	}
+
+	var seen []geobus.Result
+	for _, stage := range stages {
+		if stage.timeout == 0 {
+			continue
+		}
+		// Results that were received in an earlier stage may be acceptable now
+		if r, ok := bestResult(seen, stage.accept); ok {
+			return r, true
+		}
+
+		s.logger.Debug("waiting for startup location", slog.String("stage", stage.name),
+			slog.Duration("timeout", stage.timeout))
+		timer := time.NewTimer(stage.timeout)
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return geobus.Result{}, false
+			case <-timer.C:
+				break wait
+			case r, ok := <-sub:
+				if !ok {
+					timer.Stop()
+					return geobus.Result{}, false
+				}
+				if stage.accept(r) {
+					timer.Stop()
+					return r, true
+				}
+				seen = append(seen, r)
+			}
+		}
+	}
+
+	if r, ok := bestResult(seen, func(geobus.Result) bool { return true }); ok {
+		return r, true
+	}
+	return s.fallbackLocation()
+}
+
+// bestResult returns the best of the given results that is accepted.
+func bestResult(results []geobus.Result, accept func(geobus.Result) bool) (geobus.Result, bool) {
+	var best geobus.Result
+	found := false
+	for _, r := range results {
+		if accept(r) && (!found || r.BetterThan(best)) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
+
+// fallbackLocation returns the cached location or, if there is none, the configured fallback location.
+func (s *Service) fallbackLocation() (geobus.Result, bool) {
+	if cached, err := s.readLocationCache(); err == nil {
+		s.logger.Info("no geolocation available yet, using cached location",
+			s.logger.Coordinates(cached.Latitude, cached.Longitude), slog.Time("cached_at", cached.At))
+		result := geobus.Result{
+			Key: DesktopID, Lat: cached.Latitude, Lon: cached.Longitude, AccuracyMeters: cached.Accuracy,
+			Source: "cache", At: cached.At,
+		}
+		if cached.Altitude != nil {
+			result.Alt, result.HasAlt = *cached.Altitude, true
+		}
+		return result, true
+	} else if !os.IsNotExist(err) {
+		s.logger.Warn("failed to read location cache", logger.Err(err))
+	}
+
+	if s.config.GeoLocation.FallbackLatitude != 0 || s.config.GeoLocation.FallbackLongitude != 0 {
+		s.logger.Info("no geolocation available yet, using configured fallback location")
+		return geobus.Result{
+			Key: DesktopID, Lat: s.config.GeoLocation.FallbackLatitude, Lon: s.config.GeoLocation.FallbackLongitude,
+			AccuracyMeters: geobus.AccuarcyUnknown, Source: "config", At: time.Now(),
+		}, true
+	}
+	return geobus.Result{}, false
+}
+
+// readLocationCache reads the last location from the location cache file.
+func (s *Service) readLocationCache() (cachedLocation, error) {
+	var cached cachedLocation
+	if s.config.GeoLocation.DisableLocationCache {
+		return cached, os.ErrNotExist
+	}
+	data, err := os.ReadFile(s.config.GeoLocation.LocationCache)
+	if err != nil {
+		return cached, err
+	}
+	if err = json.Unmarshal(data, &cached); err != nil {
+		return cached, fmt.Errorf("failed to parse location cache: %w", err)
+	}
+	return cached, nil
+}
+
+// writeLocationCache stores the location in the location cache file, so that it can be used at the next
+// startup. The cache honors the log_coordinates setting: with "round", the coordinates are rounded to
+// roughly 1km, with "redact", no location is cached at all. The file is only rewritten if the cached
+// location changes.
+func (s *Service) writeLocationCache(r geobus.Result) {
+	if s.config.GeoLocation.DisableLocationCache || r.Source == "cache" || r.Source == "config" ||
+		s.config.LogCoordinates == logger.PrivacyRedact {
+		return
+	}
+	cached := cachedLocation{
+		Latitude: r.Lat, Longitude: r.Lon, Altitude: r.Altitude(), Accuracy: r.AccuracyMeters, Source: r.Source,
+		At: r.At,
+	}
+	if s.config.LogCoordinates == logger.PrivacyRound {
+		cached.Latitude, cached.Longitude = roundCoordinate(r.Lat), roundCoordinate(r.Lon)
+		cached.Accuracy = max(cached.Accuracy, roundedAccuracy)
+	}
+
+	s.locationCacheLock.Lock()
+	defer s.locationCacheLock.Unlock()
+	if written := s.locationCacheWritten; written != nil && written.Latitude == cached.Latitude &&
+		written.Longitude == cached.Longitude && written.Source == cached.Source {
+		return
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		s.logger.Error("failed to encode location cache", logger.Err(err))
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(s.config.GeoLocation.LocationCache), 0o700); err != nil {
+		s.logger.Error("failed to create location cache directory", logger.Err(err))
+		return
+	}
+	if err = writeFileAtomic(s.config.GeoLocation.LocationCache, data, 0o600); err != nil {
+		s.logger.Error("failed to write location cache", logger.Err(err))
+		return
+	}
+	s.locationCacheWritten = &cached
+}
+
+// roundCoordinate rounds the coordinate to two decimal places, which is roughly 1km.
+func roundCoordinate(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,264 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/cache"
+	"github.com/wneessen/waybar-weather/internal/forecast"
+	"github.com/wneessen/waybar-weather/internal/geocode"
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+// Status represents the state of the running service, as reported by the status command.
+type Status struct {
+	Started   time.Time        `json:"started"`
+	Location  LocationStatus   `json:"location"`
+	Providers []ProviderStatus `json:"providers"`
+	Weather   WeatherStatus    `json:"weather"`
+	Jobs      []JobStatus      `json:"jobs"`
+	Caches    []cache.Stats    `json:"caches"`
+	Budget    BudgetStatus     `json:"budget"`
+	Errors    []logger.Record  `json:"errors"`
+}
+
+// LocationStatus represents the current location. Coordinates and address are reduced according to the
+// privacy mode of the log, since the status is often shared in support requests.
+type LocationStatus struct {
+	Available   bool      `json:"available"`
+	Coordinates string    `json:"coordinates"`
+	Address     string    `json:"address"`
+	Source      string    `json:"source"`
+	Accuracy    float64   `json:"accuracy"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// Motion data of the most recent fix, speed in meters per second and heading in degrees
+	Speed   *float64  `json:"speed,omitempty"`
+	Heading *float64  `json:"heading,omitempty"`
+	FixTime time.Time `json:"fix_time"`
+}
+
+// ProviderStatus represents the latest result of a geolocation provider.
+type ProviderStatus struct {
+	Name       string    `json:"name"`
+	LastResult time.Time `json:"last_result"`
+	Accuracy   float64   `json:"accuracy"`
+	// ExcludedUntil is set while the provider is excluded because it failed its probe
+	ExcludedUntil time.Time `json:"excluded_until"`
+	// Worker lifecycle of the provider in the worker pool
+	Worker      string    `json:"worker"`
+	Started     time.Time `json:"started"`
+	Restarts    int       `json:"restarts"`
+	LastPanic   string    `json:"last_panic,omitempty"`
+	LastPanicAt time.Time `json:"last_panic_at"`
+}
+
+// WeatherStatus represents the state of the weather data.
+type WeatherStatus struct {
+	Source      string        `json:"source"`
+	LastAttempt time.Time     `json:"last_attempt"`
+	LastSuccess time.Time     `json:"last_success"`
+	Latency     time.Duration `json:"latency"`
+	LastError   string        `json:"last_error"`
+	DataTime    time.Time     `json:"data_time"`
+	Geocoder    string        `json:"geocoder"`
+	// GeocoderQuota is the request quota of the geocoder, if it reports one
+	GeocoderQuota *geocode.Quota `json:"geocoder_quota,omitempty"`
+	// Optional data sets of the active weather backend
+	Capabilities forecast.Capabilities `json:"capabilities"`
+	// ExcludedUntil is set while the active weather backend is excluded because it failed its probe
+	ExcludedUntil time.Time `json:"excluded_until"`
+}
+
+// BudgetStatus represents the daily API usage and whether a request has been refused today.
+type BudgetStatus struct {
+	Exhausted bool               `json:"exhausted"`
+	Hosts     []BudgetHostStatus `json:"hosts"`
+}
+
+// BudgetHostStatus represents the requests of the current day to an API host and its daily limit, 0 means
+// unlimited.
+type BudgetHostStatus struct {
+	Host     string `json:"host"`
+	Requests uint   `json:"requests"`
+	Limit    uint   `json:"limit"`
+}
+
+// JobStatus represents the state of a scheduled job.
+type JobStatus struct {
+	Name    string    `json:"name"`
+	LastRun time.Time `json:"last_run"`
+	NextRun time.Time `json:"next_run"`
+}
+
+// fetchState keeps track of the weather fetches for the status.
+type fetchState struct {
+	mu          sync.Mutex
+	lastAttempt time.Time
+	lastSuccess time.Time
+	latency     time.Duration
+	lastError   string
+	// Weather backends that failed their probe and the time until which they are excluded
+	excluded map[string]time.Time
+}
+
+// setBackendExcluded sets the time until which the weather backend is excluded from the weather updates.
+// The zero time removes the exclusion. Returns true if the backend was excluded before.
+func (s *Service) setBackendExcluded(backend string, until time.Time) bool {
+	s.fetches.mu.Lock()
+	defer s.fetches.mu.Unlock()
+	_, excluded := s.fetches.excluded[backend]
+	if until.IsZero() {
+		delete(s.fetches.excluded, backend)
+		return excluded
+	}
+	if s.fetches.excluded == nil {
+		s.fetches.excluded = make(map[string]time.Time)
+	}
+	s.fetches.excluded[backend] = until
+	return excluded
+}
+
+// backendExcludedUntil returns the time until which the weather backend is excluded because it failed its
+// probe.
+func (s *Service) backendExcludedUntil(backend string) (time.Time, bool) {
+	s.fetches.mu.Lock()
+	defer s.fetches.mu.Unlock()
+	until, ok := s.fetches.excluded[backend]
+	return until, ok
+}
+
+// recordFetch records the result of a weather fetch that was started at the given time.
+func (s *Service) recordFetch(start time.Time, err error) {
+	s.fetches.mu.Lock()
+	defer s.fetches.mu.Unlock()
+	s.fetches.latency = time.Since(start)
+	// Keep the wall clock reading only, so that the age of the weather data advances during suspend and
+	// follows changes of the system time
+	start = start.Round(0)
+	s.fetches.lastAttempt = start
+	if err != nil {
+		s.fetches.lastError = err.Error()
+		return
+	}
+	s.fetches.lastSuccess = start
+	s.fetches.lastError = ""
+}
+
+// weatherStale returns true if the last weather fetch failed, so that the weather data of an earlier fetch
+// is shown.
+func (s *Service) weatherStale() bool {
+	s.fetches.mu.Lock()
+	defer s.fetches.mu.Unlock()
+	return s.fetches.lastError != "" && s.fetches.lastAttempt.After(s.fetches.lastSuccess)
+}
+
+// Status returns the current state of the service.
+func (s *Service) Status() Status {
+	status := Status{Started: s.started, Errors: s.logger.RecentErrors()}
+
+	s.locationLock.RLock()
+	if s.locationIsSet {
+		status.Location = LocationStatus{
+			Available:   true,
+			Coordinates: s.logger.Coordinates(s.coordinates.Lat, s.coordinates.Lon).Value.String(),
+			Address:     s.logger.Address(s.address.DisplayName, s.address.City, s.address.Country).Value.String(),
+			Source:      s.geoSource,
+			Accuracy:    s.coordinates.Acc,
+			UpdatedAt:   s.locationUpdated,
+		}
+	}
+	s.locationLock.RUnlock()
+	s.fixLock.RLock()
+	if speed := s.lastFix.Speed; s.lastFix.HasSpeed {
+		status.Location.Speed = &speed
+	}
+	if heading := s.lastFix.Heading; s.lastFix.HasHeading {
+		status.Location.Heading = &heading
+	}
+	status.Location.FixTime = s.lastFix.SourceTime
+	s.fixLock.RUnlock()
+
+	latest := s.geobus.Latest()
+	if s.orchestrator != nil {
+		for _, provider := range s.orchestrator.Providers {
+			result := latest[provider.Name()]
+			excludedUntil, _ := s.orchestrator.ExcludedUntil(provider.Name())
+			worker, _ := s.orchestrator.Worker(provider.Name())
+			status.Providers = append(status.Providers, ProviderStatus{
+				Name:          provider.Name(),
+				LastResult:    result.At,
+				Accuracy:      result.AccuracyMeters,
+				ExcludedUntil: excludedUntil,
+				Worker:        string(worker.State),
+				Started:       worker.Started,
+				Restarts:      worker.Restarts,
+				LastPanic:     worker.LastPanic,
+				LastPanicAt:   worker.LastPanicAt,
+			})
+		}
+	}
+
+	s.backendLock.RLock()
+	status.Weather.Source = s.backend
+	status.Weather.Capabilities = s.backendCapabilities(s.backend)
+	s.backendLock.RUnlock()
+	s.fetches.mu.Lock()
+	status.Weather = WeatherStatus{
+		Source:       status.Weather.Source,
+		Capabilities: status.Weather.Capabilities,
+		LastAttempt:  s.fetches.lastAttempt,
+		LastSuccess:  s.fetches.lastSuccess,
+		Latency:      s.fetches.latency,
+		LastError:    s.fetches.lastError,
+		Geocoder:     s.geocoder.Name(),
+	}
+	status.Weather.ExcludedUntil = s.fetches.excluded[status.Weather.Source]
+	s.fetches.mu.Unlock()
+	if quota, ok := s.geocoderQuota(); ok {
+		status.Weather.GeocoderQuota = &quota
+	}
+	s.weatherLock.RLock()
+	if s.weatherIsSet {
+		status.Weather.DataTime = s.weather.CurrentWeather.Time.Time
+	}
+	s.weatherLock.RUnlock()
+
+	for _, job := range s.scheduler.Jobs() {
+		jobStatus := JobStatus{Name: job.Name()}
+		jobStatus.LastRun, _ = job.LastRun()
+		jobStatus.NextRun, _ = job.NextRun()
+		status.Jobs = append(status.Jobs, jobStatus)
+	}
+	for _, store := range s.caches {
+		status.Caches = append(status.Caches, store.Stats())
+	}
+	status.Budget = s.budgetStatus()
+	return status
+}
+
+// geocoderQuota returns the request quota of the geocoder and whether the geocoder has reported one.
+func (s *Service) geocoderQuota() (geocode.Quota, bool) {
+	reporter, ok := s.geocoder.(geocode.QuotaReporter)
+	if !ok {
+		return geocode.Quota{}, false
+	}
+	quota := reporter.Quota()
+	return quota, quota.Known
+}
+
+// budgetStatus returns the API usage of the current day, sorted by host.
+func (s *Service) budgetStatus() BudgetStatus {
+	status := BudgetStatus{Exhausted: s.budget.Exhausted()}
+	for host, requests := range s.budget.Usage() {
+		status.Hosts = append(status.Hosts, BudgetHostStatus{Host: host, Requests: requests,
+			Limit: s.budget.Limit(host)})
+	}
+	slices.SortFunc(status.Hosts, func(a, b BudgetHostStatus) int { return strings.Compare(a.Host, b.Host) })
+	return status
+}
@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"math"
+	"time"
+
+	"github.com/nathan-osman/go-sunrise"
+
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+// Sun elevations in degrees that bound the golden hour and the blue hour.
+const (
+	goldenHourUpper = 6.0
+	goldenHourLower = -4.0
+	blueHourLower   = -6.0
+	// sunriseElevation is the elevation of the center of the sun at sunrise and sunset, accounting for
+	// the atmospheric refraction and the radius of the sun
+	sunriseElevation = -0.833
+)
+
+// polarDayNight returns whether the sun stays above the horizon (midnight sun) or below the horizon
+// (polar night) for the whole given day. Both are false if the sun rises or sets on that day.
+func polarDayNight(latitude, longitude float64, day time.Time) (bool, bool) {
+	sunriseTime, sunsetTime := sunrise.SunriseSunset(latitude, longitude, day.Year(), day.Month(), day.Day())
+	if !sunriseTime.IsZero() && !sunsetTime.IsZero() {
+		return false, false
+	}
+	noon := sunrise.JulianDayToTime(sunrise.MeanSolarNoon(longitude, day.Year(), day.Month(), day.Day()))
+	if sunrise.Elevation(latitude, longitude, noon) > sunriseElevation {
+		return true, false
+	}
+	return false, true
+}
+
+// sunData returns the current position of the sun and the next golden and blue hours at the given
+// coordinates. The times are returned in the time zone of now.
+func (s *Service) sunData(latitude, longitude float64, now time.Time) template.SunData {
+	azimuth, elevation := sunPosition(latitude, longitude, now)
+	data := template.SunData{
+		Azimuth:     azimuth,
+		AzimuthText: s.compassAbbreviation(azimuth),
+		Elevation:   elevation,
+	}
+	data.GoldenHour = nextWindow(latitude, longitude, now, goldenHourLower, goldenHourUpper)
+	data.BlueHour = nextWindow(latitude, longitude, now, blueHourLower, goldenHourLower)
+	return data
+}
+
+// compassAbbreviation returns the localized abbreviation of the compass point of the azimuth in degrees.
+func (s *Service) compassAbbreviation(azimuth float64) string {
+	return s.t.Get(CompassPoints[int(math.Round(azimuth/45))%len(CompassPoints)].Abbreviation)
+}
+
+// twilightData returns the dawn and dusk of the twilight phases of the given day at the given coordinates.
+// A phase is not available if the sun doesn't sink below its elevation, e.g. during the white nights of
+// the high latitudes. The times are returned in the time zone of day.
+func (s *Service) twilightData(latitude, longitude float64, day time.Time) template.TwilightPhaseData {
+	phase := func(name string) template.TwilightData {
+		data := template.TwilightData{Name: s.t.Get(TwilightPhases[name].Name)}
+		dawn, dusk := sunrise.TimeOfElevation(latitude, longitude, TwilightPhases[name].Elevation, day.Year(),
+			day.Month(), day.Day())
+		if dawn.IsZero() || dusk.IsZero() {
+			return data
+		}
+		data.Available = true
+		data.Dawn, data.Dusk = dawn.In(day.Location()), dusk.In(day.Location())
+		return data
+	}
+	data := template.TwilightPhaseData{
+		Civil:        phase("civil"),
+		Nautical:     phase("nautical"),
+		Astronomical: phase("astronomical"),
+	}
+	for _, name := range s.config.Templates.Twilight {
+		data.Shown = append(data.Shown, phase(name))
+	}
+	return data
+}
+
+// sunPosition returns the azimuth (clockwise from north) and the elevation of the sun in degrees.
+func sunPosition(latitude, longitude float64, when time.Time) (float64, float64) {
+	utc := when.UTC()
+	var (
+		d                 = sunrise.MeanSolarNoon(longitude, utc.Year(), utc.Month(), utc.Day())
+		solarAnomaly      = sunrise.SolarMeanAnomaly(d)
+		equationOfCenter  = sunrise.EquationOfCenter(solarAnomaly)
+		eclipticLongitude = sunrise.EclipticLongitude(solarAnomaly, equationOfCenter, d)
+		solarTransit      = sunrise.SolarTransit(d, solarAnomaly, eclipticLongitude)
+		declination       = sunrise.Declination(eclipticLongitude) * sunrise.Degree
+		// The hour angle is positive in the afternoon
+		hourAngle = 2 * math.Pi * (sunrise.TimeToJulianDay(utc) - solarTransit)
+		lat       = latitude * sunrise.Degree
+	)
+	elevation := math.Asin(math.Sin(lat)*math.Sin(declination) +
+		math.Cos(lat)*math.Cos(declination)*math.Cos(hourAngle))
+	azimuth := math.Atan2(math.Sin(hourAngle),
+		math.Cos(hourAngle)*math.Sin(lat)-math.Tan(declination)*math.Cos(lat))
+	return math.Mod(azimuth/sunrise.Degree+540, 360), elevation / sunrise.Degree
+}
+
+// nextWindow returns the current or next period of today or tomorrow in which the sun is between the
+// given elevations, either in the morning or in the evening. The window is not available if the sun
+// doesn't pass the elevations, e.g. close to the poles.
+func nextWindow(latitude, longitude float64, now time.Time, lower, upper float64) template.SunWindow {
+	for day := 0; day < 2; day++ {
+		date := now.UTC().AddDate(0, 0, day)
+		lowerMorning, lowerEvening := sunrise.TimeOfElevation(latitude, longitude, lower, date.Year(),
+			date.Month(), date.Day())
+		upperMorning, upperEvening := sunrise.TimeOfElevation(latitude, longitude, upper, date.Year(),
+			date.Month(), date.Day())
+		if lowerMorning.IsZero() || upperMorning.IsZero() {
+			continue
+		}
+		windows := []template.SunWindow{
+			{Available: true, Morning: true, Start: lowerMorning, End: upperMorning},
+			{Available: true, Start: upperEvening, End: lowerEvening},
+		}
+		for _, window := range windows {
+			if window.End.After(now) {
+				window.Start, window.End = window.Start.In(now.Location()), window.End.In(now.Location())
+				return window
+			}
+		}
+	}
+	return template.SunWindow{}
+}
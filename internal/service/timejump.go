@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/event"
+)
+
+const (
+	// timeJumpInterval is the interval in which the wall clock is compared with the monotonic clock
+	timeJumpInterval = 10 * time.Second
+	// timeJumpThreshold is the divergence of the wall clock above which the system time counts as changed
+	timeJumpThreshold = 5 * time.Second
+)
+
+// monitorTimeJumps detects changes of the system time by the divergence of the wall clock from the
+// monotonic clock, which isn't affected by them. On a change, the timestamps of the last updates are
+// adjusted and the time-dependent output is re-evaluated.
+func (s *Service) monitorTimeJumps(ctx context.Context) {
+	ticker := time.NewTicker(timeJumpInterval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			jump := now.Round(0).Sub(last.Round(0)) - now.Sub(last)
+			last = now
+			if jump.Abs() <= timeJumpThreshold {
+				continue
+			}
+			// A forward jump is usually a suspend and resume, so it is not worth more than a debug message
+			if jump < 0 {
+				s.logger.Info("system time changed", slog.Duration("offset", jump))
+				s.shiftUpdateTimes(jump)
+			} else {
+				s.logger.Debug("system time jumped forward", slog.Duration("offset", jump))
+			}
+			s.events.Publish(event.TimeChanged, "clock")
+		}
+	}
+}
+
+// shiftUpdateTimes moves the timestamps of the last weather fetch and location update by the given
+// offset, so that their age is kept when the system time is set back. Forward jumps are not applied,
+// since the monotonic clock doesn't advance during suspend on every platform, so a resume can't be told
+// apart from a forward change; the weather data then merely appears older than it is. The monotonic clock
+// reading is stripped, so that the age is computed from the wall clock the offset applies to.
+func (s *Service) shiftUpdateTimes(offset time.Duration) {
+	s.fetches.mu.Lock()
+	if !s.fetches.lastAttempt.IsZero() {
+		s.fetches.lastAttempt = s.fetches.lastAttempt.Round(0).Add(offset)
+	}
+	if !s.fetches.lastSuccess.IsZero() {
+		s.fetches.lastSuccess = s.fetches.lastSuccess.Round(0).Add(offset)
+	}
+	s.fetches.mu.Unlock()
+
+	s.locationLock.Lock()
+	if !s.locationUpdated.IsZero() {
+		s.locationUpdated = s.locationUpdated.Round(0).Add(offset)
+	}
+	s.locationLock.Unlock()
+}
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	localtimeFile         = "/etc/localtime"
+	timezoneCheckInterval = time.Minute
+)
+
+// monitorTimezone watches for changes of the system time zone while the service is running, so that
+// all displayed times follow the new time zone without a restart. Changes via systemd-timedated are
+// picked up immediately, while changes of /etc/localtime are picked up by periodic checks. If the time
+// zone is set via the TZ environment variable, the system time zone doesn't apply and is not monitored.
+func (s *Service) monitorTimezone(ctx context.Context) {
+	if _, ok := os.LookupEnv("TZ"); ok {
+		return
+	}
+
+	changes, unsubscribe, err := subscribeTimezoneChanges()
+	if err != nil {
+		s.logger.Debug("failed to subscribe to time zone changes, falling back to periodic checks",
+			logger.Err(err))
+	} else {
+		defer func() {
+			if err := unsubscribe(); err != nil {
+				s.logger.Error("failed to close system bus connection", logger.Err(err))
+			}
+		}()
+	}
+
+	current, _ := os.ReadFile(localtimeFile)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changes:
+		case <-time.After(timezoneCheckInterval):
+		}
+
+		data, err := os.ReadFile(localtimeFile)
+		if err != nil {
+			s.logger.Debug("failed to read system time zone", logger.Err(err))
+			continue
+		}
+		if bytes.Equal(data, current) {
+			continue
+		}
+		current = data
+
+		zone, err := loadSystemTimezone(data)
+		if err != nil {
+			s.logger.Error("failed to load system time zone", logger.Err(err))
+			continue
+		}
+		s.logger.Info("system time zone changed", slog.String("timezone", zone.String()))
+		s.localZoneLock.Lock()
+		s.localZone = zone
+		s.localZoneLock.Unlock()
+		if !s.onDemand() {
+			s.printWeather(ctx)
+		}
+	}
+}
+
+// loadSystemTimezone returns the time zone of the given /etc/localtime data. The name of the time zone
+// is derived from the target of the /etc/localtime symlink, if available.
+func loadSystemTimezone(data []byte) (*time.Location, error) {
+	name := "Local"
+	if target, err := filepath.EvalSymlinks(localtimeFile); err == nil {
+		if _, zone, found := strings.Cut(target, "zoneinfo/"); found {
+			name = zone
+		}
+	}
+	zone, err := time.LoadLocationFromTZData(name, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse time zone data: %w", err)
+	}
+	return zone, nil
+}
+
+// localNow returns the current time in the system time zone.
+func (s *Service) localNow() time.Time {
+	s.localZoneLock.RLock()
+	defer s.localZoneLock.RUnlock()
+	return s.clock().In(s.localZone)
+}
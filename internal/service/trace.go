@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// startupTrace records the timing of the steps on the path to the first output, so that slow steps at
+// startup can be identified. Only the first occurrence of every step is recorded.
+type startupTrace struct {
+	mu    sync.Mutex
+	start time.Time
+	spans []traceSpan
+	done  bool
+}
+
+// traceSpan is a recorded step of the startup, with its start as offset from the start of the service.
+type traceSpan struct {
+	name     string
+	detail   string
+	offset   time.Duration
+	duration time.Duration
+}
+
+// begin starts the given step and returns the function that ends it. The detail, e.g. the provider in
+// use, is passed to the end function, since it is often only known once the step is done.
+func (t *startupTrace) begin(name string) func(detail string) {
+	begin := time.Now()
+	return func(detail string) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.done || t.start.IsZero() || slices.ContainsFunc(t.spans, func(span traceSpan) bool {
+			return span.name == name
+		}) {
+			return
+		}
+		t.spans = append(t.spans, traceSpan{
+			name:     name,
+			detail:   detail,
+			offset:   begin.Sub(t.start),
+			duration: time.Since(begin),
+		})
+	}
+}
+
+// mark records a step that ended at the given time, e.g. the first result of a geolocation provider.
+func (t *startupTrace) mark(name, detail string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done || t.start.IsZero() || at.Before(t.start) {
+		return
+	}
+	t.spans = append(t.spans, traceSpan{name: name, detail: detail, duration: at.Sub(t.start)})
+}
+
+// finish ends the trace and returns the time to the first output and the recorded steps in the order
+// they ended. Returns false if the trace has already been finished.
+func (t *startupTrace) finish() (time.Duration, []traceSpan, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done || t.start.IsZero() {
+		return 0, nil, false
+	}
+	t.done = true
+	spans := slices.Clone(t.spans)
+	slices.SortStableFunc(spans, func(a, b traceSpan) int {
+		return cmp.Compare(a.offset+a.duration, b.offset+b.duration)
+	})
+	return time.Since(t.start), spans, true
+}
+
+// logStartupTrace logs the timing of the startup once the first output has been written. A startup
+// exceeding the configured latency budget is logged as warning.
+func (s *Service) logStartupTrace() {
+	if s.orchestrator != nil {
+		for _, provider := range s.orchestrator.Providers {
+			if at, ok := s.orchestrator.FirstResult(provider.Name()); ok {
+				s.startup.mark("provider_"+strings.ToLower(provider.Name()), provider.Name(), at)
+			}
+		}
+	}
+	total, spans, ok := s.startup.finish()
+	if !ok {
+		return
+	}
+
+	attrs := []any{slog.Duration("total", total)}
+	for _, span := range spans {
+		group := []any{slog.Duration("start", span.offset), slog.Duration("duration", span.duration)}
+		if span.detail != "" {
+			group = append(group, slog.String("detail", span.detail))
+		}
+		attrs = append(attrs, slog.Group(span.name, group...))
+	}
+	if budget := s.config.StartupBudget; budget > 0 && total > budget {
+		s.logger.Warn("startup exceeded the latency budget", append(attrs, slog.Duration("budget", budget))...)
+		return
+	}
+	s.logger.Debug("startup timing", attrs...)
+}
@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hectormalot/omgo"
+
+	"github.com/wneessen/waybar-weather/internal/config"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+// tripMetrics are the hourly metrics requested for the trip destination.
+var tripMetrics = []string{"temperature_2m", "weather_code", "is_day", "precipitation", "wind_speed_10m"}
+
+// tripState holds the forecast at the trip destination at the expected arrival.
+type tripState struct {
+	mu   sync.RWMutex
+	data template.TripData
+}
+
+// fetchTrip fetches the forecast at the trip destination, if a trip is configured and the arrival is within
+// the forecast range. The caller is expected to hold the backend lock.
+func (s *Service) fetchTrip(ctx context.Context) {
+	if !s.config.HasTrip() {
+		return
+	}
+	data, err := s.tripForecast(ctx)
+	if err != nil {
+		s.logger.Error("failed to get trip forecast", logger.Err(err))
+	}
+
+	s.trip.mu.Lock()
+	defer s.trip.mu.Unlock()
+	s.trip.data = data
+}
+
+// tripForecast returns the forecast at the trip destination for the hour of the expected arrival. If the
+// arrival has passed or is beyond the forecast range, the returned data is not available.
+func (s *Service) tripForecast(ctx context.Context) (template.TripData, error) {
+	data := template.TripData{Name: s.config.Trip.Name}
+	arrival, err := config.ParseArrival(s.config.Trip.Arrival, s.localNow())
+	if err != nil {
+		return data, fmt.Errorf("failed to parse arrival: %w", err)
+	}
+	data.Arrival = arrival
+	if arrival.Before(s.clock().Add(-time.Hour)) {
+		return data, nil
+	}
+
+	location, err := omgo.NewLocation(s.config.Trip.Latitude, s.config.Trip.Longitude)
+	if err != nil {
+		return data, fmt.Errorf("failed to create location: %w", err)
+	}
+	opts := &omgo.Options{
+		Timezone:          "UTC",
+		HourlyMetrics:     tripMetrics,
+		TemperatureUnit:   "celsius",
+		PrecipitationUnit: s.precipitationUnit(),
+		WindspeedUnit:     windUnits[s.windUnit()].param,
+	}
+	if s.config.Units == "imperial" {
+		opts.TemperatureUnit = "fahrenheit"
+	}
+	forecast, err := s.omclient.Forecast(ctx, location, opts)
+	if err != nil {
+		return data, fmt.Errorf("failed to get forecast: %w", err)
+	}
+	for _, metric := range tripMetrics {
+		if len(forecast.HourlyMetrics[metric]) != len(forecast.HourlyTimes) {
+			return data, fmt.Errorf("hourly metric %q is missing", metric)
+		}
+	}
+
+	idx := slices.IndexFunc(forecast.HourlyTimes, func(t time.Time) bool {
+		return t.Equal(arrival.UTC().Truncate(time.Hour))
+	})
+	if idx == -1 {
+		return data, nil
+	}
+	isDay := forecast.HourlyMetrics["is_day"][idx] == 1
+	data.Available = true
+	data.Temperature = forecast.HourlyMetrics["temperature_2m"][idx]
+	data.WeatherCode = forecast.HourlyMetrics["weather_code"][idx]
+	data.ConditionIcon = s.conditionIcon(data.WeatherCode, isDay)
+	data.Condition = s.conditionText(data.WeatherCode)
+	data.Precipitation = forecast.HourlyMetrics["precipitation"][idx]
+	data.WindSpeed = s.windSpeed(forecast.HourlyMetrics["wind_speed_10m"][idx])
+	return data, nil
+}
+
+// tripData returns the forecast at the trip destination in the local time zone.
+func (s *Service) tripData(loc *time.Location) template.TripData {
+	s.trip.mu.RLock()
+	defer s.trip.mu.RUnlock()
+	data := s.trip.data
+	data.Arrival = data.Arrival.In(loc)
+	return data
+}
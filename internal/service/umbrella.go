@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+// UmbrellaClass is added to the output if precipitation is expected before the configured home time.
+const UmbrellaClass = "umbrella"
+
+// umbrellaData determines if precipitation is expected between now and the configured home time of the
+// day, based on the expected end and start of the precipitation. After the home time, no umbrella is
+// needed anymore.
+func (s *Service) umbrellaData(now time.Time, end template.PrecipitationData,
+	start template.PrecipitationStartData,
+) template.UmbrellaData {
+	// The home time is validated by the configuration
+	clock, _ := time.Parse("15:04", s.config.Umbrella.Home)
+	home := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	data := template.UmbrellaData{Home: home}
+	switch {
+	case !now.Before(home):
+	case end.IsActive:
+		data.Needed, data.Start = true, now
+	case start.Expected && start.Time.Before(home):
+		data.Needed, data.Start = true, start.Time
+	}
+	return data
+}
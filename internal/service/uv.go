@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+// uvWindows returns the time ranges of today in which the UV index reaches the configured threshold and
+// that have not ended yet. The start and end of a range are interpolated between the hourly values.
+// Returns nil if the weather backend doesn't provide the UV index.
+func (s *Service) uvWindows(snap *stateSnapshot, now time.Time) []template.UVWindowData {
+	uv, ok := snap.weather.HourlyMetrics["uv_index"]
+	if !ok {
+		return nil
+	}
+	threshold := s.config.UV.Threshold
+	times := snap.weather.HourlyTimes
+	crossing := func(i int) time.Time {
+		fraction := (threshold - uv[i-1]) / (uv[i] - uv[i-1])
+		return times[i-1].Add(time.Duration(fraction * float64(times[i].Sub(times[i-1])))).Round(time.Minute)
+	}
+
+	var windows []template.UVWindowData
+	var window *template.UVWindowData
+	year, month, day := now.Date()
+	for i, t := range times {
+		if y, m, d := t.In(now.Location()).Date(); y != year || m != month || d != day {
+			continue
+		}
+		switch {
+		case uv[i] >= threshold && window == nil:
+			window = &template.UVWindowData{Start: t, End: t, Max: uv[i]}
+			if i > 0 && uv[i-1] < threshold {
+				window.Start = crossing(i)
+			}
+		case uv[i] >= threshold:
+			window.End, window.Max = t, max(window.Max, uv[i])
+		case window != nil:
+			window.End = crossing(i)
+			windows = append(windows, *window)
+			window = nil
+		}
+	}
+	if window != nil {
+		windows = append(windows, *window)
+	}
+
+	result := make([]template.UVWindowData, 0, len(windows))
+	for _, current := range windows {
+		if current.End.Before(now) {
+			continue
+		}
+		current.Start, current.End = current.Start.In(now.Location()), current.End.In(now.Location())
+		result = append(result, current)
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/template"
+)
+
+const (
+	// dewPointMargin is the minimum difference in °C between the indoor and outdoor dew points before
+	// ventilating is considered to dry or humidify the home
+	dewPointMargin = 1.0
+
+	VentilationDryClass   = "ventilation-dry"
+	VentilationHumidClass = "ventilation-humid"
+)
+
+// indoorReading represents the content of the indoor sensor file. The temperature is expected in the
+// configured units.
+type indoorReading struct {
+	Temperature *float64 `json:"temperature"`
+	Humidity    *float64 `json:"humidity"`
+}
+
+// readIndoorSensor reads the indoor temperature and humidity from the configured sensor file. Readings
+// older than the configured maximum age are considered stale.
+func (s *Service) readIndoorSensor() (indoorReading, error) {
+	var reading indoorReading
+	info, err := os.Stat(s.config.Indoor.File)
+	if err != nil {
+		return reading, fmt.Errorf("failed to stat indoor sensor file: %w", err)
+	}
+	if time.Since(info.ModTime()) > s.config.Indoor.MaxAge {
+		return reading, fmt.Errorf("indoor sensor reading is stale, last update at %s",
+			info.ModTime().Format(time.RFC3339))
+	}
+	data, err := os.ReadFile(s.config.Indoor.File)
+	if err != nil {
+		return reading, fmt.Errorf("failed to read indoor sensor file: %w", err)
+	}
+	if err = json.Unmarshal(data, &reading); err != nil {
+		return reading, fmt.Errorf("failed to parse indoor sensor file: %w", err)
+	}
+	if reading.Temperature == nil || reading.Humidity == nil {
+		return reading, fmt.Errorf("indoor sensor file is missing temperature or humidity")
+	}
+	if *reading.Humidity <= 0 || *reading.Humidity > 100 {
+		return reading, fmt.Errorf("invalid indoor humidity: %f", *reading.Humidity)
+	}
+	return reading, nil
+}
+
+// ventilation compares the indoor and outdoor dew points and advises whether opening the windows will
+// dry or humidify the home. The absolute amount of water in the air is reflected by the dew point, so
+// if the outdoor dew point is lower, the outside air is drier even if its relative humidity is higher.
+func (s *Service) ventilation(data *template.DisplayData) template.VentilationData {
+	if s.config.Indoor.File == "" {
+		return template.VentilationData{}
+	}
+	reading, err := s.readIndoorSensor()
+	if err != nil {
+		s.logger.Debug("indoor sensor reading not available", logger.Err(err))
+		return template.VentilationData{}
+	}
+
+	if data.Current.Humidity <= 0 {
+		return template.VentilationData{}
+	}
+
+	result := template.VentilationData{
+		Available:         true,
+		IndoorTemperature: *reading.Temperature,
+		IndoorHumidity:    *reading.Humidity,
+	}
+	indoorCelsius, outdoorCelsius := result.IndoorTemperature, data.Current.Temperature
+	if s.config.Units == "imperial" {
+		indoorCelsius, outdoorCelsius = fahrenheitToCelsius(indoorCelsius), fahrenheitToCelsius(outdoorCelsius)
+	}
+	indoorDewPoint := dewPoint(indoorCelsius, result.IndoorHumidity)
+	outdoorDewPoint := dewPoint(outdoorCelsius, data.Current.Humidity)
+	result.Drying = outdoorDewPoint < indoorDewPoint-dewPointMargin
+	result.Humidifying = outdoorDewPoint > indoorDewPoint+dewPointMargin
+
+	result.IndoorDewPoint, result.OutdoorDewPoint = indoorDewPoint, outdoorDewPoint
+	if s.config.Units == "imperial" {
+		result.IndoorDewPoint = celsiusToFahrenheit(indoorDewPoint)
+		result.OutdoorDewPoint = celsiusToFahrenheit(outdoorDewPoint)
+	}
+	return result
+}
+
+// dewPoint calculates the dew point in °C for the given temperature in °C and relative humidity in %,
+// using the Magnus formula.
+func dewPoint(celsius, humidity float64) float64 {
+	const a, b = 17.62, 243.12
+	gamma := math.Log(humidity/100) + a*celsius/(b+celsius)
+	return b * gamma / (a - gamma)
+}
+
+// fahrenheitToCelsius converts a temperature value from degree Fahrenheit to degree Celsius.
+func fahrenheitToCelsius(fahrenheit float64) float64 {
+	return (fahrenheit - 32) * 5 / 9
+}
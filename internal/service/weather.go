@@ -6,18 +6,163 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
 	"time"
 
+	"github.com/wneessen/waybar-weather/internal/event"
 	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/observation"
 
 	"github.com/hectormalot/omgo"
 )
 
-const FetchTimeout = time.Second * 10
+const (
+	FetchTimeout = time.Second * 10
+
+	// lapseRate is the standard atmospheric temperature lapse rate in °C per meter
+	lapseRate = 0.0065
+)
+
+// hourlyMetrics are the hourly metrics requested from the Open-Meteo API.
+var hourlyMetrics = []string{
+	"temperature_2m", "apparent_temperature", "weather_code", "wind_speed_10m", "is_day",
+	"wind_direction_10m", "relative_humidity_2m", "pressure_msl", "freezing_level_height", "precipitation",
+	"surface_pressure",
+}
+
+// optionalHourlyMetrics are the hourly metrics requested from the Open-Meteo API that not every weather
+// backend provides. They are missing from the forecast in that case.
+var optionalHourlyMetrics = []string{"uv_index", "wind_gusts_10m"}
+
+// dailyMetrics are the daily metrics requested from the Open-Meteo API.
+var dailyMetrics = []string{
+	"temperature_2m_max", "temperature_2m_min", "weather_code", "precipitation_probability_max",
+}
+
+// forecastMeta holds the time zone information of the Open-Meteo API response, which is not parsed
+// by the Open-Meteo client.
+type forecastMeta struct {
+	UTCOffsetSeconds int    `json:"utc_offset_seconds"`
+	Timezone         string `json:"timezone"`
+	TimezoneAbbr     string `json:"timezone_abbreviation"`
+}
+
+// expectedUnits maps the configured unit system to the units we expect the Open-Meteo API to return for
+// the hourly metrics. Metrics not listed here are not checked.
+var expectedUnits = map[string]map[string]string{
+	"metric": {
+		"temperature_2m":       "°C",
+		"apparent_temperature": "°C",
+		"relative_humidity_2m": "%",
+		"pressure_msl":         "hPa",
+		"surface_pressure":     "hPa",
+	},
+	"imperial": {
+		"temperature_2m":       "°F",
+		"apparent_temperature": "°F",
+		"relative_humidity_2m": "%",
+		"pressure_msl":         "hPa",
+		"surface_pressure":     "hPa",
+	},
+}
+
+// forecastBody returns the forecast of the current location from the weather cache or, if it is not cached,
+// from the weather backend. The caller is expected to hold the location and backend locks.
+func (s *Service) forecastBody(ctx context.Context, opts *omgo.Options) ([]byte, bool, error) {
+	var body []byte
+	if s.weatherCache != nil && s.weatherCache.Get(s.forecastCacheKey(opts), &body) {
+		s.logger.Debug("using cached forecast data")
+		return body, true, nil
+	}
+	if provider, ok := s.providers[s.backend]; ok {
+		s.localZoneLock.RLock()
+		zone := s.localZone
+		s.localZoneLock.RUnlock()
+		body, err := provider.Forecast(ctx, s.coordinates.Lat, s.coordinates.Lon, zone, opts)
+		return body, false, err
+	}
+	body, err := s.omclient.Get(ctx, s.location, opts)
+	return body, false, err
+}
+
+// backendEndpoint returns the URL of the API of the active weather backend. The caller is expected to hold
+// the backend lock.
+func (s *Service) backendEndpoint() string {
+	if provider, ok := s.providers[s.backend]; ok {
+		return provider.Endpoint()
+	}
+	return s.omclient.URL
+}
+
+// cacheForecast caches the validated forecast of the current location. The caller is expected to hold the
+// location and backend locks.
+func (s *Service) cacheForecast(opts *omgo.Options, body []byte) {
+	if s.weatherCache == nil {
+		return
+	}
+	if err := s.weatherCache.Set(s.forecastCacheKey(opts), body); err != nil {
+		s.logger.Warn("failed to cache forecast data", logger.Err(err))
+	}
+}
+
+// forecastCacheKey returns the cache key of the forecast of the current location in the requested units.
+func (s *Service) forecastCacheKey(opts *omgo.Options) string {
+	return fmt.Sprintf("%s/%s/%.4f,%.4f/%s,%s,%s", s.backend, s.weatherModel(s.backend), s.coordinates.Lat,
+		s.coordinates.Lon, opts.TemperatureUnit, opts.WindspeedUnit, opts.PrecipitationUnit)
+}
+
+// SwitchBackend switches the weather backend at runtime. It waits for a running weather update to finish,
+// discards the weather data of the previous backend and fetches the weather data from the new one.
+func (s *Service) SwitchBackend(ctx context.Context, name string) error {
+	if err := s.setBackend(name); err != nil {
+		return err
+	}
+	s.fetchWeather(ctx)
+	return nil
+}
+
+// setBackend activates the weather backend with the given name and discards the weather data of the
+// previous backend. It waits for a running weather update to finish.
+func (s *Service) setBackend(name string) error {
+	backendURL, ok := s.config.WeatherBackendURL(name)
+	_, isProvider := s.providers[name]
+	if !ok && !isProvider {
+		return fmt.Errorf("unknown weather backend: %s", name)
+	}
+
+	s.backendLock.Lock()
+	previous := s.backend
+	s.backend = name
+	if ok {
+		s.omclient.URL = backendURL
+		s.omclient.Client = s.omHTTP.Client
+		if name == modelBackend {
+			s.omclient.Client = s.omModelHTTP.Client
+		}
+	}
+	s.weatherLock.Lock()
+	s.weather = nil
+	s.observation = nil
+	s.weatherIsSet = false
+	s.weatherLock.Unlock()
+	s.backendLock.Unlock()
+	s.fetches.mu.Lock()
+	s.fetches.lastError = ""
+	s.fetches.mu.Unlock()
+	s.logger.Info("switched weather backend", slog.String("from", previous), slog.String("to", name))
+	return nil
+}
 
 func (s *Service) fetchWeather(ctx context.Context) {
 	ctxFetch, cancelFetch := context.WithTimeout(ctx, FetchTimeout)
 	defer cancelFetch()
+	defer s.inhibitSleep("Updating the weather data")()
 
 	// Skip fetching weather data if no location is set
 	s.locationLock.RLock()
@@ -25,34 +170,380 @@ func (s *Service) fetchWeather(ctx context.Context) {
 	if !s.locationIsSet {
 		return
 	}
+	// Switching the backend waits for the update to finish
+	s.backendLock.RLock()
+	defer s.backendLock.RUnlock()
+	// A backend that failed its probe is not queried until it passes the probe again
+	if until, ok := s.backendExcludedUntil(s.backend); ok {
+		s.logger.Debug("skipping weather update, the weather backend is excluded",
+			slog.String("backend", s.backend), slog.Time("until", until))
+		return
+	}
+	var fetchErr error
+	defer func(start time.Time) { s.recordFetch(start, fetchErr) }(time.Now())
+	endTrace := s.startup.begin("weather_fetch")
 
 	opts := &omgo.Options{
-		PastDays: 1,
-		Timezone: "auto",
-		HourlyMetrics: []string{
-			"temperature_2m", "apparent_temperature", "weather_code", "wind_speed_10m", "is_day",
-			"wind_direction_10m", "relative_humidity_2m", "pressure_msl",
-		},
+		PastDays:      1,
+		Timezone:      "auto",
+		HourlyMetrics: slices.Concat(hourlyMetrics, optionalHourlyMetrics),
+		DailyMetrics:  dailyMetrics,
 	}
 	switch s.config.Units {
 	case "metric":
 		opts.TemperatureUnit = "celsius"
-		opts.PrecipitationUnit = "mm"
-		opts.WindspeedUnit = "kmh"
 	case "imperial":
 		opts.TemperatureUnit = "fahrenheit"
-		opts.PrecipitationUnit = "inch"
-		opts.WindspeedUnit = "mph"
 	}
+	opts.PrecipitationUnit = s.precipitationUnit()
+	opts.WindspeedUnit = windUnits[s.windUnit()].param
 
-	forecast, err := s.omclient.Forecast(ctxFetch, s.location, opts)
+	body, cached, err := s.forecastBody(ctxFetch, opts)
 	if err != nil {
 		s.logger.Error("failed to get forecast data", logger.Err(err))
+		fetchErr = err
+		return
+	}
+	forecast, err := omgo.ParseBody(body)
+	if err != nil {
+		s.logger.Error("failed to parse forecast data", logger.Err(err))
+		fetchErr = err
+		return
+	}
+	var meta forecastMeta
+	if err = json.Unmarshal(body, &meta); err != nil {
+		s.logger.Error("failed to parse forecast time zone", logger.Err(err))
+		fetchErr = err
+		return
+	}
+	timezone := localizeForecast(forecast, meta)
+	if err = s.validateForecast(forecast); err != nil {
+		s.logger.Error("received unexpected forecast data, keeping previous weather data", logger.Err(err))
+		fetchErr = err
 		return
 	}
+	if !cached {
+		s.cacheForecast(opts, body)
+	}
+	s.updateForecastDiff(forecast)
+
+	var obs *observation.Observation
+	if s.observer != nil {
+		result, err := s.observer.Nearest(ctxFetch, s.coordinates.Lat, s.coordinates.Lon)
+		if err != nil {
+			s.logger.Error("failed to get station observation data", logger.Err(err),
+				slog.String("source", s.observer.Name()))
+		} else {
+			obs = &result
+		}
+	}
+
+	s.fetchTrip(ctxFetch)
+	s.fetchModelRun(ctxFetch)
+	s.fetchEnsemble(ctxFetch)
+	s.fetchAlerts(ctxFetch)
 
 	s.weatherLock.Lock()
 	defer s.weatherLock.Unlock()
 	s.weather = forecast
+	s.weatherSource = s.backend
+	s.timezone = timezone
+	s.observation = obs
 	s.weatherIsSet = true
+	source := s.backend
+	if cached {
+		source = "cache"
+	}
+	endTrace(source)
+
+	// The subscribers need the weather lock, so they run once the update is complete
+	s.events.Publish(event.WeatherUpdated, s.backend)
+}
+
+// localizeForecast converts the hourly and current weather times of the forecast, which the API returns
+// as wall clock times of the location, into absolute points in time. Each time is converted with the UTC
+// offset in effect at that time, so that a daylight saving time change within the forecast is honored.
+// Returns the time zone of the location.
+func localizeForecast(forecast *omgo.Forecast, meta forecastMeta) *time.Location {
+	timezone, err := time.LoadLocation(meta.Timezone)
+	if err != nil || meta.Timezone == "" {
+		timezone = time.FixedZone(meta.TimezoneAbbr, meta.UTCOffsetSeconds)
+	}
+
+	var previous time.Time
+	for i, t := range forecast.HourlyTimes {
+		localized := wallClockTime(t, timezone)
+		// The wall clock times of the hour after a fall back change repeat, so the earliest point in time
+		// with the wall clock time that follows its predecessor is used
+		if i > 0 {
+			if next, ok := wallClockTimeAfter(t, timezone, previous); ok {
+				localized = next
+			}
+		}
+		forecast.HourlyTimes[i], previous = localized, localized
+	}
+	forecast.CurrentWeather.Time.Time = wallClockTime(forecast.CurrentWeather.Time.Time, timezone)
+	return timezone
+}
+
+// wallClockTime interprets the date and clock of t, which is given in UTC, as a wall clock time in the time
+// zone timezone and returns the corresponding point in time in UTC.
+func wallClockTime(t time.Time, timezone *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(),
+		timezone).UTC()
+}
+
+// wallClockTimeAfter returns the earliest point in time after the time after that has the wall clock time t
+// in the time zone timezone. Returns false if there is no such point in time within a day.
+func wallClockTimeAfter(t time.Time, timezone *time.Location, after time.Time) (time.Time, bool) {
+	var result time.Time
+	for _, reference := range []time.Time{t.Add(-24 * time.Hour), t.Add(24 * time.Hour)} {
+		_, offset := reference.In(timezone).Zone()
+		candidate := t.Add(-time.Duration(offset) * time.Second).UTC()
+		wall := candidate.In(timezone)
+		if !candidate.After(after) || wall.Hour() != t.Hour() || wall.Minute() != t.Minute() {
+			continue
+		}
+		if result.IsZero() || candidate.Before(result) {
+			result = candidate
+		}
+	}
+	return result, !result.IsZero()
+}
+
+// valueRange represents the range of plausible values for a weather metric.
+type valueRange struct {
+	min, max float64
+}
+
+// plausibleRanges maps the configured unit system to the range of plausible values for the hourly
+// metrics. Values outside of these ranges are considered corrupt.
+var plausibleRanges = map[string]map[string]valueRange{
+	"metric": {
+		"temperature_2m":       {-90, 60},
+		"apparent_temperature": {-100, 70},
+		"wind_direction_10m":   {0, 360},
+		"relative_humidity_2m": {0, 100},
+		"pressure_msl":         {850, 1100},
+		"surface_pressure":     {300, 1100},
+		"is_day":               {0, 1},
+	},
+	"imperial": {
+		"temperature_2m":       {-130, 140},
+		"apparent_temperature": {-148, 158},
+		"wind_direction_10m":   {0, 360},
+		"relative_humidity_2m": {0, 100},
+		"pressure_msl":         {850, 1100},
+		"surface_pressure":     {300, 1100},
+		"is_day":               {0, 1},
+	},
+}
+
+// windUnit describes a wind speed unit of the Open-Meteo API.
+type windUnit struct {
+	// param is the value of the windspeed_unit request parameter and unit the unit returned by the API
+	param, unit string
+	// kmh is the factor to convert the wind speed to km/h
+	kmh       float64
+	plausible valueRange
+}
+
+// windUnits maps the configurable wind units to the units requested from the Open-Meteo API. The wind
+// force on the Beaufort scale is derived from the wind speed in km/h.
+var windUnits = map[string]windUnit{
+	"kmh":      {"kmh", "km/h", 1, valueRange{0, 500}},
+	"mph":      {"mph", "mp/h", metersPerMile / 1000, valueRange{0, 311}},
+	"ms":       {"ms", "m/s", kmhPerMeterPerSecond, valueRange{0, 139}},
+	"kn":       {"kn", "kn", 1.852, valueRange{0, 270}},
+	"beaufort": {"kmh", "km/h", 1, valueRange{0, 500}},
+}
+
+// windUnit returns the configured wind unit. If no wind unit is configured, it follows the units.
+func (s *Service) windUnit() string {
+	switch {
+	case s.config.WindUnit != "":
+		return s.config.WindUnit
+	case s.config.Units == "imperial":
+		return "mph"
+	default:
+		return "kmh"
+	}
+}
+
+// plausiblePrecipitation maps the precipitation units to the range of plausible hourly precipitation.
+var plausiblePrecipitation = map[string]valueRange{
+	"mm":   {0, 500},
+	"inch": {0, 20},
+}
+
+// precipitationUnit returns the configured precipitation unit. If no precipitation unit is configured,
+// it follows the units.
+func (s *Service) precipitationUnit() string {
+	switch {
+	case s.config.PrecipitationUnit != "":
+		return s.config.PrecipitationUnit
+	case s.config.Units == "imperial":
+		return "inch"
+	default:
+		return "mm"
+	}
+}
+
+// contains returns true if the value is within the range and not NaN.
+func (r valueRange) contains(value float64) bool {
+	return value >= r.min && value <= r.max
+}
+
+// validateForecast checks that the forecast returned by the Open-Meteo API contains all required fields
+// in the expected units, so that a change of the API schema does not result in zero values being rendered.
+func (s *Service) validateForecast(forecast *omgo.Forecast) error {
+	if forecast == nil {
+		return errors.New("forecast is nil")
+	}
+	if forecast.CurrentWeather.Time.IsZero() {
+		return errors.New("current weather data is missing")
+	}
+	if len(forecast.HourlyTimes) == 0 {
+		return errors.New("hourly times are missing")
+	}
+	for _, metric := range hourlyMetrics {
+		values, ok := forecast.HourlyMetrics[metric]
+		if !ok {
+			return fmt.Errorf("hourly metric %q is missing", metric)
+		}
+		if len(values) != len(forecast.HourlyTimes) {
+			return fmt.Errorf("hourly metric %q has %d values, expected %d", metric, len(values),
+				len(forecast.HourlyTimes))
+		}
+	}
+	for _, metric := range optionalHourlyMetrics {
+		if values, ok := forecast.HourlyMetrics[metric]; ok && len(values) != len(forecast.HourlyTimes) {
+			return fmt.Errorf("hourly metric %q has %d values, expected %d", metric, len(values),
+				len(forecast.HourlyTimes))
+		}
+	}
+	if len(forecast.DailyTimes) == 0 {
+		return errors.New("daily times are missing")
+	}
+	for _, metric := range dailyMetrics {
+		values, ok := forecast.DailyMetrics[metric]
+		if !ok {
+			return fmt.Errorf("daily metric %q is missing", metric)
+		}
+		if len(values) != len(forecast.DailyTimes) {
+			return fmt.Errorf("daily metric %q has %d values, expected %d", metric, len(values),
+				len(forecast.DailyTimes))
+		}
+	}
+	for metric, unit := range expectedUnits[s.config.Units] {
+		if forecast.HourlyUnits[metric] != unit {
+			return fmt.Errorf("hourly metric %q has unit %q, expected %q", metric, forecast.HourlyUnits[metric],
+				unit)
+		}
+	}
+	if unit := windUnits[s.windUnit()].unit; forecast.HourlyUnits["wind_speed_10m"] != unit {
+		return fmt.Errorf("hourly metric %q has unit %q, expected %q", "wind_speed_10m",
+			forecast.HourlyUnits["wind_speed_10m"], unit)
+	}
+	if _, ok := forecast.HourlyMetrics["wind_gusts_10m"]; ok && forecast.HourlyUnits["wind_gusts_10m"] !=
+		windUnits[s.windUnit()].unit {
+		return fmt.Errorf("hourly metric %q has unit %q, expected %q", "wind_gusts_10m",
+			forecast.HourlyUnits["wind_gusts_10m"], windUnits[s.windUnit()].unit)
+	}
+	if unit := s.precipitationUnit(); forecast.HourlyUnits["precipitation"] != unit {
+		return fmt.Errorf("hourly metric %q has unit %q, expected %q", "precipitation",
+			forecast.HourlyUnits["precipitation"], unit)
+	}
+	if err := checkFinite(forecast); err != nil {
+		return err
+	}
+	return s.checkOutliers(forecast)
+}
+
+// checkFinite returns an error if the forecast contains a NaN or infinite value, which would slip through
+// the range checks of metrics without a plausible range.
+func checkFinite(forecast *omgo.Forecast) error {
+	current := forecast.CurrentWeather
+	for _, value := range []float64{current.Temperature, current.WindSpeed, current.WindDirection,
+		current.WeatherCode, forecast.Elevation} {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("invalid current weather value: %f", value)
+		}
+	}
+	for metric, values := range forecast.HourlyMetrics {
+		for _, value := range values {
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				return fmt.Errorf("invalid value for hourly metric %q: %f", metric, value)
+			}
+		}
+	}
+	for metric, values := range forecast.DailyMetrics {
+		for _, value := range values {
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				return fmt.Errorf("invalid value for daily metric %q: %f", metric, value)
+			}
+		}
+	}
+	return nil
+}
+
+// checkOutliers sanity checks the values of the forecast and returns an error if an obviously corrupt
+// value has been found.
+func (s *Service) checkOutliers(forecast *omgo.Forecast) error {
+	ranges := maps.Clone(plausibleRanges[s.config.Units])
+	ranges["wind_speed_10m"] = windUnits[s.windUnit()].plausible
+	ranges["wind_gusts_10m"] = windUnits[s.windUnit()].plausible
+	ranges["precipitation"] = plausiblePrecipitation[s.precipitationUnit()]
+	current := forecast.CurrentWeather
+	if !ranges["temperature_2m"].contains(current.Temperature) {
+		return fmt.Errorf("implausible current temperature: %f", current.Temperature)
+	}
+	if !ranges["wind_speed_10m"].contains(current.WindSpeed) {
+		return fmt.Errorf("implausible current wind speed: %f", current.WindSpeed)
+	}
+	if !ranges["wind_direction_10m"].contains(current.WindDirection) {
+		return fmt.Errorf("implausible current wind direction: %f", current.WindDirection)
+	}
+	if _, ok := s.conditions.Lookup(current.WeatherCode); !ok {
+		return fmt.Errorf("unknown current weather code: %f", current.WeatherCode)
+	}
+
+	for metric, valid := range ranges {
+		for i, value := range forecast.HourlyMetrics[metric] {
+			if !valid.contains(value) {
+				return fmt.Errorf("implausible value for hourly metric %q at %s: %f", metric,
+					forecast.HourlyTimes[i].Format(time.RFC3339), value)
+			}
+		}
+	}
+	for _, metric := range []string{"temperature_2m_max", "temperature_2m_min"} {
+		for i, value := range forecast.DailyMetrics[metric] {
+			if !ranges["temperature_2m"].contains(value) {
+				return fmt.Errorf("implausible value for daily metric %q at %s: %f", metric,
+					forecast.DailyTimes[i].Format(time.DateOnly), value)
+			}
+		}
+	}
+	for i, code := range forecast.HourlyMetrics["weather_code"] {
+		if _, ok := s.conditions.Lookup(code); !ok {
+			return fmt.Errorf("unknown weather code at %s: %f", forecast.HourlyTimes[i].Format(time.RFC3339), code)
+		}
+	}
+	return nil
+}
+
+// altitudeCorrected corrects the given model temperature for the difference between the elevation of the
+// model grid cell and the elevation of the location of the given state snapshot, using the standard
+// atmospheric lapse rate.
+func (s *Service) altitudeCorrected(snap *stateSnapshot, temperature float64) float64 {
+	correction := (snap.weather.Elevation - snap.coordinates.Alt) * lapseRate
+	if s.config.Units == "imperial" {
+		correction *= 9.0 / 5.0
+	}
+	return temperature + correction
+}
+
+// celsiusToFahrenheit converts a temperature value from degree Celsius to degree Fahrenheit.
+func celsiusToFahrenheit(celsius float64) float64 {
+	return celsius*9/5 + 32
 }
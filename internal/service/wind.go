@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import "math"
+
+// windDirectionText returns the localized compass point of the wind direction in degrees, in the configured
+// format. Since arrows can't be rendered on ASCII-only bars, the abbreviation is used instead.
+func (s *Service) windDirectionText(degrees float64) string {
+	point := CompassPoints[int(math.Round(math.Mod(degrees, 360)/45))%len(CompassPoints)]
+	switch s.config.Templates.WindDirectionFormat {
+	case "words":
+		return s.t.Get(point.Word)
+	case "arrow":
+		if !s.config.ASCIIOnly {
+			return point.Arrow
+		}
+	}
+	return s.t.Get(point.Abbreviation)
+}
+
+// windDescription returns the localized description of the wind speed, as returned by the weather API,
+// according to the Beaufort scale.
+func (s *Service) windDescription(speed float64) string {
+	return s.t.Get(BeaufortScale[s.beaufort(speed)].Description)
+}
+
+// windSpeed converts the wind speed, as returned by the weather API, to the configured wind unit.
+func (s *Service) windSpeed(speed float64) float64 {
+	if s.windUnit() == "beaufort" {
+		return float64(s.beaufort(speed))
+	}
+	return speed
+}
+
+// windSpeedUnit returns the symbol of the configured wind unit.
+func (s *Service) windSpeedUnit() string {
+	if s.windUnit() == "beaufort" {
+		return "Bft"
+	}
+	return windUnits[s.windUnit()].unit
+}
+
+// beaufort returns the wind force on the Beaufort scale of the wind speed, as returned by the weather API.
+func (s *Service) beaufort(speed float64) int {
+	speed *= windUnits[s.windUnit()].kmh
+	for force, limit := range BeaufortScale {
+		if speed < limit.MaxSpeed {
+			return force
+		}
+	}
+	return len(BeaufortScale) - 1
+}
@@ -0,0 +1,37 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package template
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// asciiReplacer replaces the emojis and symbols used by waybar-weather with plain ASCII equivalents.
+// Longer sequences (e.g. emojis with variation selectors) are listed first, so they take precedence.
+var asciiReplacer = strings.NewReplacer(
+	"☀️", "[sun]", "🌤️", "[sun]", "⛅", "[cloudy]", "☁️", "[cloudy]", "🌫️", "[fog]",
+	"🌦️", "[rain]", "🌧️", "[rain]", "🌨️", "[snow]", "🌩️", "[storm]", "⛈️", "[storm]",
+	"🌙", "[moon]", "🌑", "(new)", "🌒", "(wax)", "🌓", "(1q)", "🌔", "(wax)",
+	"🌕", "(full)", "🌖", "(wan)", "🌗", "(3q)", "🌘", "(wan)",
+	"🔥", "[heat]", "❄️", "[cool]", "🪟", "[window]",
+	"🌅", "sunrise", "🌇", "sunset", "°", "", "•", "-", "…", "...", "–", "-", "—", "-",
+)
+
+// ToASCII converts the given string to plain ASCII for TTY-based bars that are unable to render
+// Unicode. Known emojis and symbols are replaced with ASCII equivalents, accented characters are
+// stripped of their diacritics and all remaining non-ASCII characters are removed.
+func ToASCII(val string) string {
+	val = asciiReplacer.Replace(val)
+	val = norm.NFD.String(val)
+	return strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII {
+			return -1
+		}
+		return r
+	}, val)
+}
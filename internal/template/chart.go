@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package template
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// sparkBlocks are the block characters of the bars, from the lowest to the highest.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders the values as a line of block characters, scaled between low and high. If blankLow
+// is set, values at or below low are rendered as space, e.g. the dry hours of a precipitation chart.
+func Sparkline(values []float64, low, high float64, blankLow bool) string {
+	var line strings.Builder
+	for _, value := range values {
+		if blankLow && value <= low {
+			line.WriteRune(' ')
+			continue
+		}
+		level := 0
+		if high > low {
+			level = int(math.Round((value - low) / (high - low) * float64(len(sparkBlocks)-1)))
+		}
+		line.WriteRune(sparkBlocks[min(max(level, 0), len(sparkBlocks)-1)])
+	}
+	return line.String()
+}
+
+// ChartLabels returns the hours of the given times as labels below a chart with one bar per time. A label
+// is set every step bars, so that the two-digit hours fit below the bars.
+func ChartLabels(times []time.Time, step int) string {
+	var labels strings.Builder
+	for i := 0; i < len(times); i += step {
+		label := fmt.Sprintf("%02d", times[i].Hour())
+		if i+step < len(times) {
+			label = fmt.Sprintf("%-*s", step, label)
+		}
+		labels.WriteString(label)
+	}
+	return labels.String()
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package template
+
+import (
+	"math"
+	"strconv"
+)
+
+// minPrecipitation is the smallest precipitation amount that is shown, smaller amounts are artifacts of
+// the models or the unit conversion and shown as 0.
+const minPrecipitation = 0.005
+
+// precision holds the configured decimal places of the temperatures, wind speeds and precipitation
+// amounts. A nil value keeps the precision of the weather data.
+type precision struct {
+	temperature   *int
+	wind          *int
+	precipitation *int
+}
+
+// ApplyPrecision rounds the temperatures, wind speeds and precipitation amounts of the display data to
+// the configured decimal places, so that all templates show them alike. Negative zeros are removed and
+// tiny precipitation amounts are shown as 0, even if no precision is configured.
+func (t *Templates) ApplyPrecision(data *DisplayData) {
+	temperatures := []*float64{
+		&data.Current.Temperature, &data.Current.ApparentTemperature, &data.Current.CorrectedTemperature,
+		&data.Forecast.Temperature, &data.Forecast.ApparentTemperature, &data.Forecast.CorrectedTemperature,
+		&data.Today.TemperatureMax, &data.Today.TemperatureMin,
+		&data.Tomorrow.TemperatureMax, &data.Tomorrow.TemperatureMin, &data.TonightMin,
+		&data.Observation.Temperature, &data.Observation.ModelTemperature,
+		&data.Ventilation.IndoorTemperature, &data.Trip.Temperature,
+		&data.Ensemble.TemperatureMaxLow, &data.Ensemble.TemperatureMaxHigh,
+		&data.Ensemble.TemperatureMinLow, &data.Ensemble.TemperatureMinHigh,
+	}
+	for i := range data.Weekend {
+		temperatures = append(temperatures, &data.Weekend[i].TemperatureMax, &data.Weekend[i].TemperatureMin)
+	}
+	round(t.precision.temperature, temperatures...)
+	round(t.precision.wind, &data.Current.WindSpeed, &data.Current.WindGust, &data.Forecast.WindSpeed,
+		&data.Trip.WindSpeed)
+	precipitation := []*float64{&data.Current.Precipitation, &data.Forecast.Precipitation, &data.Trip.Precipitation}
+	for _, value := range precipitation {
+		if math.Abs(*value) < minPrecipitation {
+			*value = 0
+		}
+	}
+	round(t.precision.precipitation, precipitation...)
+}
+
+// Round rounds the value half away from zero to the given decimal places. Unlike math.Round on the
+// scaled value, it isn't affected by the binary representation of the value (e.g. 1.005 is rounded to
+// 1.01, not 1.00) and it never returns a negative zero, which would be formatted as "-0".
+func Round(val float64, decimals int) float64 {
+	if math.IsNaN(val) || math.IsInf(val, 0) {
+		return val
+	}
+	factor := math.Pow(10, float64(decimals))
+	// Rounding the scaled value to 9 decimal places first removes the representation error, e.g.
+	// 1.005 * 100 = 100.49999999999999
+	scaled, _ := strconv.ParseFloat(strconv.FormatFloat(val*factor, 'f', 9, 64), 64)
+	result := math.Round(scaled) / factor
+	if result == 0 {
+		return 0
+	}
+	return result
+}
+
+// round rounds the values to the given decimal places. If no decimal places are set, only negative zeros
+// are removed.
+func round(decimals *int, values ...*float64) {
+	for _, value := range values {
+		switch {
+		case decimals != nil:
+			*value = Round(*value, *decimals)
+		case *value == 0:
+			*value = 0
+		}
+	}
+}
+
+// decimals returns the configured decimal places, or fallback if they are not set.
+func decimals(precision *int, fallback int) int {
+	if precision == nil {
+		return fallback
+	}
+	return *precision
+}
@@ -6,6 +6,7 @@ package template
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"text/template"
 	"time"
@@ -17,24 +18,51 @@ import (
 	"github.com/vorlif/humanize"
 	"github.com/vorlif/humanize/locale/de"
 	"github.com/vorlif/spreak/localize"
+	"golang.org/x/text/message"
 
 	"github.com/wneessen/waybar-weather/internal/config"
+	"github.com/wneessen/waybar-weather/internal/coordinate"
 	"github.com/wneessen/waybar-weather/internal/geocode"
 )
 
 type DisplayData struct {
 	// Location data
-	Latitude  float64
-	Longitude float64
-	Elevation float64
-	Address   geocode.Address
+	Latitude       float64
+	Longitude      float64
+	Coordinates    string
+	Elevation      float64
+	Address        geocode.Address
+	AddressIsStale bool
+	// Label of the geofence the location is in
+	Place string
+	// Geolocation provider and accuracy of the location
+	LocationSource       string
+	LocationAccuracy     float64
+	LocationAccuracyText string
+	LocationIsCoarse     bool
+	// Speed and heading of the most recent geolocation fix
+	Motion MotionData
+
+	// Elevation of the location (as reported by the geolocation provider or the elevation lookup)
+	LocationElevation    float64
+	HasLocationElevation bool
 
 	// General weather and moon phase data
+	LocalTime              time.Time
+	Now                    time.Time
+	ClockSynchronized      bool
 	UpdateTime             time.Time
 	TempUnit               string
 	PressureUnit           string
+	WindSpeedUnit          string
+	PrecipitationUnit      string
 	SunsetTime             time.Time
 	SunriseTime            time.Time
+	SunProgress            float64
+	MidnightSun            bool
+	PolarNight             bool
+	Sun                    SunData
+	Twilight               TwilightPhaseData
 	Moonphase              string
 	MoonphaseIcon          string
 	MoonphaseIconWithSpace string
@@ -42,6 +70,94 @@ type DisplayData struct {
 	// Current weather and forecast data
 	Current  WeatherData
 	Forecast WeatherData
+
+	// Daily weather data
+	Today      DailyData
+	Tomorrow   DailyData
+	TonightMin float64
+	Weekend    []DailyData
+
+	// Heating and cooling degree days and the thermostat indicator
+	DegreeDays DegreeDayData
+
+	// Ventilation advice based on the indoor and outdoor dew points
+	Ventilation VentilationData
+
+	// Forecast at the trip destination at the expected arrival
+	Trip TripData
+
+	// Likely range of tomorrow's high and low according to the ensemble forecast
+	Ensemble EnsembleData
+
+	// Weather model the forecast has been requested from and the latest run of the configured model
+	WeatherModel string
+	ModelRun     ModelRunData
+
+	// Rapid changes between the current conditions and the short-term forecast
+	DeltaAlerts []DeltaAlertData
+
+	// Official weather warnings of the weather backend in effect for the location
+	Alerts []AlertData
+
+	// Optional data sets the weather backend of the weather data provides
+	Capabilities CapabilityData
+
+	// Expected end of the current precipitation and start of the next precipitation
+	PrecipitationEnd   PrecipitationData
+	PrecipitationStart PrecipitationStartData
+	// River discharge forecast of the configured river point
+	Flood FloodData
+	// Recent earthquakes around the current location, latest first
+	Earthquakes []EarthquakeData
+	// Tonight's remaining visible passes of the configured satellites
+	SatellitePasses []SatellitePassData
+
+	// Notable changes of today's and tomorrow's forecast since the first forecast of the day
+	ForecastChanges []ForecastChangeData
+
+	// Time ranges of today in which the UV index reaches the configured threshold
+	UVWindows []UVWindowData
+	// Whether precipitation is expected before the configured home time
+	Umbrella           UmbrellaData
+	TemperatureChart   ChartData
+	PrecipitationChart ChartData
+
+	// Weather station observation data
+	Observation ObservationData
+
+	// Attribution lines of the data providers in use
+	Attribution []string
+
+	// Daily request counts per external API host
+	APIUsage map[string]uint
+
+	// Request quota of the geocoding provider (if reported by the provider)
+	GeocoderQuota geocode.Quota
+}
+
+type DailyData struct {
+	Available                bool
+	Date                     time.Time
+	TemperatureMax           float64
+	TemperatureMin           float64
+	PrecipitationProbability float64
+	WeatherCode              float64
+	ConditionIcon            string
+	ConditionIconWithSpace   string
+	ConditionIconPath        string
+	Condition                string
+	// Allowed values: clear, cloudy, rain, snow, storm, fog
+	ConditionGroup string
+}
+
+type ObservationData struct {
+	Available        bool
+	StationID        string
+	StationName      string
+	ObservedAt       time.Time
+	Temperature      float64
+	Humidity         float64
+	ModelTemperature float64
 }
 
 type WeatherData struct {
@@ -50,21 +166,265 @@ type WeatherData struct {
 	ApparentTemperature    float64
 	Humidity               float64
 	PressureMSL            float64
+	SurfacePressure        float64
+	Pressure               float64
+	PressureText           string
 	WeatherCode            float64
 	WindDirection          float64
 	WindSpeed              float64
+	WindGust               float64
+	WindDirectionText      string
+	WindDescription        string
 	ConditionIcon          string
 	ConditionIconWithSpace string
+	ConditionIconPath      string
 	Condition              string
+	ConditionGroup         string
+	TemperatureIcon        string
 	IsDaytime              bool
+	CorrectedTemperature   float64
+	FreezingLevel          float64
+	AboveFreezingLevel     bool
+	Precipitation          float64
+	PrecipitationIntensity string
+}
+
+type DegreeDayData struct {
+	Available        bool
+	HeatingSetpoint  float64
+	CoolingSetpoint  float64
+	HeatingToday     float64
+	CoolingToday     float64
+	HeatingYesterday float64
+	CoolingYesterday float64
+	NeedsHeating     bool
+	NeedsCooling     bool
+	OpenWindows      bool
+	Indicator        string
+}
+
+type VentilationData struct {
+	Available         bool
+	IndoorTemperature float64
+	IndoorHumidity    float64
+	IndoorDewPoint    float64
+	OutdoorDewPoint   float64
+	Drying            bool
+	Humidifying       bool
+}
+
+type TripData struct {
+	Available     bool
+	Name          string
+	Arrival       time.Time
+	Temperature   float64
+	WeatherCode   float64
+	ConditionIcon string
+	Condition     string
+	Precipitation float64
+	WindSpeed     float64
+}
+
+type SunData struct {
+	Azimuth     float64
+	AzimuthText string
+	Elevation   float64
+	GoldenHour  SunWindow
+	BlueHour    SunWindow
+}
+
+type ChartData struct {
+	Available bool
+	// Bars has one block character per hour
+	Bars   string
+	Labels string
+	Min    float64
+	Max    float64
+}
+
+type TwilightData struct {
+	Available bool
+	Name      string
+	Dawn      time.Time
+	Dusk      time.Time
+}
+
+type TwilightPhaseData struct {
+	Civil        TwilightData
+	Nautical     TwilightData
+	Astronomical TwilightData
+	// Shown lists the configured phases in the configured order
+	Shown []TwilightData
+}
+
+type SunWindow struct {
+	Available bool
+	Morning   bool
+	Start     time.Time
+	End       time.Time
+}
+
+type EnsembleData struct {
+	Available          bool
+	Members            int
+	TemperatureMaxLow  float64
+	TemperatureMaxHigh float64
+	TemperatureMinLow  float64
+	TemperatureMinHigh float64
+}
+
+type ModelRunData struct {
+	Available   bool
+	Model       string
+	Initialized time.Time
+	Published   time.Time
+	AgeHours    float64
+	Stale       bool
+}
+
+type DeltaAlertData struct {
+	// Allowed values: temperature-drop, temperature-rise, wind-increase, pressure-fall
+	Kind    string
+	Change  float64
+	Time    time.Time
+	Message string
+}
+
+type CapabilityData struct {
+	Minutely   bool
+	Alerts     bool
+	AirQuality bool
+	Marine     bool
+}
+
+type AlertData struct {
+	Headline string
+	// Severity as given by the issuer, e.g. "yellow" or "warning"
+	Severity string
+	// Zero if the end of the warning is unknown
+	Expires time.Time
+}
+
+type MotionData struct {
+	HasSpeed   bool
+	Speed      float64
+	SpeedUnit  string
+	HasHeading bool
+	Heading    float64
+	FixTime    time.Time
+}
+
+type PrecipitationData struct {
+	IsActive bool
+	EndKnown bool
+	Time     time.Time
+}
+
+type PrecipitationStartData struct {
+	Expected    bool
+	Time        time.Time
+	WeatherCode float64
+	Condition   string
+}
+
+type FloodData struct {
+	Available bool
+	Name      string
+	// River discharge of today in m³/s
+	Discharge float64
+	// Highest river discharge of the forecast in m³/s and its date
+	Max     float64
+	MaxDate time.Time
+	// Allowed values: warning, danger (empty if no threshold is reached)
+	Level string
+}
+
+type EarthquakeData struct {
+	ID        string
+	Magnitude float64
+	Place     string
+	Time      time.Time
+	Latitude  float64
+	Longitude float64
+	// Distance from the current location in the distance unit of the configured units
+	Distance     float64
+	DistanceUnit string
+	URL          string
+}
+
+type SatellitePassData struct {
+	Name string
+	Rise time.Time
+	// Time of the highest elevation of the pass
+	Peak         time.Time
+	Set          time.Time
+	MaxElevation float64
+	// Azimuths in degrees clockwise from north and the localized abbreviations of their compass points
+	RiseAzimuth   float64
+	RiseDirection string
+	SetAzimuth    float64
+	SetDirection  string
+}
+
+type ForecastChangeData struct {
+	// Allowed values: warmer, cooler, wetter, drier
+	Kind     string
+	Date     time.Time
+	Previous float64
+	Current  float64
+	// Time of the forecast the current forecast is compared with
+	Since   time.Time
+	Message string
+}
+
+type UVWindowData struct {
+	Start time.Time
+	End   time.Time
+	// Highest UV index of the time range
+	Max float64
+}
+
+type UmbrellaData struct {
+	Needed bool
+	// Start of the expected precipitation, or the current time if it is raining or snowing
+	Start time.Time
+	Home  time.Time
+}
+
+// Display modes the module text can be cycled through. The mode of the currently displayed text is emitted
+// as "alt" value, so that Waybar can use it in its format and format-icons settings.
+const (
+	ModeCurrent = "current"
+	ModeHourly  = "hourly"
+	ModeDaily   = "daily"
+	ModeAstro   = "astro"
+)
+
+// Mode is a display mode with the template used to render the module text.
+type Mode struct {
+	Name     string
+	Template *template.Template
+}
+
+// Instance holds the templates of an additional module instance. The modes correspond to the global
+// display modes.
+type Instance struct {
+	Modes   []Mode
+	Tooltip *template.Template
 }
 
 type Templates struct {
 	Text      *template.Template
 	AltText   *template.Template
 	Tooltip   *template.Template
+	Briefing  *template.Template
+	Card      *template.Template
+	Modes     []Mode
+	Instances map[string]Instance
 	localizer *spreak.Localizer
 	humanizer *humanize.Humanizer
+	printer   *message.Printer
+	precision precision
 }
 
 // Supported languages for humanize
@@ -83,6 +443,22 @@ var i18nVars = map[string]localize.MsgID{
 	"sunrise":         "Sunrise",
 	"sunset":          "Sunset",
 	"moonphase":       "Moonphase",
+	"lastknown":       "last known",
+	"precipends":      "Precipitation ends",
+	"via":             "via",
+	"weekend":         "Weekend",
+	"tomorrow":        "Tomorrow",
+	"likely":          "likely",
+	"nodarkness":      "No darkness",
+	"midnightsun":     "Midnight sun",
+	"polarnight":      "Polar night",
+	"todaysweather":   "Today's weather",
+	"openwindows":     "Good time to open the windows",
+	"ventdry":         "Ventilating dries the air",
+	"venthumid":       "Ventilating humidifies the air",
+	"highuv":          "High UV",
+	"floodwarning":    "Flood warning",
+	"flooddanger":     "Flood danger",
 	"new moon":        "New moon",
 	"waxing crescent": "Waxing crescent",
 	"first quarter":   "First quarter",
@@ -96,6 +472,12 @@ var i18nVars = map[string]localize.MsgID{
 func NewTemplate(conf *config.Config, loc *spreak.Localizer) (*Templates, error) {
 	tpls := new(Templates)
 	tpls.localizer = loc
+	tpls.printer = message.NewPrinter(loc.Language())
+	tpls.precision = precision{
+		temperature:   conf.Templates.Precision.Temperature,
+		wind:          conf.Templates.Precision.Wind,
+		precipitation: conf.Templates.Precision.Precipitation,
+	}
 
 	tpl, err := template.New("text").Funcs(tpls.templateFuncMap()).Parse(conf.Templates.Text)
 	if err != nil {
@@ -115,6 +497,46 @@ func NewTemplate(conf *config.Config, loc *spreak.Localizer) (*Templates, error)
 	}
 	tpls.Tooltip = tpl
 
+	tpl, err = template.New("briefing").Funcs(tpls.templateFuncMap()).Parse(conf.Briefing.Template)
+	if err != nil {
+		return tpls, fmt.Errorf("failed to parse briefing template: %w", err)
+	}
+	tpls.Briefing = tpl
+
+	tpls.Card = tpls.Tooltip
+	if conf.WeatherCard.Template != "" {
+		tpl, err = template.New("weather_card").Funcs(tpls.templateFuncMap()).Parse(conf.WeatherCard.Template)
+		if err != nil {
+			return tpls, fmt.Errorf("failed to parse weather card template: %w", err)
+		}
+		tpls.Card = tpl
+	}
+
+	tpls.Modes = []Mode{{Name: ModeCurrent, Template: tpls.Text}, {Name: ModeHourly, Template: tpls.AltText}}
+	optional := []struct {
+		name string
+		text string
+	}{{ModeDaily, conf.Templates.Daily}, {ModeAstro, conf.Templates.Astro}}
+	for _, mode := range optional {
+		if mode.text == "" {
+			continue
+		}
+		tpl, err = template.New(mode.name).Funcs(tpls.templateFuncMap()).Parse(mode.text)
+		if err != nil {
+			return tpls, fmt.Errorf("failed to parse %s template: %w", mode.name, err)
+		}
+		tpls.Modes = append(tpls.Modes, Mode{Name: mode.name, Template: tpl})
+	}
+
+	tpls.Instances = make(map[string]Instance, len(conf.Instances))
+	for name, instance := range conf.Instances {
+		parsed, err := tpls.newInstance(name, instance)
+		if err != nil {
+			return tpls, err
+		}
+		tpls.Instances[name] = parsed
+	}
+
 	collection, err := humanize.New(humanize.WithLocale(supportedHumanizers...))
 	if err != nil {
 		return tpls, fmt.Errorf("failed to create humanizer: %w", err)
@@ -124,11 +546,44 @@ func NewTemplate(conf *config.Config, loc *spreak.Localizer) (*Templates, error)
 	return tpls, nil
 }
 
+// newInstance parses the templates of an additional module instance. Templates that are not set by the
+// instance are taken from the global templates.
+func (t *Templates) newInstance(name string, conf config.Instance) (Instance, error) {
+	instance := Instance{Tooltip: t.Tooltip, Modes: slices.Clone(t.Modes)}
+	texts := map[string]string{ModeCurrent: conf.Text, ModeHourly: conf.AltText, ModeDaily: conf.Daily,
+		ModeAstro: conf.Astro}
+	for i, mode := range instance.Modes {
+		if texts[mode.Name] == "" {
+			continue
+		}
+		tpl, err := template.New(name + "_" + mode.Name).Funcs(t.templateFuncMap()).Parse(texts[mode.Name])
+		if err != nil {
+			return instance, fmt.Errorf("failed to parse %s template of instance %s: %w", mode.Name, name, err)
+		}
+		instance.Modes[i].Template = tpl
+	}
+	if conf.Tooltip != "" {
+		tpl, err := template.New(name + "_tooltip").Funcs(t.templateFuncMap()).Parse(conf.Tooltip)
+		if err != nil {
+			return instance, fmt.Errorf("failed to parse tooltip template of instance %s: %w", name, err)
+		}
+		instance.Tooltip = tpl
+	}
+	return instance, nil
+}
+
 func (t *Templates) templateFuncMap() template.FuncMap {
 	return template.FuncMap{
 		"timeFormat":    t.timeFormat,
 		"localizedTime": t.localizedTime,
+		"localizedDate": t.localizedDate,
 		"floatFormat":   t.floatFormat,
+		"numberFormat":  t.NumberFormat,
+		"coordFormat":   coordinate.Format,
+		"after":         t.after,
+		"before":        t.before,
+		"between":       t.between,
+		"daySummary":    t.daySummary,
 		"loc":           t.loc,
 		"lc":            strings.ToLower,
 		"uc":            strings.ToUpper,
@@ -147,12 +602,82 @@ func (t *Templates) localizedTime(val time.Time) string {
 	return t.humanizer.FormatTime(val, humanize.TimeFormat)
 }
 
+// localizedDate formats the month and day of val in the format of the configured locale.
+func (t *Templates) localizedDate(val time.Time) string {
+	return t.humanizer.FormatTime(val, humanize.MonthDayFormat)
+}
+
 func (t *Templates) timeFormat(val time.Time, fmt string) string {
 	return val.Format(fmt)
 }
 
+// after returns true if the time of day of val is at or after the given clock time (HH:MM).
+func (t *Templates) after(val time.Time, clock string) (bool, error) {
+	minutes, err := clockMinutes(clock)
+	if err != nil {
+		return false, err
+	}
+	return val.Hour()*60+val.Minute() >= minutes, nil
+}
+
+// before returns true if the time of day of val is before the given clock time (HH:MM).
+func (t *Templates) before(val time.Time, clock string) (bool, error) {
+	isAfter, err := t.after(val, clock)
+	return !isAfter, err
+}
+
+// between returns true if the time of day of val is within the given clock times (HH:MM). If the end
+// is before the start, the range wraps around midnight.
+func (t *Templates) between(val time.Time, start, end string) (bool, error) {
+	afterStart, err := t.after(val, start)
+	if err != nil {
+		return false, err
+	}
+	beforeEnd, err := t.before(val, end)
+	if err != nil {
+		return false, err
+	}
+	startMinutes, _ := clockMinutes(start)
+	endMinutes, _ := clockMinutes(end)
+	if endMinutes < startMinutes {
+		return afterStart || beforeEnd, nil
+	}
+	return afterStart && beforeEnd, nil
+}
+
+// daySummary condenses the given daily weather data into a single line with the localized short day
+// name, the condition icon and the maximum temperature of each day (e.g. "Sat ☀️ 18°, Sun 🌧️ 12°").
+func (t *Templates) daySummary(days []DailyData) string {
+	parts := make([]string, 0, len(days))
+	digits := decimals(t.precision.temperature, 0)
+	for _, day := range days {
+		if !day.Available {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %.*f°", t.humanizer.FormatTime(day.Date, "D"),
+			day.ConditionIcon, digits, Round(day.TemperatureMax, digits)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// clockMinutes parses a clock time in the format HH:MM and returns the minutes since midnight.
+func clockMinutes(clock string) (int, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid clock time %q: %w", clock, err)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// floatFormat formats the number with the given precision, rounded half away from zero.
 func (t *Templates) floatFormat(val float64, precision int) string {
-	return fmt.Sprintf("%.*f", precision, val)
+	return fmt.Sprintf("%.*f", precision, Round(val, precision))
+}
+
+// NumberFormat formats the number with the given precision, rounded half away from zero, and the decimal
+// and grouping separators of the language.
+func (t *Templates) NumberFormat(val float64, precision int) string {
+	return t.printer.Sprintf("%.*f", precision, Round(val, precision))
 }
 
 func (t *Templates) EmojiWithSpace(emoji string) string {
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package weatherbar
+
+import (
+	"context"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+)
+
+// Location is a geolocation result of a Provider.
+type Location struct {
+	Latitude  float64
+	Longitude float64
+	// Altitude in meters, nil if unknown
+	Altitude *float64
+	// Accuracy radius in meters
+	Accuracy float64
+	// Time of the result, the current time is used if zero
+	Time time.Time
+	// Time after which the result is considered outdated, 0 keeps it until the next result
+	TTL time.Duration
+}
+
+// Provider provides the location of the device, e.g. from a positioning service of the embedding
+// application. The service uses the most accurate location of all providers.
+type Provider interface {
+	// Name returns the name of the provider, which is shown as location source.
+	Name() string
+	// Locate streams the locations until the context ends. The stream is restarted with a backoff once
+	// the channel has been closed. Returning nil counts as a failed lookup. A panic in a goroutine started
+	// by Locate terminates the process, so the provider has to recover it.
+	Locate(ctx context.Context) <-chan Location
+}
+
+// providerAdapter adapts a Provider to the geolocation providers of the service.
+type providerAdapter struct {
+	Provider
+}
+
+// LookupStream converts the locations of the provider into geolocation results for the given key.
+func (p providerAdapter) LookupStream(ctx context.Context, key string) <-chan geobus.Result {
+	locations := p.Locate(ctx)
+	if locations == nil {
+		return nil
+	}
+
+	results := make(chan geobus.Result)
+	go func() {
+		defer close(results)
+		for location := range locations {
+			at := location.Time
+			if at.IsZero() {
+				at = time.Now()
+			}
+			result := geobus.Result{
+				Key:            key,
+				Lat:            location.Latitude,
+				Lon:            location.Longitude,
+				AccuracyMeters: location.Accuracy,
+				Source:         p.Name(),
+				At:             at,
+				TTL:            location.TTL,
+			}
+			if location.Altitude != nil {
+				result.Alt, result.HasAlt = *location.Altitude, true
+			}
+			select {
+			case results <- result:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return results
+}
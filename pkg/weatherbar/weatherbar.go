@@ -0,0 +1,292 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package weatherbar provides the weather, geolocation and formatting core of waybar-weather as a Go API,
+// so that other bar projects can embed it instead of running the waybar-weather binary.
+//
+// A minimal embedding loads the configuration, creates a Service with an output handler and runs it:
+//
+//	conf, err := weatherbar.LoadConfig("")
+//	if err != nil {
+//		return err
+//	}
+//	svc, err := weatherbar.New(conf, weatherbar.WithOutput(func(out weatherbar.Output) {
+//		fmt.Println(out.Text)
+//	}))
+//	if err != nil {
+//		return err
+//	}
+//	return svc.Run(ctx)
+package weatherbar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/config"
+	"github.com/wneessen/waybar-weather/internal/i18n"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/service"
+)
+
+// Config is the configuration of the service. The settings are documented in the example configuration
+// file and can be overridden by the environment variables prefixed with WAYBARWEATHER_.
+type Config struct {
+	conf *config.Config
+}
+
+// Output is the module output of a single update: the text, the alternate text (the name of the display
+// mode), the tooltip and the CSS classes.
+type Output struct {
+	Text    string   `json:"text"`
+	Alt     string   `json:"alt"`
+	Tooltip string   `json:"tooltip"`
+	Class   []string `json:"class"`
+	// Percentage is nil unless it is configured
+	Percentage *int `json:"percentage,omitempty"`
+}
+
+// Status is the state of the running service.
+type Status struct {
+	Started   time.Time
+	Location  LocationStatus
+	Providers []ProviderStatus
+	Weather   WeatherStatus
+}
+
+// LocationStatus is the current location. The coordinates and the address are reduced according to the
+// log_coordinates setting.
+type LocationStatus struct {
+	Available   bool
+	Coordinates string
+	Address     string
+	Source      string
+	// Accuracy radius in meters
+	Accuracy  float64
+	UpdatedAt time.Time
+}
+
+// ProviderStatus is the latest result of a geolocation provider.
+type ProviderStatus struct {
+	Name       string
+	LastResult time.Time
+	// Accuracy radius in meters of the latest result
+	Accuracy float64
+	// ExcludedUntil is set while the provider is excluded because it failed its probe
+	ExcludedUntil time.Time
+}
+
+// WeatherStatus is the state of the weather data.
+type WeatherStatus struct {
+	// Source is the name of the active weather backend
+	Source      string
+	LastAttempt time.Time
+	LastSuccess time.Time
+	LastError   string
+	// DataTime is the time of the current weather data
+	DataTime time.Time
+}
+
+// Service fetches the weather data for the current location and renders it with the configured templates.
+type Service interface {
+	// Run starts the service and blocks until the context ends.
+	Run(ctx context.Context) error
+	// Status returns the state of the service.
+	Status() Status
+}
+
+// Formatter post-processes the output of every update before it is delivered to the output handler,
+// e.g. to convert it to the markup of another bar.
+type Formatter interface {
+	Format(out Output) Output
+}
+
+// FormatterFunc is a function that implements Formatter.
+type FormatterFunc func(out Output) Output
+
+// Format calls f(out).
+func (f FormatterFunc) Format(out Output) Output {
+	return f(out)
+}
+
+// Option configures the Service returned by New.
+type Option func(*options)
+
+// options holds the settings of the Service applied by the options.
+type options struct {
+	logLevel   *slog.Level
+	handler    func(Output)
+	formatters []Formatter
+	providers  []Provider
+	clock      func() time.Time
+}
+
+// WithLogLevel overrides the log level of the configuration.
+func WithLogLevel(level slog.Level) Option {
+	return func(o *options) {
+		o.logLevel = &level
+	}
+}
+
+// WithOutput delivers the output of every update to the handler instead of writing it to stdout. The
+// handler is called from a single goroutine; if it blocks, outdated updates are dropped.
+func WithOutput(handler func(Output)) Option {
+	return func(o *options) {
+		o.handler = handler
+	}
+}
+
+// WithFormatter adds a formatter, which is applied to the output before it is delivered. Formatters are
+// applied in the order they were added.
+func WithFormatter(f Formatter) Option {
+	return func(o *options) {
+		o.formatters = append(o.formatters, f)
+	}
+}
+
+// WithProvider adds a geolocation provider to the providers enabled in the configuration.
+func WithProvider(p Provider) Option {
+	return func(o *options) {
+		o.providers = append(o.providers, p)
+	}
+}
+
+// WithClock replaces the clock the displayed data is based on. Together with a fixed location and cached
+// weather data, this renders reproducible output, e.g. for snapshot tests of custom templates.
+func WithClock(clock func() time.Time) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// LoadConfig loads the configuration from the given file. If path is empty, the defaults and the
+// environment are used.
+func LoadConfig(path string) (*Config, error) {
+	var conf *config.Config
+	var err error
+	if path == "" {
+		conf, err = config.New()
+	} else {
+		conf, err = config.NewFromFile(filepath.Dir(path), filepath.Base(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Config{conf: conf}, nil
+}
+
+// New returns a new Service for the given configuration. The formatters are applied to the output
+// delivered to the output handler or, without WithOutput, to the output written to stdout.
+func New(cfg *Config, opts ...Option) (Service, error) {
+	if cfg == nil || cfg.conf == nil {
+		return nil, errors.New("configuration is missing, use LoadConfig")
+	}
+	conf := cfg.conf
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	level := conf.LogLevel
+	if o.logLevel != nil {
+		level = *o.logLevel
+	}
+	log := logger.NewLogger(level)
+	log.SetPrivacy(conf.LogCoordinates)
+
+	t, err := i18n.New(conf.Locale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize localizer: %w", err)
+	}
+	serv, err := service.New(conf, log, t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize service: %w", err)
+	}
+
+	if o.handler != nil {
+		serv.SetOutput(&handlerWriter{handler: o.handler})
+	}
+	if len(o.formatters) > 0 {
+		serv.SetFormatter(func(out service.Output) service.Output {
+			formatted := publicOutput(out)
+			for _, formatter := range o.formatters {
+				formatted = formatter.Format(formatted)
+			}
+			return service.Output(formatted)
+		})
+	}
+	for _, provider := range o.providers {
+		serv.AddProvider(providerAdapter{provider})
+	}
+	if o.clock != nil {
+		serv.SetClock(o.clock)
+	}
+	return &embeddedService{serv: serv}, nil
+}
+
+// embeddedService implements the Service interface with the service of waybar-weather.
+type embeddedService struct {
+	serv *service.Service
+}
+
+// Run starts the service and blocks until the context ends.
+func (e *embeddedService) Run(ctx context.Context) error {
+	return e.serv.Run(ctx)
+}
+
+// Status returns the state of the service.
+func (e *embeddedService) Status() Status {
+	internal := e.serv.Status()
+	status := Status{
+		Started: internal.Started,
+		Location: LocationStatus{
+			Available:   internal.Location.Available,
+			Coordinates: internal.Location.Coordinates,
+			Address:     internal.Location.Address,
+			Source:      internal.Location.Source,
+			Accuracy:    internal.Location.Accuracy,
+			UpdatedAt:   internal.Location.UpdatedAt,
+		},
+		Weather: WeatherStatus{
+			Source:      internal.Weather.Source,
+			LastAttempt: internal.Weather.LastAttempt,
+			LastSuccess: internal.Weather.LastSuccess,
+			LastError:   internal.Weather.LastError,
+			DataTime:    internal.Weather.DataTime,
+		},
+	}
+	for _, provider := range internal.Providers {
+		status.Providers = append(status.Providers, ProviderStatus{
+			Name:          provider.Name,
+			LastResult:    provider.LastResult,
+			Accuracy:      provider.Accuracy,
+			ExcludedUntil: provider.ExcludedUntil,
+		})
+	}
+	return status
+}
+
+// publicOutput converts the output of the service into an Output.
+func publicOutput(out service.Output) Output {
+	return Output(out)
+}
+
+// handlerWriter delivers the output to the handler.
+type handlerWriter struct {
+	handler func(Output)
+}
+
+// Write implements the output writer of the service.
+func (w *handlerWriter) Write(v any) error {
+	out, ok := v.(service.Output)
+	if !ok {
+		return fmt.Errorf("unexpected output type %T", v)
+	}
+	w.handler(publicOutput(out))
+	return nil
+}